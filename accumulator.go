@@ -2,9 +2,13 @@ package telegraf
 
 import (
 	"fmt"
+	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/influxdb/telegraf/outputs"
+
 	"github.com/influxdb/influxdb/client/v2"
 )
 
@@ -13,6 +17,7 @@ type Accumulator interface {
 		tags map[string]string, t ...time.Time)
 	AddFields(measurement string, fields map[string]interface{},
 		tags map[string]string, t ...time.Time)
+	AddEvent(title, text string, tags map[string]string, t ...time.Time)
 
 	SetDefaultTags(tags map[string]string)
 	AddDefaultTag(key, value string)
@@ -22,6 +27,8 @@ type Accumulator interface {
 
 	Debug() bool
 	SetDebug(enabled bool)
+
+	SetMaxMetrics(max int)
 }
 
 func NewAccumulator(
@@ -46,6 +53,11 @@ type accumulator struct {
 	plugin *ConfiguredPlugin
 
 	prefix string
+
+	// maxMetrics is the number of points accepted before the following ones
+	// are dropped, 0 means no limit
+	maxMetrics int
+	metrics    int
 }
 
 func (ac *accumulator) Add(
@@ -54,9 +66,34 @@ func (ac *accumulator) Add(
 	tags map[string]string,
 	t ...time.Time,
 ) {
+	// the name is the field reported by the plugin, whichever of the
+	// measurement or field it is stored as
+	if ac.plugin != nil && !ac.plugin.ShouldPassField(measurement) {
+		return
+	}
+
+	if ac.plugin != nil && (ac.plugin.NameAsField || ac.plugin.NameOverride != "") {
+		// measurement is the field of the plugin's measurement, ie
+		// "rethinkdb queries_per_sec=7" rather than
+		// "rethinkdb_queries_per_sec value=7"
+		if !ac.plugin.ShouldPass(measurement, tags) {
+			return
+		}
+		pluginMeasurement := ac.plugin.NameOverride
+		if pluginMeasurement == "" {
+			pluginMeasurement = strings.TrimSuffix(ac.prefix, "_")
+		}
+		if pluginMeasurement == "" {
+			pluginMeasurement = ac.plugin.Name
+		}
+		fields := map[string]interface{}{measurement: value}
+		ac.addFields(pluginMeasurement, fields, tags, true, t...)
+		return
+	}
+
 	fields := make(map[string]interface{})
 	fields["value"] = value
-	ac.AddFields(measurement, fields, tags, t...)
+	ac.addFields(measurement, fields, tags, false, t...)
 }
 
 func (ac *accumulator) AddFields(
@@ -65,6 +102,38 @@ func (ac *accumulator) AddFields(
 	tags map[string]string,
 	t ...time.Time,
 ) {
+	if ac.plugin != nil && (ac.plugin.FieldPass != nil || ac.plugin.FieldDrop != nil) {
+		passed := make(map[string]interface{}, len(fields))
+		for k, v := range fields {
+			if ac.plugin.ShouldPassField(k) {
+				passed[k] = v
+			}
+		}
+		if len(passed) == 0 {
+			return
+		}
+		fields = passed
+	}
+
+	if ac.plugin != nil && ac.plugin.NameOverride != "" {
+		if !ac.plugin.ShouldPass(measurement, tags) {
+			return
+		}
+		ac.addFields(ac.plugin.NameOverride, fields, tags, true, t...)
+		return
+	}
+	ac.addFields(measurement, fields, tags, false, t...)
+}
+
+// addFields sends the point of measurement, which is filtered and prefixed
+// unless raw is set
+func (ac *accumulator) addFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	raw bool,
+	t ...time.Time,
+) {
 
 	if tags == nil {
 		tags = make(map[string]string)
@@ -81,6 +150,9 @@ func (ac *accumulator) AddFields(
 			} else {
 				fields[k] = int64(9223372036854775807)
 			}
+		case []byte:
+			// the line protocol only quotes string fields
+			fields[k] = string(val)
 		}
 	}
 
@@ -91,7 +163,7 @@ func (ac *accumulator) AddFields(
 		timestamp = time.Now()
 	}
 
-	if ac.plugin != nil {
+	if ac.plugin != nil && !raw {
 		if !ac.plugin.ShouldPass(measurement, tags) {
 			return
 		}
@@ -103,8 +175,18 @@ func (ac *accumulator) AddFields(
 		}
 	}
 
-	if ac.prefix != "" {
-		measurement = ac.prefix + measurement
+	if ac.prefix != "" && !raw {
+		if measurement == "" {
+			// a plugin reporting a single measurement names it after
+			// itself, ie "zookeeper" rather than "zookeeper_"
+			measurement = strings.TrimSuffix(ac.prefix, "_")
+		} else {
+			measurement = ac.prefix + measurement
+		}
+	}
+
+	if !ac.accept() {
+		return
 	}
 
 	pt := client.NewPoint(measurement, tags, fields, timestamp)
@@ -114,6 +196,71 @@ func (ac *accumulator) AddFields(
 	ac.points <- pt
 }
 
+// AddEvent sends an event as a point of the events measurement, which is not
+// prefixed. The event is tagged with the name of the plugin as its source.
+func (ac *accumulator) AddEvent(
+	title string,
+	text string,
+	tags map[string]string,
+	t ...time.Time,
+) {
+	eventTags := make(map[string]string)
+	for k, v := range tags {
+		eventTags[k] = v
+	}
+	if ac.prefix != "" {
+		eventTags["source"] = strings.TrimSuffix(ac.prefix, "_")
+	}
+
+	if ac.plugin != nil {
+		if !ac.plugin.ShouldPass(outputs.EventMeasurement, eventTags) {
+			return
+		}
+	}
+
+	for k, v := range ac.defaultTags {
+		if _, ok := eventTags[k]; !ok {
+			eventTags[k] = v
+		}
+	}
+
+	event := outputs.Event{Title: title, Text: text, Tags: eventTags, Time: time.Now()}
+	if len(t) > 0 {
+		event.Time = t[0]
+	}
+
+	if !ac.accept() {
+		return
+	}
+
+	pt := outputs.NewEventPoint(event)
+	if ac.debug {
+		fmt.Println("> " + pt.String())
+	}
+	ac.points <- pt
+}
+
+// accept counts a point about to be sent, returning false once maxMetrics
+// points were sent. A warning is logged for the first dropped point.
+func (ac *accumulator) accept() bool {
+	ac.Lock()
+	defer ac.Unlock()
+	if ac.maxMetrics > 0 && ac.metrics >= ac.maxMetrics {
+		if ac.metrics == ac.maxMetrics {
+			name := strings.TrimSuffix(ac.prefix, "_")
+			if ac.plugin != nil {
+				name = ac.plugin.Name
+			}
+			log.Printf("WARNING: plugin [%s] reached max_metrics_per_gather (%d), "+
+				"dropping its points until the next collection\n", name, ac.maxMetrics)
+			ac.metrics++
+		}
+		return false
+	}
+	ac.metrics++
+	return true
+}
+
 func (ac *accumulator) SetDefaultTags(tags map[string]string) {
 	ac.defaultTags = tags
 }
@@ -137,3 +284,7 @@ func (ac *accumulator) Debug() bool {
 func (ac *accumulator) SetDebug(debug bool) {
 	ac.debug = debug
 }
+
+func (ac *accumulator) SetMaxMetrics(max int) {
+	ac.maxMetrics = max
+}
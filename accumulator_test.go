@@ -0,0 +1,240 @@
+package telegraf
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/outputs"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccumulator_StringAndBoolFields(t *testing.T) {
+	points := make(chan *client.Point, 1)
+	acc := NewAccumulator(&ConfiguredPlugin{Name: "rethinkdb"}, points)
+
+	acc.AddFields("server",
+		map[string]interface{}{
+			"version": `rethinkdb 2.1.5 "Forbidden Planet"`,
+			"role":    []byte("data"),
+			"ready":   true,
+			"uptime":  int64(10),
+		},
+		map[string]string{"host": "localhost"},
+		time.Unix(0, 0),
+	)
+
+	require.Len(t, points, 1)
+	pt := <-points
+	assert.Equal(t, "data", pt.Fields()["role"])
+	assert.Equal(t, true, pt.Fields()["ready"])
+	assert.Equal(t,
+		`server,host=localhost ready=true,role="data",uptime=10i,`+
+			`version="rethinkdb 2.1.5 \"Forbidden Planet\"" 0`,
+		pt.String())
+}
+
+func TestAccumulator_AddString(t *testing.T) {
+	points := make(chan *client.Point, 1)
+	acc := NewAccumulator(&ConfiguredPlugin{Name: "test"}, points)
+
+	acc.Add("status", "ok", nil, time.Unix(0, 0))
+
+	require.Len(t, points, 1)
+	assert.Equal(t, `status value="ok" 0`, (<-points).String())
+}
+
+func TestAccumulator_AddNameAsMeasurement(t *testing.T) {
+	points := make(chan *client.Point, 1)
+	acc := NewAccumulator(&ConfiguredPlugin{Name: "rethinkdb"}, points)
+	acc.SetPrefix("rethinkdb_")
+
+	acc.Add("queries_per_sec", 7, map[string]string{"type": "member"}, time.Unix(0, 0))
+
+	require.Len(t, points, 1)
+	assert.Equal(t, `rethinkdb_queries_per_sec,type=member value=7i 0`, (<-points).String())
+}
+
+func TestAccumulator_AddNameAsField(t *testing.T) {
+	points := make(chan *client.Point, 3)
+	acc := NewAccumulator(&ConfiguredPlugin{
+		Name:        "rethinkdb",
+		NameAsField: true,
+		Drop:        []string{"read_docs"},
+	}, points)
+	acc.SetPrefix("rethinkdb_")
+
+	acc.Add("queries_per_sec", 7, map[string]string{"type": "member"}, time.Unix(0, 0))
+	// pass and drop are still tested against the name
+	acc.Add("read_docs_per_sec", 2, nil, time.Unix(0, 0))
+	// points of several fields are not changed
+	acc.AddFields("server", map[string]interface{}{"ready": true}, nil, time.Unix(0, 0))
+
+	require.Len(t, points, 2)
+	assert.Equal(t, `rethinkdb,type=member queries_per_sec=7i 0`, (<-points).String())
+	assert.Equal(t, `rethinkdb_server ready=true 0`, (<-points).String())
+}
+
+func TestAccumulator_NameOverride(t *testing.T) {
+	points := make(chan *client.Point, 4)
+	acc := NewAccumulator(&ConfiguredPlugin{
+		Name:         "rethinkdb",
+		NameOverride: "rdb",
+		Drop:         []string{"connection"},
+	}, points)
+	acc.SetPrefix("rethinkdb_")
+	tags := map[string]string{"type": "member"}
+
+	acc.Add("queries_per_sec", 7, tags, time.Unix(0, 0))
+	acc.Add("clients", 2, tags, time.Unix(0, 0))
+	acc.AddFields("server", map[string]interface{}{"ready": true}, nil, time.Unix(0, 0))
+	// pass and drop are still tested against the original names
+	acc.AddFields("connection",
+		map[string]interface{}{"connection_time_ms": 1.5}, nil, time.Unix(0, 0))
+
+	require.Len(t, points, 3)
+	assert.Equal(t, `rdb,type=member queries_per_sec=7i 0`, (<-points).String())
+	assert.Equal(t, `rdb,type=member clients=2i 0`, (<-points).String())
+	assert.Equal(t, `rdb ready=true 0`, (<-points).String())
+}
+
+func TestAccumulator_FieldPass(t *testing.T) {
+	points := make(chan *client.Point, 4)
+	acc := NewAccumulator(&ConfiguredPlugin{
+		Name:      "rethinkdb",
+		FieldPass: []string{"queries_per_sec", "ready"},
+	}, points)
+	acc.SetPrefix("rethinkdb_")
+
+	acc.Add("queries_per_sec", 7, nil, time.Unix(0, 0))
+	acc.Add("disk_read_bytes_per_sec", 1024, nil, time.Unix(0, 0))
+	acc.AddFields("server",
+		map[string]interface{}{"ready": true, "uptime": int64(10)}, nil, time.Unix(0, 0))
+	acc.AddFields("connection",
+		map[string]interface{}{"connection_time_ms": 1.5}, nil, time.Unix(0, 0))
+
+	require.Len(t, points, 2)
+	assert.Equal(t, `rethinkdb_queries_per_sec value=7i 0`, (<-points).String())
+	assert.Equal(t, `rethinkdb_server ready=true 0`, (<-points).String())
+}
+
+func TestAccumulator_FieldDrop(t *testing.T) {
+	points := make(chan *client.Point, 3)
+	acc := NewAccumulator(&ConfiguredPlugin{
+		Name:        "rethinkdb",
+		FieldDrop:   []string{"disk_"},
+		NameAsField: true,
+	}, points)
+	acc.SetPrefix("rethinkdb_")
+
+	acc.Add("queries_per_sec", 7, nil, time.Unix(0, 0))
+	acc.Add("disk_read_bytes_per_sec", 1024, nil, time.Unix(0, 0))
+	acc.AddFields("server", map[string]interface{}{"disk_usage": int64(10)}, nil, time.Unix(0, 0))
+
+	require.Len(t, points, 1)
+	assert.Equal(t, `rethinkdb queries_per_sec=7i 0`, (<-points).String())
+}
+
+func TestAccumulator_EmptyMeasurementUsesPrefix(t *testing.T) {
+	points := make(chan *client.Point, 2)
+	acc := NewAccumulator(&ConfiguredPlugin{Name: "zookeeper"}, points)
+	acc.SetPrefix("zookeeper_")
+
+	acc.AddFields("", map[string]interface{}{"znode_count": 4}, nil, time.Unix(0, 0))
+	acc.Add("version", "3.4.6", nil, time.Unix(0, 0))
+
+	require.Len(t, points, 2)
+	assert.Equal(t, "zookeeper", (<-points).Name())
+	assert.Equal(t, "zookeeper_version", (<-points).Name())
+}
+
+func TestAccumulator_AddEvent(t *testing.T) {
+	points := make(chan *client.Point, 1)
+	acc := NewAccumulator(&ConfiguredPlugin{Name: "webhooks"}, points)
+	acc.SetPrefix("webhooks_")
+	acc.SetDefaultTags(map[string]string{"host": "server01"})
+
+	tags := map[string]string{"environment": "production"}
+	acc.AddEvent("Deployed api", "Version 1.2.3", tags, time.Unix(0, 0))
+	// the tags of the caller are left untouched
+	assert.Equal(t, map[string]string{"environment": "production"}, tags)
+
+	require.Len(t, points, 1)
+	assert.Equal(t,
+		`events,environment=production,host=server01,source=webhooks `+
+			`text="Version 1.2.3",title="Deployed api" 0`,
+		(<-points).String())
+}
+
+func TestAccumulator_AddEventFiltered(t *testing.T) {
+	points := make(chan *client.Point, 1)
+	acc := NewAccumulator(&ConfiguredPlugin{Name: "webhooks", Drop: []string{"events"}}, points)
+
+	acc.AddEvent("Deployed api", "", nil)
+	assert.Len(t, points, 0)
+}
+
+func TestAccumulator_EventReachesOutput(t *testing.T) {
+	points := make(chan *client.Point, 1)
+	ri := &RunningInput{Name: "webhooks", Config: &ConfiguredPlugin{Name: "webhooks"}}
+	acc := ri.Accumulator(points, nil, false)
+
+	now := time.Unix(1136214245, 0)
+	acc.AddEvent("Deployed api", "Version 1.2.3",
+		map[string]string{"environment": "production"}, now)
+
+	out := &failingOutput{}
+	ro := NewRunningOutput("capture", out, nil)
+	ro.AddPoint(<-points)
+	require.NoError(t, ro.Write())
+
+	require.Len(t, out.written, 1)
+	event, ok := outputs.ParseEvent(out.written[0])
+	require.True(t, ok)
+	assert.Equal(t, outputs.Event{
+		Title: "Deployed api",
+		Text:  "Version 1.2.3",
+		Tags:  map[string]string{"environment": "production", "source": "webhooks"},
+		Time:  now,
+	}, event)
+}
+
+func TestAccumulator_MaxMetrics(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	points := make(chan *client.Point, 10)
+	acc := NewAccumulator(&ConfiguredPlugin{Name: "rethinkdb", Drop: []string{"dropped"}}, points)
+	acc.SetMaxMetrics(2)
+
+	// points dropped by the filters do not count
+	acc.Add("dropped", 1, nil)
+	acc.Add("first", 1, nil)
+	acc.AddFields("second", map[string]interface{}{"a": 1, "b": 2}, nil)
+	acc.Add("third", 1, nil)
+	acc.AddEvent("fourth", "", nil)
+
+	require.Len(t, points, 2)
+	assert.Equal(t, "first", (<-points).Name())
+	assert.Equal(t, "second", (<-points).Name())
+
+	assert.Equal(t, 1, strings.Count(logs.String(), "WARNING"))
+	assert.Contains(t, logs.String(), "plugin [rethinkdb] reached max_metrics_per_gather (2)")
+}
+
+func TestAccumulator_NoMaxMetrics(t *testing.T) {
+	points := make(chan *client.Point, 10)
+	acc := NewAccumulator(&ConfiguredPlugin{Name: "rethinkdb"}, points)
+
+	for i := 0; i < 10; i++ {
+		acc.Add("clients", i, nil)
+	}
+	assert.Len(t, points, 10)
+}
@@ -1,50 +1,71 @@
 package telegraf
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/influxdb/telegraf/duration"
+	"github.com/influxdb/telegraf/internal"
 	"github.com/influxdb/telegraf/outputs"
 	"github.com/influxdb/telegraf/plugins"
 
 	"github.com/influxdb/influxdb/client/v2"
 )
 
-type runningOutput struct {
-	name   string
-	output outputs.Output
-}
-
-type runningPlugin struct {
-	name   string
-	plugin plugins.Plugin
-	config *ConfiguredPlugin
-}
-
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 
 	// Interval at which to gather information
-	Interval duration.Duration
+	Interval internal.Duration
 
 	// RoundInterval rounds collection interval to 'interval'.
 	//     ie, if Interval=10s then always collect on :00, :10, :20, etc.
 	RoundInterval bool
 
 	// Interval at which to flush data
-	FlushInterval duration.Duration
+	FlushInterval internal.Duration
 
 	// FlushRetries is the number of times to retry each data flush
 	FlushRetries int
 
 	// FlushJitter tells
-	FlushJitter duration.Duration
+	FlushJitter internal.Duration
+
+	// MetricBufferLimit is the maximum number of points buffered for each
+	// output between flushes, 0 means no limit
+	MetricBufferLimit int
+
+	// Dedup drops points that repeat the previous point of their series.
+	// An unchanged point is still written once every DedupInterval, which
+	// defaults to and can not exceed FlushInterval.
+	Dedup         bool
+	DedupInterval internal.Duration
+
+	// MaxMetricsPerGather is the maximum number of points accepted from a
+	// single Gather of each plugin, 0 means no limit
+	MaxMetricsPerGather int
+
+	// StartupWait is how long plugins implementing plugins.ReadyPlugin are
+	// waited for before the first collection, zero means no wait
+	StartupWait internal.Duration
+
+	// SkipFirstInterval drops the points of the first collection of every
+	// plugin, which has no previous sample to compute rates from
+	SkipFirstInterval bool
+
+	// ExpvarAddress is the address the expvar counters of the agent are
+	// served on, see serveExpvar. Empty means they are not served.
+	ExpvarAddress string
+
+	// WriteRateLimit is the maximum number of points written per second to
+	// each output, 0 means no limit
+	WriteRateLimit int
 
 	// TODO(cam): Remove UTC and Precision parameters, they are no longer
 	// valid for the agent config. Leaving them here for now for backwards-
@@ -58,24 +79,28 @@ type Agent struct {
 	Precision string
 
 	// Option for running in debug mode
-	Debug    bool
-	Hostname string
+	Debug bool
+
+	// Hostname is the host tag added to every point, the OS hostname by
+	// default. OmitHostname leaves the host tag out entirely.
+	Hostname     string
+	OmitHostname bool
 
 	Tags map[string]string
 
-	outputs []*runningOutput
-	plugins []*runningPlugin
+	outputs []*RunningOutput
+	plugins []*RunningInput
 }
 
 // NewAgent returns an Agent struct based off the given Config
 func NewAgent(config *Config) (*Agent, error) {
 	agent := &Agent{
 		Tags:          make(map[string]string),
-		Interval:      duration.Duration{10 * time.Second},
+		Interval:      internal.Duration{Duration: 10 * time.Second},
 		RoundInterval: true,
-		FlushInterval: duration.Duration{10 * time.Second},
+		FlushInterval: internal.Duration{Duration: 10 * time.Second},
 		FlushRetries:  2,
-		FlushJitter:   duration.Duration{5 * time.Second},
+		FlushJitter:   internal.Duration{Duration: 5 * time.Second},
 	}
 
 	// Apply the toml table to the agent config, overriding defaults
@@ -84,6 +109,10 @@ func NewAgent(config *Config) (*Agent, error) {
 		return nil, err
 	}
 
+	if agent.OmitHostname {
+		return agent, nil
+	}
+
 	if agent.Hostname == "" {
 		hostname, err := os.Hostname()
 		if err != nil {
@@ -102,19 +131,19 @@ func NewAgent(config *Config) (*Agent, error) {
 func (a *Agent) Connect() error {
 	for _, o := range a.outputs {
 		if a.Debug {
-			log.Printf("Attempting connection to output: %s\n", o.name)
+			log.Printf("Attempting connection to output: %s\n", o.Name)
 		}
-		err := o.output.Connect()
+		err := o.Output.Connect()
 		if err != nil {
-			log.Printf("Failed to connect to output %s, retrying in 15s\n", o.name)
+			log.Printf("Failed to connect to output %s, retrying in 15s\n", o.Name)
 			time.Sleep(15 * time.Second)
-			err = o.output.Connect()
+			err = o.Output.Connect()
 			if err != nil {
 				return err
 			}
 		}
 		if a.Debug {
-			log.Printf("Successfully connected to output: %s\n", o.name)
+			log.Printf("Successfully connected to output: %s\n", o.Name)
 		}
 	}
 	return nil
@@ -124,7 +153,7 @@ func (a *Agent) Connect() error {
 func (a *Agent) Close() error {
 	var err error
 	for _, o := range a.outputs {
-		err = o.output.Close()
+		err = o.Output.Close()
 	}
 	return err
 }
@@ -145,12 +174,15 @@ func (a *Agent) LoadOutputs(filters []string, config *Config) ([]string, error)
 			}
 			output := creator()
 
-			err := config.ApplyOutput(name, output)
+			oconfig, err := config.ApplyOutput(name, output)
 			if err != nil {
 				return nil, err
 			}
 
-			a.outputs = append(a.outputs, &runningOutput{name, output})
+			ro := NewRunningOutput(name, output, oconfig)
+			ro.MetricBufferLimit = a.metricBufferLimit(ro)
+			ro.WriteRateLimit = a.WriteRateLimit
+			a.outputs = append(a.outputs, ro)
 			names = append(names, name)
 		}
 	}
@@ -178,7 +210,7 @@ func (a *Agent) LoadPlugins(filters []string, config *Config) ([]string, error)
 				return nil, err
 			}
 
-			a.plugins = append(a.plugins, &runningPlugin{name, plugin, config})
+			a.plugins = append(a.plugins, NewRunningInput(name, plugin, config))
 			names = append(names, name)
 		}
 	}
@@ -196,22 +228,17 @@ func (a *Agent) gatherParallel(pointChan chan *client.Point) error {
 	start := time.Now()
 	counter := 0
 	for _, plugin := range a.plugins {
-		if plugin.config.Interval != 0 {
+		if plugin.Interval() != 0 {
 			continue
 		}
 
 		wg.Add(1)
 		counter++
-		go func(plugin *runningPlugin) {
+		go func(plugin *RunningInput) {
 			defer wg.Done()
 
-			acc := NewAccumulator(plugin.config, pointChan)
-			acc.SetDebug(a.Debug)
-			acc.SetPrefix(plugin.name + "_")
-			acc.SetDefaultTags(a.Tags)
-
-			if err := plugin.plugin.Gather(acc); err != nil {
-				log.Printf("Error in plugin [%s]: %s", plugin.name, err)
+			if err := a.collect(plugin, pointChan, a.Interval.Duration); err != nil {
+				log.Printf("Error in plugin [%s]: %s", plugin.Name, err)
 			}
 
 		}(plugin)
@@ -225,31 +252,83 @@ func (a *Agent) gatherParallel(pointChan chan *client.Point) error {
 	return nil
 }
 
+// collect runs a Gather of the plugin on its collection interval, see
+// gather. The points of the first collection are dropped when
+// SkipFirstInterval is set.
+func (a *Agent) collect(
+	plugin *RunningInput,
+	pointChan chan *client.Point,
+	interval time.Duration,
+) error {
+	if !a.SkipFirstInterval || plugin.Gathered() {
+		return a.gather(plugin, pointChan, interval)
+	}
+
+	discard := make(chan *client.Point)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range discard {
+		}
+	}()
+	err := a.gather(plugin, discard, interval)
+	close(discard)
+	<-done
+	log.Printf("Skipped the first collection of plugin [%s]\n", plugin.Name)
+	return err
+}
+
+// gatherRetryBackoff is the delay before the first retry of a failed Gather,
+// doubled before each following retry
+var gatherRetryBackoff = time.Second
+
+// gather runs a Gather of the plugin, retrying it up to the plugin's
+// gather_retries times when it fails. Retries that would start after
+// interval has elapsed since the first attempt are not run, so that a
+// plugin is never still retrying when its next collection is due.
+func (a *Agent) gather(
+	plugin *RunningInput,
+	pointChan chan *client.Point,
+	interval time.Duration,
+) error {
+	deadline := time.Now().Add(interval)
+	backoff := gatherRetryBackoff
+	for attempt := 0; ; attempt++ {
+		acc := plugin.Accumulator(pointChan, a.Tags, a.Debug)
+		acc.SetMaxMetrics(a.maxMetricsPerGather(plugin))
+		err := plugin.Gather(acc)
+		if err == nil || attempt >= plugin.Config.GatherRetries {
+			return err
+		}
+		if interval > 0 && time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+
+		log.Printf("Error in plugin [%s]: %s, retrying in %s (%d/%d)\n",
+			plugin.Name, err, backoff, attempt+1, plugin.Config.GatherRetries)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
 // gatherSeparate runs the plugins that have been configured with their own
 // reporting interval.
 func (a *Agent) gatherSeparate(
 	shutdown chan struct{},
-	plugin *runningPlugin,
+	plugin *RunningInput,
 	pointChan chan *client.Point,
 ) error {
-	ticker := time.NewTicker(plugin.config.Interval)
+	ticker := time.NewTicker(plugin.Interval())
 
 	for {
 		var outerr error
-		start := time.Now()
 
-		acc := NewAccumulator(plugin.config, pointChan)
-		acc.SetDebug(a.Debug)
-		acc.SetPrefix(plugin.name + "_")
-		acc.SetDefaultTags(a.Tags)
-
-		if err := plugin.plugin.Gather(acc); err != nil {
-			log.Printf("Error in plugin [%s]: %s", plugin.name, err)
+		if err := a.collect(plugin, pointChan, plugin.Interval()); err != nil {
+			log.Printf("Error in plugin [%s]: %s", plugin.Name, err)
 		}
 
-		elapsed := time.Since(start)
 		log.Printf("Gathered metrics, (separate %s interval), from %s in %s\n",
-			plugin.config.Interval, plugin.name, elapsed)
+			plugin.Interval(), plugin.Name, plugin.GatherTime())
 
 		if outerr != nil {
 			return outerr
@@ -284,26 +363,24 @@ func (a *Agent) Test() error {
 	}()
 
 	for _, plugin := range a.plugins {
-		acc := NewAccumulator(plugin.config, pointChan)
-		acc.SetDebug(true)
-		acc.SetPrefix(plugin.name + "_")
+		acc := plugin.Accumulator(pointChan, nil, true)
 
-		fmt.Printf("* Plugin: %s, Collection 1\n", plugin.name)
-		if plugin.config.Interval != 0 {
-			fmt.Printf("* Internal: %s\n", plugin.config.Interval)
+		fmt.Printf("* Plugin: %s, Collection 1\n", plugin.Name)
+		if plugin.Interval() != 0 {
+			fmt.Printf("* Internal: %s\n", plugin.Interval())
 		}
 
-		if err := plugin.plugin.Gather(acc); err != nil {
+		if err := plugin.Gather(acc); err != nil {
 			return err
 		}
 
 		// Special instructions for some plugins. cpu, for example, needs to be
 		// run twice in order to return cpu usage percentages.
-		switch plugin.name {
+		switch plugin.Name {
 		case "cpu":
 			time.Sleep(500 * time.Millisecond)
-			fmt.Printf("* Plugin: %s, Collection 2\n", plugin.name)
-			if err := plugin.plugin.Gather(acc); err != nil {
+			fmt.Printf("* Plugin: %s, Collection 2\n", plugin.Name)
+			if err := plugin.Gather(acc); err != nil {
 				return err
 			}
 		}
@@ -312,97 +389,239 @@ func (a *Agent) Test() error {
 	return nil
 }
 
-// writeOutput writes a list of points to a single output, with retries.
-// Optionally takes a `done` channel to indicate that it is done writing.
-func (a *Agent) writeOutput(
-	points []*client.Point,
-	ro *runningOutput,
-	shutdown chan struct{},
-	wg *sync.WaitGroup,
-) {
-	defer wg.Done()
-	if len(points) == 0 {
-		return
+// Once gathers every plugin a single time and writes the points to every
+// output, for running telegraf from cron. The returned error names every
+// plugin whose Gather and every output whose write failed. The services of
+// ServicePlugins are started before gathering and stopped after, as in Run.
+func (a *Agent) Once() error {
+	for _, plugin := range a.plugins {
+		if p, ok := plugin.Plugin.(plugins.ServicePlugin); ok {
+			if err := p.Start(); err != nil {
+				return fmt.Errorf("Service for plugin %s failed to start: %s",
+					plugin.Name, err)
+			}
+			defer p.Stop()
+		}
 	}
-	retry := 0
-	retries := a.FlushRetries
-	start := time.Now()
 
-	for {
-		err := ro.output.Write(points)
+	pointChan := make(chan *client.Point, 1000)
+	buffered := make(chan struct{})
+	go func() {
+		defer close(buffered)
+		for pt := range pointChan {
+			for _, o := range a.outputs {
+				o.AddPoint(pt)
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []string
+	for _, plugin := range a.plugins {
+		wg.Add(1)
+		go func(plugin *RunningInput) {
+			defer wg.Done()
+
+			if err := a.gather(plugin, pointChan, a.Interval.Duration); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Sprintf("Error in plugin [%s]: %s", plugin.Name, err))
+				mu.Unlock()
+			}
+		}(plugin)
+	}
+	wg.Wait()
+	close(pointChan)
+	<-buffered
+	sort.Strings(errs)
+
+	for _, o := range a.outputs {
+		if err := o.Write(); err != nil {
+			errs = append(errs, fmt.Sprintf("Error in output [%s]: %s", o.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// flushInterval returns the flush interval of the output, falling back to
+// the agent's flush interval.
+func (a *Agent) flushInterval(ro *RunningOutput) time.Duration {
+	if ro.Config != nil && ro.Config.FlushInterval != 0 {
+		return ro.Config.FlushInterval
+	}
+	return a.FlushInterval.Duration
+}
+
+// metricBufferLimit returns the buffer limit of the output, falling back to
+// the agent's metric buffer limit.
+func (a *Agent) metricBufferLimit(ro *RunningOutput) int {
+	if ro.Config != nil && ro.Config.MetricBufferLimit != 0 {
+		return ro.Config.MetricBufferLimit
+	}
+	return a.MetricBufferLimit
+}
+
+// maxMetricsPerGather returns the limit of points for each Gather of the
+// plugin, falling back to the agent's limit.
+func (a *Agent) maxMetricsPerGather(ri *RunningInput) int {
+	if ri.Config != nil && ri.Config.MaxMetricsPerGather != 0 {
+		return ri.Config.MaxMetricsPerGather
+	}
+	return a.MaxMetricsPerGather
+}
+
+// flush writes the points buffered for a single output, with retries. Points
+// that still could not be written stay buffered for the next flush.
+func (a *Agent) flush(ro *RunningOutput, shutdown chan struct{}) {
+	retries := a.FlushRetries
+	for retry := 0; ; retry++ {
+		err := ro.Write()
 		if err == nil {
-			// Write successful
-			elapsed := time.Since(start)
-			log.Printf("Flushed %d metrics to output %s in %s\n",
-				len(points), ro.name, elapsed)
 			return
 		}
 
 		select {
 		case <-shutdown:
+			log.Printf("FATAL: Write to output [%s] failed during shutdown, "+
+				"dropping %d metrics: %s\n", ro.Name, ro.Buffered(), err)
 			return
 		default:
-			if retry >= retries {
-				// No more retries
-				msg := "FATAL: Write to output [%s] failed %d times, dropping" +
-					" %d metrics\n"
-				log.Printf(msg, ro.name, retries+1, len(points))
-				return
-			} else if err != nil {
-				// Sleep for a retry
-				log.Printf("Error in output [%s]: %s, retrying in %s",
-					ro.name, err.Error(), a.FlushInterval.Duration)
-				time.Sleep(a.FlushInterval.Duration)
-			}
 		}
 
-		retry++
+		if retry >= retries {
+			log.Printf("Write to output [%s] failed %d times, keeping %d metrics "+
+				"for the next flush: %s\n", ro.Name, retries+1, ro.Buffered(), err)
+			return
+		}
+		log.Printf("Error in output [%s]: %s, retrying in %s",
+			ro.Name, err.Error(), a.flushInterval(ro))
+		time.Sleep(a.flushInterval(ro))
 	}
 }
 
-// flush writes a list of points to all configured outputs
-func (a *Agent) flush(
-	points []*client.Point,
-	shutdown chan struct{},
-	wait bool,
-) {
-	var wg sync.WaitGroup
-	for _, o := range a.outputs {
-		wg.Add(1)
-		go a.writeOutput(points, o, shutdown, &wg)
+// outputFlusher flushes a single output on its own flush interval
+func (a *Agent) outputFlusher(shutdown chan struct{}, ro *RunningOutput) {
+	ticker := time.NewTicker(a.flushInterval(ro))
+	defer ticker.Stop()
+	var jitter int64
+	if a.FlushJitter.Duration > 0 {
+		jitter = rand.Int63n(int64(a.FlushJitter.Duration))
 	}
-	if wait {
-		wg.Wait()
+	for {
+		select {
+		case <-shutdown:
+			log.Printf("Hang on, flushing any cached points to output %s before shutdown\n",
+				ro.Name)
+			a.flush(ro, shutdown)
+			return
+		case <-ticker.C:
+			timer := time.NewTimer(time.Duration(jitter))
+			select {
+			case <-timer.C:
+				a.flush(ro, shutdown)
+			case <-shutdown:
+				log.Printf("Hang on, flushing any cached points to output %s before shutdown\n",
+					ro.Name)
+				a.flush(ro, shutdown)
+				return
+			}
+		}
 	}
 }
 
-// flusher monitors the points input channel and flushes on the minimum interval
+// flusher monitors the points input channel, buffering points for every
+// output, which are each flushed on their own interval
 func (a *Agent) flusher(shutdown chan struct{}, pointChan chan *client.Point) error {
 	// Inelegant, but this sleep is to allow the Gather threads to run, so that
 	// the flusher will flush after metrics are collected.
 	time.Sleep(time.Millisecond * 100)
-	ticker := time.NewTicker(a.FlushInterval.Duration)
-	points := make([]*client.Point, 0)
-	jitter := rand.Int63n(int64(a.FlushJitter.Duration))
+
+	// stop is closed once no more points will be buffered, so that the
+	// final flush of each output includes every point received
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, o := range a.outputs {
+		wg.Add(1)
+		go func(o *RunningOutput) {
+			defer wg.Done()
+			a.outputFlusher(stop, o)
+		}(o)
+	}
+
+	var dd *dedup
+	if a.Dedup {
+		interval := a.DedupInterval.Duration
+		if interval == 0 || interval > a.FlushInterval.Duration {
+			interval = a.FlushInterval.Duration
+		}
+		dd = newDedup(interval)
+	}
 	for {
 		select {
 		case <-shutdown:
-			log.Println("Hang on, flushing any cached points before shutdown")
-			a.flush(points, shutdown, true)
+			close(stop)
+			wg.Wait()
 			return nil
-		case <-ticker.C:
-			timer := time.NewTimer(time.Duration(jitter))
+		case pt := <-pointChan:
+			if dd != nil && !dd.ShouldPass(pt) {
+				continue
+			}
+			for _, o := range a.outputs {
+				o.AddPoint(pt)
+			}
+		}
+	}
+}
+
+var (
+	// startupBackoff is the delay before checking again whether a plugin
+	// is ready, doubled after each check up to maxStartupBackoff
+	startupBackoff    = time.Second
+	maxStartupBackoff = 30 * time.Second
+)
+
+// waitForReady waits until every plugin implementing plugins.ReadyPlugin is
+// ready, for at most StartupWait overall. Plugins that are still not ready
+// are gathered from anyway.
+func (a *Agent) waitForReady(shutdown chan struct{}) {
+	if a.StartupWait.Duration == 0 {
+		return
+	}
+
+	deadline := time.Now().Add(a.StartupWait.Duration)
+	for _, plugin := range a.plugins {
+		p, ok := plugin.Plugin.(plugins.ReadyPlugin)
+		if !ok {
+			continue
+		}
+
+		backoff := startupBackoff
+		for !p.Ready() {
+			remaining := deadline.Sub(time.Now())
+			if remaining <= 0 {
+				log.Printf("Plugin [%s] is not ready after %s, starting anyway\n",
+					plugin.Name, a.StartupWait.Duration)
+				break
+			}
+			if backoff > remaining {
+				backoff = remaining
+			}
+			log.Printf("Plugin [%s] is not ready, retrying in %s\n", plugin.Name, backoff)
+
 			select {
-			case <-timer.C:
-				a.flush(points, shutdown, false)
 			case <-shutdown:
-				log.Println("Hang on, flushing any cached points before shutdown")
-				a.flush(points, shutdown, true)
-				return nil
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if backoff > maxStartupBackoff {
+				backoff = maxStartupBackoff
 			}
-			points = make([]*client.Point, 0)
-		case pt := <-pointChan:
-			points = append(points, pt)
 		}
 	}
 }
@@ -411,6 +630,16 @@ func (a *Agent) flusher(shutdown chan struct{}, pointChan chan *client.Point) er
 func (a *Agent) Run(shutdown chan struct{}) error {
 	var wg sync.WaitGroup
 
+	if a.ExpvarAddress != "" {
+		listener, err := a.serveExpvar()
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+	}
+
+	a.waitForReady(shutdown)
+
 	// channel shared between all plugin threads for accumulating points
 	pointChan := make(chan *client.Point, 1000)
 
@@ -433,11 +662,11 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	for _, plugin := range a.plugins {
 
 		// Start service of any ServicePlugins
-		switch p := plugin.plugin.(type) {
+		switch p := plugin.Plugin.(type) {
 		case plugins.ServicePlugin:
 			if err := p.Start(); err != nil {
 				log.Printf("Service for plugin %s failed to start, exiting\n%s\n",
-					plugin.name, err.Error())
+					plugin.Name, err.Error())
 				return err
 			}
 			defer p.Stop()
@@ -445,9 +674,9 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 
 		// Special handling for plugins that have their own collection interval
 		// configured. Default intervals are handled below with gatherParallel
-		if plugin.config.Interval != 0 {
+		if plugin.Interval() != 0 {
 			wg.Add(1)
-			go func(plugin *runningPlugin) {
+			go func(plugin *RunningInput) {
 				defer wg.Done()
 				if err := a.gatherSeparate(shutdown, plugin, pointChan); err != nil {
 					log.Printf(err.Error())
@@ -1,8 +1,18 @@
 package telegraf
 
 import (
-	"github.com/stretchr/testify/assert"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	// needing to load the plugins
 	_ "github.com/influxdb/telegraf/plugins/all"
@@ -55,3 +65,387 @@ func TestAgent_LoadOutput(t *testing.T) {
 	outputsEnabled, _ = a.LoadOutputs([]string{"influxdb", "foo", "kafka", "bar"}, config)
 	assert.Equal(t, 2, len(outputsEnabled))
 }
+
+type countingOutput struct {
+	sync.Mutex
+	writes int
+	points int
+}
+
+func (o *countingOutput) Connect() error       { return nil }
+func (o *countingOutput) Close() error         { return nil }
+func (o *countingOutput) Description() string  { return "" }
+func (o *countingOutput) SampleConfig() string { return "" }
+func (o *countingOutput) Write(points []*client.Point) error {
+	o.Lock()
+	defer o.Unlock()
+	o.writes++
+	o.points += len(points)
+	return nil
+}
+
+func TestAgent_OutputFlushIntervals(t *testing.T) {
+	fast := &countingOutput{}
+	slow := &countingOutput{}
+	a := &Agent{FlushInterval: internal.Duration{Duration: time.Second}}
+	a.outputs = []*RunningOutput{
+		NewRunningOutput("fast", fast,
+			&ConfiguredOutput{FlushInterval: 50 * time.Millisecond}),
+		NewRunningOutput("slow", slow,
+			&ConfiguredOutput{FlushInterval: 400 * time.Millisecond}),
+	}
+
+	shutdown := make(chan struct{})
+	pointChan := make(chan *client.Point)
+	done := make(chan struct{})
+	go func() {
+		a.flusher(shutdown, pointChan)
+		close(done)
+	}()
+
+	deadline := time.After(700 * time.Millisecond)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	sent := 0
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			pointChan <- client.NewPoint("test", nil,
+				map[string]interface{}{"value": sent}, time.Now())
+			sent++
+		case <-deadline:
+			break loop
+		}
+	}
+	close(shutdown)
+	<-done
+
+	fast.Lock()
+	slow.Lock()
+	defer fast.Unlock()
+	defer slow.Unlock()
+
+	assert.True(t, fast.writes > slow.writes,
+		"fast output flushed %d times, slow output %d times", fast.writes, slow.writes)
+	assert.Equal(t, sent, fast.points)
+	assert.Equal(t, sent, slow.points)
+}
+
+type readyPlugin struct {
+	checks     int
+	readyAfter int
+}
+
+func (p *readyPlugin) SampleConfig() string                 { return "" }
+func (p *readyPlugin) Description() string                  { return "" }
+func (p *readyPlugin) Gather(acc plugins.Accumulator) error { return nil }
+func (p *readyPlugin) Ready() bool {
+	p.checks++
+	return p.checks > p.readyAfter
+}
+
+func withStartupBackoff(backoff time.Duration, f func()) {
+	defer func(initial, max time.Duration) {
+		startupBackoff, maxStartupBackoff = initial, max
+	}(startupBackoff, maxStartupBackoff)
+	startupBackoff, maxStartupBackoff = backoff, 4*backoff
+	f()
+}
+
+func TestAgent_WaitForReady(t *testing.T) {
+	p := &readyPlugin{readyAfter: 2}
+	a := &Agent{StartupWait: internal.Duration{Duration: time.Second}}
+	a.plugins = []*RunningInput{NewRunningInput("db", p, nil)}
+
+	withStartupBackoff(time.Millisecond, func() {
+		a.waitForReady(make(chan struct{}))
+	})
+
+	// not ready twice, then ready
+	assert.Equal(t, 3, p.checks)
+}
+
+func TestAgent_WaitForReadyGivesUp(t *testing.T) {
+	p := &readyPlugin{readyAfter: 1000000}
+	a := &Agent{StartupWait: internal.Duration{Duration: 50 * time.Millisecond}}
+	a.plugins = []*RunningInput{NewRunningInput("db", p, nil)}
+
+	start := time.Now()
+	withStartupBackoff(time.Millisecond, func() {
+		a.waitForReady(make(chan struct{}))
+	})
+
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 50*time.Millisecond, "returned after %s", elapsed)
+	assert.True(t, elapsed < time.Second, "returned after %s", elapsed)
+	assert.True(t, p.checks > 1)
+}
+
+func TestAgent_WaitForReadyDisabled(t *testing.T) {
+	p := &readyPlugin{readyAfter: 1000000}
+	a := &Agent{}
+	a.plugins = []*RunningInput{NewRunningInput("db", p, nil)}
+
+	a.waitForReady(make(chan struct{}))
+	assert.Equal(t, 0, p.checks)
+}
+
+func newTestAgent(t *testing.T, agentConfig string) *Agent {
+	f, err := ioutil.TempFile("", "telegraf")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("[agent]\n" + agentConfig + "\n")
+	require.NoError(t, err)
+	f.Close()
+
+	config, err := LoadConfig(f.Name())
+	require.NoError(t, err)
+	a, err := NewAgent(config)
+	require.NoError(t, err)
+	return a
+}
+
+func TestAgent_Hostname(t *testing.T) {
+	a := newTestAgent(t, `hostname = "rethinkdb-01"`)
+	assert.Equal(t, "rethinkdb-01", a.Tags["host"])
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	a = newTestAgent(t, `interval = "10s"`)
+	assert.Equal(t, hostname, a.Tags["host"])
+}
+
+func TestAgent_OmitHostname(t *testing.T) {
+	a := newTestAgent(t, `omit_hostname = true`)
+	_, ok := a.Tags["host"]
+	assert.False(t, ok)
+
+	// points are not tagged with a host either
+	points := make(chan *client.Point, 1)
+	ri := &RunningInput{Name: "rethinkdb", Config: &ConfiguredPlugin{Name: "rethinkdb"}}
+	ri.Accumulator(points, a.Tags, false).Add("clients", 1, nil)
+	_, ok = (<-points).Tags()["host"]
+	assert.False(t, ok)
+}
+
+func TestAgent_HostnameDefaultTag(t *testing.T) {
+	a := newTestAgent(t, `hostname = "rethinkdb-01"`)
+
+	// a host tag set by the plugin takes precedence
+	points := make(chan *client.Point, 2)
+	acc := (&RunningInput{Name: "rethinkdb"}).Accumulator(points, a.Tags, false)
+	acc.Add("clients", 1, map[string]string{"host": "10.0.0.1:28015"})
+	acc.Add("clients", 1, nil)
+	assert.Equal(t, "10.0.0.1:28015", (<-points).Tags()["host"])
+	assert.Equal(t, "rethinkdb-01", (<-points).Tags()["host"])
+}
+
+func TestAgent_MaxMetricsPerGather(t *testing.T) {
+	a := &Agent{MaxMetricsPerGather: 100}
+
+	assert.Equal(t, 100, a.maxMetricsPerGather(NewRunningInput("mysql", nil, nil)))
+	assert.Equal(t, 10, a.maxMetricsPerGather(NewRunningInput("rethinkdb", nil,
+		&ConfiguredPlugin{Name: "rethinkdb", MaxMetricsPerGather: 10})))
+}
+
+type gatherPlugin struct {
+	err error
+}
+
+func (p *gatherPlugin) SampleConfig() string { return "" }
+func (p *gatherPlugin) Description() string  { return "" }
+func (p *gatherPlugin) Gather(acc plugins.Accumulator) error {
+	acc.Add("value", 1, nil)
+	return p.err
+}
+
+func TestAgent_Once(t *testing.T) {
+	out := &failingOutput{}
+	a := &Agent{Tags: map[string]string{"host": "server01"}}
+	a.plugins = []*RunningInput{
+		NewRunningInput("first", &gatherPlugin{}, nil),
+		NewRunningInput("second", &gatherPlugin{}, nil),
+	}
+	a.outputs = []*RunningOutput{NewRunningOutput("capture", out, nil)}
+
+	require.NoError(t, a.Once())
+	require.Len(t, out.written, 2)
+	assert.Equal(t, "server01", out.written[0].Tags()["host"])
+}
+
+func TestAgent_OnceFailingOutput(t *testing.T) {
+	out := &failingOutput{fail: true}
+	a := &Agent{}
+	a.plugins = []*RunningInput{NewRunningInput("first", &gatherPlugin{}, nil)}
+	a.outputs = []*RunningOutput{
+		NewRunningOutput("failing", out, nil),
+		NewRunningOutput("working", &failingOutput{}, nil),
+	}
+
+	assert.EqualError(t, a.Once(), "Error in output [failing]: write failed")
+}
+
+func TestAgent_OnceFailingPlugins(t *testing.T) {
+	out := &failingOutput{}
+	a := &Agent{}
+	a.plugins = []*RunningInput{
+		NewRunningInput("second", &gatherPlugin{err: errors.New("timeout")}, nil),
+		NewRunningInput("first", &gatherPlugin{err: errors.New("refused")}, nil),
+	}
+	a.outputs = []*RunningOutput{NewRunningOutput("capture", out, nil)}
+
+	assert.EqualError(t, a.Once(), "Error in plugin [first]: refused\n"+
+		"Error in plugin [second]: timeout")
+	// the points gathered before the errors are still written
+	assert.Len(t, out.written, 2)
+}
+
+// servicePlugin only gathers a point once its service is started, and
+// records the calls of its service methods
+type servicePlugin struct {
+	gatherPlugin
+	startErr error
+	calls    []string
+}
+
+func (p *servicePlugin) Start() error {
+	p.calls = append(p.calls, "start")
+	return p.startErr
+}
+
+func (p *servicePlugin) Stop() { p.calls = append(p.calls, "stop") }
+
+func (p *servicePlugin) Gather(acc plugins.Accumulator) error {
+	p.calls = append(p.calls, "gather")
+	if p.calls[0] != "start" {
+		return nil
+	}
+	return p.gatherPlugin.Gather(acc)
+}
+
+func TestAgent_OnceServicePlugins(t *testing.T) {
+	out := &failingOutput{}
+	service := &servicePlugin{}
+	a := &Agent{}
+	a.plugins = []*RunningInput{
+		NewRunningInput("first", &gatherPlugin{}, nil),
+		NewRunningInput("service", service, nil),
+	}
+	a.outputs = []*RunningOutput{NewRunningOutput("capture", out, nil)}
+
+	require.NoError(t, a.Once())
+	assert.Equal(t, []string{"start", "gather", "stop"}, service.calls)
+	assert.Len(t, out.written, 2)
+}
+
+func TestAgent_OnceServicePluginFailsToStart(t *testing.T) {
+	out := &failingOutput{}
+	started := &servicePlugin{}
+	a := &Agent{}
+	a.plugins = []*RunningInput{
+		NewRunningInput("started", started, nil),
+		NewRunningInput("failing", &servicePlugin{startErr: errors.New("address in use")}, nil),
+	}
+	a.outputs = []*RunningOutput{NewRunningOutput("capture", out, nil)}
+
+	assert.EqualError(t, a.Once(), "Service for plugin failing failed to start: address in use")
+	// nothing is gathered, and the services already started are stopped
+	assert.Equal(t, []string{"start", "stop"}, started.calls)
+	assert.Empty(t, out.written)
+}
+
+// flakyPlugin returns an error from the first failures calls to Gather
+type flakyPlugin struct {
+	failures int
+	gathers  int
+}
+
+func (p *flakyPlugin) SampleConfig() string { return "" }
+func (p *flakyPlugin) Description() string  { return "" }
+func (p *flakyPlugin) Gather(acc plugins.Accumulator) error {
+	p.gathers++
+	if p.gathers <= p.failures {
+		return errors.New("connection reset")
+	}
+	acc.Add("value", p.gathers, nil)
+	return nil
+}
+
+func withGatherRetryBackoff(backoff time.Duration, f func()) {
+	defer func(initial time.Duration) {
+		gatherRetryBackoff = initial
+	}(gatherRetryBackoff)
+	gatherRetryBackoff = backoff
+	f()
+}
+
+func TestAgent_GatherRetries(t *testing.T) {
+	p := &flakyPlugin{failures: 1}
+	plugin := NewRunningInput("flaky", p,
+		&ConfiguredPlugin{Name: "flaky", GatherRetries: 2})
+	points := make(chan *client.Point, 10)
+
+	withGatherRetryBackoff(time.Millisecond, func() {
+		require.NoError(t, (&Agent{}).gather(plugin, points, time.Second))
+	})
+
+	assert.Equal(t, 2, p.gathers)
+	require.Len(t, points, 1)
+	assert.Equal(t, "flaky_value", (<-points).Name())
+}
+
+func TestAgent_GatherRetriesExhausted(t *testing.T) {
+	p := &flakyPlugin{failures: 5}
+	plugin := NewRunningInput("flaky", p,
+		&ConfiguredPlugin{Name: "flaky", GatherRetries: 2})
+
+	withGatherRetryBackoff(time.Millisecond, func() {
+		assert.Error(t, (&Agent{}).gather(plugin, make(chan *client.Point, 10), time.Second))
+	})
+	assert.Equal(t, 3, p.gathers)
+}
+
+func TestAgent_GatherRetriesWithinInterval(t *testing.T) {
+	p := &flakyPlugin{failures: 5}
+	plugin := NewRunningInput("flaky", p,
+		&ConfiguredPlugin{Name: "flaky", GatherRetries: 5})
+
+	// the second retry would start after the interval
+	withGatherRetryBackoff(20*time.Millisecond, func() {
+		assert.Error(t, (&Agent{}).gather(plugin, make(chan *client.Point, 10),
+			50*time.Millisecond))
+	})
+	assert.Equal(t, 2, p.gathers)
+}
+
+func TestAgent_GatherNoRetries(t *testing.T) {
+	p := &flakyPlugin{failures: 1}
+	plugin := NewRunningInput("flaky", p, nil)
+
+	assert.Error(t, (&Agent{}).gather(plugin, make(chan *client.Point, 10), time.Second))
+	assert.Equal(t, 1, p.gathers)
+}
+
+func TestAgent_SkipFirstInterval(t *testing.T) {
+	p := &flakyPlugin{}
+	plugin := NewRunningInput("flaky", p, nil)
+	points := make(chan *client.Point, 10)
+	a := &Agent{SkipFirstInterval: true}
+
+	require.NoError(t, a.collect(plugin, points, time.Second))
+	assert.Equal(t, 1, p.gathers)
+	assert.Len(t, points, 0)
+
+	require.NoError(t, a.collect(plugin, points, time.Second))
+	require.Len(t, points, 1)
+	assert.Equal(t, int64(2), (<-points).Fields()["value"])
+}
+
+func TestAgent_NoSkipFirstInterval(t *testing.T) {
+	plugin := NewRunningInput("flaky", &flakyPlugin{}, nil)
+	points := make(chan *client.Point, 10)
+
+	require.NoError(t, (&Agent{}).collect(plugin, points, time.Second))
+	assert.Len(t, points, 1)
+}
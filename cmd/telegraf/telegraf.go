@@ -16,6 +16,8 @@ import (
 var fDebug = flag.Bool("debug", false,
 	"show metrics as they're generated to stdout")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
+var fOnce = flag.Bool("once", false,
+	"gather metrics once, write them to the outputs, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("configdirectory", "",
 	"directory containing additional configuration files")
@@ -29,6 +31,8 @@ var fOutputFilters = flag.String("outputfilter", "",
 	"filter the outputs to enable, separator is :")
 var fUsage = flag.String("usage", "",
 	"print usage for a plugin, ie, 'telegraf -usage mysql'")
+var fUsageDir = flag.String("usage-dir", "",
+	"write the usage of every plugin and output to files of a directory")
 
 // Telegraf version
 //	-ldflags "-X main.Version=`git describe --always --tags`"
@@ -69,6 +73,13 @@ func main() {
 		return
 	}
 
+	if *fUsageDir != "" {
+		if err := telegraf.DumpSampleConfigs(*fUsageDir); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	var (
 		config *telegraf.Config
 		err    error
@@ -132,6 +143,15 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if *fOnce {
+		err = ag.Once()
+		ag.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	shutdown := make(chan struct{})
 	signals := make(chan os.Signal)
 	signal.Notify(signals, os.Interrupt)
@@ -1,9 +1,12 @@
 package telegraf
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
@@ -28,11 +31,13 @@ type Config struct {
 	plugins              map[string]plugins.Plugin
 	pluginConfigurations map[string]*ConfiguredPlugin
 	outputs              map[string]outputs.Output
+	outputConfigurations map[string]*ConfiguredOutput
 
 	agentFieldsSet               []string
 	pluginFieldsSet              map[string][]string
 	pluginConfigurationFieldsSet map[string][]string
 	outputFieldsSet              map[string][]string
+	outputConfigurationFieldsSet map[string][]string
 }
 
 // Plugins returns the configured plugins as a map of name -> plugins.Plugin
@@ -59,10 +64,36 @@ type ConfiguredPlugin struct {
 	Drop []string
 	Pass []string
 
+	// FieldDrop and FieldPass filter the fields of the points, a point left
+	// without fields is dropped
+	FieldDrop []string
+	FieldPass []string
+
 	TagDrop []TagFilter
 	TagPass []TagFilter
 
 	Interval time.Duration
+
+	// MaxMetricsPerGather is the maximum number of points accepted from a
+	// single Gather, 0 means the agent's limit applies
+	MaxMetricsPerGather int
+
+	// GatherRetries is the number of times a failed Gather is run again
+	// within the same interval
+	GatherRetries int
+
+	// NameAsField makes Add(name, value, tags) report the value as the field
+	// name of the measurement named after the plugin, rather than as the
+	// field "value" of the measurement name
+	NameAsField bool
+
+	// NameOverride replaces the measurement of every point of the plugin,
+	// the names given to Add being fields as with NameAsField
+	NameOverride string
+
+	// Tags are added to every point of the plugin, overriding the global tags
+	// of the same name but not the tags set by the plugin
+	Tags map[string]string
 }
 
 // ShouldPass returns true if the metric should pass, false if should drop
@@ -116,13 +147,51 @@ func (cp *ConfiguredPlugin) ShouldPass(measurement string, tags map[string]strin
 	return true
 }
 
+// ShouldPassField returns true if the field should be kept, false if it
+// should be dropped
+func (cp *ConfiguredPlugin) ShouldPassField(field string) bool {
+	if cp.FieldPass != nil {
+		for _, pat := range cp.FieldPass {
+			if strings.HasPrefix(field, pat) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if cp.FieldDrop != nil {
+		for _, pat := range cp.FieldDrop {
+			if strings.HasPrefix(field, pat) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ConfiguredOutput containing a name, and the flush interval and metric
+// buffer limit overriding the agent's defaults for this output
+type ConfiguredOutput struct {
+	Name string
+
+	FlushInterval     time.Duration
+	MetricBufferLimit int
+}
+
 // ApplyOutput loads the Output struct built from the config into the given Output struct.
 // Overrides only values in the given struct that were set in the config.
-func (c *Config) ApplyOutput(name string, v interface{}) error {
+// Additionally return a ConfiguredOutput, which is always generated from the config.
+func (c *Config) ApplyOutput(name string, v interface{}) (*ConfiguredOutput, error) {
 	if c.outputs[name] != nil {
-		return mergeStruct(v, c.outputs[name], c.outputFieldsSet[name])
+		err := mergeStruct(v, c.outputs[name], c.outputFieldsSet[name])
+		if err != nil {
+			return nil, err
+		}
+		return c.outputConfigurations[name], nil
 	}
-	return nil
+	return nil, nil
 }
 
 // ApplyAgent loads the Agent struct built from the config into the given Agent struct.
@@ -237,11 +306,47 @@ var header = `# Telegraf configuration
   flush_jitter = "5s"
   # Number of times to retry each data flush
   flush_retries = 2
+  # Maximum number of points buffered for each output between flushes, the
+  # oldest points are dropped when it is reached. 0 means no limit.
+  # Both flush_interval and metric_buffer_limit can be overridden in the
+  # configuration of each output.
+  metric_buffer_limit = 0
+
+  # Drop points whose fields have not changed since the last point of the
+  # same series. Unchanged points are still written every dedup_interval,
+  # which defaults to and can not exceed flush_interval.
+  dedup = false
+  # dedup_interval = "10s"
+
+  # Wait up to startup_wait for plugins depending on a service, ie a
+  # database, to reach it before the first collection. 0s means no wait.
+  # startup_wait = "0s"
+
+  # Drop the points of the first collection of every plugin, ie rates
+  # computed without a previous sample. -once and -test are not affected.
+  # skip_first_interval = false
+
+  # Maximum number of points written per second to each output, smoothing
+  # the bursts of many plugins or of a backlog of buffered points into
+  # batches of a second of points. 0 means no limit.
+  # write_rate_limit = 0
+
+  # Serve counters of collections and writes with expvar, as JSON on
+  # http://<expvar_address>/debug/vars. Not served by default.
+  # expvar_address = "localhost:8126"
+
+  # Maximum number of points accepted from each plugin on each collection,
+  # points past it are dropped with a warning. 0 means no limit. It can be
+  # overridden in the configuration of each plugin.
+  max_metrics_per_gather = 0
 
   # Run telegraf in debug mode
   debug = false
   # Override default hostname, if empty use os.Hostname()
   hostname = ""
+  # Do not add the host tag to points, ie when plugins tag their points with
+  # the identity of the service they gather
+  omit_hostname = false
 
 
 ###############################################################################
@@ -331,12 +436,16 @@ type printer interface {
 }
 
 func printConfig(name string, p printer) {
-	fmt.Printf("\n# %s\n[%s]", p.Description(), name)
+	fprintConfig(os.Stdout, name, p)
+}
+
+func fprintConfig(w io.Writer, name string, p printer) {
+	fmt.Fprintf(w, "\n# %s\n[%s]", p.Description(), name)
 	config := p.SampleConfig()
 	if config == "" {
-		fmt.Printf("\n  # no configuration\n")
+		fmt.Fprintf(w, "\n  # no configuration\n")
 	} else {
-		fmt.Printf(config)
+		fmt.Fprint(w, config)
 	}
 }
 
@@ -369,6 +478,41 @@ func PrintOutputConfig(name string) error {
 	return nil
 }
 
+// DumpSampleConfigs writes the description and sample config of every plugin
+// and output to its own file of dir, plugins/<name>.conf and
+// outputs/<name>.conf, ie to generate their documentation.
+func DumpSampleConfigs(dir string) error {
+	for name, creator := range plugins.Plugins {
+		err := dumpConfig(filepath.Join(dir, "plugins"), name, name, creator())
+		if err != nil {
+			return err
+		}
+	}
+	for name, creator := range outputs.Outputs {
+		err := dumpConfig(filepath.Join(dir, "outputs"), name, "outputs."+name, creator())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumpConfig writes the config of p, under the table section, to
+// dir/<name>.conf
+func dumpConfig(dir, name, section string, p printer) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("Unable to create %s, %s", dir, err)
+	}
+
+	var buf bytes.Buffer
+	fprintConfig(&buf, section, p)
+	path := filepath.Join(dir, name+".conf")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("Unable to write the config of %s, %s", name, err)
+	}
+	return nil
+}
+
 // Used for fuzzy matching struct field names in FieldByNameFunc calls below
 func fieldMatch(field string) func(string) bool {
 	return func(name string) bool {
@@ -461,6 +605,8 @@ func (c *Config) LoadDirectory(path string) error {
 			if _, ok := c.outputs[outputName]; !ok {
 				c.outputs[outputName] = output
 				c.outputFieldsSet[outputName] = subConfig.outputFieldsSet[outputName]
+				c.outputConfigurations[outputName] = subConfig.outputConfigurations[outputName]
+				c.outputConfigurationFieldsSet[outputName] = subConfig.outputConfigurationFieldsSet[outputName]
 				continue
 			}
 			err = mergeStruct(c.outputs[outputName], output, subConfig.outputFieldsSet[outputName])
@@ -472,6 +618,15 @@ func (c *Config) LoadDirectory(path string) error {
 					c.outputFieldsSet[outputName] = append(c.outputFieldsSet[outputName], field)
 				}
 			}
+			err = mergeStruct(c.outputConfigurations[outputName], subConfig.outputConfigurations[outputName], subConfig.outputConfigurationFieldsSet[outputName])
+			if err != nil {
+				return err
+			}
+			for _, field := range subConfig.outputConfigurationFieldsSet[outputName] {
+				if !sliceContains(field, c.outputConfigurationFieldsSet[outputName]) {
+					c.outputConfigurationFieldsSet[outputName] = append(c.outputConfigurationFieldsSet[outputName], field)
+				}
+			}
 		}
 	}
 	return nil
@@ -491,14 +646,20 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := resolveSecretFiles(tbl); err != nil {
+		return nil, err
+	}
+
 	c := &Config{
 		Tags:                         make(map[string]string),
 		plugins:                      make(map[string]plugins.Plugin),
 		pluginConfigurations:         make(map[string]*ConfiguredPlugin),
 		outputs:                      make(map[string]outputs.Output),
+		outputConfigurations:         make(map[string]*ConfiguredOutput),
 		pluginFieldsSet:              make(map[string][]string),
 		pluginConfigurationFieldsSet: make(map[string][]string),
 		outputFieldsSet:              make(map[string][]string),
+		outputConfigurationFieldsSet: make(map[string][]string),
 	}
 
 	for name, val := range tbl.Fields {
@@ -539,6 +700,57 @@ func LoadConfig(path string) (*Config, error) {
 	return c, nil
 }
 
+// secretFilePrefix marks string values read from a file, ie
+// password = "@/run/secrets/mysql" is set to the content of that file
+const secretFilePrefix = "@/"
+
+// resolveSecretFiles replaces every string value of tbl and its subtables
+// starting with secretFilePrefix by the content of the file it names,
+// without its trailing newline.
+func resolveSecretFiles(tbl *ast.Table) error {
+	for _, field := range tbl.Fields {
+		switch node := field.(type) {
+		case *ast.Table:
+			if err := resolveSecretFiles(node); err != nil {
+				return err
+			}
+		case []*ast.Table:
+			for _, t := range node {
+				if err := resolveSecretFiles(t); err != nil {
+					return err
+				}
+			}
+		case *ast.KeyValue:
+			if err := resolveSecretValue(node.Key, node.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resolveSecretValue(key string, value ast.Value) error {
+	switch v := value.(type) {
+	case *ast.String:
+		if !strings.HasPrefix(v.Value, secretFilePrefix) {
+			return nil
+		}
+		path := v.Value[1:]
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Unable to read %s from file: %s", key, err)
+		}
+		v.Value = strings.TrimRight(string(data), "\r\n")
+	case *ast.Array:
+		for _, elem := range v.Value {
+			if err := resolveSecretValue(key, elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Needs to have the field names, for merging later.
 func extractFieldNames(ast *ast.Table) []string {
 	// A reasonable capacity?
@@ -549,6 +761,24 @@ func extractFieldNames(ast *ast.Table) []string {
 	return names
 }
 
+// isEnabled returns false when the table sets enabled = false, in which case
+// the plugin or output it configures is not loaded. The enabled field is
+// removed from the table.
+func isEnabled(tbl *ast.Table) (bool, error) {
+	node, ok := tbl.Fields["enabled"]
+	if !ok {
+		return true, nil
+	}
+	delete(tbl.Fields, "enabled")
+
+	if kv, ok := node.(*ast.KeyValue); ok {
+		if b, ok := kv.Value.(*ast.Boolean); ok {
+			return b.Boolean()
+		}
+	}
+	return false, fmt.Errorf("Invalid enabled option for %s, expected true or false", tbl.Name)
+}
+
 // Parse the agent config out of the given *ast.Table.
 func (c *Config) parseAgent(agentAst *ast.Table) error {
 	c.agentFieldsSet = extractFieldNames(agentAst)
@@ -561,19 +791,58 @@ func (c *Config) parseAgent(agentAst *ast.Table) error {
 	return nil
 }
 
-// Parse an output config out of the given *ast.Table.
+// Parse an output config, plus output meta-config, out of the given *ast.Table.
 func (c *Config) parseOutput(name string, outputAst *ast.Table) error {
-	c.outputFieldsSet[name] = extractFieldNames(outputAst)
 	creator, ok := outputs.Outputs[name]
 	if !ok {
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
+	if enabled, err := isEnabled(outputAst); err != nil || !enabled {
+		return err
+	}
+
 	output := creator()
+	co := &ConfiguredOutput{Name: name}
+	coFields := make([]string, 0, 2)
+
+	if node, ok := outputAst.Fields["flush_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return err
+				}
+
+				co.FlushInterval = dur
+				coFields = append(coFields, "flush_interval")
+			}
+		}
+	}
+
+	if node, ok := outputAst.Fields["metric_buffer_limit"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				limit, err := integer.Int()
+				if err != nil {
+					return err
+				}
+
+				co.MetricBufferLimit = int(limit)
+				coFields = append(coFields, "metric_buffer_limit")
+			}
+		}
+	}
+
+	delete(outputAst.Fields, "flush_interval")
+	delete(outputAst.Fields, "metric_buffer_limit")
+	c.outputFieldsSet[name] = extractFieldNames(outputAst)
+	c.outputConfigurationFieldsSet[name] = coFields
 	err := toml.UnmarshalTable(outputAst, output)
 	if err != nil {
 		return err
 	}
 	c.outputs[name] = output
+	c.outputConfigurations[name] = co
 	return nil
 }
 
@@ -583,6 +852,10 @@ func (c *Config) parsePlugin(name string, pluginAst *ast.Table) error {
 	if !ok {
 		return fmt.Errorf("Undefined but requested plugin: %s", name)
 	}
+	if enabled, err := isEnabled(pluginAst); err != nil || !enabled {
+		return err
+	}
+
 	plugin := creator()
 	cp := &ConfiguredPlugin{Name: name}
 	cpFields := make([]string, 0, 5)
@@ -613,6 +886,32 @@ func (c *Config) parsePlugin(name string, pluginAst *ast.Table) error {
 		}
 	}
 
+	if node, ok := pluginAst.Fields["fieldpass"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						cp.FieldPass = append(cp.FieldPass, str.Value)
+					}
+				}
+				cpFields = append(cpFields, "fieldpass")
+			}
+		}
+	}
+
+	if node, ok := pluginAst.Fields["fielddrop"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						cp.FieldDrop = append(cp.FieldDrop, str.Value)
+					}
+				}
+				cpFields = append(cpFields, "fielddrop")
+			}
+		}
+	}
+
 	if node, ok := pluginAst.Fields["interval"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -627,6 +926,59 @@ func (c *Config) parsePlugin(name string, pluginAst *ast.Table) error {
 		}
 	}
 
+	if node, ok := pluginAst.Fields["max_metrics_per_gather"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				limit, err := integer.Int()
+				if err != nil {
+					return err
+				}
+
+				cp.MaxMetricsPerGather = int(limit)
+				cpFields = append(cpFields, "max_metrics_per_gather")
+			}
+		}
+	}
+
+	if node, ok := pluginAst.Fields["gather_retries"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				retries, err := integer.Int()
+				if err != nil {
+					return err
+				}
+
+				cp.GatherRetries = int(retries)
+				cpFields = append(cpFields, "gather_retries")
+			}
+		}
+	}
+
+	if node, ok := pluginAst.Fields["name_as_field"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			b, ok := kv.Value.(*ast.Boolean)
+			if !ok {
+				return fmt.Errorf("Invalid name_as_field option for %s, expected true or false", name)
+			}
+			nameAsField, err := b.Boolean()
+			if err != nil {
+				return err
+			}
+
+			cp.NameAsField = nameAsField
+			cpFields = append(cpFields, "name_as_field")
+		}
+	}
+
+	if node, ok := pluginAst.Fields["name_override"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				cp.NameOverride = str.Value
+				cpFields = append(cpFields, "name_override")
+			}
+		}
+	}
+
 	if node, ok := pluginAst.Fields["tagpass"]; ok {
 		if subtbl, ok := node.(*ast.Table); ok {
 			for name, val := range subtbl.Fields {
@@ -665,11 +1017,34 @@ func (c *Config) parsePlugin(name string, pluginAst *ast.Table) error {
 		}
 	}
 
+	if node, ok := pluginAst.Fields["tags"]; ok {
+		if subtbl, ok := node.(*ast.Table); ok {
+			cp.Tags = make(map[string]string)
+			for tag, val := range subtbl.Fields {
+				if kv, ok := val.(*ast.KeyValue); ok {
+					str, ok := kv.Value.(*ast.String)
+					if !ok {
+						return fmt.Errorf("Invalid tag %s for %s, expected a string", tag, name)
+					}
+					cp.Tags[tag] = str.Value
+				}
+			}
+			cpFields = append(cpFields, "tags")
+		}
+	}
+
 	delete(pluginAst.Fields, "drop")
 	delete(pluginAst.Fields, "pass")
+	delete(pluginAst.Fields, "fielddrop")
+	delete(pluginAst.Fields, "fieldpass")
 	delete(pluginAst.Fields, "interval")
+	delete(pluginAst.Fields, "max_metrics_per_gather")
+	delete(pluginAst.Fields, "gather_retries")
+	delete(pluginAst.Fields, "name_as_field")
+	delete(pluginAst.Fields, "name_override")
 	delete(pluginAst.Fields, "tagdrop")
 	delete(pluginAst.Fields, "tagpass")
+	delete(pluginAst.Fields, "tags")
 	c.pluginFieldsSet[name] = extractFieldNames(pluginAst)
 	c.pluginConfigurationFieldsSet[name] = cpFields
 	err := toml.UnmarshalTable(pluginAst, plugin)
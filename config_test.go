@@ -3,16 +3,25 @@ package telegraf
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/outputs"
+	"github.com/influxdb/telegraf/outputs/file"
+	"github.com/influxdb/telegraf/outputs/influxdb"
 	"github.com/influxdb/telegraf/plugins"
 	"github.com/influxdb/telegraf/plugins/exec"
+	"github.com/influxdb/telegraf/plugins/jolokia"
 	"github.com/influxdb/telegraf/plugins/kafka_consumer"
+	"github.com/influxdb/telegraf/plugins/mysql"
 	"github.com/influxdb/telegraf/plugins/procstat"
+	"github.com/influxdb/telegraf/plugins/rethinkdb"
 	"github.com/naoina/toml"
 	"github.com/naoina/toml/ast"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -238,9 +247,11 @@ func TestConfig_parsePlugin(t *testing.T) {
 	kafka.BatchSize = 1000
 
 	kConfig := &ConfiguredPlugin{
-		Name: "kafka",
-		Drop: []string{"other", "stuff"},
-		Pass: []string{"some", "strings"},
+		Name:      "kafka",
+		Drop:      []string{"other", "stuff"},
+		Pass:      []string{"some", "strings"},
+		FieldDrop: []string{"other_field"},
+		FieldPass: []string{"some_field"},
 		TagDrop: []TagFilter{
 			TagFilter{
 				Name:   "badtag",
@@ -253,7 +264,12 @@ func TestConfig_parsePlugin(t *testing.T) {
 				Filter: []string{"mytag"},
 			},
 		},
-		Interval: 5 * time.Second,
+		Interval:            5 * time.Second,
+		MaxMetricsPerGather: 500,
+		GatherRetries:       2,
+		NameAsField:         true,
+		NameOverride:        "kafka_metrics",
+		Tags:                map[string]string{"cluster": "metrics"},
 	}
 
 	assert.Equal(t, kafka, c.plugins["kafka"], "Testdata did not produce a correct kafka struct.")
@@ -330,3 +346,248 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	assert.Equal(t, pstat, c.plugins["procstat"], "Merged Testdata did not produce a correct procstat struct.")
 	assert.Equal(t, pConfig, c.pluginConfigurations["procstat"], "Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_parseOutput(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/single_output.toml")
+	if err != nil {
+		t.Error(err)
+	}
+
+	tbl, err := toml.Parse(data)
+	if err != nil {
+		t.Error(err)
+	}
+
+	c := &Config{
+		outputs:                      make(map[string]outputs.Output),
+		outputConfigurations:         make(map[string]*ConfiguredOutput),
+		outputFieldsSet:              make(map[string][]string),
+		outputConfigurationFieldsSet: make(map[string][]string),
+	}
+
+	subtbl := tbl.Fields["influxdb"].(*ast.Table)
+	err = c.parseOutput("influxdb", subtbl)
+	assert.NoError(t, err)
+
+	influx := outputs.Outputs["influxdb"]().(*influxdb.InfluxDB)
+	influx.URLs = []string{"http://localhost:8086"}
+	influx.Database = "telegraf"
+
+	iConfig := &ConfiguredOutput{
+		Name:              "influxdb",
+		FlushInterval:     30 * time.Second,
+		MetricBufferLimit: 5000,
+	}
+
+	assert.Equal(t, influx, c.outputs["influxdb"], "Testdata did not produce a correct influxdb struct.")
+	assert.Equal(t, iConfig, c.outputConfigurations["influxdb"], "Testdata did not produce correct influxdb metadata.")
+}
+
+func writeTempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "telegraf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestConfig_SecretFiles(t *testing.T) {
+	secret := writeTempFile(t, "s3cr3t\n")
+	defer os.Remove(secret)
+	dsn := writeTempFile(t, "root:s3cr3t@tcp(127.0.0.1:3306)/")
+	defer os.Remove(dsn)
+
+	path := writeTempFile(t, fmt.Sprintf(`
+[rethinkdb]
+  servers = ["127.0.0.1:28015"]
+  auth_key = "@%s"
+
+[jolokia]
+  [[jolokia.servers]]
+    name = "as1"
+    url = "http://127.0.0.1:8080/jolokia"
+    password = "@%s"
+
+[mysql]
+  servers = ["@%s"]
+
+[outputs.influxdb]
+  urls = ["http://localhost:8086"]
+  password = "@%s"
+  username = "@not a file"
+`, secret, secret, dsn, secret))
+	defer os.Remove(path)
+
+	c, err := LoadConfig(path)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "s3cr3t", c.plugins["rethinkdb"].(*rethinkdb.RethinkDB).AuthKey)
+	assert.Equal(t, "s3cr3t", c.plugins["jolokia"].(*jolokia.Jolokia).Servers[0].Password)
+	assert.Equal(t, []string{"root:s3cr3t@tcp(127.0.0.1:3306)/"},
+		c.plugins["mysql"].(*mysql.Mysql).Servers)
+
+	influx := c.outputs["influxdb"].(*influxdb.InfluxDB)
+	assert.Equal(t, "s3cr3t", influx.Password)
+	// only values starting with "@/" are read from a file
+	assert.Equal(t, "@not a file", influx.Username)
+}
+
+func TestConfig_SecretFileMissing(t *testing.T) {
+	path := writeTempFile(t, `
+[rethinkdb]
+  auth_key = "@/does/not/exist"
+`)
+	defer os.Remove(path)
+
+	_, err := LoadConfig(path)
+	assert.Error(t, err)
+}
+
+func TestConfig_Disabled(t *testing.T) {
+	path := writeTempFile(t, `
+[rethinkdb]
+  enabled = false
+  servers = ["127.0.0.1:28015"]
+
+[mysql]
+  enabled = true
+  servers = ["root@tcp(127.0.0.1:3306)/"]
+
+[outputs.influxdb]
+  enabled = false
+  urls = ["http://localhost:8086"]
+
+[outputs.file]
+  files = ["stdout"]
+`)
+	defer os.Remove(path)
+
+	c, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mysql"}, c.PluginsDeclared())
+	assert.Equal(t, []string{"file"}, c.OutputsDeclared())
+
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+	loaded, err := a.LoadPlugins(nil, c)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mysql"}, loaded)
+	loaded, err = a.LoadOutputs(nil, c)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"file"}, loaded)
+}
+
+func TestConfig_OutputDataFormat(t *testing.T) {
+	tests := []struct {
+		dataFormat string
+		expected   string
+	}{
+		{"json", `{"name":"rethinkdb_active_clients","tags":{"host":"localhost"},"fields":{"value":3},"timestamp":0}` + "\n"},
+		{"influx", "rethinkdb_active_clients,host=localhost value=3i 0\n"},
+	}
+
+	pt := client.NewPoint("rethinkdb_active_clients",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": int64(3)},
+		time.Unix(0, 0))
+
+	for _, tt := range tests {
+		out, err := ioutil.TempFile("", "telegraf")
+		require.NoError(t, err)
+		out.Close()
+		defer os.Remove(out.Name())
+
+		path := writeTempFile(t, fmt.Sprintf(`
+[outputs.influxdb]
+  urls = ["http://localhost:8086"]
+
+[outputs.file]
+  files = [%q]
+  data_format = %q
+`, out.Name(), tt.dataFormat))
+		defer os.Remove(path)
+
+		c, err := LoadConfig(path)
+		require.NoError(t, err)
+
+		f := &file.File{}
+		_, err = c.ApplyOutput("file", f)
+		require.NoError(t, err)
+		assert.Equal(t, tt.dataFormat, f.DataFormat)
+		require.NoError(t, f.Connect())
+		require.NoError(t, f.Write([]*client.Point{pt}))
+		require.NoError(t, f.Close())
+
+		b, err := ioutil.ReadFile(out.Name())
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, string(b), tt.dataFormat)
+	}
+}
+
+func TestConfig_InvalidEnabled(t *testing.T) {
+	path := writeTempFile(t, `
+[rethinkdb]
+  enabled = "no"
+`)
+	defer os.Remove(path)
+
+	_, err := LoadConfig(path)
+	assert.Error(t, err)
+}
+
+// captureStdout returns what f prints to the standard output
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	f()
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestConfig_PrintPluginConfig(t *testing.T) {
+	r := plugins.Plugins["rethinkdb"]()
+	out := captureStdout(t, func() {
+		assert.NoError(t, PrintPluginConfig("rethinkdb"))
+	})
+
+	assert.Equal(t, "\n# "+r.Description()+"\n[rethinkdb]"+r.SampleConfig(), out)
+	assert.EqualError(t, PrintPluginConfig("no_such_plugin"), "Plugin no_such_plugin not found")
+}
+
+func TestConfig_DumpSampleConfigs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, DumpSampleConfigs(dir))
+
+	r := plugins.Plugins["rethinkdb"]()
+	data, err := ioutil.ReadFile(dir + "/plugins/rethinkdb.conf")
+	assert.NoError(t, err)
+	assert.Equal(t, "\n# "+r.Description()+"\n[rethinkdb]"+r.SampleConfig(), string(data))
+
+	o := outputs.Outputs["influxdb"]()
+	data, err = ioutil.ReadFile(dir + "/outputs/influxdb.conf")
+	assert.NoError(t, err)
+	assert.Equal(t, "\n# "+o.Description()+"\n[outputs.influxdb]"+o.SampleConfig(), string(data))
+
+	files, err := ioutil.ReadDir(dir + "/plugins")
+	assert.NoError(t, err)
+	assert.Equal(t, len(plugins.Plugins), len(files))
+}
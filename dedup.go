@@ -0,0 +1,61 @@
+package telegraf
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// dedup drops points whose fields are unchanged since the last point of the
+// same series was let through, as long as that was less than interval ago.
+type dedup struct {
+	interval time.Duration
+	last     map[string]dedupEntry
+}
+
+type dedupEntry struct {
+	fields map[string]interface{}
+	time   time.Time
+}
+
+func newDedup(interval time.Duration) *dedup {
+	return &dedup{
+		interval: interval,
+		last:     make(map[string]dedupEntry),
+	}
+}
+
+// ShouldPass returns false if the point repeats the previous point of its
+// series within the dedup interval.
+func (d *dedup) ShouldPass(pt *client.Point) bool {
+	key := seriesKey(pt)
+	now := pt.Time()
+
+	if entry, ok := d.last[key]; ok {
+		if now.Sub(entry.time) < d.interval &&
+			reflect.DeepEqual(entry.fields, pt.Fields()) {
+			return false
+		}
+	}
+
+	d.last[key] = dedupEntry{fields: pt.Fields(), time: now}
+	return true
+}
+
+func seriesKey(pt *client.Point) string {
+	tags := pt.Tags()
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := []string{pt.Name()}
+	for _, k := range keys {
+		parts = append(parts, k+"="+tags[k])
+	}
+	return strings.Join(parts, ",")
+}
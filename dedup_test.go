@@ -0,0 +1,53 @@
+package telegraf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func dedupPoint(value int64, tags map[string]string, t time.Time) *client.Point {
+	fields := map[string]interface{}{"value": value}
+	return client.NewPoint("rethinkdb_clients", tags, fields, t)
+}
+
+func TestDedup_DropsUnchanged(t *testing.T) {
+	d := newDedup(time.Minute)
+	now := time.Now()
+	tags := map[string]string{"host": "a"}
+
+	assert.True(t, d.ShouldPass(dedupPoint(3, tags, now)))
+	assert.False(t, d.ShouldPass(dedupPoint(3, tags, now.Add(10*time.Second))))
+	assert.False(t, d.ShouldPass(dedupPoint(3, tags, now.Add(20*time.Second))))
+}
+
+func TestDedup_PassesChanged(t *testing.T) {
+	d := newDedup(time.Minute)
+	now := time.Now()
+	tags := map[string]string{"host": "a"}
+
+	assert.True(t, d.ShouldPass(dedupPoint(3, tags, now)))
+	assert.True(t, d.ShouldPass(dedupPoint(4, tags, now.Add(10*time.Second))))
+	assert.True(t, d.ShouldPass(dedupPoint(3, tags, now.Add(20*time.Second))))
+}
+
+func TestDedup_SeriesAreIndependent(t *testing.T) {
+	d := newDedup(time.Minute)
+	now := time.Now()
+
+	assert.True(t, d.ShouldPass(dedupPoint(3, map[string]string{"host": "a"}, now)))
+	assert.True(t, d.ShouldPass(dedupPoint(3, map[string]string{"host": "b"}, now)))
+}
+
+func TestDedup_EmitsOncePerInterval(t *testing.T) {
+	d := newDedup(30 * time.Second)
+	now := time.Now()
+	tags := map[string]string{"host": "a"}
+
+	assert.True(t, d.ShouldPass(dedupPoint(3, tags, now)))
+	assert.False(t, d.ShouldPass(dedupPoint(3, tags, now.Add(10*time.Second))))
+	assert.True(t, d.ShouldPass(dedupPoint(3, tags, now.Add(30*time.Second))))
+	assert.False(t, d.ShouldPass(dedupPoint(3, tags, now.Add(40*time.Second))))
+}
@@ -0,0 +1,61 @@
+package telegraf
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+)
+
+// Counters of the agent published with expvar, served on the agent's
+// expvar_address under /debug/vars as the "telegraf" map.
+var (
+	expvarStats = expvar.NewMap("telegraf")
+
+	// gathers and gatherErrors count the calls to Gather of every plugin
+	// and how many of them failed, retries included
+	gathers      = new(expvar.Int)
+	gatherErrors = new(expvar.Int)
+
+	// writes and writeErrors count the writes to every output and how many
+	// of them failed. pointsWritten counts the points successfully written.
+	writes        = new(expvar.Int)
+	writeErrors   = new(expvar.Int)
+	pointsWritten = new(expvar.Int)
+)
+
+func init() {
+	expvarStats.Set("gathers", gathers)
+	expvarStats.Set("gather_errors", gatherErrors)
+	expvarStats.Set("writes", writes)
+	expvarStats.Set("write_errors", writeErrors)
+	expvarStats.Set("points_written", pointsWritten)
+}
+
+// serveExpvar serves the expvar counters on ExpvarAddress, along with the
+// number of points buffered for the agent's outputs. The returned listener
+// is closed to stop serving.
+func (a *Agent) serveExpvar() (net.Listener, error) {
+	expvarStats.Set("buffered", expvar.Func(func() interface{} {
+		buffered := 0
+		for _, o := range a.outputs {
+			buffered += o.Buffered()
+		}
+		return buffered
+	}))
+
+	listener, err := net.Listen("tcp", a.ExpvarAddress)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to serve expvar on %s, %s", a.ExpvarAddress, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	go func() {
+		// Serve returns once the listener is closed
+		http.Serve(listener, mux)
+	}()
+	log.Printf("Serving expvar on http://%s/debug/vars\n", listener.Addr())
+	return listener, nil
+}
@@ -0,0 +1,69 @@
+package telegraf
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type expvarCounters struct {
+	Gathers       int64 `json:"gathers"`
+	GatherErrors  int64 `json:"gather_errors"`
+	Writes        int64 `json:"writes"`
+	WriteErrors   int64 `json:"write_errors"`
+	PointsWritten int64 `json:"points_written"`
+	Buffered      int64 `json:"buffered"`
+}
+
+func scrapeExpvar(t *testing.T, addr string) expvarCounters {
+	resp, err := http.Get("http://" + addr + "/debug/vars")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var vars struct {
+		Telegraf expvarCounters `json:"telegraf"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&vars))
+	return vars.Telegraf
+}
+
+func TestExpvar(t *testing.T) {
+	out := &failingOutput{}
+	ro := NewRunningOutput("capture", out, nil)
+	a := &Agent{ExpvarAddress: "127.0.0.1:0", outputs: []*RunningOutput{ro}}
+	listener, err := a.serveExpvar()
+	require.NoError(t, err)
+	defer listener.Close()
+
+	before := scrapeExpvar(t, listener.Addr().String())
+
+	// a gather cycle, with one failed gather before the point is written
+	points := make(chan *client.Point, 10)
+	plugin := NewRunningInput("flaky", &flakyPlugin{failures: 1},
+		&ConfiguredPlugin{Name: "flaky", GatherRetries: 1})
+	withGatherRetryBackoff(time.Millisecond, func() {
+		require.NoError(t, a.gather(plugin, points, time.Second))
+	})
+	ro.AddPoint(<-points)
+
+	after := scrapeExpvar(t, listener.Addr().String())
+	assert.Equal(t, before.Gathers+2, after.Gathers)
+	assert.Equal(t, before.GatherErrors+1, after.GatherErrors)
+	assert.Equal(t, int64(1), after.Buffered)
+
+	out.fail = true
+	assert.Error(t, ro.Write())
+	out.fail = false
+	require.NoError(t, ro.Write())
+
+	after = scrapeExpvar(t, listener.Addr().String())
+	assert.Equal(t, before.Writes+2, after.Writes)
+	assert.Equal(t, before.WriteErrors+1, after.WriteErrors)
+	assert.Equal(t, before.PointsWritten+1, after.PointsWritten)
+	assert.Equal(t, int64(0), after.Buffered)
+}
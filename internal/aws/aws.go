@@ -0,0 +1,51 @@
+// Package aws holds a minimal client for the AWS query APIs used by
+// telegraf's plugins and outputs, signing requests with Signature Version 4.
+package aws
+
+import (
+	"fmt"
+	"os"
+)
+
+// CredentialConfig holds the region and credentials of a plugin or output
+// calling AWS. It is meant to be embedded, so the options are set with the
+// region, access_key, secret_key and token config keys. Credentials that are
+// not set are read from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN environment variables.
+type CredentialConfig struct {
+	Region    string
+	AccessKey string
+	SecretKey string
+	// Token is the session token of temporary credentials
+	Token string
+}
+
+// Credentials are the keys used to sign requests
+type Credentials struct {
+	AccessKey string
+	SecretKey string
+	Token     string
+}
+
+// Credentials returns the configured credentials, falling back to the
+// environment
+func (c *CredentialConfig) Credentials() (Credentials, error) {
+	creds := Credentials{
+		AccessKey: c.AccessKey,
+		SecretKey: c.SecretKey,
+		Token:     c.Token,
+	}
+	if creds.AccessKey == "" && creds.SecretKey == "" {
+		creds.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		creds.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if creds.Token == "" {
+			creds.Token = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+
+	if creds.AccessKey == "" || creds.SecretKey == "" {
+		return Credentials{}, fmt.Errorf("No AWS credentials, set access_key and secret_key " +
+			"or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return creds, nil
+}
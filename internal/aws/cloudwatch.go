@@ -0,0 +1,227 @@
+package aws
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const cloudWatchVersion = "2010-08-01"
+
+// CloudWatch calls the CloudWatch query API of a region
+type CloudWatch struct {
+	Credentials Credentials
+	Region      string
+	// Endpoint defaults to https://monitoring.<region>.amazonaws.com/
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewCloudWatch returns a CloudWatch client for the configured region and
+// credentials
+func NewCloudWatch(config *CredentialConfig) (*CloudWatch, error) {
+	if config.Region == "" {
+		return nil, fmt.Errorf("No AWS region set")
+	}
+	creds, err := config.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	return &CloudWatch{
+		Credentials: creds,
+		Region:      config.Region,
+		Client:      &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Dimension is a name/value pair identifying a metric
+type Dimension struct {
+	Name  string
+	Value string
+}
+
+// GetMetricStatisticsInput are the parameters of a GetMetricStatistics call
+type GetMetricStatisticsInput struct {
+	Namespace  string
+	MetricName string
+	Dimensions []Dimension
+	StartTime  time.Time
+	EndTime    time.Time
+	// Period is a multiple of 60 seconds
+	Period time.Duration
+	// Statistics are any of Average, Sum, SampleCount, Maximum and Minimum
+	Statistics []string
+}
+
+// Datapoint holds the statistics of a metric over a period. Statistics
+// that were not requested are nil.
+type Datapoint struct {
+	Timestamp   time.Time `xml:"Timestamp"`
+	Unit        string    `xml:"Unit"`
+	Average     *float64  `xml:"Average"`
+	Sum         *float64  `xml:"Sum"`
+	SampleCount *float64  `xml:"SampleCount"`
+	Maximum     *float64  `xml:"Maximum"`
+	Minimum     *float64  `xml:"Minimum"`
+}
+
+// Statistic returns the named statistic of the datapoint
+func (d *Datapoint) Statistic(name string) (float64, bool) {
+	var v *float64
+	switch name {
+	case "Average":
+		v = d.Average
+	case "Sum":
+		v = d.Sum
+	case "SampleCount":
+		v = d.SampleCount
+	case "Maximum":
+		v = d.Maximum
+	case "Minimum":
+		v = d.Minimum
+	}
+	if v == nil {
+		return 0, false
+	}
+	return *v, true
+}
+
+// GetMetricStatisticsOutput is the result of a GetMetricStatistics call
+type GetMetricStatisticsOutput struct {
+	Label      string      `xml:"GetMetricStatisticsResult>Label"`
+	Datapoints []Datapoint `xml:"GetMetricStatisticsResult>Datapoints>member"`
+}
+
+// GetMetricStatistics returns the datapoints of a metric
+func (c *CloudWatch) GetMetricStatistics(input *GetMetricStatisticsInput) (*GetMetricStatisticsOutput, error) {
+	params := url.Values{
+		"Action":     {"GetMetricStatistics"},
+		"Namespace":  {input.Namespace},
+		"MetricName": {input.MetricName},
+		"StartTime":  {input.StartTime.UTC().Format(time.RFC3339)},
+		"EndTime":    {input.EndTime.UTC().Format(time.RFC3339)},
+		"Period":     {strconv.Itoa(int(input.Period / time.Second))},
+	}
+	addDimensions(params, "Dimensions.member.", input.Dimensions)
+	for i, stat := range input.Statistics {
+		params.Set(fmt.Sprintf("Statistics.member.%d", i+1), stat)
+	}
+
+	output := &GetMetricStatisticsOutput{}
+	if err := c.call(params, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// MaxDatapoints is the number of datapoints PutMetricData accepts at once
+const MaxDatapoints = 20
+
+// MaxDimensions is the number of dimensions a metric may have
+const MaxDimensions = 10
+
+// MetricDatum is a datapoint published with PutMetricData
+type MetricDatum struct {
+	MetricName string
+	Dimensions []Dimension
+	Timestamp  time.Time
+	Value      float64
+	// Unit is optional, ie Seconds, Bytes or Count
+	Unit string
+}
+
+// PutMetricDataInput are the parameters of a PutMetricData call, which
+// takes at most MaxDatapoints datapoints
+type PutMetricDataInput struct {
+	Namespace  string
+	MetricData []MetricDatum
+}
+
+// PutMetricData publishes datapoints of custom metrics
+func (c *CloudWatch) PutMetricData(input *PutMetricDataInput) error {
+	params := url.Values{
+		"Action":    {"PutMetricData"},
+		"Namespace": {input.Namespace},
+	}
+	for i, d := range input.MetricData {
+		prefix := fmt.Sprintf("MetricData.member.%d.", i+1)
+		params.Set(prefix+"MetricName", d.MetricName)
+		params.Set(prefix+"Value", strconv.FormatFloat(d.Value, 'g', -1, 64))
+		if !d.Timestamp.IsZero() {
+			params.Set(prefix+"Timestamp", d.Timestamp.UTC().Format(time.RFC3339))
+		}
+		if d.Unit != "" {
+			params.Set(prefix+"Unit", d.Unit)
+		}
+		addDimensions(params, prefix+"Dimensions.member.", d.Dimensions)
+	}
+
+	return c.call(params, nil)
+}
+
+func addDimensions(params url.Values, prefix string, dimensions []Dimension) {
+	for i, d := range dimensions {
+		params.Set(fmt.Sprintf("%s%d.Name", prefix, i+1), d.Name)
+		params.Set(fmt.Sprintf("%s%d.Value", prefix, i+1), d.Value)
+	}
+}
+
+// Error is an error returned by the API
+type Error struct {
+	StatusCode int
+	Code       string `xml:"Error>Code"`
+	Message    string `xml:"Error>Message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("CloudWatch returned status %d, %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// call posts an action and decodes its response into output
+func (c *CloudWatch) call(params url.Values, output interface{}) error {
+	params.Set("Version", cloudWatchVersion)
+	body := []byte(params.Encode())
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = "https://monitoring." + c.Region + ".amazonaws.com/"
+	}
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	Sign(req, body, c.Credentials, "monitoring", c.Region, time.Now())
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		xml.Unmarshal(b, apiErr)
+		return apiErr
+	}
+	if output == nil {
+		return nil
+	}
+	if err := xml.Unmarshal(b, output); err != nil {
+		return fmt.Errorf("Error decoding CloudWatch response: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package aws
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const getMetricStatisticsResponse = `
+<GetMetricStatisticsResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+  <GetMetricStatisticsResult>
+    <Datapoints>
+      <member>
+        <Timestamp>2015-11-02T10:00:00Z</Timestamp>
+        <Unit>Seconds</Unit>
+        <Average>0.0125</Average>
+        <Maximum>0.75</Maximum>
+      </member>
+      <member>
+        <Timestamp>2015-11-02T10:05:00Z</Timestamp>
+        <Unit>Seconds</Unit>
+        <Average>0.0150</Average>
+        <Maximum>0.5</Maximum>
+      </member>
+    </Datapoints>
+    <Label>Latency</Label>
+  </GetMetricStatisticsResult>
+  <ResponseMetadata>
+    <RequestId>6a3aa1b8-8146-11e5-9e6c-d1a94b0e8f4e</RequestId>
+  </ResponseMetadata>
+</GetMetricStatisticsResponse>
+`
+
+const errorResponse = `
+<ErrorResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+  <Error>
+    <Type>Sender</Type>
+    <Code>InvalidClientTokenId</Code>
+    <Message>The security token included in the request is invalid.</Message>
+  </Error>
+  <RequestId>1f3b7c0c-8146-11e5-a4b4-1b2a1a9e6a3f</RequestId>
+</ErrorResponse>
+`
+
+func cloudWatchServer(t *testing.T, status int, response string, params *url.Values) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=AKID/"))
+		body, _ := ioutil.ReadAll(r.Body)
+		*params, _ = url.ParseQuery(string(body))
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+}
+
+func TestGetMetricStatistics(t *testing.T) {
+	var params url.Values
+	ts := cloudWatchServer(t, 200, getMetricStatisticsResponse, &params)
+	defer ts.Close()
+
+	c := &CloudWatch{
+		Credentials: Credentials{AccessKey: "AKID", SecretKey: "secret"},
+		Region:      "us-east-1",
+		Endpoint:    ts.URL,
+	}
+	end := time.Date(2015, 11, 2, 10, 10, 0, 0, time.UTC)
+	out, err := c.GetMetricStatistics(&GetMetricStatisticsInput{
+		Namespace:  "AWS/ELB",
+		MetricName: "Latency",
+		Dimensions: []Dimension{{Name: "LoadBalancerName", Value: "p-example"}},
+		StartTime:  end.Add(-10 * time.Minute),
+		EndTime:    end,
+		Period:     5 * time.Minute,
+		Statistics: []string{"Average", "Maximum"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, url.Values{
+		"Action":                    {"GetMetricStatistics"},
+		"Version":                   {"2010-08-01"},
+		"Namespace":                 {"AWS/ELB"},
+		"MetricName":                {"Latency"},
+		"Dimensions.member.1.Name":  {"LoadBalancerName"},
+		"Dimensions.member.1.Value": {"p-example"},
+		"StartTime":                 {"2015-11-02T10:00:00Z"},
+		"EndTime":                   {"2015-11-02T10:10:00Z"},
+		"Period":                    {"300"},
+		"Statistics.member.1":       {"Average"},
+		"Statistics.member.2":       {"Maximum"},
+	}, params)
+
+	assert.Equal(t, "Latency", out.Label)
+	require.Len(t, out.Datapoints, 2)
+	d := out.Datapoints[0]
+	assert.Equal(t, time.Date(2015, 11, 2, 10, 0, 0, 0, time.UTC), d.Timestamp)
+	assert.Equal(t, "Seconds", d.Unit)
+	avg, ok := d.Statistic("Average")
+	assert.True(t, ok)
+	assert.Equal(t, 0.0125, avg)
+	_, ok = d.Statistic("Sum")
+	assert.False(t, ok)
+}
+
+func TestPutMetricData(t *testing.T) {
+	var params url.Values
+	ts := cloudWatchServer(t, 200, "<PutMetricDataResponse/>", &params)
+	defer ts.Close()
+
+	c := &CloudWatch{
+		Credentials: Credentials{AccessKey: "AKID", SecretKey: "secret"},
+		Region:      "us-east-1",
+		Endpoint:    ts.URL,
+	}
+	err := c.PutMetricData(&PutMetricDataInput{
+		Namespace: "Telegraf",
+		MetricData: []MetricDatum{
+			{
+				MetricName: "rethinkdb_queries_per_sec",
+				Dimensions: []Dimension{{Name: "host", Value: "db1"}},
+				Timestamp:  time.Date(2015, 11, 2, 10, 0, 0, 0, time.UTC),
+				Value:      1234.5,
+			},
+			{MetricName: "uptime", Value: 10, Unit: "Seconds"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, url.Values{
+		"Action":                         {"PutMetricData"},
+		"Version":                        {"2010-08-01"},
+		"Namespace":                      {"Telegraf"},
+		"MetricData.member.1.MetricName": {"rethinkdb_queries_per_sec"},
+		"MetricData.member.1.Value":      {"1234.5"},
+		"MetricData.member.1.Timestamp":  {"2015-11-02T10:00:00Z"},
+		"MetricData.member.1.Dimensions.member.1.Name":  {"host"},
+		"MetricData.member.1.Dimensions.member.1.Value": {"db1"},
+		"MetricData.member.2.MetricName":                {"uptime"},
+		"MetricData.member.2.Value":                     {"10"},
+		"MetricData.member.2.Unit":                      {"Seconds"},
+	}, params)
+}
+
+func TestCloudWatchError(t *testing.T) {
+	var params url.Values
+	ts := cloudWatchServer(t, 403, errorResponse, &params)
+	defer ts.Close()
+
+	c := &CloudWatch{
+		Credentials: Credentials{AccessKey: "AKID", SecretKey: "secret"},
+		Region:      "us-east-1",
+		Endpoint:    ts.URL,
+	}
+	_, err := c.GetMetricStatistics(&GetMetricStatisticsInput{Namespace: "AWS/ELB"})
+	require.Error(t, err)
+	apiErr, ok := err.(*Error)
+	require.True(t, ok)
+	assert.Equal(t, 403, apiErr.StatusCode)
+	assert.Equal(t, "InvalidClientTokenId", apiErr.Code)
+}
+
+func TestCredentials(t *testing.T) {
+	c := &CredentialConfig{AccessKey: "AKID", SecretKey: "secret"}
+	creds, err := c.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{AccessKey: "AKID", SecretKey: "secret"}, creds)
+
+	c = &CredentialConfig{AccessKey: "AKID"}
+	_, err = c.Credentials()
+	assert.Error(t, err)
+
+	_, err = NewCloudWatch(&CredentialConfig{AccessKey: "AKID", SecretKey: "secret"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package aws
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The example request of the Signature Version 4 documentation
+func TestSign(t *testing.T) {
+	req, err := http.NewRequest("GET",
+		"https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	creds := Credentials{
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	Sign(req, nil, creds, "iam", "us-east-1", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 "+
+		"Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		req.Header.Get("Authorization"))
+}
+
+func TestSignSessionToken(t *testing.T) {
+	req, err := http.NewRequest("POST", "https://monitoring.us-east-1.amazonaws.com/", nil)
+	require.NoError(t, err)
+
+	creds := Credentials{AccessKey: "AKIDEXAMPLE", SecretKey: "secret", Token: "session"}
+	Sign(req, []byte("Action=ListMetrics"), creds, "monitoring", "us-east-1", time.Now())
+
+	assert.Equal(t, "session", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"),
+		"SignedHeaders=host;x-amz-date;x-amz-security-token")
+}
@@ -0,0 +1,158 @@
+// Package connpool caches the connections of plugins to the services they
+// gather, so that they are reused across Gathers instead of being opened and
+// closed on every one.
+package connpool
+
+import (
+	"io"
+	"sync"
+)
+
+// Pool is a cache of connections keyed by the service they connect to, ie
+// the URL of a server. It is safe for concurrent use.
+//
+// Get returns the connection of a key, opening it when there is none yet,
+// and every Get is matched by a Put or a Discard once the connection is no
+// longer used. Released connections stay open for the next Get until Close.
+type Pool struct {
+	mu    sync.Mutex
+	byKey map[string]*entry
+	// every open connection, cached or discarded but still in use
+	byConn map[io.Closer]*entry
+}
+
+type entry struct {
+	key  string
+	conn io.Closer
+	err  error
+	refs int
+	// cached is false once the connection is discarded, it is then closed
+	// when its last user releases it
+	cached bool
+	// ready is closed once the connection is opened or failed to open
+	ready chan struct{}
+}
+
+func New() *Pool {
+	return &Pool{
+		byKey:  make(map[string]*entry),
+		byConn: make(map[io.Closer]*entry),
+	}
+}
+
+// Get returns the cached connection of key, or opens it with open. Concurrent
+// Gets of the same key wait for a single open, whose error they all return.
+// Failed opens are not cached.
+func (p *Pool) Get(key string, open func() (io.Closer, error)) (io.Closer, error) {
+	p.mu.Lock()
+	e, ok := p.byKey[key]
+	if ok {
+		e.refs++
+		p.mu.Unlock()
+
+		<-e.ready
+		if e.err != nil {
+			return nil, e.err
+		}
+		return e.conn, nil
+	}
+
+	e = &entry{key: key, refs: 1, cached: true, ready: make(chan struct{})}
+	p.byKey[key] = e
+	p.mu.Unlock()
+
+	conn, err := open()
+
+	p.mu.Lock()
+	e.conn, e.err = conn, err
+	if err != nil {
+		p.remove(e)
+	} else {
+		// when the pool was closed meanwhile, the connection is closed once
+		// released like any other connection in use
+		p.byConn[conn] = e
+	}
+	p.mu.Unlock()
+	close(e.ready)
+
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Put releases a connection returned by Get, keeping it open for the next
+// Get of its key unless it was discarded or the pool was closed meanwhile.
+func (p *Pool) Put(conn io.Closer) {
+	p.release(conn, false)
+}
+
+// Discard releases a connection returned by Get and removes it from the
+// pool, ie after it failed, so that the next Get of its key opens a new one.
+// The connection is closed once every user of it released it.
+func (p *Pool) Discard(conn io.Closer) {
+	p.release(conn, true)
+}
+
+func (p *Pool) release(conn io.Closer, discard bool) {
+	p.mu.Lock()
+	e, ok := p.byConn[conn]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	e.refs--
+	if discard {
+		p.remove(e)
+	}
+	closing := !e.cached && e.refs <= 0
+	if closing {
+		delete(p.byConn, conn)
+	}
+	p.mu.Unlock()
+
+	if closing {
+		conn.Close()
+	}
+}
+
+// remove takes e out of the cache, p must be locked
+func (p *Pool) remove(e *entry) {
+	if p.byKey[e.key] == e {
+		delete(p.byKey, e.key)
+	}
+	e.cached = false
+}
+
+// Len returns the number of cached connections
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.byKey)
+}
+
+// Close closes every released connection and empties the pool, the
+// connections still in use are closed when they are released. The pool can
+// be used again afterwards, ie when its plugin is started again.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	var idle []io.Closer
+	for _, e := range p.byKey {
+		p.remove(e)
+	}
+	for conn, e := range p.byConn {
+		if e.refs <= 0 {
+			idle = append(idle, conn)
+			delete(p.byConn, conn)
+		}
+	}
+	p.mu.Unlock()
+
+	var err error
+	for _, conn := range idle {
+		if closeErr := conn.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
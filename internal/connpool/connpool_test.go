@@ -0,0 +1,178 @@
+package connpool
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConn struct {
+	key    string
+	closed int32
+}
+
+func (c *fakeConn) Close() error {
+	if atomic.AddInt32(&c.closed, 1) > 1 {
+		return fmt.Errorf("%s closed twice", c.key)
+	}
+	return nil
+}
+
+func (c *fakeConn) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) > 0
+}
+
+// opener returns an open func counting the connections it opens
+func opener(key string, opened *int32) func() (io.Closer, error) {
+	return func() (io.Closer, error) {
+		atomic.AddInt32(opened, 1)
+		return &fakeConn{key: key}, nil
+	}
+}
+
+func TestGetReusesConnection(t *testing.T) {
+	p := New()
+	var opened int32
+
+	c1, err := p.Get("a", opener("a", &opened))
+	require.NoError(t, err)
+	p.Put(c1)
+	c2, err := p.Get("a", opener("a", &opened))
+	require.NoError(t, err)
+	p.Put(c2)
+
+	assert.True(t, c1 == c2, "the released connection is reused")
+	assert.Equal(t, int32(1), opened)
+	assert.False(t, c1.(*fakeConn).isClosed())
+	assert.Equal(t, 1, p.Len())
+}
+
+func TestConcurrentGetPut(t *testing.T) {
+	p := New()
+	keys := []string{"a", "b", "c"}
+	opened := make([]int32, len(keys))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for k, key := range keys {
+			wg.Add(1)
+			go func(k int, key string) {
+				defer wg.Done()
+				conn, err := p.Get(key, opener(key, &opened[k]))
+				if assert.NoError(t, err) {
+					assert.Equal(t, key, conn.(*fakeConn).key)
+					p.Put(conn)
+				}
+			}(k, key)
+		}
+	}
+	wg.Wait()
+
+	for k := range keys {
+		assert.Equal(t, int32(1), opened[k], keys[k])
+	}
+	assert.Equal(t, len(keys), p.Len())
+	assert.NoError(t, p.Close())
+	assert.Equal(t, 0, p.Len())
+}
+
+func TestFailedOpenIsNotCached(t *testing.T) {
+	p := New()
+	_, err := p.Get("a", func() (io.Closer, error) {
+		return nil, errors.New("connection refused")
+	})
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 0, p.Len())
+
+	var opened int32
+	conn, err := p.Get("a", opener("a", &opened))
+	require.NoError(t, err)
+	p.Put(conn)
+	assert.Equal(t, int32(1), opened)
+}
+
+func TestDiscard(t *testing.T) {
+	p := New()
+	var opened int32
+
+	c1, err := p.Get("a", opener("a", &opened))
+	require.NoError(t, err)
+	shared, err := p.Get("a", opener("a", &opened))
+	require.NoError(t, err)
+
+	// the connection stays open for its other user
+	p.Discard(c1)
+	assert.False(t, c1.(*fakeConn).isClosed())
+	assert.Equal(t, 0, p.Len())
+
+	c2, err := p.Get("a", opener("a", &opened))
+	require.NoError(t, err)
+	assert.True(t, c1 != c2, "a discarded connection is not reused")
+	assert.Equal(t, int32(2), opened)
+
+	p.Put(shared)
+	assert.True(t, c1.(*fakeConn).isClosed())
+	p.Put(c2)
+	assert.False(t, c2.(*fakeConn).isClosed())
+}
+
+func TestCloseOnShutdown(t *testing.T) {
+	p := New()
+	var opened int32
+
+	idle, err := p.Get("idle", opener("idle", &opened))
+	require.NoError(t, err)
+	p.Put(idle)
+	inUse, err := p.Get("in_use", opener("in_use", &opened))
+	require.NoError(t, err)
+
+	assert.NoError(t, p.Close())
+	assert.True(t, idle.(*fakeConn).isClosed())
+	assert.False(t, inUse.(*fakeConn).isClosed())
+	assert.Equal(t, 0, p.Len())
+
+	p.Put(inUse)
+	assert.True(t, inUse.(*fakeConn).isClosed())
+
+	// the pool opens new connections after it was closed
+	conn, err := p.Get("idle", opener("idle", &opened))
+	require.NoError(t, err)
+	assert.True(t, idle != conn, "a closed connection is not reused")
+	p.Put(conn)
+	assert.NoError(t, p.Close())
+	assert.True(t, conn.(*fakeConn).isClosed())
+}
+
+func TestCloseWhileOpening(t *testing.T) {
+	p := New()
+	var opened int32
+	started := make(chan struct{})
+	proceed := make(chan struct{})
+
+	done := make(chan io.Closer)
+	go func() {
+		conn, err := p.Get("a", func() (io.Closer, error) {
+			close(started)
+			<-proceed
+			return opener("a", &opened)()
+		})
+		assert.NoError(t, err)
+		done <- conn
+	}()
+
+	<-started
+	assert.NoError(t, p.Close())
+	close(proceed)
+	conn := <-done
+
+	assert.False(t, conn.(*fakeConn).isClosed())
+	p.Put(conn)
+	assert.True(t, conn.(*fakeConn).isClosed())
+	assert.Equal(t, 0, p.Len())
+}
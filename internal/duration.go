@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Duration wraps time.Duration so it can be read from the TOML config file,
+// either as a string with units such as "5s" or "1m30s", or as an integer
+// number of seconds.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalTOML parses the duration from the TOML config file
+func (d *Duration) UnmarshalTOML(b []byte) error {
+	s := string(b)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		dur, err := time.ParseDuration(s[1 : len(s)-1])
+		if err != nil {
+			return fmt.Errorf("Invalid duration %s: %s", s, err)
+		}
+		d.Duration = dur
+		return nil
+	}
+
+	secs, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid duration %s, expected a string such as \"10s\"", s)
+	}
+	d.Duration = time.Duration(secs) * time.Second
+	return nil
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurationUnmarshalTOML(t *testing.T) {
+	tests := map[string]time.Duration{
+		`"5s"`:    5 * time.Second,
+		`"1m"`:    time.Minute,
+		`"1m30s"`: 90 * time.Second,
+		`"250ms"`: 250 * time.Millisecond,
+		`"1h"`:    time.Hour,
+		`'10s'`:   10 * time.Second,
+		`"0s"`:    0,
+		`10`:      10 * time.Second,
+		`0`:       0,
+	}
+
+	for raw, expected := range tests {
+		var d Duration
+		assert.NoError(t, d.UnmarshalTOML([]byte(raw)), raw)
+		assert.Equal(t, expected, d.Duration, raw)
+	}
+}
+
+func TestDurationUnmarshalTOMLInvalid(t *testing.T) {
+	for _, raw := range []string{
+		``,
+		`"`,
+		`""`,
+		`"5"`,
+		`"5 seconds"`,
+		`"abc"`,
+		`"5s'`,
+		`1.5`,
+		`true`,
+	} {
+		var d Duration
+		assert.Error(t, d.UnmarshalTOML([]byte(raw)), raw)
+	}
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTPProxy holds the proxy option of a plugin or output making HTTP
+// requests. It is meant to be embedded, so the option is set with the
+// http_proxy_url config key.
+type HTTPProxy struct {
+	// URL of the proxy all requests go through. When empty the HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables are honored.
+	HTTPProxyURL string `toml:"http_proxy_url"`
+}
+
+// DefaultUserAgent is the User-Agent of the requests of outputs that do not
+// set their own
+const DefaultUserAgent = "telegraf"
+
+// DefaultHTTPTimeout bounds the requests of outputs that do not set their
+// own timeout, so that a write to an unresponsive server does not hang
+const DefaultHTTPTimeout = 5 * time.Second
+
+// NewHTTPClient builds an *http.Client with the given timeout and TLS
+// config, sending its requests through proxyURL, or through the proxy from
+// the environment when proxyURL is empty.
+func NewHTTPClient(
+	timeout time.Duration,
+	tlsConfig *tls.Config,
+	proxyURL string,
+) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid proxy URL %s: %s", proxyURL, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("Invalid proxy URL %s", proxyURL)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:           proxy,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
+
+// Results of an HTTP scrape, used as the "result" tag of its stats
+const (
+	ScrapeSuccess          = "success"
+	ScrapeTimeout          = "timeout"
+	ScrapeDNSError         = "dns_error"
+	ScrapeConnectionFailed = "connection_failed"
+)
+
+// ScrapeStats returns the fields and the result describing an HTTP request
+// that took elapsed and returned resp and err. The fields are
+// response_time_ms and, when a response was received, http_response_code.
+func ScrapeStats(
+	elapsed time.Duration,
+	resp *http.Response,
+	err error,
+) (map[string]interface{}, string) {
+	fields := map[string]interface{}{
+		"response_time_ms": float64(elapsed) / float64(time.Millisecond),
+	}
+	if resp != nil {
+		fields["http_response_code"] = resp.StatusCode
+	}
+
+	return fields, ScrapeResult(err)
+}
+
+// ScrapeResult classifies the error of a request or connection attempt
+func ScrapeResult(err error) string {
+	if err == nil {
+		return ScrapeSuccess
+	}
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		return ScrapeTimeout
+	}
+
+	// unwrap *url.Error and *net.OpError to find a failed lookup
+	for err != nil {
+		switch e := err.(type) {
+		case *net.DNSError:
+			return ScrapeDNSError
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		default:
+			err = nil
+		}
+	}
+	return ScrapeConnectionFailed
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type timeoutError struct{}
+
+func (e timeoutError) Error() string   { return "timeout" }
+func (e timeoutError) Timeout() bool   { return true }
+func (e timeoutError) Temporary() bool { return true }
+
+func TestScrapeStats(t *testing.T) {
+	fields, result := ScrapeStats(1500*time.Microsecond, &http.Response{StatusCode: 500}, nil)
+	assert.Equal(t, ScrapeSuccess, result)
+	assert.Equal(t, map[string]interface{}{
+		"response_time_ms":   1.5,
+		"http_response_code": 500,
+	}, fields)
+
+	fields, result = ScrapeStats(time.Second,
+		nil, &url.Error{Op: "Get", URL: "http://localhost", Err: timeoutError{}})
+	assert.Equal(t, ScrapeTimeout, result)
+	assert.Equal(t, map[string]interface{}{"response_time_ms": 1000.0}, fields)
+
+	_, result = ScrapeStats(time.Second, nil, errors.New("connection refused"))
+	assert.Equal(t, ScrapeConnectionFailed, result)
+}
+
+func TestScrapeResultDNSError(t *testing.T) {
+	err := &url.Error{
+		Op:  "Get",
+		URL: "http://nonexistent.invalid",
+		Err: &net.OpError{
+			Op:  "dial",
+			Net: "tcp",
+			Err: &net.DNSError{Err: "no such host", Name: "nonexistent.invalid"},
+		},
+	}
+	assert.Equal(t, ScrapeDNSError, ScrapeResult(err))
+	assert.Equal(t, ScrapeSuccess, ScrapeResult(nil))
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(time.Second, nil, proxy.URL)
+	require.NoError(t, err)
+
+	resp, err := client.Get("http://stats.example.invalid/status")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "http://stats.example.invalid/status", proxied)
+}
+
+func TestNewHTTPClientInvalidProxy(t *testing.T) {
+	_, err := NewHTTPClient(time.Second, nil, "localhost")
+	assert.Error(t, err)
+
+	_, err = NewHTTPClient(time.Second, nil, "http://%zz")
+	assert.Error(t, err)
+}
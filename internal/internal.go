@@ -0,0 +1,2 @@
+// Package internal holds helpers shared by telegraf's plugins and outputs.
+package internal
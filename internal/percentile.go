@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrNoSamples is returned when a percentile is asked of an empty sample set.
+var ErrNoSamples = errors.New("No samples to compute a percentile from")
+
+// Percentile returns the p-th percentile (0 <= p <= 100) of samples. The
+// samples are not modified.
+//
+// It uses linear interpolation between the closest ranks: the samples are
+// sorted and the percentile lies at the fractional index (n-1)*p/100, so
+// the 0th percentile is the minimum, the 100th the maximum and the 50th the
+// median. This is the method used by Excel's PERCENTILE.INC and numpy's
+// default.
+func Percentile(samples []float64, p float64) (float64, error) {
+	if len(samples) == 0 {
+		return 0, ErrNoSamples
+	}
+	if math.IsNaN(p) || p < 0 || p > 100 {
+		return 0, fmt.Errorf("Invalid percentile %v, must be between 0 and 100", p)
+	}
+
+	sorted := make([]float64, len(samples))
+	copy(sorted, samples)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower], nil
+	}
+	frac := rank - float64(lower)
+	return sorted[lower] + frac*(sorted[upper]-sorted[lower]), nil
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	samples := []float64{15, 20, 35, 40, 50}
+	tests := map[float64]float64{
+		0:   15,
+		5:   16,
+		25:  20,
+		40:  29,
+		50:  35,
+		75:  40,
+		90:  46,
+		100: 50,
+	}
+
+	for p, expected := range tests {
+		v, err := Percentile(samples, p)
+		require.NoError(t, err)
+		assert.InDelta(t, expected, v, 1e-9, "percentile %v", p)
+	}
+}
+
+func TestPercentileUnsorted(t *testing.T) {
+	samples := []float64{10, 1, 7, 3, 5, 9, 2, 8, 4, 6}
+
+	v, err := Percentile(samples, 50)
+	require.NoError(t, err)
+	assert.InDelta(t, 5.5, v, 1e-9)
+
+	v, err = Percentile(samples, 90)
+	require.NoError(t, err)
+	assert.InDelta(t, 9.1, v, 1e-9)
+
+	// the samples are left untouched
+	assert.Equal(t, []float64{10, 1, 7, 3, 5, 9, 2, 8, 4, 6}, samples)
+}
+
+func TestPercentileSingleSample(t *testing.T) {
+	for _, p := range []float64{0, 50, 99, 100} {
+		v, err := Percentile([]float64{42}, p)
+		require.NoError(t, err)
+		assert.Equal(t, 42.0, v)
+	}
+}
+
+func TestPercentileErrors(t *testing.T) {
+	_, err := Percentile(nil, 50)
+	assert.Equal(t, ErrNoSamples, err)
+
+	_, err = Percentile([]float64{}, 50)
+	assert.Equal(t, ErrNoSamples, err)
+
+	_, err = Percentile([]float64{1, 2}, -1)
+	assert.Error(t, err)
+
+	_, err = Percentile([]float64{1, 2}, 101)
+	assert.Error(t, err)
+}
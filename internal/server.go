@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ParseServer parses an entry of a plugin's servers list. addr may be a bare
+// host, a host:port pair or a full URL with scheme and credentials, each
+// optionally followed by a query string, ie "10.0.0.1?authkey=secret"; when
+// addr does not name a port, defaultPort is used. Errors mask the
+// credentials of addr, see RedactAddress.
+func ParseServer(addr, defaultPort string) (*url.URL, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return nil, fmt.Errorf("Unable to parse address '': empty address")
+	}
+
+	u := &url.URL{Host: addr}
+	if strings.Contains(addr, "://") {
+		var err error
+		u, err = url.Parse(addr)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse address '%s': %s",
+				RedactAddress(addr), redactError(err))
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("Unable to parse address '%s': missing host",
+				RedactAddress(addr))
+		}
+	} else if i := strings.Index(addr, "?"); i >= 0 {
+		u = &url.URL{Host: addr[:i], RawQuery: addr[i+1:]}
+	}
+	if _, err := url.ParseQuery(u.RawQuery); err != nil {
+		return nil, fmt.Errorf("Unable to parse the query string of address '%s'",
+			RedactAddress(addr))
+	}
+
+	host, err := withDefaultPort(u.Host, defaultPort)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse address '%s': %s", RedactAddress(addr), err)
+	}
+	u.Host = host
+	return u, nil
+}
+
+var (
+	userinfoPassword = regexp.MustCompile(`(://[^/@]*?:)[^/@]*@`)
+	queryString      = regexp.MustCompile(`\?.*$`)
+)
+
+// RedactAddress returns addr with the password of its userinfo and its query
+// string, which may hold credentials, masked, so that it can be logged
+func RedactAddress(addr string) string {
+	addr = userinfoPassword.ReplaceAllString(addr, "${1}xxxxx@")
+	return queryString.ReplaceAllString(addr, "?xxxxx")
+}
+
+// redactError returns the message of an error of url.Parse without the URL
+// it quotes
+func redactError(err error) string {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err.Error()
+	}
+	return err.Error()
+}
+
+func withDefaultPort(hostport, defaultPort string) (string, error) {
+	// an unbracketed IPv6 address never carries a port
+	if ip := net.ParseIP(hostport); ip != nil {
+		if defaultPort == "" {
+			return hostport, nil
+		}
+		return net.JoinHostPort(hostport, defaultPort), nil
+	}
+
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		if strings.Contains(err.Error(), "missing port") {
+			host = strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+			port = defaultPort
+		} else {
+			return "", err
+		}
+	}
+	if host == "" {
+		return "", fmt.Errorf("missing host")
+	}
+	if port == "" {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]", nil
+		}
+		return host, nil
+	}
+	return net.JoinHostPort(host, port), nil
+}
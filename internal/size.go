@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Size is a number of bytes read from the TOML config file, either as an
+// integer or as a string with a unit such as "64MB" or "1GiB". KB, MB, GB
+// and TB are powers of 1000, KiB, MiB, GiB and TiB are powers of 1024.
+type Size struct {
+	Size int64
+}
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	// longest suffixes first so that "MiB" is not read as "B"
+	{"kib", 1 << 10},
+	{"mib", 1 << 20},
+	{"gib", 1 << 30},
+	{"tib", 1 << 40},
+	{"kb", 1000},
+	{"mb", 1000 * 1000},
+	{"gb", 1000 * 1000 * 1000},
+	{"tb", 1000 * 1000 * 1000 * 1000},
+	{"b", 1},
+}
+
+// UnmarshalTOML parses the size from the TOML config file
+func (s *Size) UnmarshalTOML(b []byte) error {
+	str := string(b)
+	if len(str) >= 2 && (str[0] == '"' || str[0] == '\'') && str[len(str)-1] == str[0] {
+		size, err := ParseSize(str[1 : len(str)-1])
+		if err != nil {
+			return err
+		}
+		s.Size = size
+		return nil
+	}
+
+	size, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("Invalid size %s, expected a string such as \"10MB\"", str)
+	}
+	s.Size = size
+	return nil
+}
+
+// ParseSize parses a human readable byte size such as "10MB" or "1GiB".
+// A number without a unit is a number of bytes.
+func ParseSize(str string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(str))
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.bytes
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid size '%s'", str)
+	}
+	size := n * float64(multiplier)
+	if size > float64(1<<63-1) {
+		return 0, fmt.Errorf("Invalid size '%s': too large", str)
+	}
+	return int64(size), nil
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizeUnmarshalTOML(t *testing.T) {
+	tests := map[string]int64{
+		`"10MB"`:   10 * 1000 * 1000,
+		`"1GiB"`:   1 << 30,
+		`"64KB"`:   64 * 1000,
+		`"64KiB"`:  64 * 1024,
+		`"2GB"`:    2 * 1000 * 1000 * 1000,
+		`"1TiB"`:   1 << 40,
+		`"512B"`:   512,
+		`"512"`:    512,
+		`"1.5MiB"`: 3 << 19,
+		`"10 mb"`:  10 * 1000 * 1000,
+		`'10MB'`:   10 * 1000 * 1000,
+		`1024`:     1024,
+		`0`:        0,
+	}
+
+	for raw, expected := range tests {
+		var s Size
+		assert.NoError(t, s.UnmarshalTOML([]byte(raw)), raw)
+		assert.Equal(t, expected, s.Size, raw)
+	}
+}
+
+func TestSizeUnmarshalTOMLInvalid(t *testing.T) {
+	for _, raw := range []string{
+		``,
+		`""`,
+		`"MB"`,
+		`"10XB"`,
+		`"ten MB"`,
+		`"-1MB"`,
+		`"10MB`,
+		`"99999999999TB"`,
+		`-1`,
+		`1.5`,
+		`true`,
+	} {
+		var s Size
+		assert.Error(t, s.UnmarshalTOML([]byte(raw)), raw)
+	}
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// ClientConfig holds the TLS options of a plugin or output connecting to a
+// server. It is meant to be embedded, so the options are set with the
+// ssl_ca, ssl_ca_dir, ssl_cert, ssl_key and insecure_skip_verify config keys.
+type ClientConfig struct {
+	// Path to the PEM encoded CA certificate(s) used to verify the server
+	SslCa string
+	// Directory of PEM encoded CA certificates, added to those of SslCa
+	SslCaDir string
+	// Path to the PEM encoded client certificate and key
+	SslCert string
+	SslKey  string
+	// Skip verification of the server's certificate chain and host name
+	InsecureSkipVerify bool
+}
+
+// TLSConfig builds a *tls.Config from the ClientConfig. It returns nil when
+// no TLS options are set, so callers keep their default transport.
+func (c *ClientConfig) TLSConfig() (*tls.Config, error) {
+	if c.SslCa == "" && c.SslCaDir == "" && c.SslCert == "" && c.SslKey == "" &&
+		!c.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.SslCa != "" || c.SslCaDir != "" {
+		pool := x509.NewCertPool()
+		if c.SslCa != "" {
+			pem, err := ioutil.ReadFile(c.SslCa)
+			if err != nil {
+				return nil, fmt.Errorf("Could not read CA certificate %s: %s", c.SslCa, err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("Could not parse any CA certificate from %s", c.SslCa)
+			}
+		}
+		if c.SslCaDir != "" {
+			if err := appendCertsFromDir(pool, c.SslCaDir); err != nil {
+				return nil, err
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.SslCert != "" || c.SslKey != "" {
+		if c.SslCert == "" || c.SslKey == "" {
+			return nil, fmt.Errorf("Both ssl_cert and ssl_key must be set")
+		}
+		cert, err := tls.LoadX509KeyPair(c.SslCert, c.SslKey)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load client certificate %s: %s", c.SslCert, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// appendCertsFromDir adds the certificates of every PEM file in dir to pool.
+// Files holding no certificate, ie a README, are skipped, but dir must hold
+// at least one certificate.
+func appendCertsFromDir(pool *x509.CertPool, dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("Could not read CA directory %s: %s", dir, err)
+	}
+
+	found := false
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Could not read CA certificate %s: %s", path, err)
+		}
+		if pool.AppendCertsFromPEM(pem) {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("Could not parse any CA certificate from %s", dir)
+	}
+	return nil
+}
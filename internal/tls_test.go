@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadCert(t *testing.T, path string) *x509.Certificate {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	block, _ := pem.Decode(data)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestTLSConfig(t *testing.T) {
+	c := &ClientConfig{
+		SslCa:   "testdata/ca.pem",
+		SslCert: "testdata/client.pem",
+		SslKey:  "testdata/client.key",
+	}
+
+	tlsConfig, err := c.TLSConfig()
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	ca := loadCert(t, "testdata/ca.pem")
+	client := loadCert(t, "testdata/client.pem")
+
+	require.NotNil(t, tlsConfig.RootCAs)
+	expected := x509.NewCertPool()
+	expected.AddCert(ca)
+	assert.True(t, tlsConfig.RootCAs.Equal(expected))
+
+	// the client certificate is signed by the CA in the pool
+	_, err = client.Verify(x509.VerifyOptions{
+		Roots:     tlsConfig.RootCAs,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	assert.NoError(t, err)
+
+	require.Len(t, tlsConfig.Certificates, 1)
+	assert.Equal(t, client.Raw, tlsConfig.Certificates[0].Certificate[0])
+}
+
+func TestTLSConfigEmpty(t *testing.T) {
+	c := &ClientConfig{}
+
+	tlsConfig, err := c.TLSConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+}
+
+func TestTLSConfigInsecureSkipVerify(t *testing.T) {
+	c := &ClientConfig{InsecureSkipVerify: true}
+
+	tlsConfig, err := c.TLSConfig()
+	require.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	assert.Nil(t, tlsConfig.RootCAs)
+	assert.Empty(t, tlsConfig.Certificates)
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	tests := []ClientConfig{
+		{SslCa: "testdata/missing.pem"},
+		{SslCa: "testdata/client.key"},
+		{SslCert: "testdata/client.pem"},
+		{SslCert: "testdata/missing.pem", SslKey: "testdata/client.key"},
+		{SslCaDir: "testdata/missing"},
+	}
+	for _, c := range tests {
+		_, err := c.TLSConfig()
+		assert.Error(t, err, "expected an error for %+v", c)
+	}
+}
+
+// writeCA writes a new self-signed CA certificate to path and returns it
+func writeCA(t *testing.T, path, name string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestTLSConfigCaDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-ca")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	first := writeCA(t, filepath.Join(dir, "first.pem"), "first")
+	second := writeCA(t, filepath.Join(dir, "second.crt"), "second")
+	// files without certificates and sub directories are skipped
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("CAs"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "old"), 0755))
+
+	c := &ClientConfig{SslCa: "testdata/ca.pem", SslCaDir: dir}
+	tlsConfig, err := c.TLSConfig()
+	require.NoError(t, err)
+
+	expected := x509.NewCertPool()
+	expected.AddCert(loadCert(t, "testdata/ca.pem"))
+	expected.AddCert(first)
+	expected.AddCert(second)
+	assert.True(t, tlsConfig.RootCAs.Equal(expected))
+}
+
+func TestTLSConfigCaDirEmpty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-ca")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = (&ClientConfig{SslCaDir: dir}).TLSConfig()
+	assert.Error(t, err)
+}
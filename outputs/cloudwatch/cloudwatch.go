@@ -0,0 +1,156 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal/aws"
+	"github.com/influxdb/telegraf/outputs"
+	"github.com/influxdb/telegraf/outputs/serializers"
+)
+
+// cloudWatchClient is the part of *aws.CloudWatch used to publish metrics
+type cloudWatchClient interface {
+	PutMetricData(input *aws.PutMetricDataInput) error
+}
+
+type CloudWatch struct {
+	aws.CredentialConfig
+
+	Namespace string
+	// MaxDimensions caps the number of tags published as dimensions, the
+	// tags are kept in alphabetical order
+	MaxDimensions int
+
+	client cloudWatchClient
+}
+
+var sampleConfig = `
+  # AWS region and credentials, read from AWS_ACCESS_KEY_ID,
+  # AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN when not set
+  region = "us-east-1"
+  # access_key = ""
+  # secret_key = ""
+  # token = ""
+
+  # Namespace of the custom metrics
+  namespace = "Telegraf"
+  # Number of tags published as dimensions, at most 10
+  # max_dimensions = 10
+`
+
+func (c *CloudWatch) Connect() error {
+	if c.Namespace == "" {
+		return fmt.Errorf("namespace is a required field for cloudwatch output")
+	}
+	if c.MaxDimensions > aws.MaxDimensions {
+		return fmt.Errorf("max_dimensions must be at most %d", aws.MaxDimensions)
+	}
+	client, err := aws.NewCloudWatch(&c.CredentialConfig)
+	if err != nil {
+		return err
+	}
+	c.client = client
+	return nil
+}
+
+func (c *CloudWatch) Close() error {
+	return nil
+}
+
+func (c *CloudWatch) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CloudWatch) Description() string {
+	return "Configuration for AWS CloudWatch output."
+}
+
+func (c *CloudWatch) Write(points []*client.Point) error {
+	var data []aws.MetricDatum
+	// CloudWatch stores a single value per metric
+	for _, pt := range serializers.Flatten(points) {
+		value, ok := numericValue(pt.Fields()["value"])
+		if !ok {
+			// string fields are skipped
+			continue
+		}
+		data = append(data, aws.MetricDatum{
+			MetricName: pt.Name(),
+			Dimensions: c.dimensions(pt.Tags()),
+			Timestamp:  pt.Time(),
+			Value:      value,
+		})
+	}
+
+	for len(data) > 0 {
+		n := len(data)
+		if n > aws.MaxDatapoints {
+			n = aws.MaxDatapoints
+		}
+		err := c.client.PutMetricData(&aws.PutMetricDataInput{
+			Namespace:  c.Namespace,
+			MetricData: data[:n],
+		})
+		if err != nil {
+			return fmt.Errorf("unable to put metric data, %s", err)
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// dimensions returns the tags of a point as dimensions, sorted by name and
+// capped at MaxDimensions
+func (c *CloudWatch) dimensions(tags map[string]string) []aws.Dimension {
+	max := c.MaxDimensions
+	if max <= 0 {
+		max = aws.MaxDimensions
+	}
+
+	names := make([]string, 0, len(tags))
+	for k, v := range tags {
+		// CloudWatch rejects empty dimension values
+		if v != "" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > max {
+		names = names[:max]
+	}
+
+	dimensions := make([]aws.Dimension, len(names))
+	for i, k := range names {
+		dimensions[i] = aws.Dimension{Name: k, Value: tags[k]}
+	}
+	return dimensions
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	switch d := v.(type) {
+	case int:
+		return float64(d), true
+	case int32:
+		return float64(d), true
+	case int64:
+		return float64(d), true
+	case float32:
+		return float64(d), true
+	case float64:
+		return d, true
+	case bool:
+		if d {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func init() {
+	outputs.Add("cloudwatch", func() outputs.Output {
+		return &CloudWatch{}
+	})
+}
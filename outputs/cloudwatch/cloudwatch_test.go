@@ -0,0 +1,135 @@
+package cloudwatch
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCloudWatchClient struct {
+	inputs []*aws.PutMetricDataInput
+	err    error
+}
+
+func (m *mockCloudWatchClient) PutMetricData(input *aws.PutMetricDataInput) error {
+	m.inputs = append(m.inputs, input)
+	return m.err
+}
+
+func TestWrite(t *testing.T) {
+	mock := &mockCloudWatchClient{}
+	c := &CloudWatch{Namespace: "Telegraf", client: mock}
+
+	now := time.Date(2015, 11, 2, 10, 0, 0, 0, time.UTC)
+	points := []*client.Point{
+		client.NewPoint(
+			"rethinkdb_queries_per_sec",
+			map[string]string{"host": "db1", "type": "cluster", "ns": ""},
+			map[string]interface{}{"value": 1234.5},
+			now,
+		),
+		client.NewPoint(
+			"rethinkdb_server",
+			map[string]string{"host": "db1"},
+			map[string]interface{}{"ready": true, "version": "2.1.5"},
+			now,
+		),
+	}
+	require.NoError(t, c.Write(points))
+
+	require.Len(t, mock.inputs, 1)
+	assert.Equal(t, &aws.PutMetricDataInput{
+		Namespace: "Telegraf",
+		MetricData: []aws.MetricDatum{
+			{
+				MetricName: "rethinkdb_queries_per_sec",
+				Dimensions: []aws.Dimension{
+					{Name: "host", Value: "db1"},
+					{Name: "type", Value: "cluster"},
+				},
+				Timestamp: now,
+				Value:     1234.5,
+			},
+			{
+				MetricName: "rethinkdb_server_ready",
+				Dimensions: []aws.Dimension{{Name: "host", Value: "db1"}},
+				Timestamp:  now,
+				Value:      1,
+			},
+		},
+	}, mock.inputs[0])
+}
+
+func TestWriteBatches(t *testing.T) {
+	mock := &mockCloudWatchClient{}
+	c := &CloudWatch{Namespace: "Telegraf", client: mock}
+
+	var points []*client.Point
+	for i := 0; i < 45; i++ {
+		points = append(points, client.NewPoint(
+			fmt.Sprintf("metric_%d", i),
+			nil,
+			map[string]interface{}{"value": i},
+			time.Now(),
+		))
+	}
+	require.NoError(t, c.Write(points))
+
+	require.Len(t, mock.inputs, 3)
+	assert.Len(t, mock.inputs[0].MetricData, 20)
+	assert.Len(t, mock.inputs[1].MetricData, 20)
+	assert.Len(t, mock.inputs[2].MetricData, 5)
+	assert.Equal(t, "metric_44", mock.inputs[2].MetricData[4].MetricName)
+}
+
+func TestWriteMaxDimensions(t *testing.T) {
+	mock := &mockCloudWatchClient{}
+	c := &CloudWatch{Namespace: "Telegraf", MaxDimensions: 2, client: mock}
+
+	pt := client.NewPoint(
+		"cpu_usage_idle",
+		map[string]string{"host": "db1", "cpu": "cpu0", "dc": "us-east-1a"},
+		map[string]interface{}{"value": 98.5},
+		time.Now(),
+	)
+	require.NoError(t, c.Write([]*client.Point{pt}))
+
+	require.Len(t, mock.inputs, 1)
+	assert.Equal(t, []aws.Dimension{
+		{Name: "cpu", Value: "cpu0"},
+		{Name: "dc", Value: "us-east-1a"},
+	}, mock.inputs[0].MetricData[0].Dimensions)
+}
+
+func TestWriteError(t *testing.T) {
+	mock := &mockCloudWatchClient{err: errors.New("throttled")}
+	c := &CloudWatch{Namespace: "Telegraf", client: mock}
+
+	pt := client.NewPoint("uptime", nil, map[string]interface{}{"value": 10}, time.Now())
+	assert.Error(t, c.Write([]*client.Point{pt}))
+
+	// nothing to publish
+	assert.NoError(t, c.Write(nil))
+	assert.Len(t, mock.inputs, 1)
+}
+
+func TestConnect(t *testing.T) {
+	c := &CloudWatch{}
+	assert.Error(t, c.Connect())
+
+	c = &CloudWatch{
+		CredentialConfig: aws.CredentialConfig{Region: "us-east-1", AccessKey: "AKID", SecretKey: "secret"},
+		Namespace:        "Telegraf",
+		MaxDimensions:    11,
+	}
+	assert.Error(t, c.Connect())
+
+	c.MaxDimensions = 10
+	assert.NoError(t, c.Connect())
+}
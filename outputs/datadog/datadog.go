@@ -10,13 +10,18 @@ import (
 	"strings"
 
 	"github.com/influxdb/influxdb/client/v2"
-	"github.com/influxdb/telegraf/duration"
+	"github.com/influxdb/telegraf/internal"
 	"github.com/influxdb/telegraf/outputs"
+	"github.com/influxdb/telegraf/outputs/serializers"
 )
 
 type Datadog struct {
-	Apikey  string
-	Timeout duration.Duration
+	Apikey    string
+	Timeout   internal.Duration
+	UserAgent string
+
+	internal.ClientConfig
+	internal.HTTPProxy
 
 	apiUrl string
 	client *http.Client
@@ -26,8 +31,22 @@ var sampleConfig = `
   # Datadog API key
   apikey = "my-secret-key" # required.
 
-  # Connection timeout.
+  # Timeout of each write. Defaults to 5s, "0s" waits forever.
   # timeout = "5s"
+  # User agent of the POSTs, ie to recognize them in the logs of a proxy
+  # user_agent = "telegraf"
+
+  # Optional TLS config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_ca_dir = "/etc/telegraf/ca.d"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  # HTTP proxy to send the writes through, HTTP_PROXY and HTTPS_PROXY
+  # from the environment are used when not set
+  # http_proxy_url = "http://localhost:8888"
 `
 
 type TimeSeries struct {
@@ -47,7 +66,8 @@ const datadog_api = "https://app.datadoghq.com/api/v1/series"
 
 func NewDatadog(apiUrl string) *Datadog {
 	return &Datadog{
-		apiUrl: apiUrl,
+		Timeout: internal.Duration{Duration: internal.DefaultHTTPTimeout},
+		apiUrl:  apiUrl,
 	}
 }
 
@@ -55,10 +75,13 @@ func (d *Datadog) Connect() error {
 	if d.Apikey == "" {
 		return fmt.Errorf("apikey is a required field for datadog output")
 	}
-	d.client = &http.Client{
-		Timeout: d.Timeout.Duration,
+	tlsConfig, err := d.TLSConfig()
+	if err != nil {
+		return err
 	}
-	return nil
+	d.client, err = internal.NewHTTPClient(d.Timeout.Duration, tlsConfig,
+		d.HTTPProxyURL)
+	return err
 }
 
 func (d *Datadog) Write(points []*client.Point) error {
@@ -66,18 +89,25 @@ func (d *Datadog) Write(points []*client.Point) error {
 		return nil
 	}
 	ts := TimeSeries{
-		Series: make([]*Metric, len(points)),
+		Series: make([]*Metric, 0, len(points)),
 	}
-	for index, pt := range points {
+	// datadog stores a single value per metric
+	for _, pt := range serializers.Flatten(points) {
+		p, err := buildPoint(pt)
+		if err != nil {
+			// datadog only stores numeric values, ie string fields are skipped
+			continue
+		}
 		metric := &Metric{
 			Metric: strings.Replace(pt.Name(), "_", ".", -1),
 			Tags:   buildTags(pt.Tags()),
 			Host:   pt.Tags()["host"],
 		}
-		if p, err := buildPoint(pt); err == nil {
-			metric.Points[0] = p
-		}
-		ts.Series[index] = metric
+		metric.Points[0] = p
+		ts.Series = append(ts.Series, metric)
+	}
+	if len(ts.Series) == 0 {
+		return nil
 	}
 	tsBytes, err := json.Marshal(ts)
 	if err != nil {
@@ -88,6 +118,7 @@ func (d *Datadog) Write(points []*client.Point) error {
 		return fmt.Errorf("unable to create http.Request, %s\n", err.Error())
 	}
 	req.Header.Add("Content-Type", "application/json")
+	req.Header.Set("User-Agent", d.userAgent())
 
 	resp, err := d.client.Do(req)
 	if err != nil {
@@ -110,6 +141,13 @@ func (d *Datadog) Description() string {
 	return "Configuration for DataDog API to send metrics to."
 }
 
+func (d *Datadog) userAgent() string {
+	if d.UserAgent == "" {
+		return internal.DefaultUserAgent
+	}
+	return d.UserAgent
+}
+
 func (d *Datadog) authenticatedUrl() string {
 	q := url.Values{
 		"api_key": []string{d.Apikey},
@@ -149,6 +187,12 @@ func (p *Point) setValue(v interface{}) error {
 		p[1] = float64(d)
 	case float64:
 		p[1] = float64(d)
+	case bool:
+		if d {
+			p[1] = 1
+		} else {
+			p[1] = 0
+		}
 	default:
 		return fmt.Errorf("undeterminable type")
 	}
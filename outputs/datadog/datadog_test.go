@@ -65,6 +65,77 @@ func TestBadStatusCode(t *testing.T) {
 	}
 }
 
+func TestWriteSkipsStringValues(t *testing.T) {
+	var series TimeSeries
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&series))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	d := NewDatadog(ts.URL)
+	d.Apikey = "123456"
+	require.NoError(t, d.Connect())
+
+	now := time.Now()
+	err := d.Write([]*client.Point{
+		client.NewPoint("version", nil, map[string]interface{}{"value": "2.1.5"}, now),
+		client.NewPoint("uptime", nil, map[string]interface{}{"value": 10.0}, now),
+	})
+	require.NoError(t, err)
+	require.Len(t, series.Series, 1)
+	assert.Equal(t, "uptime", series.Series[0].Metric)
+}
+
+func TestUserAgentAndTimeout(t *testing.T) {
+	release := make(chan struct{})
+	// the handler of the timed out write may still run while the test reads
+	userAgents := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents <- r.Header.Get("User-Agent")
+		if r.URL.Query().Get("api_key") == "slow" {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	d := NewDatadog(ts.URL)
+	assert.Equal(t, 5*time.Second, d.Timeout.Duration)
+	d.Apikey = "123456"
+	require.NoError(t, d.Connect())
+	require.NoError(t, d.Write(testutil.MockBatchPoints().Points()))
+	assert.Equal(t, "telegraf", <-userAgents)
+
+	d = NewDatadog(ts.URL)
+	d.Apikey = "slow"
+	d.UserAgent = "telegraf-audit"
+	d.Timeout.Duration = 50 * time.Millisecond
+	require.NoError(t, d.Connect())
+	assert.Error(t, d.Write(testutil.MockBatchPoints().Points()))
+	assert.Equal(t, "telegraf-audit", <-userAgents)
+}
+
+func TestHTTPProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	d := NewDatadog("http://api.example.invalid/api/v1/series")
+	d.Apikey = "123456"
+	d.HTTPProxyURL = proxy.URL
+	require.NoError(t, d.Connect())
+	require.NoError(t, d.Write(testutil.MockBatchPoints().Points()))
+	assert.Equal(t, "http://api.example.invalid/api/v1/series?api_key=123456", proxied)
+
+	d.HTTPProxyURL = "localhost"
+	assert.Error(t, d.Connect())
+}
+
 func TestAuthenticatedUrl(t *testing.T) {
 	d := fakeDatadog()
 
@@ -196,6 +267,19 @@ func TestBuildPoint(t *testing.T) {
 			},
 			fmt.Errorf("unable to extract value from Fields, undeterminable type"),
 		},
+		{
+			client.NewPoint(
+				"test8",
+				tags,
+				map[string]interface{}{"value": true},
+				time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+			),
+			Point{
+				float64(time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC).Unix()),
+				1.0,
+			},
+			nil,
+		},
 	}
 	for _, tt := range tagtests {
 		pt, err := buildPoint(tt.ptIn)
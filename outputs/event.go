@@ -0,0 +1,47 @@
+package outputs
+
+import (
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// EventMeasurement is the measurement events are written to. Events are
+// points of this measurement, so that outputs without support for events
+// write them like any other point.
+const EventMeasurement = "events"
+
+// Event is a discrete occurrence, ie a deploy or a failed check, rather than
+// a sample of a time series.
+type Event struct {
+	Title string
+	Text  string
+	Tags  map[string]string
+	Time  time.Time
+}
+
+// NewEventPoint returns the point e is written as, its title and text being
+// the fields of the point
+func NewEventPoint(e Event) *client.Point {
+	fields := map[string]interface{}{
+		"title": e.Title,
+		"text":  e.Text,
+	}
+	return client.NewPoint(EventMeasurement, e.Tags, fields, e.Time)
+}
+
+// ParseEvent returns the event pt was created from by NewEventPoint, for
+// outputs that handle events differently from metrics. ok is false when pt
+// is not an event.
+func ParseEvent(pt *client.Point) (e Event, ok bool) {
+	if pt.Name() != EventMeasurement {
+		return e, false
+	}
+	fields := pt.Fields()
+	title, ok := fields["title"].(string)
+	if !ok {
+		return e, false
+	}
+	text, _ := fields["text"].(string)
+	return Event{Title: title, Text: text, Tags: pt.Tags(), Time: pt.Time()}, true
+}
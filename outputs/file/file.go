@@ -0,0 +1,132 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/outputs"
+	"github.com/influxdb/telegraf/outputs/serializers"
+)
+
+type File struct {
+	Files []string
+
+	// DataFormat is "influx", "json", "graphite" or "csv"
+	DataFormat string   `toml:"data_format"`
+	CSVColumns []string `toml:"csv_columns"`
+	CSVHeader  bool     `toml:"csv_header"`
+	Template   string   `toml:"template"`
+	Prefix     string   `toml:"prefix"`
+
+	// FieldInclude are the only field keys written, all fields are when it
+	// is empty
+	FieldInclude []string `toml:"field_include"`
+
+	writers    []io.Writer
+	closers    []io.Closer
+	serializer serializers.Serializer
+}
+
+var sampleConfig = `
+  # Files to write to, "stdout" is a specially handled file.
+  files = ["stdout", "/tmp/metrics.out"]
+
+  # Data format to output, "influx" (line protocol), "json" (one object per
+  # line), "graphite" (plaintext protocol) or "csv"
+  data_format = "influx"
+
+  # CSV column order. "tags" and "fields" expand to one column per tag or
+  # field key, sorted by key. Points with different keys share the union of
+  # all columns seen, leaving the ones they lack empty.
+  # csv_columns = ["timestamp", "measurement", "tags", "fields"]
+  # Write a header row, repeated whenever new columns appear
+  # csv_header = true
+
+  # Graphite metric names, dot separated components: "measurement", "field",
+  # "tags" (the tags not used by other components) or a tag key. Alternatives
+  # are separated by "|", ie "dc|region", and the first one set is used.
+  # template = "host.tags.measurement.field"
+  # prefix = "telegraf"
+
+  # Only write these fields, ie of very wide measurements. Points without any
+  # of them are not written. Default is to write all fields.
+  # field_include = ["queries_per_sec", "value"]
+`
+
+func (f *File) Connect() error {
+	serializer, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat:   f.DataFormat,
+		CSVColumns:   f.CSVColumns,
+		CSVHeader:    f.CSVHeader,
+		Template:     f.Template,
+		Prefix:       f.Prefix,
+		FieldInclude: f.FieldInclude,
+	})
+	if err != nil {
+		return err
+	}
+	f.serializer = serializer
+
+	if len(f.Files) == 0 {
+		f.Files = []string{"stdout"}
+	}
+
+	for _, file := range f.Files {
+		if file == "stdout" {
+			f.writers = append(f.writers, os.Stdout)
+			continue
+		}
+		of, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("Unable to open file %s, %s", file, err)
+		}
+		f.writers = append(f.writers, of)
+		f.closers = append(f.closers, of)
+	}
+	return nil
+}
+
+func (f *File) Close() error {
+	var err error
+	for _, c := range f.closers {
+		if cerr := c.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+	f.writers = nil
+	f.closers = nil
+	return err
+}
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Send telegraf metrics to file(s)"
+}
+
+func (f *File) Write(points []*client.Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	b, err := f.serializer.Serialize(points)
+	if err != nil {
+		return fmt.Errorf("Failed to serialize points, %s", err)
+	}
+	for _, w := range f.writers {
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("Failed to write to file, %s", err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("file", func() outputs.Output {
+		return &File{DataFormat: "influx", CSVHeader: true}
+	})
+}
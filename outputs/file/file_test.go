@@ -0,0 +1,68 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWriteCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.csv")
+
+	f := &File{
+		Files:      []string{path},
+		DataFormat: "csv",
+		CSVColumns: []string{"measurement", "tags", "fields"},
+		CSVHeader:  true,
+	}
+	require.NoError(t, f.Connect())
+
+	pt := client.NewPoint("rethinkdb_active_clients",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": int64(3)},
+		time.Now())
+	require.NoError(t, f.Write([]*client.Point{pt}))
+	require.NoError(t, f.Write([]*client.Point{pt}))
+	require.NoError(t, f.Close())
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "measurement,host,value\n"+
+		"rethinkdb_active_clients,localhost,3\n"+
+		"rethinkdb_active_clients,localhost,3\n", string(b))
+}
+
+func TestFileBadDataFormat(t *testing.T) {
+	f := &File{DataFormat: "xml"}
+	assert.Error(t, f.Connect())
+}
+
+func TestFileFieldInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.out")
+
+	f := &File{Files: []string{path}, FieldInclude: []string{"queries_per_sec"}}
+	require.NoError(t, f.Connect())
+
+	pt := client.NewPoint("rethinkdb_engine",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"clients": int64(2), "queries_per_sec": int64(7)},
+		time.Unix(0, 0))
+	require.NoError(t, f.Write([]*client.Point{pt}))
+	require.NoError(t, f.Close())
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "rethinkdb_engine,host=localhost queries_per_sec=7i 0\n", string(b))
+}
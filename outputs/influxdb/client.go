@@ -0,0 +1,248 @@
+package influxdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal"
+)
+
+// writeError is returned by httpClient.Write when the server answers with an
+// unsuccessful status code.
+type writeError struct {
+	statusCode int
+	retryAfter time.Duration
+	message    string
+}
+
+func (e *writeError) Error() string {
+	return e.message
+}
+
+// temporary reports whether the write may succeed if the same batch is sent
+// again.
+func (e *writeError) temporary() bool {
+	return e.statusCode >= 500
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as
+// an HTTP date, returning 0 if it is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(time.Now()); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// httpClient is a client.Client that allows a full TLS configuration, which
+// the vendored InfluxDB client does not support.
+type httpClient struct {
+	url       url.URL
+	username  string
+	password  string
+	userAgent string
+	client    *http.Client
+
+	// v2 is set when writing with the InfluxDB 2.x API
+	v2 *v2Write
+}
+
+// v2Write holds the options of writes to the /api/v2/write endpoint of
+// InfluxDB 2.x, which writes to a bucket of an organization and
+// authenticates with a token.
+type v2Write struct {
+	org    string
+	bucket string
+	token  string
+	// database is the default database, the batches routed to another
+	// database by InfluxDB.DatabaseTag are written to the bucket of its name
+	database string
+}
+
+// v2Precisions maps the precisions of the 1.x API to the ones accepted by
+// the 2.x API, which has no minute or hour precision
+var v2Precisions = map[string]string{
+	"":   "ns",
+	"n":  "ns",
+	"ns": "ns",
+	"u":  "us",
+	"us": "us",
+	"ms": "ms",
+	"s":  "s",
+}
+
+// isV2URL reports whether u points at the InfluxDB 2.x API, ie
+// "http://localhost:8086/api/v2"
+func isV2URL(u *url.URL) bool {
+	return strings.HasPrefix(u.Path, "/api/v2")
+}
+
+func newHTTPClient(
+	u *url.URL,
+	username, password, userAgent string,
+	client *http.Client,
+) *httpClient {
+	if userAgent == "" {
+		userAgent = internal.DefaultUserAgent
+	}
+	return &httpClient{
+		url:       *u,
+		username:  username,
+		password:  password,
+		userAgent: userAgent,
+		client:    client,
+	}
+}
+
+func (c *httpClient) newRequest(method, path string, params url.Values, body *bytes.Buffer) (*http.Request, error) {
+	u := c.url
+	u.Path = path
+	u.RawQuery = params.Encode()
+
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequest(method, u.String(), body)
+	} else {
+		req, err = http.NewRequest(method, u.String(), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	return req, nil
+}
+
+func (c *httpClient) Write(bp client.BatchPoints) error {
+	var b bytes.Buffer
+	for _, p := range bp.Points() {
+		b.WriteString(p.PrecisionString(bp.Precision()))
+		b.WriteByte('\n')
+	}
+
+	path := "write"
+	params := url.Values{}
+	if c.v2 != nil {
+		path = "api/v2/write"
+		params.Set("org", c.v2.org)
+		bucket := c.v2.bucket
+		if db := bp.Database(); db != c.v2.database {
+			bucket = db
+		}
+		params.Set("bucket", bucket)
+		params.Set("precision", v2Precisions[bp.Precision()])
+	} else {
+		params.Set("db", bp.Database())
+		params.Set("rp", bp.RetentionPolicy())
+		params.Set("precision", bp.Precision())
+		params.Set("consistency", bp.WriteConsistency())
+	}
+
+	req, err := c.newRequest("POST", path, params, &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "")
+	if c.v2 != nil {
+		req.Header.Set("Authorization", "Token "+c.v2.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		message := string(body)
+		if message == "" {
+			message = fmt.Sprintf("received status code %d from server",
+				resp.StatusCode)
+		}
+		return &writeError{
+			statusCode: resp.StatusCode,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			message:    message,
+		}
+	}
+	return nil
+}
+
+func (c *httpClient) Query(q client.Query) (*client.Response, error) {
+	params := url.Values{}
+	params.Set("q", q.Command)
+	params.Set("db", q.Database)
+	if q.Precision != "" {
+		params.Set("epoch", q.Precision)
+	}
+
+	req, err := c.newRequest("GET", "query", params, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+		Error string `json:"error"`
+	}
+	decErr := json.NewDecoder(resp.Body).Decode(&body)
+	if decErr != nil && !(decErr.Error() == "EOF" && resp.StatusCode != http.StatusOK) {
+		return nil, decErr
+	}
+
+	response := &client.Response{}
+	if body.Error != "" {
+		response.Err = errors.New(body.Error)
+	}
+	for _, result := range body.Results {
+		r := client.Result{}
+		if result.Error != "" {
+			r.Err = errors.New(result.Error)
+		}
+		response.Results = append(response.Results, r)
+	}
+
+	if err := response.Error(); err != nil {
+		return response, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("received status code %d from server",
+			resp.StatusCode)
+	}
+	return response, nil
+}
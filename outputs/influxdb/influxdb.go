@@ -7,9 +7,10 @@ import (
 	"math/rand"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/influxdb/influxdb/client/v2"
-	"github.com/influxdb/telegraf/duration"
+	"github.com/influxdb/telegraf/internal"
 	"github.com/influxdb/telegraf/outputs"
 )
 
@@ -22,9 +23,34 @@ type InfluxDB struct {
 	Database  string
 	UserAgent string
 	Precision string
-	Timeout   duration.Duration
+	Timeout   internal.Duration
+
+	// DatabaseTag routes each point to the database named by the value of
+	// this tag, points without it are written to Database
+	DatabaseTag string
+
+	// Version 2 writes with the InfluxDB 2.x API, to Bucket of Organization
+	// authenticated with Token. It is also used for urls pointing at the
+	// 2.x API, ie "http://localhost:8086/api/v2".
+	Version      int
+	Token        string
+	Organization string
+	Bucket       string
+
+	// MaxRetries is the number of times a batch is sent again after every
+	// server answered with a temporary (5xx) error.
+	MaxRetries   int
+	RetryBackoff internal.Duration
+
+	internal.ClientConfig
+	internal.HTTPProxy
 
 	conns []client.Client
+	// v1Conns are the conns writing with the 1.x API, on which the
+	// databases are created
+	v1Conns []client.Client
+	// created are the databases created so far, see createDatabase
+	created map[string]bool
 }
 
 var sampleConfig = `
@@ -33,17 +59,48 @@ var sampleConfig = `
   urls = ["http://localhost:8086"] # required
   # The target database for metrics (telegraf will create it if not exists)
   database = "telegraf" # required
+  # Write each point to the database named by the value of this tag, ie
+  # team = "ops" to the ops database, created if it does not exist. Points
+  # without the tag are written to the database above. With InfluxDB 2.x the
+  # tag names the bucket instead.
+  # database_tag = "team"
   # Precision of writes, valid values are n, u, ms, s, m, and h
   # note: using second precision greatly helps InfluxDB compression
   precision = "s"
 
-  # Connection timeout (for the connection with InfluxDB), formatted as a string.
-  # If not provided, will default to 0 (no timeout)
+  # Timeout of each write and query, formatted as a string. Defaults to
+  # 5s, "0s" waits forever.
   # timeout = "5s"
   # username = "telegraf"
   # password = "metricsmetricsmetricsmetrics"
   # Set the user agent for the POSTs (can be useful for log differentiation)
   # user_agent = "telegraf"
+
+  # Number of times to retry a batch when the servers return a 5xx error,
+  # waiting retry_backoff (doubled on each attempt) or the Retry-After given
+  # by the server between attempts
+  # max_retries = 0
+  # retry_backoff = "1s"
+
+  # HTTP proxy to send the writes through, HTTP_PROXY and HTTPS_PROXY
+  # from the environment are used when not set
+  # http_proxy_url = "http://localhost:8888"
+
+  # InfluxDB 2.x support, also used for urls ending in /api/v2. Points are
+  # written to the bucket, which defaults to the database, of the
+  # organization, authenticated with the token. Precision can't be m or h.
+  # version = 2
+  # token = "my-token"
+  # organization = "my-org"
+  # bucket = "telegraf"
+
+  # Optional TLS config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_ca_dir = "/etc/telegraf/ca.d"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
 `
 
 func (i *InfluxDB) Connect() error {
@@ -66,32 +123,91 @@ func (i *InfluxDB) Connect() error {
 		urls = append(urls, u)
 	}
 
-	var conns []client.Client
+	tlsConfig, err := i.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := internal.NewHTTPClient(i.Timeout.Duration, tlsConfig,
+		i.HTTPProxyURL)
+	if err != nil {
+		return err
+	}
+
+	var conns, v1Conns []client.Client
 	for _, parsed_url := range urls {
-		c := client.NewClient(client.Config{
-			URL:       parsed_url,
-			Username:  i.Username,
-			Password:  i.Password,
-			UserAgent: i.UserAgent,
-			Timeout:   i.Timeout.Duration,
-		})
+		c := newHTTPClient(parsed_url, i.Username, i.Password, i.UserAgent,
+			httpClient)
+		if i.Version == 2 || isV2URL(parsed_url) {
+			if c.v2, err = i.v2Write(); err != nil {
+				return err
+			}
+		} else {
+			v1Conns = append(v1Conns, c)
+		}
 		conns = append(conns, c)
 	}
 
-	for _, conn := range conns {
+	i.conns = conns
+	i.v1Conns = v1Conns
+	i.created = nil
+	i.createDatabase(i.Database)
+	return nil
+}
+
+// createDatabase creates the database db unless it was created already. A
+// database whose creation failed on every server is created again with the
+// next write. Buckets of InfluxDB 2.x are not created by telegraf.
+func (i *InfluxDB) createDatabase(db string) {
+	if i.created[db] {
+		return
+	}
+
+	for _, conn := range i.v1Conns {
 		_, e := conn.Query(client.Query{
-			Command: fmt.Sprintf("CREATE DATABASE %s", i.Database),
+			Command: "CREATE DATABASE " + quoteIdent(db),
 		})
 
 		if e != nil && !strings.Contains(e.Error(), "database already exists") {
 			log.Println("Database creation failed: " + e.Error())
 		} else {
+			if i.created == nil {
+				i.created = make(map[string]bool)
+			}
+			i.created[db] = true
 			break
 		}
 	}
+}
 
-	i.conns = conns
-	return nil
+// quoteIdent quotes name as an InfluxQL identifier, so database names taken
+// from tag values may hold any character, ie "my-team.ops"
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `\"`, -1) + `"`
+}
+
+// v2Write returns the options of writes with the InfluxDB 2.x API
+func (i *InfluxDB) v2Write() (*v2Write, error) {
+	if i.Organization == "" {
+		return nil, errors.New("Organization must be set for InfluxDB 2.x")
+	}
+	if _, ok := v2Precisions[i.Precision]; !ok {
+		return nil, fmt.Errorf("Precision %s is not supported by InfluxDB 2.x",
+			i.Precision)
+	}
+	bucket := i.Bucket
+	if bucket == "" {
+		bucket = i.Database
+	}
+	if bucket == "" {
+		return nil, errors.New("Bucket or database must be set for InfluxDB 2.x")
+	}
+	return &v2Write{
+		org:      i.Organization,
+		bucket:   bucket,
+		token:    i.Token,
+		database: i.Database,
+	}, nil
 }
 
 func (i *InfluxDB) Close() error {
@@ -107,35 +223,115 @@ func (i *InfluxDB) Description() string {
 	return "Configuration for influxdb server to send metrics to"
 }
 
-// Choose a random server in the cluster to write to until a successful write
-// occurs, logging each unsuccessful. If all servers fail, return error.
+const defaultRetryBackoff = time.Second
+
+// retrySleep is replaced in tests to avoid waiting between retries.
+var retrySleep = time.Sleep
+
+// Write sends the points to the cluster, in a batch per database when
+// DatabaseTag is set. The batches that fail do not keep the others from
+// being written, their errors are joined.
 func (i *InfluxDB) Write(points []*client.Point) error {
-	bp, _ := client.NewBatchPoints(client.BatchPointsConfig{
-		Database:  i.Database,
-		Precision: i.Precision,
-	})
+	batches := i.batches(points)
+	if len(batches) == 1 {
+		return i.writeRetrying(batches[0])
+	}
+
+	var errs []string
+	for _, bp := range batches {
+		if err := i.writeRetrying(bp); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", bp.Database(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
 
+// batches splits the points by the database they are written to, see
+// DatabaseTag, in the order the databases first appear
+func (i *InfluxDB) batches(points []*client.Point) []client.BatchPoints {
+	var batches []client.BatchPoints
+	byDatabase := make(map[string]client.BatchPoints)
 	for _, point := range points {
+		db := i.Database
+		if i.DatabaseTag != "" {
+			if value := point.Tags()[i.DatabaseTag]; value != "" {
+				db = value
+			}
+		}
+		bp, ok := byDatabase[db]
+		if !ok {
+			i.createDatabase(db)
+			bp, _ = client.NewBatchPoints(client.BatchPointsConfig{
+				Database:  db,
+				Precision: i.Precision,
+			})
+			byDatabase[db] = bp
+			batches = append(batches, bp)
+		}
 		bp.AddPoint(point)
 	}
+	return batches
+}
+
+// writeRetrying sends a batch to the cluster, retrying it up to MaxRetries
+// times while the servers only return temporary errors.
+func (i *InfluxDB) writeRetrying(bp client.BatchPoints) error {
+	backoff := i.RetryBackoff.Duration
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		wait, retry, err := i.writeBatch(bp)
+		if err == nil || !retry || attempt >= i.MaxRetries {
+			return err
+		}
+		if wait <= 0 {
+			wait = backoff << uint(attempt)
+		}
+		log.Printf("Retrying write to InfluxDB in %s (%d/%d)\n",
+			wait, attempt+1, i.MaxRetries)
+		retrySleep(wait)
+	}
+}
 
+// writeBatch chooses a random server in the cluster to write to until a
+// successful write occurs, logging each unsuccessful. If all servers fail it
+// returns an error, whether every failure was temporary, and the longest
+// Retry-After requested by the servers.
+func (i *InfluxDB) writeBatch(bp client.BatchPoints) (time.Duration, bool, error) {
 	// This will get set to nil if a successful write occurs
 	err := errors.New("Could not write to any InfluxDB server in cluster")
 
+	var wait time.Duration
+	retry := len(i.conns) > 0
 	p := rand.Perm(len(i.conns))
 	for _, n := range p {
-		if e := i.conns[n].Write(bp); e != nil {
-			log.Println("ERROR: " + e.Error())
-		} else {
-			err = nil
-			break
+		e := i.conns[n].Write(bp)
+		if e == nil {
+			return 0, false, nil
+		}
+		log.Println("ERROR: " + e.Error())
+
+		werr, ok := e.(*writeError)
+		if !ok || !werr.temporary() {
+			retry = false
+			continue
+		}
+		if werr.retryAfter > wait {
+			wait = werr.retryAfter
 		}
 	}
-	return err
+	return wait, retry, err
 }
 
 func init() {
 	outputs.Add("influxdb", func() outputs.Output {
-		return &InfluxDB{}
+		return &InfluxDB{
+			Timeout: internal.Duration{Duration: internal.DefaultHTTPTimeout},
+		}
 	})
 }
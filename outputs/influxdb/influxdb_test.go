@@ -0,0 +1,520 @@
+package influxdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/outputs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPInflux(t *testing.T) {
+	var written string
+	var query, userAgent string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/query":
+			query = r.URL.Query().Get("q")
+			userAgent = r.Header.Get("User-Agent")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"results":[{}]}`))
+		case "/write":
+			assert.Equal(t, "telegraf", r.URL.Query().Get("db"))
+			assert.Equal(t, "s", r.URL.Query().Get("precision"))
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			written = string(body)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	i := InfluxDB{
+		URLs:      []string{ts.URL},
+		Database:  "telegraf",
+		Precision: "s",
+	}
+	i.InsecureSkipVerify = true
+
+	require.NoError(t, i.Connect())
+	assert.Equal(t, `CREATE DATABASE "telegraf"`, query)
+	assert.Equal(t, internal.DefaultUserAgent, userAgent)
+
+	pt := client.NewPoint(
+		"test_point",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1136214245, 0),
+	)
+
+	require.NoError(t, i.Write([]*client.Point{pt}))
+	assert.Equal(t, "test_point,host=localhost value=1 1136214245\n", written)
+}
+
+func TestHTTPInfluxUntrustedCert(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	i := InfluxDB{
+		URLs:     []string{ts.URL},
+		Database: "telegraf",
+	}
+	require.NoError(t, i.Connect())
+
+	pt := client.NewPoint(
+		"test_point",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1136214245, 0),
+	)
+	assert.Error(t, i.Write([]*client.Point{pt}))
+}
+
+func TestHTTPInfluxBadTLSConfig(t *testing.T) {
+	i := InfluxDB{
+		URLs:     []string{"https://localhost:8086"},
+		Database: "telegraf",
+	}
+	i.SslCert = "../../internal/testdata/client.pem"
+
+	assert.Error(t, i.Connect())
+}
+
+func TestHTTPInfluxRetry(t *testing.T) {
+	var writes int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/query":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"results":[{}]}`))
+		case "/write":
+			writes++
+			if writes <= 2 {
+				if writes == 2 {
+					w.Header().Set("Retry-After", "3")
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	var waits []time.Duration
+	retrySleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { retrySleep = time.Sleep }()
+
+	i := InfluxDB{
+		URLs:         []string{ts.URL},
+		Database:     "telegraf",
+		MaxRetries:   3,
+		RetryBackoff: internal.Duration{Duration: 10 * time.Millisecond},
+	}
+	require.NoError(t, i.Connect())
+
+	pt := client.NewPoint(
+		"test_point",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1136214245, 0),
+	)
+	require.NoError(t, i.Write([]*client.Point{pt}))
+	assert.Equal(t, 3, writes)
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 3 * time.Second}, waits)
+}
+
+func TestHTTPInfluxRetryExhausted(t *testing.T) {
+	var writes int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/write" {
+			writes++
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results":[{}]}`))
+	}))
+	defer ts.Close()
+
+	retrySleep = func(time.Duration) {}
+	defer func() { retrySleep = time.Sleep }()
+
+	i := InfluxDB{
+		URLs:       []string{ts.URL},
+		Database:   "telegraf",
+		MaxRetries: 2,
+	}
+	require.NoError(t, i.Connect())
+
+	pt := client.NewPoint(
+		"test_point",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1136214245, 0),
+	)
+	assert.Error(t, i.Write([]*client.Point{pt}))
+	assert.Equal(t, 3, writes)
+}
+
+func TestHTTPInfluxNoRetryOnClientError(t *testing.T) {
+	var writes int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/write" {
+			writes++
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results":[{}]}`))
+	}))
+	defer ts.Close()
+
+	retrySleep = func(time.Duration) {}
+	defer func() { retrySleep = time.Sleep }()
+
+	i := InfluxDB{
+		URLs:       []string{ts.URL},
+		Database:   "telegraf",
+		MaxRetries: 2,
+	}
+	require.NoError(t, i.Connect())
+
+	pt := client.NewPoint(
+		"test_point",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1136214245, 0),
+	)
+	assert.Error(t, i.Write([]*client.Point{pt}))
+	assert.Equal(t, 1, writes)
+}
+
+func TestHTTPInfluxProxy(t *testing.T) {
+	var written string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "influxdb.example.invalid:8086", r.URL.Host)
+		switch r.URL.Path {
+		case "/query":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"results":[{}]}`))
+		case "/write":
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			written = string(body)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer proxy.Close()
+
+	i := InfluxDB{
+		URLs:     []string{"http://influxdb.example.invalid:8086"},
+		Database: "telegraf",
+	}
+	i.HTTPProxyURL = proxy.URL
+	require.NoError(t, i.Connect())
+
+	pt := client.NewPoint(
+		"test_point",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1136214245, 0),
+	)
+	require.NoError(t, i.Write([]*client.Point{pt}))
+	assert.Equal(t, "test_point value=1 1136214245000000000\n", written)
+}
+
+func TestHTTPInfluxV2(t *testing.T) {
+	var path, written, auth string
+	var params url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		params = r.URL.Query()
+		auth = r.Header.Get("Authorization")
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		written = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	i := InfluxDB{
+		URLs:         []string{ts.URL},
+		Database:     "telegraf",
+		Precision:    "s",
+		Version:      2,
+		Token:        "my-token",
+		Organization: "my-org",
+		Bucket:       "metrics",
+	}
+	require.NoError(t, i.Connect())
+	// no database is created on InfluxDB 2.x
+	assert.Equal(t, "", path)
+
+	pt := client.NewPoint(
+		"test_point",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1136214245, 0),
+	)
+	require.NoError(t, i.Write([]*client.Point{pt}))
+
+	assert.Equal(t, "/api/v2/write", path)
+	assert.Equal(t, url.Values{
+		"org":       []string{"my-org"},
+		"bucket":    []string{"metrics"},
+		"precision": []string{"s"},
+	}, params)
+	assert.Equal(t, "Token my-token", auth)
+	assert.Equal(t, "test_point,host=localhost value=1 1136214245\n", written)
+}
+
+func TestHTTPInfluxV2URL(t *testing.T) {
+	var path string
+	var params url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		params = r.URL.Query()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	i := InfluxDB{
+		URLs:         []string{ts.URL + "/api/v2"},
+		Database:     "telegraf",
+		Token:        "my-token",
+		Organization: "my-org",
+	}
+	require.NoError(t, i.Connect())
+
+	pt := client.NewPoint(
+		"test_point",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1136214245, 0),
+	)
+	require.NoError(t, i.Write([]*client.Point{pt}))
+	assert.Equal(t, "/api/v2/write", path)
+	// the bucket defaults to the database
+	assert.Equal(t, "telegraf", params.Get("bucket"))
+	assert.Equal(t, "ns", params.Get("precision"))
+}
+
+func TestHTTPInfluxV2Config(t *testing.T) {
+	i := InfluxDB{
+		URLs:     []string{"http://localhost:8086"},
+		Database: "telegraf",
+		Version:  2,
+	}
+	assert.Error(t, i.Connect())
+
+	i.Organization = "my-org"
+	i.Precision = "m"
+	assert.Error(t, i.Connect())
+}
+
+func TestHTTPInfluxDatabaseTag(t *testing.T) {
+	var queries []string
+	written := make(map[string]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/query":
+			queries = append(queries, r.URL.Query().Get("q"))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"results":[{}]}`))
+		case "/write":
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			written[r.URL.Query().Get("db")] += string(body)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	i := InfluxDB{
+		URLs:        []string{ts.URL},
+		Database:    "telegraf",
+		DatabaseTag: "team",
+		Precision:   "s",
+	}
+	require.NoError(t, i.Connect())
+
+	point := func(team string) *client.Point {
+		tags := map[string]string{"host": "localhost"}
+		if team != "" {
+			tags["team"] = team
+		}
+		return client.NewPoint("cpu", tags, map[string]interface{}{"value": 1.0},
+			time.Unix(1136214245, 0))
+	}
+	require.NoError(t, i.Write([]*client.Point{
+		point("ops"), point("web"), point(""), point("ops"),
+	}))
+	require.NoError(t, i.Write([]*client.Point{point("web")}))
+
+	assert.Equal(t, map[string]string{
+		"ops":      "cpu,host=localhost,team=ops value=1 1136214245\ncpu,host=localhost,team=ops value=1 1136214245\n",
+		"web":      "cpu,host=localhost,team=web value=1 1136214245\ncpu,host=localhost,team=web value=1 1136214245\n",
+		"telegraf": "cpu,host=localhost value=1 1136214245\n",
+	}, written)
+	// each database is created once
+	assert.Equal(t, []string{
+		`CREATE DATABASE "telegraf"`,
+		`CREATE DATABASE "ops"`,
+		`CREATE DATABASE "web"`,
+	}, queries)
+}
+
+func TestHTTPInfluxDatabaseTagQuoted(t *testing.T) {
+	var queries []string
+	fail := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/query":
+			q := r.URL.Query().Get("q")
+			queries = append(queries, q)
+			if fail && q != `CREATE DATABASE "telegraf"` {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"error":"timeout"}`))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"results":[{}]}`))
+		case "/write":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	i := InfluxDB{URLs: []string{ts.URL}, Database: "telegraf", DatabaseTag: "team"}
+	require.NoError(t, i.Connect())
+
+	points := []*client.Point{
+		client.NewPoint("cpu", map[string]string{"team": `my-team.ops "eu"`},
+			map[string]interface{}{"value": 1.0}, time.Unix(1136214245, 0)),
+	}
+	require.NoError(t, i.Write(points))
+	// the failed creation is tried again with the next write, until it works
+	require.NoError(t, i.Write(points))
+	fail = false
+	require.NoError(t, i.Write(points))
+	require.NoError(t, i.Write(points))
+
+	assert.Equal(t, []string{
+		`CREATE DATABASE "telegraf"`,
+		`CREATE DATABASE "my-team.ops \"eu\""`,
+		`CREATE DATABASE "my-team.ops \"eu\""`,
+		`CREATE DATABASE "my-team.ops \"eu\""`,
+	}, queries)
+}
+
+func TestHTTPInfluxDatabaseTagErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/query":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"results":[{}]}`))
+		case r.URL.Query().Get("db") == "ops":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("database not found"))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	i := InfluxDB{URLs: []string{ts.URL}, Database: "telegraf", DatabaseTag: "team"}
+	require.NoError(t, i.Connect())
+
+	// the failure of a database does not keep the others from being written
+	err := i.Write([]*client.Point{
+		client.NewPoint("cpu", map[string]string{"team": "ops"},
+			map[string]interface{}{"value": 1.0}, time.Unix(1136214245, 0)),
+		client.NewPoint("cpu", map[string]string{"team": "web"},
+			map[string]interface{}{"value": 1.0}, time.Unix(1136214245, 0)),
+	})
+	assert.EqualError(t, err, "ops: Could not write to any InfluxDB server in cluster")
+}
+
+func TestHTTPInfluxV2DatabaseTag(t *testing.T) {
+	var buckets []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buckets = append(buckets, r.URL.Query().Get("bucket"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	i := InfluxDB{
+		URLs:         []string{ts.URL + "/api/v2"},
+		Database:     "telegraf",
+		Bucket:       "metrics",
+		DatabaseTag:  "team",
+		Organization: "my-org",
+	}
+	require.NoError(t, i.Connect())
+
+	require.NoError(t, i.Write([]*client.Point{
+		client.NewPoint("cpu", map[string]string{},
+			map[string]interface{}{"value": 1.0}, time.Unix(1136214245, 0)),
+		client.NewPoint("cpu", map[string]string{"team": "ops"},
+			map[string]interface{}{"value": 1.0}, time.Unix(1136214245, 0)),
+	}))
+	assert.Equal(t, []string{"metrics", "ops"}, buckets)
+}
+
+func TestHTTPInfluxUserAgentAndTimeout(t *testing.T) {
+	release := make(chan struct{})
+	// the handler of the timed out write may still run while the test reads
+	userAgents := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents <- r.Header.Get("User-Agent")
+		switch r.URL.Path {
+		case "/query":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"results":[{}]}`))
+		case "/write":
+			// a server that never answers writes
+			<-release
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	i := InfluxDB{
+		URLs:      []string{ts.URL},
+		Database:  "telegraf",
+		UserAgent: "telegraf-audit",
+		Timeout:   internal.Duration{Duration: 50 * time.Millisecond},
+	}
+	require.NoError(t, i.Connect())
+	assert.Equal(t, "telegraf-audit", <-userAgents)
+
+	pt := client.NewPoint(
+		"test_point",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1136214245, 0),
+	)
+	start := time.Now()
+	assert.Error(t, i.Write([]*client.Point{pt}))
+	assert.True(t, time.Since(start) < 5*time.Second, "the write was not timed out")
+	assert.Equal(t, "telegraf-audit", <-userAgents)
+}
+
+func TestHTTPInfluxDefaultTimeout(t *testing.T) {
+	i := outputs.Outputs["influxdb"]().(*InfluxDB)
+	assert.Equal(t, 5*time.Second, i.Timeout.Duration)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/influxdb/influxdb/client/v2"
 	"github.com/influxdb/telegraf/outputs"
+	"github.com/influxdb/telegraf/outputs/serializers"
 )
 
 type OpenTSDB struct {
@@ -70,7 +71,8 @@ func (o *OpenTSDB) Write(points []*client.Point) error {
 	if err != nil {
 		return fmt.Errorf("OpenTSDB: Telnet connect fail")
 	}
-	for _, pt := range points {
+	// OpenTSDB stores a single value per metric
+	for _, pt := range serializers.Flatten(points) {
 		metric := &MetricLine{
 			Metric:    fmt.Sprintf("%s%s", o.Prefix, pt.Name()),
 			Timestamp: timeNow.Unix(),
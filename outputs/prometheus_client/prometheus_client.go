@@ -0,0 +1,306 @@
+package prometheus_client
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/outputs"
+	"github.com/influxdb/telegraf/outputs/serializers"
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	defaultListen     = ":9126"
+	defaultExpiration = 60 * time.Second
+)
+
+// timeNow is the clock of the expiration of series, replaced in tests
+var timeNow = time.Now
+
+// PrometheusClient serves the latest value of every metric written to it on
+// an HTTP /metrics endpoint, to be scraped by Prometheus.
+type PrometheusClient struct {
+	Listen string
+
+	// MetricExpiration is how long a series is served after it was last
+	// written, so that the series of sources gone away are dropped
+	MetricExpiration internal.Duration `toml:"metric_expiration"`
+
+	sync.Mutex
+	samples map[string]*sample
+
+	listener net.Listener
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// sample is the latest value of a single series
+type sample struct {
+	name    string
+	labels  map[string]string
+	value   float64
+	expires time.Time
+}
+
+var sampleConfig = `
+  # Address to listen on for Prometheus scrapes of /metrics
+  listen = ":9126"
+
+  # How long a series is still served after it was last written
+  # metric_expiration = "60s"
+`
+
+var (
+	invalidNameChars  = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+// sanitize replaces the characters Prometheus does not allow in names by
+// "_", and prefixes names that would start with a digit
+func sanitize(name string, invalid *regexp.Regexp) string {
+	name = invalid.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func (p *PrometheusClient) Connect() error {
+	listen := p.Listen
+	if listen == "" {
+		listen = defaultListen
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("Prometheus client: unable to listen on %s, %s", listen, err)
+	}
+
+	p.Lock()
+	p.samples = make(map[string]*sample)
+	p.listener = listener
+	p.done = make(chan struct{})
+	p.Unlock()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", p.serveMetrics)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		err := http.Serve(listener, mux)
+		select {
+		case <-p.done:
+		default:
+			log.Printf("Prometheus client endpoint stopped, %s\n", err)
+		}
+	}()
+	return nil
+}
+
+func (p *PrometheusClient) Close() error {
+	p.Lock()
+	listener := p.listener
+	p.listener = nil
+	p.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+	close(p.done)
+	err := listener.Close()
+	p.wg.Wait()
+	return err
+}
+
+// Addr returns the address the endpoint listens on, nil before Connect
+func (p *PrometheusClient) Addr() net.Addr {
+	p.Lock()
+	defer p.Unlock()
+	if p.listener == nil {
+		return nil
+	}
+	return p.listener.Addr()
+}
+
+func (p *PrometheusClient) expiration() time.Duration {
+	if p.MetricExpiration.Duration > 0 {
+		return p.MetricExpiration.Duration
+	}
+	return defaultExpiration
+}
+
+// Write keeps the value of every point until it is replaced by a newer point
+// of the same series or expires. Points with non numeric values are skipped.
+func (p *PrometheusClient) Write(points []*client.Point) error {
+	expires := timeNow().Add(p.expiration())
+
+	p.Lock()
+	defer p.Unlock()
+	if p.samples == nil {
+		p.samples = make(map[string]*sample)
+	}
+
+	// Prometheus stores a single value per series
+	for _, pt := range serializers.Flatten(points) {
+		value, ok := floatValue(pt.Fields()["value"])
+		if !ok {
+			continue
+		}
+
+		s := &sample{
+			name:    sanitize(pt.Name(), invalidNameChars),
+			labels:  make(map[string]string),
+			value:   value,
+			expires: expires,
+		}
+		for k, v := range pt.Tags() {
+			s.labels[sanitize(k, invalidLabelChars)] = v
+		}
+		p.samples[s.key()] = s
+	}
+	return nil
+}
+
+// key identifies the series of the sample by its name and labels
+func (s *sample) key() string {
+	keys := make([]string, 0, len(s.labels))
+	for k := range s.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	b.WriteString(s.name)
+	for _, k := range keys {
+		b.WriteString("\x00" + k + "\x00" + s.labels[k])
+	}
+	return b.String()
+}
+
+func floatValue(v interface{}) (float64, bool) {
+	switch d := v.(type) {
+	case int64:
+		return float64(d), true
+	case uint64:
+		return float64(d), true
+	case float64:
+		return d, true
+	case bool:
+		if d {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// families drops the expired samples and returns the others as gauges,
+// grouped in metric families sorted by name.
+func (p *PrometheusClient) families(now time.Time) []*dto.MetricFamily {
+	p.Lock()
+	defer p.Unlock()
+
+	byName := make(map[string]*dto.MetricFamily)
+	for key, s := range p.samples {
+		if now.After(s.expires) {
+			delete(p.samples, key)
+			continue
+		}
+
+		family, ok := byName[s.name]
+		if !ok {
+			family = &dto.MetricFamily{
+				Name: proto.String(s.name),
+				Help: proto.String("Telegraf collected metric"),
+				Type: dto.MetricType_GAUGE.Enum(),
+			}
+			byName[s.name] = family
+		}
+
+		metric := &dto.Metric{Gauge: &dto.Gauge{Value: proto.Float64(s.value)}}
+		for k, v := range s.labels {
+			metric.Label = append(metric.Label, &dto.LabelPair{
+				Name:  proto.String(k),
+				Value: proto.String(v),
+			})
+		}
+		sort.Sort(labelPairs(metric.Label))
+		family.Metric = append(family.Metric, metric)
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	families := make([]*dto.MetricFamily, 0, len(names))
+	for _, name := range names {
+		family := byName[name]
+		sort.Sort(metrics(family.Metric))
+		families = append(families, family)
+	}
+	return families
+}
+
+func (p *PrometheusClient) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", `text/plain; version=0.0.4`)
+	for _, family := range p.families(timeNow()) {
+		if _, err := text.MetricFamilyToText(w, family); err != nil {
+			log.Printf("Prometheus client: unable to write %s, %s\n",
+				family.GetName(), err)
+			return
+		}
+	}
+}
+
+type labelPairs []*dto.LabelPair
+
+func (l labelPairs) Len() int           { return len(l) }
+func (l labelPairs) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l labelPairs) Less(i, j int) bool { return l[i].GetName() < l[j].GetName() }
+
+// metrics sorts the metrics of a family by their labels, so that scrapes
+// list series in a stable order
+type metrics []*dto.Metric
+
+func (m metrics) Len() int      { return len(m) }
+func (m metrics) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+func (m metrics) Less(i, j int) bool {
+	return labelString(m[i]) < labelString(m[j])
+}
+
+func labelString(m *dto.Metric) string {
+	pairs := make([]string, 0, len(m.Label))
+	for _, l := range m.Label {
+		pairs = append(pairs, l.GetName()+"="+l.GetValue())
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p *PrometheusClient) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusClient) Description() string {
+	return "Configuration for the Prometheus client to spawn"
+}
+
+func init() {
+	outputs.Add("prometheus_client", func() outputs.Output {
+		return &PrometheusClient{}
+	})
+}
@@ -0,0 +1,132 @@
+package prometheus_client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/prometheus/client_golang/text"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, p *PrometheusClient) map[string]*dto.MetricFamily {
+	resp, err := http.Get("http://" + p.Addr().String() + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var parser text.Parser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	require.NoError(t, err)
+	return families
+}
+
+func labels(m *dto.Metric) map[string]string {
+	l := make(map[string]string)
+	for _, pair := range m.Label {
+		l[pair.GetName()] = pair.GetValue()
+	}
+	return l
+}
+
+func TestScrape(t *testing.T) {
+	p := &PrometheusClient{Listen: "127.0.0.1:0"}
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	now := time.Now()
+	points := []*client.Point{
+		client.NewPoint(
+			"rethinkdb_queries_per_sec",
+			map[string]string{"host": "10.0.0.1:28015", "type": "member"},
+			map[string]interface{}{"value": int64(7)},
+			now,
+		),
+		client.NewPoint(
+			"disk",
+			map[string]string{"host": "server01", "path name": "/var"},
+			map[string]interface{}{"used": 0.5, "mode": "rw"},
+			now,
+		),
+	}
+	require.NoError(t, p.Write(points))
+
+	families := scrape(t, p)
+	require.Len(t, families, 2)
+
+	family, ok := families["rethinkdb_queries_per_sec"]
+	require.True(t, ok)
+	assert.Equal(t, dto.MetricType_GAUGE, family.GetType())
+	require.Len(t, family.Metric, 1)
+	assert.Equal(t, 7.0, family.Metric[0].GetGauge().GetValue())
+	assert.Equal(t, map[string]string{"host": "10.0.0.1:28015", "type": "member"},
+		labels(family.Metric[0]))
+
+	// string fields are skipped and label names sanitized
+	family, ok = families["disk_used"]
+	require.True(t, ok)
+	require.Len(t, family.Metric, 1)
+	assert.Equal(t, 0.5, family.Metric[0].GetGauge().GetValue())
+	assert.Equal(t, map[string]string{"host": "server01", "path_name": "/var"},
+		labels(family.Metric[0]))
+
+	// the latest value of a series replaces the previous one
+	require.NoError(t, p.Write([]*client.Point{client.NewPoint(
+		"rethinkdb_queries_per_sec",
+		map[string]string{"host": "10.0.0.1:28015", "type": "member"},
+		map[string]interface{}{"value": int64(9)},
+		now,
+	)}))
+	family = scrape(t, p)["rethinkdb_queries_per_sec"]
+	require.Len(t, family.Metric, 1)
+	assert.Equal(t, 9.0, family.Metric[0].GetGauge().GetValue())
+}
+
+func TestMetricExpiration(t *testing.T) {
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	p := &PrometheusClient{
+		Listen:           "127.0.0.1:0",
+		MetricExpiration: internal.Duration{Duration: time.Minute},
+	}
+	require.NoError(t, p.Connect())
+	defer p.Close()
+
+	point := func(host string) *client.Point {
+		return client.NewPoint(
+			"rethinkdb_queries_per_sec",
+			map[string]string{"host": host},
+			map[string]interface{}{"value": int64(7)},
+			now,
+		)
+	}
+	require.NoError(t, p.Write([]*client.Point{point("db1"), point("db2")}))
+	require.Len(t, scrape(t, p)["rethinkdb_queries_per_sec"].Metric, 2)
+
+	// only db1 is refreshed, db2 went away
+	now = now.Add(40 * time.Second)
+	require.NoError(t, p.Write([]*client.Point{point("db1")}))
+	require.Len(t, scrape(t, p)["rethinkdb_queries_per_sec"].Metric, 2)
+
+	now = now.Add(40 * time.Second)
+	family := scrape(t, p)["rethinkdb_queries_per_sec"]
+	require.Len(t, family.Metric, 1)
+	assert.Equal(t, map[string]string{"host": "db1"}, labels(family.Metric[0]))
+	assert.Len(t, p.samples, 1)
+
+	now = now.Add(40 * time.Second)
+	assert.Len(t, scrape(t, p), 0)
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "disk_used_", sanitize("disk used%", invalidNameChars))
+	assert.Equal(t, "_5xx", sanitize("5xx", invalidNameChars))
+	assert.Equal(t, "a_b", sanitize("a:b", invalidLabelChars))
+	assert.Equal(t, "a:b", sanitize("a:b", invalidNameChars))
+}
@@ -0,0 +1,210 @@
+package riemann
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/outputs"
+	"github.com/influxdb/telegraf/outputs/serializers"
+)
+
+type Riemann struct {
+	Host string
+	Port int
+
+	// TTL of the events, after which Riemann expires them. Zero leaves it to
+	// the server's default.
+	TTL     internal.Duration `toml:"ttl"`
+	Timeout internal.Duration
+
+	conn net.Conn
+}
+
+var sampleConfig = `
+  # DNS name and TCP port of the Riemann server
+  host = "localhost"
+  port = 5555
+
+  # Time to live of the events, defaults to the server's default
+  # ttl = "60s"
+
+  # Timeout for connecting and for each write
+  # timeout = "5s"
+`
+
+const defaultPort = 5555
+
+func (r *Riemann) address() string {
+	port := r.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	return net.JoinHostPort(r.Host, strconv.Itoa(port))
+}
+
+func (r *Riemann) Connect() error {
+	conn, err := net.DialTimeout("tcp", r.address(), r.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("Riemann: connect fail, %s", err)
+	}
+	r.conn = conn
+	return nil
+}
+
+func (r *Riemann) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
+
+func (r *Riemann) Write(points []*client.Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	msg := &Msg{}
+	// Riemann events hold a single metric
+	for _, pt := range serializers.Flatten(points) {
+		event, err := r.buildEvent(pt)
+		if err != nil {
+			// only numeric values are sent, ie string fields are skipped
+			continue
+		}
+		msg.Events = append(msg.Events, event)
+	}
+	if len(msg.Events) == 0 {
+		return nil
+	}
+
+	if r.conn == nil {
+		if err := r.Connect(); err != nil {
+			return err
+		}
+	}
+	if err := r.send(msg); err != nil {
+		// reconnect on the next write
+		r.Close()
+		return fmt.Errorf("Riemann: writing error %s", err.Error())
+	}
+	return nil
+}
+
+// send writes msg prefixed by its length, as Riemann expects over TCP, and
+// waits for the server to acknowledge it
+func (r *Riemann) send(msg *Msg) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if r.Timeout.Duration > 0 {
+		r.conn.SetDeadline(time.Now().Add(r.Timeout.Duration))
+	}
+
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+	if _, err := r.conn.Write(frame); err != nil {
+		return err
+	}
+
+	var size uint32
+	if err := binary.Read(r.conn, binary.BigEndian, &size); err != nil {
+		return err
+	}
+	reply := make([]byte, size)
+	if _, err := io.ReadFull(r.conn, reply); err != nil {
+		return err
+	}
+
+	var resp Msg
+	if err := proto.Unmarshal(reply, &resp); err != nil {
+		return err
+	}
+	if !resp.GetOk() {
+		if resp.Error != nil {
+			return errors.New(*resp.Error)
+		}
+		return errors.New("events were not acknowledged")
+	}
+	return nil
+}
+
+// buildEvent returns the event of pt, whose service is the name of the point
+// and whose host is its host tag. The other tags are sent as attributes.
+func (r *Riemann) buildEvent(pt *client.Point) (*Event, error) {
+	event := &Event{
+		Time:    proto.Int64(pt.Time().Unix()),
+		Service: proto.String(pt.Name()),
+	}
+
+	switch v := pt.Fields()["value"].(type) {
+	case int64:
+		event.MetricSint64 = proto.Int64(v)
+	case uint64:
+		event.MetricD = proto.Float64(float64(v))
+	case float64:
+		event.MetricD = proto.Float64(v)
+	case bool:
+		var i int64
+		if v {
+			i = 1
+		}
+		event.MetricSint64 = proto.Int64(i)
+	default:
+		return nil, fmt.Errorf("unexpected type %T with value %v for Riemann", v, v)
+	}
+
+	tags := pt.Tags()
+	host := tags["host"]
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	event.Host = proto.String(host)
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		if k != "host" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		event.Attributes = append(event.Attributes, &Attribute{
+			Key:   proto.String(k),
+			Value: proto.String(tags[k]),
+		})
+	}
+
+	if r.TTL.Duration > 0 {
+		event.Ttl = proto.Float32(float32(r.TTL.Duration.Seconds()))
+	}
+	return event, nil
+}
+
+func (r *Riemann) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Riemann) Description() string {
+	return "Configuration for the Riemann server to send metrics to"
+}
+
+func init() {
+	outputs.Add("riemann", func() outputs.Output {
+		return &Riemann{}
+	})
+}
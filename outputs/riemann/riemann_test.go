@@ -0,0 +1,140 @@
+package riemann
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockRiemann accepts a single connection and acknowledges every message
+// received on it, or rejects them with reject as error when set
+type mockRiemann struct {
+	listener net.Listener
+	msgs     chan *Msg
+	reject   string
+}
+
+func newMockRiemann(t *testing.T) *mockRiemann {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	m := &mockRiemann{listener: listener, msgs: make(chan *Msg, 10)}
+	go m.serve()
+	return m
+}
+
+func (m *mockRiemann) serve() {
+	conn, err := m.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	for {
+		var size uint32
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return
+		}
+		msg := &Msg{}
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return
+		}
+		m.msgs <- msg
+
+		reply := &Msg{Ok: proto.Bool(m.reject == "")}
+		if m.reject != "" {
+			reply.Error = proto.String(m.reject)
+		}
+		out, _ := proto.Marshal(reply)
+		binary.Write(conn, binary.BigEndian, uint32(len(out)))
+		conn.Write(out)
+	}
+}
+
+func (m *mockRiemann) output() *Riemann {
+	host, port, _ := net.SplitHostPort(m.listener.Addr().String())
+	r := &Riemann{Host: host}
+	r.Port, _ = strconv.Atoi(port)
+	r.Timeout.Duration = 5 * time.Second
+	return r
+}
+
+func TestWrite(t *testing.T) {
+	m := newMockRiemann(t)
+	defer m.listener.Close()
+
+	r := m.output()
+	r.TTL.Duration = 30 * time.Second
+	require.NoError(t, r.Connect())
+	defer r.Close()
+
+	require.NoError(t, r.Write([]*client.Point{
+		client.NewPoint(
+			"rethinkdb",
+			map[string]string{"host": "db1", "type": "member"},
+			map[string]interface{}{"queries_per_sec": int64(7), "cache_ratio": 0.5},
+			time.Unix(1136214245, 0),
+		),
+		client.NewPoint(
+			"rethinkdb_version",
+			map[string]string{"host": "db1"},
+			map[string]interface{}{"value": "2.1.5"},
+			time.Unix(1136214245, 0),
+		),
+	}))
+
+	msg := <-m.msgs
+	require.Len(t, msg.Events, 2)
+
+	e := msg.Events[0]
+	assert.Equal(t, "rethinkdb_cache_ratio", *e.Service)
+	assert.Equal(t, 0.5, *e.MetricD)
+	assert.Nil(t, e.MetricSint64)
+
+	e = msg.Events[1]
+	assert.Equal(t, "rethinkdb_queries_per_sec", *e.Service)
+	assert.Equal(t, int64(7), *e.MetricSint64)
+	assert.Equal(t, "db1", *e.Host)
+	assert.Equal(t, int64(1136214245), *e.Time)
+	assert.Equal(t, float32(30), *e.Ttl)
+	require.Len(t, e.Attributes, 1)
+	assert.Equal(t, "type", *e.Attributes[0].Key)
+	assert.Equal(t, "member", *e.Attributes[0].Value)
+}
+
+func TestWriteRejected(t *testing.T) {
+	m := newMockRiemann(t)
+	defer m.listener.Close()
+	m.reject = "invalid event"
+
+	r := m.output()
+	require.NoError(t, r.Connect())
+	defer r.Close()
+
+	err := r.Write([]*client.Point{client.NewPoint(
+		"load",
+		map[string]string{"host": "db1"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1136214245, 0),
+	)})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid event")
+	// the connection is opened again on the next write
+	assert.Nil(t, r.conn)
+}
+
+func TestConnectFail(t *testing.T) {
+	// nothing listens on the discard port
+	r := &Riemann{Host: "127.0.0.1", Port: 9}
+	assert.Error(t, r.Connect())
+}
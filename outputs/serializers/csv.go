@@ -0,0 +1,172 @@
+package serializers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// DefaultCSVColumns is the column order used when none is configured
+var DefaultCSVColumns = []string{"timestamp", "measurement", "tags", "fields"}
+
+// CSVSerializer writes points as CSV rows. Columns lists the column groups
+// in order: "timestamp", "measurement", "tags" (one column per tag key) and
+// "fields" (one column per field key), tag and field columns being sorted by
+// key.
+//
+// Points with differing tags or fields share a single set of columns, the
+// union of every key seen so far, and leave the columns they do not have
+// empty. The union only grows, so earlier rows keep their meaning; when it
+// grows and Header is set, a new header row is written before the next row.
+type CSVSerializer struct {
+	Columns []string
+	Header  bool
+
+	tags   []string
+	fields []string
+
+	headerWritten bool
+}
+
+// NewCSVSerializer returns a CSVSerializer, columns defaults to
+// DefaultCSVColumns.
+func NewCSVSerializer(columns []string, header bool) (*CSVSerializer, error) {
+	if len(columns) == 0 {
+		columns = DefaultCSVColumns
+	}
+	seen := make(map[string]bool)
+	for _, c := range columns {
+		switch c {
+		case "timestamp", "measurement", "tags", "fields":
+		default:
+			return nil, fmt.Errorf("Invalid csv column: %s", c)
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("Duplicate csv column: %s", c)
+		}
+		seen[c] = true
+	}
+	return &CSVSerializer{Columns: columns, Header: header}, nil
+}
+
+func (s *CSVSerializer) Serialize(points []*client.Point) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+
+	grew := false
+	for _, pt := range points {
+		if s.addColumns(pt) {
+			grew = true
+		}
+	}
+	if s.Header && (grew || !s.headerWritten) {
+		w.Write(s.header())
+		s.headerWritten = true
+	}
+
+	for _, pt := range points {
+		w.Write(s.row(pt))
+	}
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
+
+// addColumns adds the tag and field keys of pt to the union of columns and
+// returns true if there were new keys.
+func (s *CSVSerializer) addColumns(pt *client.Point) bool {
+	grew := false
+	for k := range pt.Tags() {
+		if !contains(s.tags, k) {
+			s.tags = append(s.tags, k)
+			grew = true
+		}
+	}
+	for k := range pt.Fields() {
+		if !contains(s.fields, k) {
+			s.fields = append(s.fields, k)
+			grew = true
+		}
+	}
+	if grew {
+		sort.Strings(s.tags)
+		sort.Strings(s.fields)
+	}
+	return grew
+}
+
+func (s *CSVSerializer) header() []string {
+	var header []string
+	for _, c := range s.Columns {
+		switch c {
+		case "timestamp", "measurement":
+			header = append(header, c)
+		case "tags":
+			header = append(header, s.tags...)
+		case "fields":
+			header = append(header, s.fields...)
+		}
+	}
+	return header
+}
+
+func (s *CSVSerializer) row(pt *client.Point) []string {
+	var row []string
+	for _, c := range s.Columns {
+		switch c {
+		case "timestamp":
+			row = append(row, pt.Time().UTC().Format(time.RFC3339Nano))
+		case "measurement":
+			row = append(row, pt.Name())
+		case "tags":
+			tags := pt.Tags()
+			for _, k := range s.tags {
+				row = append(row, tags[k])
+			}
+		case "fields":
+			fields := pt.Fields()
+			for _, k := range s.fields {
+				row = append(row, formatField(fields[k]))
+			}
+		}
+	}
+	return row
+}
+
+func formatField(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	Add("csv", func(config *Config) (Serializer, error) {
+		csv, err := NewCSVSerializer(config.CSVColumns, config.CSVHeader)
+		if err != nil {
+			return nil, err
+		}
+		return csv, nil
+	})
+}
@@ -0,0 +1,130 @@
+package serializers
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2015, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+func rethinkdbPoints() []*client.Point {
+	return []*client.Point{
+		client.NewPoint("rethinkdb_active_clients",
+			map[string]string{"host": "localhost", "type": "cluster"},
+			map[string]interface{}{"value": int64(3)},
+			testTime),
+		client.NewPoint("rethinkdb_table_replicas",
+			map[string]string{"host": "localhost", "db": "test", "table": "users"},
+			map[string]interface{}{"value": int64(2)},
+			testTime),
+	}
+}
+
+func TestCSVSerializer(t *testing.T) {
+	s, err := NewCSVSerializer(nil, true)
+	require.NoError(t, err)
+
+	b, err := s.Serialize(rethinkdbPoints())
+	require.NoError(t, err)
+
+	expected := "timestamp,measurement,db,host,table,type,value\n" +
+		"2015-11-10T23:00:00Z,rethinkdb_active_clients,,localhost,,cluster,3\n" +
+		"2015-11-10T23:00:00Z,rethinkdb_table_replicas,test,localhost,users,,2\n"
+	assert.Equal(t, expected, string(b))
+
+	// every row has as many columns as the header
+	records, err := csv.NewReader(strings.NewReader(string(b))).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestCSVSerializerColumnOrder(t *testing.T) {
+	s, err := NewCSVSerializer([]string{"measurement", "fields", "timestamp"}, false)
+	require.NoError(t, err)
+
+	b, err := s.Serialize(rethinkdbPoints()[:1])
+	require.NoError(t, err)
+	assert.Equal(t, "rethinkdb_active_clients,3,2015-11-10T23:00:00Z\n", string(b))
+}
+
+func TestCSVSerializerHeaderOnlyOnNewColumns(t *testing.T) {
+	s, err := NewCSVSerializer([]string{"measurement", "fields"}, true)
+	require.NoError(t, err)
+
+	pt := client.NewPoint("rethinkdb",
+		nil,
+		map[string]interface{}{"version": `2.1 "Forbidden Planet"`, "up": true},
+		testTime)
+
+	b, err := s.Serialize([]*client.Point{pt})
+	require.NoError(t, err)
+	assert.Equal(t, "measurement,up,version\n"+
+		`rethinkdb,true,"2.1 ""Forbidden Planet"""`+"\n", string(b))
+
+	b, err = s.Serialize([]*client.Point{pt})
+	require.NoError(t, err)
+	assert.Equal(t, `rethinkdb,true,"2.1 ""Forbidden Planet"""`+"\n", string(b))
+
+	pt = client.NewPoint("rethinkdb",
+		nil,
+		map[string]interface{}{"cache_mb": 1.5},
+		testTime)
+	b, err = s.Serialize([]*client.Point{pt})
+	require.NoError(t, err)
+	assert.Equal(t, "measurement,cache_mb,up,version\nrethinkdb,1.5,,\n", string(b))
+}
+
+func TestNewCSVSerializerErrors(t *testing.T) {
+	_, err := NewCSVSerializer([]string{"timestamp", "value"}, true)
+	assert.Error(t, err)
+
+	_, err = NewCSVSerializer([]string{"tags", "tags"}, true)
+	assert.Error(t, err)
+}
+
+func TestNewSerializer(t *testing.T) {
+	s, err := NewSerializer(&Config{})
+	require.NoError(t, err)
+	b, err := s.Serialize(rethinkdbPoints()[:1])
+	require.NoError(t, err)
+	assert.Equal(t,
+		"rethinkdb_active_clients,host=localhost,type=cluster value=3i 1447196400000000000\n",
+		string(b))
+
+	s, err = NewSerializer(&Config{DataFormat: "csv"})
+	require.NoError(t, err)
+	assert.IsType(t, &CSVSerializer{}, s)
+
+	_, err = NewSerializer(&Config{DataFormat: "xml"})
+	assert.Error(t, err)
+}
+
+type lineCountSerializer struct{}
+
+func (s *lineCountSerializer) Serialize(points []*client.Point) ([]byte, error) {
+	return []byte(strings.Repeat(".", len(points))), nil
+}
+
+func TestAddSerializer(t *testing.T) {
+	Add("count", func(config *Config) (Serializer, error) {
+		return &lineCountSerializer{}, nil
+	})
+	defer delete(Serializers, "count")
+
+	s, err := NewSerializer(&Config{DataFormat: "count"})
+	require.NoError(t, err)
+	b, err := s.Serialize(rethinkdbPoints())
+	require.NoError(t, err)
+	assert.Equal(t, "..", string(b))
+
+	for _, format := range []string{"influx", "json", "graphite", "csv"} {
+		_, ok := Serializers[format]
+		assert.True(t, ok, format)
+	}
+}
@@ -0,0 +1,31 @@
+package serializers
+
+import (
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// FieldIncludeSerializer serializes only the fields of Fields, so that an
+// output writes a subset of wide measurements. Points without any of these
+// fields are not serialized.
+type FieldIncludeSerializer struct {
+	Serializer Serializer
+	Fields     []string
+}
+
+func (s *FieldIncludeSerializer) Serialize(points []*client.Point) ([]byte, error) {
+	included := make([]*client.Point, 0, len(points))
+	for _, pt := range points {
+		fields := make(map[string]interface{})
+		for k, v := range pt.Fields() {
+			if contains(s.Fields, k) {
+				fields[k] = v
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		included = append(included,
+			client.NewPoint(pt.Name(), pt.Tags(), fields, pt.Time()))
+	}
+	return s.Serializer.Serialize(included)
+}
@@ -0,0 +1,63 @@
+package serializers
+
+import (
+	"testing"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func widePoints() []*client.Point {
+	return []*client.Point{
+		client.NewPoint("rethinkdb_engine",
+			map[string]string{"host": "localhost", "type": "cluster"},
+			map[string]interface{}{
+				"clients":         int64(2),
+				"queries_per_sec": int64(7),
+				"total_reads":     int64(100),
+			},
+			testTime),
+		client.NewPoint("rethinkdb_storage",
+			map[string]string{"host": "localhost", "type": "data"},
+			map[string]interface{}{"cache_bytes_in_use": int64(3)},
+			testTime),
+	}
+}
+
+func TestFieldIncludeInflux(t *testing.T) {
+	s, err := NewSerializer(&Config{FieldInclude: []string{"queries_per_sec", "clients"}})
+	require.NoError(t, err)
+
+	b, err := s.Serialize(widePoints())
+	require.NoError(t, err)
+	assert.Equal(t,
+		"rethinkdb_engine,host=localhost,type=cluster clients=2i,queries_per_sec=7i 1447196400000000000\n",
+		string(b))
+}
+
+func TestFieldIncludeCSV(t *testing.T) {
+	s, err := NewSerializer(&Config{
+		DataFormat:   "csv",
+		CSVColumns:   []string{"measurement", "fields"},
+		CSVHeader:    true,
+		FieldInclude: []string{"queries_per_sec", "cache_bytes_in_use"},
+	})
+	require.NoError(t, err)
+
+	b, err := s.Serialize(widePoints())
+	require.NoError(t, err)
+	assert.Equal(t, "measurement,cache_bytes_in_use,queries_per_sec\n"+
+		"rethinkdb_engine,,7\n"+
+		"rethinkdb_storage,3,\n", string(b))
+}
+
+func TestFieldIncludeKeepsPoints(t *testing.T) {
+	points := widePoints()
+	s := &FieldIncludeSerializer{Serializer: &InfluxSerializer{}, Fields: []string{"clients"}}
+	_, err := s.Serialize(points)
+	require.NoError(t, err)
+
+	// the points are shared with the other outputs
+	assert.Len(t, points[0].Fields(), 3)
+}
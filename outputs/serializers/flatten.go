@@ -0,0 +1,38 @@
+package serializers
+
+import (
+	"sort"
+
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// Flatten splits every multi-field point into one point per field, named
+// measurement_field with the field's value as its "value" field, for
+// outputs that store a single value per series. Points whose only field is
+// "value" are returned as is.
+func Flatten(points []*client.Point) []*client.Point {
+	flat := make([]*client.Point, 0, len(points))
+	for _, pt := range points {
+		fields := pt.Fields()
+		if _, ok := fields["value"]; ok && len(fields) == 1 {
+			flat = append(flat, pt)
+			continue
+		}
+
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			flat = append(flat, client.NewPoint(
+				pt.Name()+"_"+k,
+				pt.Tags(),
+				map[string]interface{}{"value": fields[k]},
+				pt.Time(),
+			))
+		}
+	}
+	return flat
+}
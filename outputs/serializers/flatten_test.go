@@ -0,0 +1,54 @@
+package serializers
+
+import (
+	"testing"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatten(t *testing.T) {
+	tags := map[string]string{"host": "localhost", "type": "cluster"}
+	pt := client.NewPoint("rethinkdb",
+		tags,
+		map[string]interface{}{
+			"active_clients":       int64(3),
+			"clients":              int64(5),
+			"queries_per_sec":      10.5,
+			"read_docs_per_sec":    2.0,
+			"written_docs_per_sec": 1.0,
+		},
+		testTime)
+
+	points := Flatten([]*client.Point{pt})
+	require.Len(t, points, 5)
+
+	expected := []struct {
+		name  string
+		value interface{}
+	}{
+		{"rethinkdb_active_clients", int64(3)},
+		{"rethinkdb_clients", int64(5)},
+		{"rethinkdb_queries_per_sec", 10.5},
+		{"rethinkdb_read_docs_per_sec", 2.0},
+		{"rethinkdb_written_docs_per_sec", 1.0},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.name, points[i].Name())
+		assert.Equal(t, map[string]interface{}{"value": e.value}, points[i].Fields())
+		assert.Equal(t, tags, points[i].Tags())
+		assert.Equal(t, testTime, points[i].Time())
+	}
+}
+
+func TestFlattenSingleValue(t *testing.T) {
+	pt := client.NewPoint("rethinkdb_active_clients",
+		nil,
+		map[string]interface{}{"value": int64(3)},
+		testTime)
+
+	points := Flatten([]*client.Point{pt})
+	require.Len(t, points, 1)
+	assert.True(t, pt == points[0])
+}
@@ -0,0 +1,77 @@
+package serializers
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// GraphiteSerializer writes points in the Graphite plaintext protocol, one
+// line per field: "<prefix>.<name> <value> <timestamp>", the name being
+// built by Template. Fields that are not numbers are skipped and booleans
+// are written as 0 or 1.
+type GraphiteSerializer struct {
+	Template *Template
+	Prefix   string
+}
+
+// NewGraphiteSerializer returns a GraphiteSerializer, an empty template is
+// DefaultTemplate
+func NewGraphiteSerializer(template, prefix string) (*GraphiteSerializer, error) {
+	t, err := NewTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	return &GraphiteSerializer{Template: t, Prefix: prefix}, nil
+}
+
+func (s *GraphiteSerializer) Serialize(points []*client.Point) ([]byte, error) {
+	var b bytes.Buffer
+	for _, pt := range points {
+		fields := pt.Fields()
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			value, ok := graphiteValue(fields[k])
+			if !ok {
+				continue
+			}
+			name := s.Template.Name(pt.Name(), pt.Tags(), k)
+			if s.Prefix != "" {
+				name = s.Prefix + "." + name
+			}
+			fmt.Fprintf(&b, "%s %s %d\n", name, value, pt.Time().Unix())
+		}
+	}
+	return b.Bytes(), nil
+}
+
+func graphiteValue(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case bool:
+		if val {
+			return "1", true
+		}
+		return "0", true
+	case int, int32, int64, uint, uint32, uint64, float32, float64:
+		return formatField(val), true
+	default:
+		return "", false
+	}
+}
+
+func init() {
+	Add("graphite", func(config *Config) (Serializer, error) {
+		graphite, err := NewGraphiteSerializer(config.Template, config.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		return graphite, nil
+	})
+}
@@ -0,0 +1,35 @@
+package serializers
+
+import (
+	"testing"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphiteSerializer(t *testing.T) {
+	s, err := NewSerializer(&Config{
+		DataFormat: "graphite",
+		Template:   "host.measurement.field",
+		Prefix:     "telegraf",
+	})
+	require.NoError(t, err)
+
+	points := append(rethinkdbPoints(), client.NewPoint("rethinkdb_server",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"name": "db1", "ready": true, "uptime": 1.5},
+		testTime))
+	b, err := s.Serialize(points)
+	require.NoError(t, err)
+	assert.Equal(t, "telegraf.localhost.rethinkdb_active_clients 3 1447196400\n"+
+		"telegraf.localhost.rethinkdb_table_replicas 2 1447196400\n"+
+		"telegraf.localhost.rethinkdb_server.ready 1 1447196400\n"+
+		"telegraf.localhost.rethinkdb_server.uptime 1.5 1447196400\n",
+		string(b))
+}
+
+func TestGraphiteSerializerBadTemplate(t *testing.T) {
+	_, err := NewSerializer(&Config{DataFormat: "graphite", Template: "host..field"})
+	assert.Error(t, err)
+}
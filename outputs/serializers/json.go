@@ -0,0 +1,49 @@
+package serializers
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// JSONSerializer writes points as JSON objects, one per line:
+//
+//	{"name":"rethinkdb_engine","tags":{"host":"localhost"},"fields":{"clients":2},"timestamp":1447196400}
+//
+// The timestamp is in seconds since the epoch.
+type JSONSerializer struct{}
+
+type jsonPoint struct {
+	Name      string                 `json:"name"`
+	Tags      map[string]string      `json:"tags"`
+	Fields    map[string]interface{} `json:"fields"`
+	Timestamp int64                  `json:"timestamp"`
+}
+
+func (s *JSONSerializer) Serialize(points []*client.Point) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, pt := range points {
+		tags := pt.Tags()
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		err := enc.Encode(&jsonPoint{
+			Name:      pt.Name(),
+			Tags:      tags,
+			Fields:    pt.Fields(),
+			Timestamp: pt.Time().Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
+func init() {
+	Add("json", func(config *Config) (Serializer, error) {
+		return &JSONSerializer{}, nil
+	})
+}
@@ -0,0 +1,20 @@
+package serializers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSerializer(t *testing.T) {
+	s, err := NewSerializer(&Config{DataFormat: "json"})
+	require.NoError(t, err)
+
+	b, err := s.Serialize(widePoints())
+	require.NoError(t, err)
+	assert.Equal(t,
+		`{"name":"rethinkdb_engine","tags":{"host":"localhost","type":"cluster"},"fields":{"clients":2,"queries_per_sec":7,"total_reads":100},"timestamp":1447196400}`+"\n"+
+			`{"name":"rethinkdb_storage","tags":{"host":"localhost","type":"data"},"fields":{"cache_bytes_in_use":3},"timestamp":1447196400}`+"\n",
+		string(b))
+}
@@ -0,0 +1,89 @@
+// Package serializers turns points into the bytes written by outputs that
+// support several data formats.
+package serializers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// Serializer serializes a batch of points. A serializer may keep state
+// between calls, ie the CSV serializer only writes its header once.
+type Serializer interface {
+	Serialize(points []*client.Point) ([]byte, error)
+}
+
+// Config selects and configures a serializer
+type Config struct {
+	// DataFormat is the name of a registered serializer, ie "influx" (the
+	// default), "json", "graphite" or "csv"
+	DataFormat string
+
+	// CSV options, see CSVSerializer
+	CSVColumns []string
+	CSVHeader  bool
+
+	// Graphite options, see GraphiteSerializer
+	Template string
+	Prefix   string
+
+	// FieldInclude are the only field keys serialized, all fields are when
+	// it is empty, see FieldIncludeSerializer
+	FieldInclude []string
+}
+
+// Creator builds a serializer from the options of its data format
+type Creator func(config *Config) (Serializer, error)
+
+// Serializers are the registered data formats
+var Serializers = map[string]Creator{}
+
+// Add registers the serializer of a data format
+func Add(name string, creator Creator) {
+	Serializers[name] = creator
+}
+
+// NewSerializer returns the serializer for the configured data format
+func NewSerializer(config *Config) (Serializer, error) {
+	format := config.DataFormat
+	if format == "" {
+		format = "influx"
+	}
+	creator, ok := Serializers[format]
+	if !ok {
+		return nil, fmt.Errorf("Invalid data format: %s", config.DataFormat)
+	}
+	serializer, err := creator(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(config.FieldInclude) > 0 {
+		serializer = &FieldIncludeSerializer{
+			Serializer: serializer,
+			Fields:     config.FieldInclude,
+		}
+	}
+	return serializer, nil
+}
+
+// InfluxSerializer writes points in the InfluxDB line protocol, one point
+// per line.
+type InfluxSerializer struct{}
+
+func (s *InfluxSerializer) Serialize(points []*client.Point) ([]byte, error) {
+	var b bytes.Buffer
+	for _, pt := range points {
+		b.WriteString(pt.String())
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+func init() {
+	Add("influx", func(config *Config) (Serializer, error) {
+		return &InfluxSerializer{}, nil
+	})
+}
@@ -0,0 +1,102 @@
+package serializers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Template builds flat metric names, as used by Graphite or OpenTSDB, from a
+// point's measurement, tags and field. A template is a dot separated list of
+// components, ie "host.measurement.field":
+//
+//	measurement  the point's measurement
+//	field        the field name, omitted for the single field "value"
+//	tags         every tag not used by another component, sorted by key
+//	<key>        the value of tag <key>
+//
+// A component may list fallbacks separated by "|", ie "dc|region", the first
+// one that is set is used and the component is left out when none is.
+// Dots and spaces in values are replaced by underscores.
+type Template struct {
+	components [][]string
+	used       map[string]bool
+}
+
+// DefaultTemplate names metrics host.tags.measurement.field
+const DefaultTemplate = "host.tags.measurement.field"
+
+// NewTemplate parses a template, an empty template is DefaultTemplate
+func NewTemplate(template string) (*Template, error) {
+	if template == "" {
+		template = DefaultTemplate
+	}
+
+	t := &Template{used: make(map[string]bool)}
+	for _, component := range strings.Split(template, ".") {
+		alternatives := strings.Split(component, "|")
+		for _, a := range alternatives {
+			if a == "" {
+				return nil, fmt.Errorf("Invalid template '%s': empty component", template)
+			}
+			if a != "measurement" && a != "field" && a != "tags" {
+				t.used[a] = true
+			}
+		}
+		t.components = append(t.components, alternatives)
+	}
+	return t, nil
+}
+
+// Name returns the name of the given field of a point
+func (t *Template) Name(measurement string, tags map[string]string, field string) string {
+	var parts []string
+	for _, alternatives := range t.components {
+		for _, a := range alternatives {
+			part := t.resolve(a, measurement, tags, field)
+			if part != "" {
+				parts = append(parts, part)
+				break
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func (t *Template) resolve(
+	component string,
+	measurement string,
+	tags map[string]string,
+	field string,
+) string {
+	switch component {
+	case "measurement":
+		return sanitize(measurement)
+	case "field":
+		if field == "value" {
+			return ""
+		}
+		return sanitize(field)
+	case "tags":
+		var keys []string
+		for k := range tags {
+			if !t.used[k] && tags[k] != "" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			values[i] = sanitize(tags[k])
+		}
+		return strings.Join(values, ".")
+	default:
+		return sanitize(tags[component])
+	}
+}
+
+var sanitizer = strings.NewReplacer(".", "_", " ", "_")
+
+func sanitize(s string) string {
+	return sanitizer.Replace(s)
+}
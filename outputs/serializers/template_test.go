@@ -0,0 +1,59 @@
+package serializers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rethinkdbTags = map[string]string{
+	"host":   "db1.example.com",
+	"type":   "member",
+	"ns":     "rethinkdb-1",
+	"role":   "data",
+	"region": "us east",
+}
+
+func TestTemplateName(t *testing.T) {
+	tests := []struct {
+		template string
+		field    string
+		expected string
+	}{
+		{"host.measurement.field", "active_clients",
+			"db1_example_com.rethinkdb.active_clients"},
+		{"host.measurement.field", "value", "db1_example_com.rethinkdb"},
+		{"measurement.type.ns.field", "active_clients",
+			"rethinkdb.member.rethinkdb-1.active_clients"},
+		{"", "active_clients",
+			"db1_example_com.rethinkdb-1.us_east.data.member.rethinkdb.active_clients"},
+		{"host.type.tags.measurement", "active_clients",
+			"db1_example_com.member.rethinkdb-1.us_east.data.rethinkdb"},
+		{"dc|region.host.measurement", "active_clients",
+			"us_east.db1_example_com.rethinkdb"},
+		{"dc.host.measurement", "active_clients", "db1_example_com.rethinkdb"},
+		{"dc|zone.measurement", "active_clients", "rethinkdb"},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := NewTemplate(tt.template)
+		require.NoError(t, err, tt.template)
+		assert.Equal(t, tt.expected,
+			tmpl.Name("rethinkdb", rethinkdbTags, tt.field), tt.template)
+	}
+}
+
+func TestTemplateNameNoTags(t *testing.T) {
+	tmpl, err := NewTemplate("")
+	require.NoError(t, err)
+	assert.Equal(t, "rethinkdb.active_clients",
+		tmpl.Name("rethinkdb", nil, "active_clients"))
+}
+
+func TestNewTemplateErrors(t *testing.T) {
+	for _, template := range []string{"host..field", "host.|dc", ".measurement"} {
+		_, err := NewTemplate(template)
+		assert.Error(t, err, template)
+	}
+}
@@ -0,0 +1,260 @@
+package wavefront
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/outputs"
+	"github.com/influxdb/telegraf/outputs/serializers"
+)
+
+type Wavefront struct {
+	Prefix string
+
+	// Host and Port of a Wavefront proxy, receiving metrics over TCP
+	Host string
+	Port int
+
+	// URL and Token of the direct ingestion API, used instead of a proxy
+	// when set
+	URL       string
+	Token     string
+	Timeout   internal.Duration
+	UserAgent string
+
+	internal.ClientConfig
+	internal.HTTPProxy
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  # prefix for metric names
+  # prefix = "telegraf."
+
+  ## Proxy Mode ##
+  # DNS name and port of the Wavefront proxy
+  host = "wavefront.example.com"
+  port = 2878
+
+  ## Direct Ingestion Mode ##
+  # Wavefront URL and API token, used instead of a proxy when url is set
+  # url = "https://example.wavefront.com"
+  # token = "my-api-token"
+
+  # Timeout of each write of direct ingestion. Defaults to 5s, "0s" waits
+  # forever.
+  # timeout = "5s"
+  # User agent of direct ingestion requests, ie to recognize them in the
+  # logs of a proxy
+  # user_agent = "telegraf"
+
+  # HTTP proxy to send direct ingestion requests through
+  # http_proxy_url = "http://localhost:8888"
+
+  # Optional TLS config for direct ingestion
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_ca_dir = "/etc/telegraf/ca.d"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// invalidNameChars are the characters not allowed in unquoted Wavefront
+// metric names and point tag keys
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+func (w *Wavefront) Connect() error {
+	if w.URL != "" {
+		if w.Token == "" {
+			return fmt.Errorf("Wavefront: token is required for direct ingestion")
+		}
+		tlsConfig, err := w.TLSConfig()
+		if err != nil {
+			return err
+		}
+		w.client, err = internal.NewHTTPClient(w.Timeout.Duration, tlsConfig,
+			w.HTTPProxyURL)
+		return err
+	}
+
+	// Test Connection to the Wavefront proxy
+	connection, err := net.Dial("tcp", w.proxyAddress())
+	if err != nil {
+		return fmt.Errorf("Wavefront: proxy connect fail, %s", err)
+	}
+	return connection.Close()
+}
+
+func (w *Wavefront) proxyAddress() string {
+	return net.JoinHostPort(w.Host, strconv.Itoa(w.Port))
+}
+
+func (w *Wavefront) Write(points []*client.Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	var b bytes.Buffer
+	// Wavefront stores a single value per metric
+	for _, pt := range serializers.Flatten(points) {
+		line, err := w.buildLine(pt)
+		if err != nil {
+			// only numeric values are stored, ie string fields are skipped
+			continue
+		}
+		b.WriteString(line)
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+
+	if w.URL != "" {
+		return w.writeDirect(&b)
+	}
+	return w.writeProxy(&b)
+}
+
+func (w *Wavefront) writeProxy(b *bytes.Buffer) error {
+	connection, err := net.Dial("tcp", w.proxyAddress())
+	if err != nil {
+		return fmt.Errorf("Wavefront: proxy connect fail, %s", err)
+	}
+	defer connection.Close()
+
+	if _, err := connection.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("Wavefront: proxy writing error %s", err.Error())
+	}
+	return nil
+}
+
+func (w *Wavefront) writeDirect(b *bytes.Buffer) error {
+	url := strings.TrimSuffix(w.URL, "/") + "/report?f=wavefront"
+	req, err := http.NewRequest("POST", url, b)
+	if err != nil {
+		return fmt.Errorf("unable to create http.Request, %s\n", err.Error())
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Authorization", "Bearer "+w.Token)
+	req.Header.Set("User-Agent", w.userAgent())
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error POSTing metrics, %s\n", err.Error())
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("received bad status code, %d\n", resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *Wavefront) userAgent() string {
+	if w.UserAgent == "" {
+		return internal.DefaultUserAgent
+	}
+	return w.UserAgent
+}
+
+// buildLine formats pt as "<metric> <value> <timestamp> source=<source>
+// <tagk>=<tagv>...", the source being the host tag of the point.
+func (w *Wavefront) buildLine(pt *client.Point) (string, error) {
+	value, err := buildValue(pt.Fields()["value"])
+	if err != nil {
+		return "", err
+	}
+
+	tags := pt.Tags()
+	source := tags["host"]
+	if source == "" {
+		source, _ = os.Hostname()
+	}
+
+	return fmt.Sprintf("%s %s %d source=%s%s\n",
+		sanitizeName(w.Prefix+pt.Name()),
+		value,
+		pt.Time().Unix(),
+		quoteTagValue(source),
+		buildTags(tags),
+	), nil
+}
+
+// buildTags formats the point tags other than host, which is the source,
+// sorted by key and each preceded by a space
+func buildTags(ptTags map[string]string) string {
+	tags := make([]string, 0, len(ptTags))
+	for k, v := range ptTags {
+		if k == "host" || v == "" {
+			continue
+		}
+		tags = append(tags, fmt.Sprintf(" %s=%s", sanitizeName(k), quoteTagValue(v)))
+	}
+	sort.Strings(tags)
+	return strings.Join(tags, "")
+}
+
+// sanitizeName replaces the characters Wavefront does not allow in metric
+// names and tag keys by "-"
+func sanitizeName(name string) string {
+	return invalidNameChars.ReplaceAllString(name, "-")
+}
+
+// quoteTagValue quotes tag values holding characters that would end the
+// value, ie spaces
+func quoteTagValue(value string) string {
+	if !strings.ContainsAny(value, " \t\"=") {
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+func buildValue(v interface{}) (string, error) {
+	switch p := v.(type) {
+	case int64:
+		return strconv.FormatInt(p, 10), nil
+	case uint64:
+		return strconv.FormatUint(p, 10), nil
+	case float64:
+		return strconv.FormatFloat(p, 'f', -1, 64), nil
+	case bool:
+		if p {
+			return "1", nil
+		}
+		return "0", nil
+	default:
+		return "", fmt.Errorf("unexpected type %T with value %v for Wavefront", v, v)
+	}
+}
+
+func (w *Wavefront) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *Wavefront) Description() string {
+	return "Configuration for Wavefront proxy or API to send metrics to"
+}
+
+func (w *Wavefront) Close() error {
+	return nil
+}
+
+func init() {
+	outputs.Add("wavefront", func() outputs.Output {
+		return &Wavefront{
+			Timeout: internal.Duration{Duration: internal.DefaultHTTPTimeout},
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package wavefront
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rethinkdbPoint() *client.Point {
+	return client.NewPoint(
+		"rethinkdb_queries_per_sec",
+		map[string]string{
+			"host":     "10.0.0.1:28015",
+			"hostname": "db1",
+			"type":     "member",
+		},
+		map[string]interface{}{"value": int64(7)},
+		time.Unix(1136214245, 0),
+	)
+}
+
+func TestBuildLine(t *testing.T) {
+	w := &Wavefront{Prefix: "telegraf."}
+
+	line, err := w.buildLine(rethinkdbPoint())
+	require.NoError(t, err)
+	assert.Equal(t, "telegraf.rethinkdb_queries_per_sec 7 1136214245 "+
+		"source=10.0.0.1:28015 hostname=db1 type=member\n", line)
+
+	line, err = w.buildLine(client.NewPoint(
+		"disk used%",
+		map[string]string{"host": "server01", "path name": "/var/lib/my data"},
+		map[string]interface{}{"value": 0.5},
+		time.Unix(1136214245, 0),
+	))
+	require.NoError(t, err)
+	assert.Equal(t, "telegraf.disk-used- 0.5 1136214245 "+
+		`source=server01 path-name="/var/lib/my data"`+"\n", line)
+
+	_, err = w.buildLine(client.NewPoint(
+		"version",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"value": "2.1.5"},
+		time.Unix(1136214245, 0),
+	))
+	assert.Error(t, err)
+}
+
+func TestWriteProxy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	w := &Wavefront{Host: "127.0.0.1"}
+	w.Port, _ = strconv.Atoi(port)
+	require.NoError(t, w.Connect())
+
+	require.NoError(t, w.Write([]*client.Point{rethinkdbPoint()}))
+	select {
+	case line := <-lines:
+		assert.Equal(t, "rethinkdb_queries_per_sec 7 1136214245 "+
+			"source=10.0.0.1:28015 hostname=db1 type=member", line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no line received by the proxy")
+	}
+}
+
+func TestWriteDirect(t *testing.T) {
+	var body, auth, path, format, userAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		format = r.URL.Query().Get("f")
+		auth = r.Header.Get("Authorization")
+		userAgent = r.Header.Get("User-Agent")
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	w := &Wavefront{URL: ts.URL, Token: "my-api-token"}
+	require.NoError(t, w.Connect())
+	require.NoError(t, w.Write([]*client.Point{rethinkdbPoint()}))
+
+	assert.Equal(t, "/report", path)
+	assert.Equal(t, "wavefront", format)
+	assert.Equal(t, "Bearer my-api-token", auth)
+	assert.Equal(t, "telegraf", userAgent)
+	assert.Equal(t, "rethinkdb_queries_per_sec 7 1136214245 "+
+		"source=10.0.0.1:28015 hostname=db1 type=member\n", body)
+
+	w.UserAgent = "telegraf-audit"
+	require.NoError(t, w.Write([]*client.Point{rethinkdbPoint()}))
+	assert.Equal(t, "telegraf-audit", userAgent)
+}
+
+func TestConnectDirectRequiresToken(t *testing.T) {
+	w := &Wavefront{URL: "https://example.wavefront.com"}
+	assert.Error(t, w.Connect())
+}
@@ -4,17 +4,27 @@ import (
 	_ "github.com/influxdb/telegraf/plugins/aerospike"
 	_ "github.com/influxdb/telegraf/plugins/apache"
 	_ "github.com/influxdb/telegraf/plugins/bcache"
+	_ "github.com/influxdb/telegraf/plugins/cassandra"
+	_ "github.com/influxdb/telegraf/plugins/cloudwatch"
+	_ "github.com/influxdb/telegraf/plugins/collectd"
 	_ "github.com/influxdb/telegraf/plugins/disque"
+	_ "github.com/influxdb/telegraf/plugins/dns_query"
+	_ "github.com/influxdb/telegraf/plugins/docker"
 	_ "github.com/influxdb/telegraf/plugins/elasticsearch"
 	_ "github.com/influxdb/telegraf/plugins/exec"
+	_ "github.com/influxdb/telegraf/plugins/graphite"
 	_ "github.com/influxdb/telegraf/plugins/haproxy"
+	_ "github.com/influxdb/telegraf/plugins/http_response"
 	_ "github.com/influxdb/telegraf/plugins/httpjson"
+	_ "github.com/influxdb/telegraf/plugins/jolokia"
 	_ "github.com/influxdb/telegraf/plugins/kafka_consumer"
+	_ "github.com/influxdb/telegraf/plugins/kubernetes"
 	_ "github.com/influxdb/telegraf/plugins/leofs"
 	_ "github.com/influxdb/telegraf/plugins/lustre2"
 	_ "github.com/influxdb/telegraf/plugins/memcached"
 	_ "github.com/influxdb/telegraf/plugins/mongodb"
 	_ "github.com/influxdb/telegraf/plugins/mysql"
+	_ "github.com/influxdb/telegraf/plugins/net_response"
 	_ "github.com/influxdb/telegraf/plugins/nginx"
 	_ "github.com/influxdb/telegraf/plugins/phpfpm"
 	_ "github.com/influxdb/telegraf/plugins/ping"
@@ -25,7 +35,14 @@ import (
 	_ "github.com/influxdb/telegraf/plugins/rabbitmq"
 	_ "github.com/influxdb/telegraf/plugins/redis"
 	_ "github.com/influxdb/telegraf/plugins/rethinkdb"
+	_ "github.com/influxdb/telegraf/plugins/snmp"
+	_ "github.com/influxdb/telegraf/plugins/socket_listener"
 	_ "github.com/influxdb/telegraf/plugins/statsd"
+	_ "github.com/influxdb/telegraf/plugins/stdin"
+	_ "github.com/influxdb/telegraf/plugins/syslog"
 	_ "github.com/influxdb/telegraf/plugins/system"
+	_ "github.com/influxdb/telegraf/plugins/tail"
+	_ "github.com/influxdb/telegraf/plugins/trig"
+	_ "github.com/influxdb/telegraf/plugins/webhooks"
 	_ "github.com/influxdb/telegraf/plugins/zookeeper"
 )
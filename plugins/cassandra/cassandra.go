@@ -0,0 +1,293 @@
+package cassandra
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const defaultPort = "8778"
+
+type JolokiaClient interface {
+	MakeRequest(req *http.Request) (*http.Response, error)
+}
+
+type JolokiaClientImpl struct {
+	client *http.Client
+}
+
+func (c JolokiaClientImpl) MakeRequest(req *http.Request) (*http.Response, error) {
+	return c.client.Do(req)
+}
+
+// Cassandra reads JMX metrics of Cassandra nodes through their Jolokia
+// agents
+type Cassandra struct {
+	jClient JolokiaClient
+	Context string
+	Servers []string
+	Metrics []string
+}
+
+type readResponse struct {
+	Request struct {
+		Mbean     string      `json:"mbean"`
+		Attribute interface{} `json:"attribute"`
+	} `json:"request"`
+	Status int         `json:"status"`
+	Error  string      `json:"error"`
+	Value  interface{} `json:"value"`
+}
+
+var sampleConfig = `
+  # Path of the Jolokia read endpoint
+  context = "/jolokia/read"
+  # Jolokia agents of the Cassandra nodes, as [user:password@]host[:port],
+  # port 8778 by default
+  servers = ["myuser:mypassword@10.10.10.1:8778", "10.10.10.2"]
+  # Metrics to read, as /<mbean>/<attribute>. The mbean may be a pattern and
+  # the attribute may be omitted to read all attributes. Cassandra metrics
+  # are tagged by keyspace and table where applicable.
+  metrics = [
+    "/java.lang:type=Memory/HeapMemoryUsage",
+    "/org.apache.cassandra.metrics:type=Compaction,name=PendingTasks/Value",
+    "/org.apache.cassandra.metrics:type=ClientRequest,scope=Read,name=Latency",
+    "/org.apache.cassandra.metrics:type=ClientRequest,scope=Write,name=Latency",
+    "/org.apache.cassandra.metrics:type=Table,keyspace=*,scope=*,name=ReadLatency",
+    "/org.apache.cassandra.metrics:type=Table,keyspace=*,scope=*,name=WriteLatency",
+  ]
+`
+
+func (c *Cassandra) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Cassandra) Description() string {
+	return "Read Cassandra metrics through Jolokia"
+}
+
+func (c *Cassandra) Gather(acc plugins.Accumulator) error {
+	context := c.Context
+	if context == "" {
+		context = "/jolokia/read"
+	}
+
+	var errs []string
+	for _, server := range c.Servers {
+		if !strings.Contains(server, "://") {
+			// credentials are only parsed from a full URL
+			server = "http://" + server
+		}
+		u, err := internal.ParseServer(server, defaultPort)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if u.Path == "" {
+			u.Path = context
+		}
+
+		for _, metric := range c.Metrics {
+			if err := c.gatherMetric(acc, u, metric); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func (c *Cassandra) gatherMetric(acc plugins.Accumulator, server *url.URL, metric string) error {
+	resp, err := c.read(server, metric)
+	if err != nil {
+		return err
+	}
+	if resp.Status != http.StatusOK {
+		return fmt.Errorf("Reading %s from %s failed, status %d: %s",
+			metric, server.Host, resp.Status, resp.Error)
+	}
+
+	host := server.Hostname()
+
+	// a pattern read returns the attributes of each matching mbean keyed by
+	// its name, and a read of a single attribute of one mbean returns the
+	// bare value of the attribute
+	values := map[string]interface{}{resp.Request.Mbean: resp.Value}
+	if strings.Contains(resp.Request.Mbean, "*") {
+		if m, ok := resp.Value.(map[string]interface{}); ok {
+			values = m
+		}
+	} else if attribute, ok := resp.Request.Attribute.(string); ok && attribute != "" {
+		values[resp.Request.Mbean] = map[string]interface{}{attribute: resp.Value}
+	}
+
+	for mbean, value := range values {
+		measurement, tags, prefix, err := parseMbean(mbean)
+		if err != nil {
+			return err
+		}
+		tags["cassandra_host"] = host
+
+		fields := make(map[string]interface{})
+		flatten(fields, prefix, value)
+		if len(fields) > 0 {
+			acc.AddFields(measurement, fields, tags)
+		}
+	}
+	return nil
+}
+
+func (c *Cassandra) read(server *url.URL, metric string) (*readResponse, error) {
+	u := *server
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + strings.TrimLeft(metric, "/")
+	u.User = nil
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if server.User != nil {
+		password, _ := server.User.Password()
+		req.SetBasicAuth(server.User.Username(), password)
+	}
+
+	resp, err := c.jClient.MakeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Response from url \"%s\" has status code %d (%s), expected %d (%s)",
+			u.String(),
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode),
+			http.StatusOK,
+			http.StatusText(http.StatusOK))
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	r := &readResponse{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("Error decoding JSON response from %s: %s", u.String(), err)
+	}
+	return r, nil
+}
+
+// parseMbean splits an mbean name such as
+// "org.apache.cassandra.metrics:type=Table,keyspace=ks,scope=users,name=ReadLatency"
+// into a measurement named after its type, tags from its other keys and the
+// prefix of its fields from its name
+func parseMbean(mbean string) (measurement string, tags map[string]string, prefix string, err error) {
+	i := strings.Index(mbean, ":")
+	if i < 0 {
+		return "", nil, "", fmt.Errorf("Invalid mbean '%s'", mbean)
+	}
+	domain := mbean[:i]
+
+	props := make(map[string]string)
+	for _, prop := range strings.Split(mbean[i+1:], ",") {
+		kv := strings.SplitN(prop, "=", 2)
+		if len(kv) != 2 {
+			return "", nil, "", fmt.Errorf("Invalid mbean '%s'", mbean)
+		}
+		props[kv[0]] = kv[1]
+	}
+
+	measurement = snakeCase(props["type"])
+	if domain == "java.lang" {
+		measurement = "java_" + measurement
+	}
+	prefix = snakeCase(props["name"])
+
+	tags = make(map[string]string)
+	for k, v := range props {
+		switch k {
+		case "type":
+		case "name":
+			if domain == "java.lang" {
+				// ie the name of a garbage collector
+				tags["name"] = v
+			}
+		case "scope":
+			if props["type"] == "Table" || props["type"] == "ColumnFamily" {
+				tags["table"] = v
+			} else {
+				tags["scope"] = v
+			}
+		default:
+			tags[k] = v
+		}
+	}
+	if domain == "java.lang" {
+		prefix = ""
+	}
+	return measurement, tags, prefix, nil
+}
+
+// flatten adds the numeric values found in v to fields, keyed by their snake
+// cased path joined with "_"
+func flatten(fields map[string]interface{}, prefix string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			key := snakeCase(k)
+			if prefix != "" {
+				key = prefix + "_" + key
+			}
+			flatten(fields, key, t[k])
+		}
+	case float64:
+		if prefix == "" {
+			prefix = "value"
+		}
+		fields[prefix] = t
+	}
+}
+
+// snakeCase converts a JMX name such as "ReadLatency" or "99thPercentile" to
+// "read_latency" or "99th_percentile"
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var out []rune
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) && runes[i-1] != '_' {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+func init() {
+	plugins.Add("cassandra", func() plugins.Plugin {
+		return &Cassandra{jClient: &JolokiaClientImpl{client: &http.Client{
+			Timeout: 5 * time.Second,
+		}}}
+	})
+}
@@ -0,0 +1,204 @@
+package cassandra
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const heapMemoryJSON = `
+{
+  "request": {
+    "mbean": "java.lang:type=Memory",
+    "attribute": "HeapMemoryUsage",
+    "type": "read"
+  },
+  "value": {
+    "init": 65011712,
+    "committed": 65011712,
+    "max": 1862270976,
+    "used": 203288528
+  },
+  "timestamp": 1446129191,
+  "status": 200
+}
+`
+
+const pendingTasksJSON = `
+{
+  "request": {
+    "mbean": "org.apache.cassandra.metrics:name=PendingTasks,type=Compaction",
+    "attribute": "Value",
+    "type": "read"
+  },
+  "value": 12,
+  "timestamp": 1446129191,
+  "status": 200
+}
+`
+
+const readLatencyJSON = `
+{
+  "request": {
+    "mbean": "org.apache.cassandra.metrics:keyspace=*,name=ReadLatency,scope=*,type=Table",
+    "type": "read"
+  },
+  "value": {
+    "org.apache.cassandra.metrics:keyspace=app,name=ReadLatency,scope=users,type=Table": {
+      "Count": 1024,
+      "Mean": 512.5,
+      "99thPercentile": 2000.0,
+      "LatencyUnit": "MICROSECONDS"
+    },
+    "org.apache.cassandra.metrics:keyspace=app,name=ReadLatency,scope=events,type=Table": {
+      "Count": 10,
+      "Mean": 100.0,
+      "99thPercentile": 300.0,
+      "LatencyUnit": "MICROSECONDS"
+    }
+  },
+  "timestamp": 1446129191,
+  "status": 200
+}
+`
+
+const notFoundJSON = `
+{
+  "request": {"mbean": "org.apache.cassandra.metrics:type=Missing", "type": "read"},
+  "error_type": "javax.management.InstanceNotFoundException",
+  "error": "javax.management.InstanceNotFoundException : org.apache.cassandra.metrics:type=Missing",
+  "status": 404
+}
+`
+
+type jolokiaClientStub struct {
+	responses map[string]string
+	requests  []*http.Request
+}
+
+func (c *jolokiaClientStub) MakeRequest(req *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, req)
+	for path, body := range c.responses {
+		if strings.HasSuffix(req.URL.Path, path) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+			}, nil
+		}
+	}
+	return &http.Response{
+		StatusCode: http.StatusNotFound,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil
+}
+
+func genJolokiaClientStub(metrics map[string]string) (*Cassandra, *jolokiaClientStub) {
+	stub := &jolokiaClientStub{responses: metrics}
+	c := &Cassandra{jClient: stub, Servers: []string{"user:secret@10.10.10.1"}}
+	for metric := range metrics {
+		c.Metrics = append(c.Metrics, metric)
+	}
+	return c, stub
+}
+
+func TestGatherHeapMemory(t *testing.T) {
+	c, stub := genJolokiaClientStub(map[string]string{
+		"/java.lang:type=Memory/HeapMemoryUsage": heapMemoryJSON,
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Len(t, acc.Points, 1)
+
+	pt := acc.Points[0]
+	assert.Equal(t, "java_memory", pt.Measurement)
+	assert.Equal(t, map[string]string{"cassandra_host": "10.10.10.1"}, pt.Tags)
+	assert.Equal(t, map[string]interface{}{
+		"heap_memory_usage_init":      65011712.0,
+		"heap_memory_usage_committed": 65011712.0,
+		"heap_memory_usage_max":       1862270976.0,
+		"heap_memory_usage_used":      203288528.0,
+	}, pt.Values)
+
+	require.Len(t, stub.requests, 1)
+	req := stub.requests[0]
+	assert.Equal(t, "10.10.10.1:8778", req.URL.Host)
+	assert.Equal(t, "/jolokia/read/java.lang:type=Memory/HeapMemoryUsage", req.URL.Path)
+	user, pass, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "secret", pass)
+}
+
+func TestGatherPendingCompactions(t *testing.T) {
+	c, _ := genJolokiaClientStub(map[string]string{
+		"/org.apache.cassandra.metrics:type=Compaction,name=PendingTasks/Value": pendingTasksJSON,
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Len(t, acc.Points, 1)
+
+	pt := acc.Points[0]
+	assert.Equal(t, "compaction", pt.Measurement)
+	assert.Equal(t, map[string]string{"cassandra_host": "10.10.10.1"}, pt.Tags)
+	assert.Equal(t, map[string]interface{}{"pending_tasks_value": 12.0}, pt.Values)
+}
+
+func TestGatherTableLatency(t *testing.T) {
+	c, _ := genJolokiaClientStub(map[string]string{
+		"/org.apache.cassandra.metrics:type=Table,keyspace=*,scope=*,name=ReadLatency": readLatencyJSON,
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	require.Len(t, acc.Points, 2)
+
+	latencies := make(map[string]map[string]interface{})
+	for _, pt := range acc.Points {
+		assert.Equal(t, "table", pt.Measurement)
+		assert.Equal(t, "app", pt.Tags["keyspace"])
+		assert.Equal(t, "10.10.10.1", pt.Tags["cassandra_host"])
+		latencies[pt.Tags["table"]] = pt.Values
+	}
+	assert.Equal(t, map[string]interface{}{
+		"read_latency_count":           1024.0,
+		"read_latency_mean":            512.5,
+		"read_latency_99th_percentile": 2000.0,
+	}, latencies["users"])
+	assert.Equal(t, 300.0, latencies["events"]["read_latency_99th_percentile"])
+}
+
+func TestGatherErrors(t *testing.T) {
+	c, _ := genJolokiaClientStub(map[string]string{
+		"/org.apache.cassandra.metrics:type=Missing": notFoundJSON,
+	})
+	var acc testutil.Accumulator
+	err := c.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "InstanceNotFoundException")
+
+	c.Metrics = []string{"/java.lang:type=Threading/ThreadCount"}
+	assert.Error(t, c.Gather(&acc))
+	assert.Len(t, acc.Points, 0)
+}
+
+func TestSnakeCase(t *testing.T) {
+	for in, out := range map[string]string{
+		"ReadLatency":        "read_latency",
+		"99thPercentile":     "99th_percentile",
+		"HeapMemoryUsage":    "heap_memory_usage",
+		"PendingTasks":       "pending_tasks",
+		"ClientRequest":      "client_request",
+		"TotalDiskSpaceUsed": "total_disk_space_used",
+		"CASRead":            "cas_read",
+		"count":              "count",
+	} {
+		assert.Equal(t, out, snakeCase(in))
+	}
+}
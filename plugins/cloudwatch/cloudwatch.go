@@ -0,0 +1,192 @@
+package cloudwatch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/aws"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// cloudWatchClient is the part of *aws.CloudWatch used to read metrics
+type cloudWatchClient interface {
+	GetMetricStatistics(input *aws.GetMetricStatisticsInput) (*aws.GetMetricStatisticsOutput, error)
+}
+
+// CloudWatch reads the statistics of CloudWatch metrics
+type CloudWatch struct {
+	aws.CredentialConfig
+
+	Namespace string
+	// Period of the statistics, a multiple of 60s
+	Period internal.Duration
+	// Delay accounts for the time CloudWatch takes to aggregate a period
+	Delay      internal.Duration
+	Statistics []string
+	Metrics    []Metric
+
+	client cloudWatchClient
+}
+
+// Metric selects metrics by name, all sharing the same dimensions
+type Metric struct {
+	Names      []string
+	Dimensions []Dimension
+}
+
+type Dimension struct {
+	Name  string
+	Value string
+}
+
+var sampleConfig = `
+  # AWS region and credentials, read from AWS_ACCESS_KEY_ID,
+  # AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN when not set
+  region = "us-east-1"
+  # access_key = ""
+  # secret_key = ""
+  # token = ""
+
+  # Namespace of the metrics, ie AWS/ELB
+  namespace = "AWS/ELB"
+  # Period of the statistics, a multiple of 60s
+  period = "5m"
+  # Time CloudWatch takes to aggregate a period, the statistics of the
+  # period ending delay ago are gathered
+  delay = "5m"
+  # Statistics of each metric, any of Average, Sum, SampleCount, Maximum and
+  # Minimum
+  statistics = ["Average", "Sum", "Maximum"]
+
+  # Metrics to read, tagged by their dimensions
+  [[cloudwatch.metrics]]
+    names = ["Latency", "RequestCount"]
+
+    [[cloudwatch.metrics.dimensions]]
+      name = "LoadBalancerName"
+      value = "p-example"
+`
+
+func (c *CloudWatch) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CloudWatch) Description() string {
+	return "Pull metric statistics from Amazon CloudWatch"
+}
+
+func (c *CloudWatch) Gather(acc plugins.Accumulator) error {
+	if c.client == nil {
+		client, err := aws.NewCloudWatch(&c.CredentialConfig)
+		if err != nil {
+			return err
+		}
+		c.client = client
+	}
+
+	period := c.Period.Duration
+	if period == 0 {
+		period = 5 * time.Minute
+	}
+	if period%time.Minute != 0 {
+		return fmt.Errorf("Invalid period %s, must be a multiple of 60s", period)
+	}
+	statistics := c.Statistics
+	if len(statistics) == 0 {
+		statistics = []string{"Average"}
+	}
+
+	end := time.Now().Add(-c.Delay.Duration).Truncate(period)
+	start := end.Add(-period)
+
+	var errs []string
+	for _, m := range c.Metrics {
+		dimensions := make([]aws.Dimension, len(m.Dimensions))
+		for i, d := range m.Dimensions {
+			dimensions[i] = aws.Dimension{Name: d.Name, Value: d.Value}
+		}
+
+		for _, name := range m.Names {
+			out, err := c.client.GetMetricStatistics(&aws.GetMetricStatisticsInput{
+				Namespace:  c.Namespace,
+				MetricName: name,
+				Dimensions: dimensions,
+				StartTime:  start,
+				EndTime:    end,
+				Period:     period,
+				Statistics: statistics,
+			})
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("Unable to get %s %s: %s", c.Namespace, name, err))
+				continue
+			}
+			c.addDatapoints(acc, name, dimensions, statistics, out.Datapoints)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// addDatapoints adds a point per datapoint of a metric, with a field per
+// statistic named after the metric, ie latency_average
+func (c *CloudWatch) addDatapoints(
+	acc plugins.Accumulator,
+	name string,
+	dimensions []aws.Dimension,
+	statistics []string,
+	datapoints []aws.Datapoint,
+) {
+	tags := map[string]string{"region": c.Region}
+	for _, d := range dimensions {
+		tags[snakeCase(d.Name)] = d.Value
+	}
+
+	for _, d := range datapoints {
+		fields := make(map[string]interface{})
+		for _, stat := range statistics {
+			if v, ok := d.Statistic(stat); ok {
+				fields[snakeCase(name)+"_"+snakeCase(stat)] = v
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(snakeCase(c.Namespace), fields, tags, d.Timestamp)
+		}
+	}
+}
+
+// snakeCase converts a CloudWatch name such as "AWS/ELB" or
+// "CPUUtilization" to "aws_elb" or "cpu_utilization"
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var out []rune
+	for i, r := range runes {
+		switch {
+		case r == '/' || r == ' ' || r == '-' || r == '.':
+			r = '_'
+		case unicode.IsUpper(r):
+			if i > 0 && out[len(out)-1] != '_' && (unicode.IsLower(runes[i-1]) ||
+				(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+func init() {
+	plugins.Add("cloudwatch", func() plugins.Plugin {
+		return &CloudWatch{
+			Period: internal.Duration{Duration: 5 * time.Minute},
+			Delay:  internal.Duration{Duration: 5 * time.Minute},
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package cloudwatch
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/internal/aws"
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/naoina/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCloudWatchClient struct {
+	inputs []*aws.GetMetricStatisticsInput
+}
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func (m *mockCloudWatchClient) GetMetricStatistics(input *aws.GetMetricStatisticsInput) (*aws.GetMetricStatisticsOutput, error) {
+	m.inputs = append(m.inputs, input)
+	switch input.MetricName {
+	case "Latency":
+		return &aws.GetMetricStatisticsOutput{
+			Label: "Latency",
+			Datapoints: []aws.Datapoint{{
+				Timestamp: input.StartTime,
+				Unit:      "Seconds",
+				Average:   float(0.0125),
+				Sum:       float(12.5),
+				Maximum:   float(0.75),
+			}},
+		}, nil
+	case "RequestCount":
+		return &aws.GetMetricStatisticsOutput{Label: "RequestCount"}, nil
+	}
+	return nil, errors.New("metric not found")
+}
+
+func newCloudWatch(client cloudWatchClient) *CloudWatch {
+	return &CloudWatch{
+		CredentialConfig: aws.CredentialConfig{Region: "us-east-1"},
+		Namespace:        "AWS/ELB",
+		Period:           internal.Duration{Duration: 5 * time.Minute},
+		Delay:            internal.Duration{Duration: 5 * time.Minute},
+		Statistics:       []string{"Average", "Sum", "Maximum"},
+		Metrics: []Metric{{
+			Names:      []string{"Latency", "RequestCount"},
+			Dimensions: []Dimension{{Name: "LoadBalancerName", Value: "p-example"}},
+		}},
+		client: client,
+	}
+}
+
+func TestGather(t *testing.T) {
+	client := &mockCloudWatchClient{}
+	c := newCloudWatch(client)
+
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+
+	require.Len(t, client.inputs, 2)
+	input := client.inputs[0]
+	assert.Equal(t, "AWS/ELB", input.Namespace)
+	assert.Equal(t, "Latency", input.MetricName)
+	assert.Equal(t, []aws.Dimension{{Name: "LoadBalancerName", Value: "p-example"}}, input.Dimensions)
+	assert.Equal(t, 5*time.Minute, input.Period)
+	assert.Equal(t, 5*time.Minute, input.EndTime.Sub(input.StartTime))
+	assert.Equal(t, time.Duration(0), input.EndTime.Sub(input.EndTime.Truncate(5*time.Minute)))
+	assert.True(t, input.EndTime.Before(time.Now().Add(-5*time.Minute)))
+	assert.Equal(t, []string{"Average", "Sum", "Maximum"}, input.Statistics)
+
+	// RequestCount returned no datapoints
+	require.Len(t, acc.Points, 1)
+	pt := acc.Points[0]
+	assert.Equal(t, "aws_elb", pt.Measurement)
+	assert.Equal(t, map[string]string{
+		"region":             "us-east-1",
+		"load_balancer_name": "p-example",
+	}, pt.Tags)
+	assert.Equal(t, map[string]interface{}{
+		"latency_average": 0.0125,
+		"latency_sum":     12.5,
+		"latency_maximum": 0.75,
+	}, pt.Values)
+	assert.Equal(t, input.StartTime, pt.Time)
+}
+
+func TestGatherErrors(t *testing.T) {
+	c := newCloudWatch(&mockCloudWatchClient{})
+	c.Metrics[0].Names = []string{"Missing", "Latency"}
+
+	var acc testutil.Accumulator
+	err := c.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Missing")
+	assert.Len(t, acc.Points, 1)
+
+	c.Period.Duration = 90 * time.Second
+	assert.Error(t, c.Gather(&acc))
+}
+
+func TestSnakeCase(t *testing.T) {
+	for in, out := range map[string]string{
+		"AWS/ELB":          "aws_elb",
+		"AWS/EC2":          "aws_ec2",
+		"CPUUtilization":   "cpu_utilization",
+		"LoadBalancerName": "load_balancer_name",
+		"HTTPCode_ELB_5XX": "http_code_elb_5xx",
+		"SampleCount":      "sample_count",
+		"Average":          "average",
+		"NetworkIn":        "network_in",
+	} {
+		assert.Equal(t, out, snakeCase(in), in)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	var c CloudWatch
+	require.NoError(t, toml.Unmarshal([]byte(`
+region = "eu-west-1"
+access_key = "AKID"
+secret_key = "secret"
+namespace = "AWS/EC2"
+period = "1m"
+statistics = ["Maximum"]
+
+[[metrics]]
+  names = ["CPUUtilization"]
+  [[metrics.dimensions]]
+    name = "InstanceId"
+    value = "i-12345678"
+`), &c))
+
+	assert.Equal(t, "eu-west-1", c.Region)
+	assert.Equal(t, "AKID", c.AccessKey)
+	assert.Equal(t, "secret", c.SecretKey)
+	assert.Equal(t, time.Minute, c.Period.Duration)
+	assert.Equal(t, []Metric{{
+		Names:      []string{"CPUUtilization"},
+		Dimensions: []Dimension{{Name: "InstanceId", Value: "i-12345678"}},
+	}}, c.Metrics)
+}
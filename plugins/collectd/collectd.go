@@ -0,0 +1,213 @@
+package collectd
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const (
+	defaultAllowedPendingPoints = 10000
+	// collectd packets are at most a UDP datagram
+	udpBufferSize = 64 * 1024
+)
+
+var dropwarn = "ERROR: Point buffer full. Discarding collectd packet from %s. " +
+	"You may want to increase allowed_pending_points in the config\n"
+
+// Collectd listens for the binary network protocol of collectd over UDP and
+// adds the received values on each Gather. Values are named after the
+// collectd plugin, ie collectd_cpu, and tagged with their host, plugin,
+// plugin instance, type and type instance.
+type Collectd struct {
+	// ServiceAddress is the UDP address to listen on
+	ServiceAddress string
+
+	// TypesDB are collectd types.db files naming the values of each type,
+	// ie the rx and tx fields of if_octets. Values of unknown types are
+	// value, or value0, value1... for types of several values.
+	TypesDB []string
+
+	// SecurityLevel is "none", "sign" or "encrypt", the minimal security of
+	// the accepted packets, whose users and passwords are read from AuthFile
+	SecurityLevel string
+	AuthFile      string
+
+	// Number of points allowed to queue up in between calls to Gather.
+	// Packets received while the buffer is full are dropped.
+	AllowedPendingPoints int
+
+	sync.Mutex
+	points []point
+
+	parser *parser
+	conn   net.PacketConn
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+var sampleConfig = `
+  # UDP address to listen on
+  service_address = ":25826"
+
+  # collectd types.db files naming the values of each type, ie the rx and tx
+  # fields of if_octets. Values of unknown types are named value.
+  typesdb = ["/usr/share/collectd/types.db"]
+
+  # Minimal security of the accepted packets: "none", "sign" or "encrypt".
+  # Users and passwords are read from the auth file, one "user: password"
+  # per line, as in collectd's AuthFile.
+  security_level = "none"
+  # auth_file = "/etc/collectd/auth_file"
+
+  # Number of points to buffer in between collection intervals
+  allowed_pending_points = 10000
+`
+
+func (c *Collectd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Collectd) Description() string {
+	return "Listen for the binary network protocol of collectd"
+}
+
+func (c *Collectd) Start() error {
+	p, err := c.newParser()
+	if err != nil {
+		return err
+	}
+	c.parser = p
+
+	c.conn, err = net.ListenPacket("udp", c.ServiceAddress)
+	if err != nil {
+		return err
+	}
+	c.done = make(chan struct{})
+	c.wg.Add(1)
+	go c.read()
+
+	log.Printf("Collectd listening on %s\n", c.ServiceAddress)
+	return nil
+}
+
+func (c *Collectd) newParser() (*parser, error) {
+	level, ok := securityLevels[c.SecurityLevel]
+	if !ok {
+		return nil, fmt.Errorf("Invalid security_level '%s', expected none, sign or encrypt",
+			c.SecurityLevel)
+	}
+	p := &parser{level: level, typesDB: make(map[string][]string)}
+
+	if c.AuthFile != "" {
+		f, err := os.Open(c.AuthFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		p.passwords, err = parseAuthFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", c.AuthFile, err)
+		}
+	} else if level > levelNone {
+		return nil, fmt.Errorf("security_level %s requires an auth_file", c.SecurityLevel)
+	}
+
+	for _, typesDB := range c.TypesDB {
+		f, err := os.Open(typesDB)
+		if err != nil {
+			return nil, err
+		}
+		err = parseTypesDB(f, p.typesDB)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path.Base(typesDB), err)
+		}
+	}
+	return p, nil
+}
+
+// Addr returns the address the listener is bound to
+func (c *Collectd) Addr() net.Addr {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.LocalAddr()
+}
+
+func (c *Collectd) read() {
+	defer c.wg.Done()
+	buf := make([]byte, udpBufferSize)
+	for {
+		n, addr, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-c.done:
+				return
+			default:
+				log.Printf("ERROR: reading packet on %s: %s\n", c.ServiceAddress, err)
+				continue
+			}
+		}
+		c.parsePacket(addr, buf[:n])
+	}
+}
+
+// parsePacket adds the points of a packet, the whole packet is dropped when
+// it is malformed or its signature or encryption can not be verified
+func (c *Collectd) parsePacket(addr net.Addr, packet []byte) {
+	points, err := c.parser.parse(packet)
+	if err != nil {
+		log.Printf("ERROR: unable to parse collectd packet from %s: %s\n", addr, err)
+		return
+	}
+
+	limit := c.AllowedPendingPoints
+	if limit == 0 {
+		limit = defaultAllowedPendingPoints
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if len(c.points)+len(points) > limit {
+		log.Printf(dropwarn, addr)
+		return
+	}
+	c.points = append(c.points, points...)
+}
+
+func (c *Collectd) Gather(acc plugins.Accumulator) error {
+	c.Lock()
+	points := c.points
+	c.points = nil
+	c.Unlock()
+
+	for _, pt := range points {
+		acc.AddFields(pt.measurement, pt.fields, pt.tags, pt.time)
+	}
+	return nil
+}
+
+func (c *Collectd) Stop() {
+	if c.done == nil {
+		return
+	}
+	close(c.done)
+	c.conn.Close()
+	c.wg.Wait()
+}
+
+func init() {
+	plugins.Add("collectd", func() plugins.Plugin {
+		return &Collectd{
+			ServiceAddress:       ":25826",
+			SecurityLevel:        "none",
+			AllowedPendingPoints: defaultAllowedPendingPoints,
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package collectd
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitForPoints(t *testing.T, c *Collectd, acc *testutil.Accumulator, n int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		require.NoError(t, c.Gather(acc))
+		if len(acc.Points) >= n {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, acc.Points, n)
+}
+
+func tempFiles(t *testing.T, files map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "collectd")
+	require.NoError(t, err)
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestCollectd(t *testing.T) {
+	dir, cleanup := tempFiles(t, map[string]string{"types.db": testTypesDB})
+	defer cleanup()
+
+	c := &Collectd{
+		ServiceAddress: "127.0.0.1:0",
+		TypesDB:        []string{filepath.Join(dir, "types.db")},
+	}
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	packet, err := hex.DecodeString(capturedPacket)
+	require.NoError(t, err)
+	conn, err := net.Dial("udp", c.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(packet)
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	waitForPoints(t, c, &acc, 19)
+	pt := acc.Points[3]
+	assert.Equal(t, "df", pt.Measurement)
+	assert.Equal(t, map[string]interface{}{"used": 378576896.0, "free": 50287988736.0}, pt.Values)
+	assert.Equal(t, "pf1-62-210-94-173", pt.Tags["host"])
+	assert.Equal(t, capturedTime, pt.Time)
+}
+
+func TestCollectdEncrypted(t *testing.T) {
+	dir, cleanup := tempFiles(t, map[string]string{"auth_file": "telegraf: secret\n"})
+	defer cleanup()
+
+	c := &Collectd{
+		ServiceAddress: "127.0.0.1:0",
+		SecurityLevel:  "encrypt",
+		AuthFile:       filepath.Join(dir, "auth_file"),
+	}
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	conn, err := net.Dial("udp", c.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	for _, packet := range [][]byte{
+		// unencrypted packets are dropped
+		testParts(),
+		signedPacket("telegraf", "secret", testParts()),
+		encryptedPacket("telegraf", "secret", testParts()),
+	} {
+		_, err = conn.Write(packet)
+		require.NoError(t, err)
+	}
+
+	var acc testutil.Accumulator
+	waitForPoints(t, c, &acc, 1)
+	assert.Equal(t, "load", acc.Points[0].Measurement)
+	assert.Equal(t, 0.5, acc.Points[0].Values["value"])
+}
+
+func TestCollectdStartErrors(t *testing.T) {
+	dir, cleanup := tempFiles(t, map[string]string{
+		"types.db":  "df used",
+		"auth_file": "telegraf",
+	})
+	defer cleanup()
+
+	for _, c := range []*Collectd{
+		{ServiceAddress: "127.0.0.1:0", SecurityLevel: "paranoid"},
+		{ServiceAddress: "127.0.0.1:0", SecurityLevel: "sign"},
+		{ServiceAddress: "127.0.0.1:0", AuthFile: filepath.Join(dir, "auth_file")},
+		{ServiceAddress: "127.0.0.1:0", AuthFile: filepath.Join(dir, "missing")},
+		{ServiceAddress: "127.0.0.1:0", TypesDB: []string{filepath.Join(dir, "types.db")}},
+		{ServiceAddress: "not an address"},
+	} {
+		assert.Error(t, c.Start(), "%+v", c)
+	}
+}
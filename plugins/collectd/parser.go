@@ -0,0 +1,351 @@
+package collectd
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// part types of the collectd binary protocol, see
+// https://collectd.org/wiki/index.php/Binary_protocol
+const (
+	partHost           = 0x0000
+	partTime           = 0x0001
+	partPlugin         = 0x0002
+	partPluginInstance = 0x0003
+	partType           = 0x0004
+	partTypeInstance   = 0x0005
+	partValues         = 0x0006
+	partTimeHR         = 0x0008
+	partSignature      = 0x0200
+	partEncryption     = 0x0210
+)
+
+// data source types of the values of a values part
+const (
+	dsCounter  = 0
+	dsGauge    = 1
+	dsDerive   = 2
+	dsAbsolute = 3
+)
+
+// security levels, a packet part is accepted when its level is at least
+// the configured one
+const (
+	levelNone = iota
+	levelSign
+	levelEncrypt
+)
+
+var securityLevels = map[string]int{
+	"":        levelNone,
+	"none":    levelNone,
+	"sign":    levelSign,
+	"encrypt": levelEncrypt,
+}
+
+type point struct {
+	measurement string
+	fields      map[string]interface{}
+	tags        map[string]string
+	time        time.Time
+}
+
+// parser decodes packets of the collectd binary protocol into points
+type parser struct {
+	// level is the minimal security level of the accepted values
+	level int
+	// passwords of the users signing or encrypting packets
+	passwords map[string]string
+	// typesDB names the values of each type, ie if_octets is rx and tx
+	typesDB map[string][]string
+}
+
+// valueList is the identifier and time of the next values part. Every part
+// but values sets a member, which is kept for the following values parts.
+type valueList struct {
+	host           string
+	plugin         string
+	pluginInstance string
+	typ            string
+	typeInstance   string
+	time           time.Time
+}
+
+// parse decodes a packet, skipping the values below the security level of
+// the parser. It fails on a malformed packet or on a signature or
+// encryption it can not verify when the security level requires them.
+func (p *parser) parse(packet []byte) ([]point, error) {
+	var points []point
+	err := p.parseParts(packet, levelNone, &points)
+	return points, err
+}
+
+func (p *parser) parseParts(buf []byte, level int, points *[]point) error {
+	var vl valueList
+	for len(buf) > 0 {
+		if len(buf) < 4 {
+			return errors.New("Truncated part header")
+		}
+		typ := binary.BigEndian.Uint16(buf[0:2])
+		length := int(binary.BigEndian.Uint16(buf[2:4]))
+		if length < 4 || length > len(buf) {
+			return fmt.Errorf("Invalid length %d of part 0x%04x", length, typ)
+		}
+		payload := buf[4:length]
+		buf = buf[length:]
+
+		var err error
+		switch typ {
+		case partHost:
+			vl.host, err = parseString(payload)
+		case partPlugin:
+			vl.plugin, err = parseString(payload)
+		case partPluginInstance:
+			vl.pluginInstance, err = parseString(payload)
+		case partType:
+			vl.typ, err = parseString(payload)
+		case partTypeInstance:
+			vl.typeInstance, err = parseString(payload)
+		case partTime:
+			var t uint64
+			t, err = parseUint64(payload)
+			vl.time = time.Unix(int64(t), 0)
+		case partTimeHR:
+			var t uint64
+			t, err = parseUint64(payload)
+			vl.time = timeHR(t)
+		case partValues:
+			var values []interface{}
+			values, err = parseValues(payload)
+			if err == nil && level >= p.level {
+				*points = append(*points, p.point(&vl, values))
+			}
+		case partSignature:
+			var ok bool
+			ok, err = p.verify(payload, buf)
+			if err == nil && ok && level < levelSign {
+				level = levelSign
+			}
+			if err == nil && !ok && p.level >= levelSign {
+				err = errors.New("Invalid signature")
+			}
+		case partEncryption:
+			var plain []byte
+			plain, err = p.decrypt(payload)
+			if err == nil {
+				err = p.parseParts(plain, levelEncrypt, points)
+			} else if p.level < levelEncrypt {
+				// encrypted parts that can not be decrypted are skipped
+				// when unencrypted parts are accepted
+				err = nil
+			}
+		}
+		// other parts, ie interval or notifications, are ignored
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// point returns the point of a values part, named after the plugin of vl
+func (p *parser) point(vl *valueList, values []interface{}) point {
+	tags := make(map[string]string)
+	for k, v := range map[string]string{
+		"host":            vl.host,
+		"plugin":          vl.plugin,
+		"plugin_instance": vl.pluginInstance,
+		"type":            vl.typ,
+		"type_instance":   vl.typeInstance,
+	} {
+		if v != "" {
+			tags[k] = v
+		}
+	}
+
+	names := p.typesDB[vl.typ]
+	fields := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		switch {
+		case len(names) == len(values):
+			fields[names[i]] = v
+		case len(values) == 1:
+			fields["value"] = v
+		default:
+			fields[fmt.Sprintf("value%d", i)] = v
+		}
+	}
+
+	t := vl.time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return point{measurement: vl.plugin, fields: fields, tags: tags, time: t}
+}
+
+// verify checks the HMAC-SHA256 of a signature part, signing its user name
+// and the rest of the packet. It returns false when the user is unknown.
+func (p *parser) verify(payload, rest []byte) (bool, error) {
+	if len(payload) < sha256.Size {
+		return false, errors.New("Truncated signature part")
+	}
+	user := payload[sha256.Size:]
+	password, ok := p.passwords[string(user)]
+	if !ok {
+		return false, nil
+	}
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write(user)
+	mac.Write(rest)
+	return hmac.Equal(mac.Sum(nil), payload[:sha256.Size]), nil
+}
+
+// decrypt returns the parts of an encryption part, encrypted with AES-256 in
+// OFB mode with the SHA-256 of the user's password as the key and prefixed
+// by their SHA-1
+func (p *parser) decrypt(payload []byte) ([]byte, error) {
+	if len(payload) < 2 {
+		return nil, errors.New("Truncated encryption part")
+	}
+	userLen := int(binary.BigEndian.Uint16(payload[0:2]))
+	if len(payload) < 2+userLen+aes.BlockSize+sha1.Size {
+		return nil, errors.New("Truncated encryption part")
+	}
+	user := string(payload[2 : 2+userLen])
+	password, ok := p.passwords[user]
+	if !ok {
+		return nil, fmt.Errorf("Unknown user %s of encrypted packet", user)
+	}
+	iv := payload[2+userLen : 2+userLen+aes.BlockSize]
+	encrypted := payload[2+userLen+aes.BlockSize:]
+
+	key := sha256.Sum256([]byte(password))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(encrypted))
+	cipher.NewOFB(block, iv).XORKeyStream(plain, encrypted)
+
+	checksum := sha1.Sum(plain[sha1.Size:])
+	if !hmac.Equal(checksum[:], plain[:sha1.Size]) {
+		return nil, fmt.Errorf("Unable to decrypt packet of user %s", user)
+	}
+	return plain[sha1.Size:], nil
+}
+
+func parseString(payload []byte) (string, error) {
+	if len(payload) == 0 || payload[len(payload)-1] != 0 {
+		return "", errors.New("String part is not null terminated")
+	}
+	return string(payload[:len(payload)-1]), nil
+}
+
+func parseUint64(payload []byte) (uint64, error) {
+	if len(payload) != 8 {
+		return 0, errors.New("Invalid numeric part")
+	}
+	return binary.BigEndian.Uint64(payload), nil
+}
+
+// timeHR converts a high resolution time, in 2^-30 seconds
+func timeHR(t uint64) time.Time {
+	sec := t >> 30
+	nsec := (t & (1<<30 - 1)) * uint64(time.Second) >> 30
+	return time.Unix(int64(sec), int64(nsec))
+}
+
+// parseValues decodes a values part: the number of values, their data
+// source types and the values. Gauges are float64, the other types int64,
+// or float64 for counters too large for an int64.
+func parseValues(payload []byte) ([]interface{}, error) {
+	if len(payload) < 2 {
+		return nil, errors.New("Truncated values part")
+	}
+	n := int(binary.BigEndian.Uint16(payload[0:2]))
+	if len(payload) != 2+n*9 {
+		return nil, fmt.Errorf("Invalid values part of %d values", n)
+	}
+	types := payload[2 : 2+n]
+	data := payload[2+n:]
+
+	values := make([]interface{}, n)
+	for i, typ := range types {
+		b := data[i*8 : i*8+8]
+		switch typ {
+		case dsGauge:
+			// gauges are the only little endian values
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		case dsDerive:
+			values[i] = int64(binary.BigEndian.Uint64(b))
+		case dsCounter, dsAbsolute:
+			v := binary.BigEndian.Uint64(b)
+			if v > math.MaxInt64 {
+				values[i] = float64(v)
+			} else {
+				values[i] = int64(v)
+			}
+		default:
+			return nil, fmt.Errorf("Invalid data source type %d", typ)
+		}
+	}
+	return values, nil
+}
+
+// parseTypesDB reads the data source names of each type from a collectd
+// types.db, whose lines are a type followed by its data sources, ie
+// "if_octets rx:DERIVE:0:U, tx:DERIVE:0:U"
+func parseTypesDB(r io.Reader, typesDB map[string][]string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return fmt.Errorf("Invalid types.db line '%s'", line)
+		}
+
+		var names []string
+		for _, ds := range strings.Split(strings.Join(fields[1:], ""), ",") {
+			spec := strings.Split(ds, ":")
+			if len(spec) != 4 {
+				return fmt.Errorf("Invalid data source '%s' of type %s", ds, fields[0])
+			}
+			names = append(names, spec[0])
+		}
+		typesDB[fields[0]] = names
+	}
+	return scanner.Err()
+}
+
+// parseAuthFile reads the passwords of a collectd auth file, whose lines are
+// "user: password"
+func parseAuthFile(r io.Reader) (map[string]string, error) {
+	passwords := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("Invalid auth file line, expected 'user: password'")
+		}
+		passwords[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+	}
+	return passwords, scanner.Err()
+}
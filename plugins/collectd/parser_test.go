@@ -0,0 +1,237 @@
+package collectd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// packet captured from collectd 5, sent by the cpu, df, entropy and
+// interface plugins of host pf1-62-210-94-173
+const capturedPacket = "000000167066312d36322d3231302d39342d313733000001000c00000000544928ff0007000c00000000000000050002000c656e74726f7079000004000c656e74726f7079000006000f0001010000000000007240000200086370750000030006310000040008637075000005000969646c65000006000f0001000000000000a674620005000977616974000006000f0001000000000000000000000200076466000003000500000400076466000005000d6c6976652d636f7700000600180002010100000000a090b641000000a0cb6a2742000200086370750000030006310000040008637075000005000e696e74657272757074000006000f00010000000000000000fe0005000c736f6674697271000006000f000100000000000000000000020007646600000300050000040007646600000500096c6976650000060018000201010000000000000000000000e0ec972742000200086370750000030006310000040008637075000005000a737465616c000006000f00010000000000000000000003000632000005000975736572000006000f0001000000000000005f36000500096e696365000006000f0001000000000000000ad80002000e696e746572666163650000030005000004000e69665f6f6374657473000005000b64756d6d79300000060018000200000000000000000000000000000000041a000200076466000004000764660000050008746d70000006001800020101000000000000f240000000a0ea972742000200086370750000030006320000040008637075000005000b73797374656d000006000f00010000000000000045d30002000e696e746572666163650000030005000004000f69665f7061636b657473000005000b64756d6d79300000060018000200000000000000000000000000000000000f000200086370750000030006320000040008637075000005000969646c65000006000f0001000000000000a66480000200076466000003000500000400076466000005000d72756e2d6c6f636b000006001800020101000000000000000000000000000054410002000e696e74657266616365000004000e69665f6572726f7273000005000b64756d6d793000000600180002000000000000000000000000000000000000000200086370750000030006320000040008637075000005000977616974000006000f00010000000000000000000005000e696e74657272757074000006000f0001000000000000000132"
+
+const testTypesDB = `
+# data source names of the captured packet
+df              used:GAUGE:0:1125899906842623, free:GAUGE:0:1125899906842623
+if_octets       rx:DERIVE:0:U, tx:DERIVE:0:U
+`
+
+var capturedTime = time.Unix(1414080767, 0)
+
+func capturedParser(t *testing.T) *parser {
+	p := &parser{typesDB: make(map[string][]string)}
+	require.NoError(t, parseTypesDB(strings.NewReader(testTypesDB), p.typesDB))
+	return p
+}
+
+func TestParseCapturedPacket(t *testing.T) {
+	packet, err := hex.DecodeString(capturedPacket)
+	require.NoError(t, err)
+
+	points, err := capturedParser(t).parse(packet)
+	require.NoError(t, err)
+	require.Len(t, points, 19)
+
+	assert.Equal(t, point{
+		measurement: "entropy",
+		fields:      map[string]interface{}{"value": 288.0},
+		tags: map[string]string{
+			"host":   "pf1-62-210-94-173",
+			"plugin": "entropy",
+			"type":   "entropy",
+		},
+		time: capturedTime,
+	}, points[0])
+
+	assert.Equal(t, point{
+		measurement: "cpu",
+		fields:      map[string]interface{}{"value": int64(10908770)},
+		tags: map[string]string{
+			"host":            "pf1-62-210-94-173",
+			"plugin":          "cpu",
+			"plugin_instance": "1",
+			"type":            "cpu",
+			"type_instance":   "idle",
+		},
+		time: capturedTime,
+	}, points[1])
+
+	// the types.db names the values of multi valued types
+	assert.Equal(t, "df", points[3].measurement)
+	assert.Equal(t, "live-cow", points[3].tags["type_instance"])
+	assert.Equal(t, map[string]interface{}{"used": 378576896.0, "free": 50287988736.0},
+		points[3].fields)
+	assert.Equal(t, "interface", points[10].measurement)
+	assert.Equal(t, map[string]interface{}{"rx": int64(0), "tx": int64(1050)},
+		points[10].fields)
+
+	// without types.db names, if_packets values are numbered
+	assert.Equal(t, "if_packets", points[13].tags["type"])
+	assert.Equal(t, map[string]interface{}{"value0": int64(0), "value1": int64(15)},
+		points[13].fields)
+}
+
+func TestParseMalformedPackets(t *testing.T) {
+	packet, err := hex.DecodeString(capturedPacket)
+	require.NoError(t, err)
+
+	p := &parser{}
+	for _, malformed := range [][]byte{
+		packet[:len(packet)-3],
+		packet[:2],
+		// the host is not null terminated
+		{0x00, 0x00, 0x00, 0x05, 'a'},
+		// 2 values, but a single value type
+		{0x00, 0x06, 0x00, 0x0f, 0x00, 0x02, 0x01, 0, 0, 0, 0, 0, 0, 0, 0},
+		// an unknown data source type
+		{0x00, 0x06, 0x00, 0x0f, 0x00, 0x01, 0x07, 0, 0, 0, 0, 0, 0, 0, 0},
+	} {
+		_, err := p.parse(malformed)
+		assert.Error(t, err, "%x", malformed)
+	}
+}
+
+func TestTimeHR(t *testing.T) {
+	hr := uint64(1414080767)<<30 | 1<<29
+	assert.Equal(t, time.Unix(1414080767, 500000000), timeHR(hr))
+}
+
+// stringPart, gaugePart and the following build the packets collectd sends
+// with SecurityLevel Sign and Encrypt
+func stringPart(typ uint16, s string) []byte {
+	b := make([]byte, 4, 4+len(s)+1)
+	binary.BigEndian.PutUint16(b[0:2], typ)
+	binary.BigEndian.PutUint16(b[2:4], uint16(4+len(s)+1))
+	return append(append(b, s...), 0)
+}
+
+func gaugePart(v float64) []byte {
+	b := []byte{0x00, 0x06, 0x00, 0x0f, 0x00, 0x01, dsGauge, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint64(b[7:], math.Float64bits(v))
+	return b
+}
+
+func testParts() []byte {
+	parts := [][]byte{
+		stringPart(partHost, "db1"),
+		stringPart(partPlugin, "load"),
+		stringPart(partType, "load"),
+		gaugePart(0.5),
+	}
+	return bytes.Join(parts, nil)
+}
+
+func signedPacket(user, password string, parts []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write([]byte(user))
+	mac.Write(parts)
+
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint16(b[0:2], partSignature)
+	binary.BigEndian.PutUint16(b[2:4], uint16(4+sha256.Size+len(user)))
+	b = append(b, mac.Sum(nil)...)
+	b = append(b, user...)
+	return append(b, parts...)
+}
+
+func encryptedPacket(user, password string, parts []byte) []byte {
+	checksum := sha1.Sum(parts)
+	plain := append(checksum[:], parts...)
+
+	key := sha256.Sum256([]byte(password))
+	block, _ := aes.NewCipher(key[:])
+	iv := bytes.Repeat([]byte{7}, aes.BlockSize)
+	encrypted := make([]byte, len(plain))
+	cipher.NewOFB(block, iv).XORKeyStream(encrypted, plain)
+
+	b := make([]byte, 6)
+	binary.BigEndian.PutUint16(b[0:2], partEncryption)
+	binary.BigEndian.PutUint16(b[2:4], uint16(6+len(user)+len(iv)+len(encrypted)))
+	binary.BigEndian.PutUint16(b[4:6], uint16(len(user)))
+	b = append(b, user...)
+	b = append(b, iv...)
+	return append(b, encrypted...)
+}
+
+func TestSecurityLevels(t *testing.T) {
+	passwords := map[string]string{"telegraf": "secret"}
+	plain := testParts()
+	signed := signedPacket("telegraf", "secret", plain)
+	badSignature := signedPacket("telegraf", "wrong", plain)
+	unknownUser := signedPacket("nobody", "secret", plain)
+	encrypted := encryptedPacket("telegraf", "secret", plain)
+	badKey := encryptedPacket("telegraf", "wrong", plain)
+
+	tests := []struct {
+		level   int
+		packet  []byte
+		points  int
+		invalid bool
+	}{
+		{levelNone, plain, 1, false},
+		{levelNone, signed, 1, false},
+		{levelNone, badSignature, 1, false},
+		{levelNone, encrypted, 1, false},
+		{levelNone, badKey, 0, false},
+
+		{levelSign, plain, 0, false},
+		{levelSign, signed, 1, false},
+		{levelSign, badSignature, 0, true},
+		{levelSign, unknownUser, 0, true},
+		{levelSign, encrypted, 1, false},
+
+		{levelEncrypt, plain, 0, false},
+		{levelEncrypt, signed, 0, false},
+		{levelEncrypt, encrypted, 1, false},
+		{levelEncrypt, badKey, 0, true},
+	}
+
+	for i, tt := range tests {
+		p := &parser{level: tt.level, passwords: passwords}
+		points, err := p.parse(tt.packet)
+		if tt.invalid {
+			assert.Error(t, err, "test %d", i)
+		} else {
+			assert.NoError(t, err, "test %d", i)
+		}
+		require.Len(t, points, tt.points, "test %d", i)
+		if tt.points > 0 {
+			assert.Equal(t, map[string]string{"host": "db1", "plugin": "load", "type": "load"},
+				points[0].tags)
+			assert.Equal(t, map[string]interface{}{"value": 0.5}, points[0].fields)
+		}
+	}
+}
+
+func TestParseTypesDB(t *testing.T) {
+	typesDB := make(map[string][]string)
+	require.NoError(t, parseTypesDB(strings.NewReader(testTypesDB), typesDB))
+	assert.Equal(t, map[string][]string{
+		"df":        {"used", "free"},
+		"if_octets": {"rx", "tx"},
+	}, typesDB)
+
+	assert.Error(t, parseTypesDB(strings.NewReader("df"), typesDB))
+	assert.Error(t, parseTypesDB(strings.NewReader("df used:GAUGE"), typesDB))
+}
+
+func TestParseAuthFile(t *testing.T) {
+	passwords, err := parseAuthFile(strings.NewReader("# users\ntelegraf: secret\nadmin:p:ss\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"telegraf": "secret", "admin": "p:ss"}, passwords)
+
+	_, err = parseAuthFile(strings.NewReader("telegraf secret"))
+	assert.Error(t, err)
+}
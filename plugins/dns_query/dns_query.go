@@ -0,0 +1,186 @@
+package dns_query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const defaultPort = "53"
+
+// Result codes of a query
+const (
+	resultNoError  = "NOERROR"
+	resultNXDomain = "NXDOMAIN"
+	resultServFail = "SERVFAIL"
+	resultTimeout  = "TIMEOUT"
+)
+
+// resolver is the part of a *net.Resolver used to query a server
+type resolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// DnsQuery resolves domains against DNS servers, reporting how long each
+// query takes
+type DnsQuery struct {
+	Servers     []string
+	Domains     []string
+	RecordTypes []string
+	Timeout     internal.Duration
+
+	// newResolver returns the resolver querying the server at addr
+	newResolver func(addr string) resolver
+}
+
+var sampleConfig = `
+  # DNS servers to query, as host or host:port (port 53 by default)
+  servers = ["8.8.8.8"]
+  # Domains to resolve
+  domains = ["example.com"]
+  # Record types to query, any of A, AAAA, CNAME and MX
+  record_types = ["A"]
+  # Time to wait for each query
+  timeout = "2s"
+`
+
+func (d *DnsQuery) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DnsQuery) Description() string {
+	return "Query DNS servers, reporting query time and result code"
+}
+
+func (d *DnsQuery) Gather(acc plugins.Accumulator) error {
+	recordTypes := []string{"A"}
+	if len(d.RecordTypes) > 0 {
+		recordTypes = make([]string, len(d.RecordTypes))
+	}
+	for i, rt := range d.RecordTypes {
+		recordTypes[i] = strings.ToUpper(rt)
+		switch recordTypes[i] {
+		case "A", "AAAA", "CNAME", "MX":
+		default:
+			return fmt.Errorf("Invalid record type %s, must be A, AAAA, CNAME or MX", rt)
+		}
+	}
+
+	newResolver := d.newResolver
+	if newResolver == nil {
+		newResolver = netResolver
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []string
+	for _, server := range d.Servers {
+		u, err := internal.ParseServer(server, defaultPort)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		r := newResolver(u.Host)
+
+		for _, domain := range d.Domains {
+			for _, rt := range recordTypes {
+				wg.Add(1)
+				go func(server, domain, rt string) {
+					defer wg.Done()
+					fields := d.query(r, domain, rt)
+					tags := map[string]string{
+						"server":      server,
+						"domain":      domain,
+						"record_type": rt,
+					}
+					mu.Lock()
+					acc.AddFields("query", fields, tags)
+					mu.Unlock()
+				}(server, domain, rt)
+			}
+		}
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// query resolves a single record type of domain and returns the fields
+// describing the query
+func (d *DnsQuery) query(r resolver, domain, recordType string) map[string]interface{} {
+	ctx := context.Background()
+	if d.Timeout.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout.Duration)
+		defer cancel()
+	}
+
+	start := time.Now()
+	var err error
+	switch recordType {
+	case "A":
+		_, err = r.LookupIP(ctx, "ip4", domain)
+	case "AAAA":
+		_, err = r.LookupIP(ctx, "ip6", domain)
+	case "CNAME":
+		_, err = r.LookupCNAME(ctx, domain)
+	case "MX":
+		_, err = r.LookupMX(ctx, domain)
+	}
+	elapsed := time.Since(start)
+
+	return map[string]interface{}{
+		"query_time_ms": float64(elapsed) / float64(time.Millisecond),
+		"result_code":   resultCode(err),
+	}
+}
+
+// resultCode maps the error of a lookup to a DNS result code
+func resultCode(err error) string {
+	if err == nil {
+		return resultNoError
+	}
+	if derr, ok := err.(*net.DNSError); ok {
+		switch {
+		case derr.IsNotFound:
+			return resultNXDomain
+		case derr.IsTimeout:
+			return resultTimeout
+		}
+	}
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		return resultTimeout
+	}
+	return resultServFail
+}
+
+// netResolver returns a resolver sending every query to the server at addr
+func netResolver(addr string) resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+func init() {
+	plugins.Add("dns_query", func() plugins.Plugin {
+		return &DnsQuery{
+			Timeout: internal.Duration{Duration: 2 * time.Second},
+		}
+	})
+}
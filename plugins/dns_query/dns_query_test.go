@@ -0,0 +1,160 @@
+package dns_query
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockResolver knows the records of a single zone
+type mockResolver struct {
+	addr  string
+	ips   map[string][]net.IP
+	cname map[string]string
+	mx    map[string][]*net.MX
+	delay time.Duration
+}
+
+func (m *mockResolver) notFound(host string) error {
+	return &net.DNSError{Err: "no such host", Name: host, Server: m.addr, IsNotFound: true}
+}
+
+func (m *mockResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	if m.delay > 0 {
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+		}
+	}
+	var ips []net.IP
+	for _, ip := range m.ips[host] {
+		if (network == "ip4") == (ip.To4() != nil) {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, m.notFound(host)
+	}
+	return ips, nil
+}
+
+func (m *mockResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	if cname, ok := m.cname[host]; ok {
+		return cname, nil
+	}
+	return "", m.notFound(host)
+}
+
+func (m *mockResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if mx, ok := m.mx[name]; ok {
+		return mx, nil
+	}
+	return nil, m.notFound(name)
+}
+
+func newMockQuery(servers, domains, recordTypes []string, mock *mockResolver) (*DnsQuery, *[]string) {
+	var addrs []string
+	return &DnsQuery{
+		Servers:     servers,
+		Domains:     domains,
+		RecordTypes: recordTypes,
+		newResolver: func(addr string) resolver {
+			addrs = append(addrs, addr)
+			mock.addr = addr
+			return mock
+		},
+	}, &addrs
+}
+
+var zone = &mockResolver{
+	ips: map[string][]net.IP{
+		"example.com": {net.ParseIP("93.184.216.34"), net.ParseIP("2606:2800:220:1::1")},
+	},
+	cname: map[string]string{"www.example.com": "example.com."},
+	mx: map[string][]*net.MX{
+		"example.com": {{Host: "mail.example.com.", Pref: 10}},
+	},
+}
+
+// gatheredCode returns the result code of the query with the given tags
+func gatheredCode(acc *testutil.Accumulator, tags map[string]string) interface{} {
+	for _, pt := range acc.Points {
+		if reflect.DeepEqual(tags, pt.Tags) {
+			return pt.Values["result_code"]
+		}
+	}
+	return nil
+}
+
+func TestGatherSuccess(t *testing.T) {
+	d, addrs := newMockQuery([]string{"10.0.0.1"}, []string{"example.com"},
+		[]string{"A", "aaaa", "MX"}, zone)
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	assert.Equal(t, []string{"10.0.0.1:53"}, *addrs)
+	require.Len(t, acc.Points, 3)
+
+	for _, rt := range []string{"A", "AAAA", "MX"} {
+		tags := map[string]string{
+			"server":      "10.0.0.1",
+			"domain":      "example.com",
+			"record_type": rt,
+		}
+		assert.Equal(t, "NOERROR", gatheredCode(&acc, tags), rt)
+	}
+	for _, pt := range acc.Points {
+		assert.Equal(t, "query", pt.Measurement)
+		assert.IsType(t, float64(0), pt.Values["query_time_ms"])
+	}
+}
+
+func TestGatherNXDomain(t *testing.T) {
+	d, _ := newMockQuery([]string{"10.0.0.1:5353"},
+		[]string{"missing.example.com", "www.example.com"}, []string{"CNAME"}, zone)
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Len(t, acc.Points, 2)
+
+	assert.Equal(t, "NXDOMAIN", gatheredCode(&acc, map[string]string{
+		"server":      "10.0.0.1:5353",
+		"domain":      "missing.example.com",
+		"record_type": "CNAME",
+	}))
+	assert.Equal(t, "NOERROR", gatheredCode(&acc, map[string]string{
+		"server":      "10.0.0.1:5353",
+		"domain":      "www.example.com",
+		"record_type": "CNAME",
+	}))
+}
+
+func TestGatherTimeout(t *testing.T) {
+	slow := &mockResolver{delay: time.Second}
+	d, _ := newMockQuery([]string{"10.0.0.1"}, []string{"example.com"}, nil, slow)
+	d.Timeout.Duration = 10 * time.Millisecond
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Len(t, acc.Points, 1)
+	assert.Equal(t, "A", acc.Points[0].Tags["record_type"])
+	assert.Equal(t, "TIMEOUT", acc.Points[0].Values["result_code"])
+}
+
+func TestGatherBadConfig(t *testing.T) {
+	d, _ := newMockQuery([]string{"10.0.0.1"}, []string{"example.com"},
+		[]string{"TXT"}, zone)
+	var acc testutil.Accumulator
+	assert.Error(t, d.Gather(&acc))
+
+	d, _ = newMockQuery([]string{""}, []string{"example.com"}, nil, zone)
+	assert.Error(t, d.Gather(&acc))
+	assert.Len(t, acc.Points, 0)
+}
@@ -0,0 +1,242 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// DockerClient is the part of a *docker.Client used to read container stats
+type DockerClient interface {
+	ListContainers(opts docker.ListContainersOptions) ([]docker.APIContainers, error)
+	Stats(opts docker.StatsOptions) error
+}
+
+type Docker struct {
+	Endpoint       string
+	ContainerNames []string
+
+	client DockerClient
+}
+
+var sampleConfig = `
+  # Docker daemon endpoint, ie "unix:///var/run/docker.sock" or
+  # "tcp://[ip]:[port]"
+  endpoint = "unix:///var/run/docker.sock"
+  # Only gather stats of the containers with these names, all running
+  # containers when empty
+  container_names = []
+`
+
+func (d *Docker) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Docker) Description() string {
+	return "Read metrics about docker containers from the Docker daemon"
+}
+
+func (d *Docker) Gather(acc plugins.Accumulator) error {
+	if d.client == nil {
+		endpoint := d.Endpoint
+		if endpoint == "" {
+			endpoint = "unix:///var/run/docker.sock"
+		}
+		c, err := docker.NewClient(endpoint)
+		if err != nil {
+			return err
+		}
+		d.client = c
+	}
+
+	containers, err := d.client.ListContainers(docker.ListContainersOptions{})
+	if err != nil {
+		return fmt.Errorf("Unable to list containers from %s: %s", d.Endpoint, err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []string
+	for _, container := range containers {
+		name := containerName(container)
+		if len(d.ContainerNames) > 0 && !contains(d.ContainerNames, name) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(container docker.APIContainers, name string) {
+			defer wg.Done()
+			if err := d.gatherContainer(acc, container, name); err != nil {
+				mu.Lock()
+				errs = append(errs, err.Error())
+				mu.Unlock()
+			}
+		}(container, name)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func (d *Docker) gatherContainer(
+	acc plugins.Accumulator,
+	container docker.APIContainers,
+	name string,
+) error {
+	statsc := make(chan *docker.Stats)
+	done := make(chan bool)
+	defer close(done)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- d.client.Stats(docker.StatsOptions{
+			ID:      container.ID,
+			Stats:   statsc,
+			Stream:  false,
+			Done:    done,
+			Timeout: 5 * time.Second,
+		})
+	}()
+
+	stats, ok := <-statsc
+	if !ok {
+		err := <-errc
+		if err == nil {
+			err = errors.New("no stats returned")
+		}
+		return fmt.Errorf("Unable to read stats of container %s: %s", name, err)
+	}
+
+	tags := map[string]string{
+		"container_name":  name,
+		"container_image": container.Image,
+	}
+	addStats(acc, stats, tags)
+	return nil
+}
+
+// addStats adds the cpu, memory, network and block IO stats of a container
+func addStats(acc plugins.Accumulator, stats *docker.Stats, tags map[string]string) {
+	now := stats.Read
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	cpu := map[string]interface{}{
+		"usage_total":                  stats.CPUStats.CPUUsage.TotalUsage,
+		"usage_in_usermode":            stats.CPUStats.CPUUsage.UsageInUsermode,
+		"usage_in_kernelmode":          stats.CPUStats.CPUUsage.UsageInKernelmode,
+		"usage_system":                 stats.CPUStats.SystemCPUUsage,
+		"throttling_periods":           stats.CPUStats.ThrottlingData.Periods,
+		"throttling_throttled_periods": stats.CPUStats.ThrottlingData.ThrottledPeriods,
+		"throttling_throttled_time":    stats.CPUStats.ThrottlingData.ThrottledTime,
+		"usage_percent":                cpuPercent(stats),
+	}
+	acc.AddFields("cpu", cpu, tags, now)
+
+	mem := map[string]interface{}{
+		"usage":      stats.MemoryStats.Usage,
+		"max_usage":  stats.MemoryStats.MaxUsage,
+		"limit":      stats.MemoryStats.Limit,
+		"fail_count": stats.MemoryStats.Failcnt,
+		"cache":      stats.MemoryStats.Stats.Cache,
+		"rss":        stats.MemoryStats.Stats.Rss,
+	}
+	if stats.MemoryStats.Limit > 0 {
+		mem["usage_percent"] = float64(stats.MemoryStats.Usage) /
+			float64(stats.MemoryStats.Limit) * 100.0
+	}
+	acc.AddFields("mem", mem, tags, now)
+
+	net := map[string]interface{}{
+		"rx_bytes":   stats.Network.RxBytes,
+		"rx_packets": stats.Network.RxPackets,
+		"rx_errors":  stats.Network.RxErrors,
+		"rx_dropped": stats.Network.RxDropped,
+		"tx_bytes":   stats.Network.TxBytes,
+		"tx_packets": stats.Network.TxPackets,
+		"tx_errors":  stats.Network.TxErrors,
+		"tx_dropped": stats.Network.TxDropped,
+	}
+	acc.AddFields("net", net, tags, now)
+
+	// block IO is reported per device
+	devices := make(map[string]map[string]interface{})
+	addBlkio := func(prefix string, entries []docker.BlkioStatsEntry) {
+		for _, e := range entries {
+			device := fmt.Sprintf("%d:%d", e.Major, e.Minor)
+			if devices[device] == nil {
+				devices[device] = make(map[string]interface{})
+			}
+			op := strings.ToLower(e.Op)
+			if op == "" {
+				devices[device][prefix] = e.Value
+			} else {
+				devices[device][prefix+"_"+op] = e.Value
+			}
+		}
+	}
+	addBlkio("io_service_bytes_recursive", stats.BlkioStats.IOServiceBytesRecursive)
+	addBlkio("io_serviced_recursive", stats.BlkioStats.IOServicedRecursive)
+	addBlkio("io_queue_recursive", stats.BlkioStats.IOQueueRecursive)
+	addBlkio("io_service_time_recursive", stats.BlkioStats.IOServiceTimeRecursive)
+	addBlkio("io_wait_time_recursive", stats.BlkioStats.IOWaitTimeRecursive)
+	addBlkio("io_merged_recursive", stats.BlkioStats.IOMergedRecursive)
+	addBlkio("io_time_recursive", stats.BlkioStats.IOTimeRecursive)
+	addBlkio("sectors_recursive", stats.BlkioStats.SectorsRecursive)
+	for device, fields := range devices {
+		blkioTags := map[string]string{"device": device}
+		for k, v := range tags {
+			blkioTags[k] = v
+		}
+		acc.AddFields("blkio", fields, blkioTags, now)
+	}
+}
+
+// cpuPercent computes the cpu usage of the container between the previous
+// and the current sample, as a percentage of one cpu
+func cpuPercent(stats *docker.Stats) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) -
+		float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemCPUUsage) -
+		float64(stats.PreCPUStats.SystemCPUUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0.0
+	}
+	ncpu := float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	if ncpu == 0 {
+		ncpu = 1
+	}
+	return cpuDelta / systemDelta * ncpu * 100.0
+}
+
+// containerName returns the name of a container without its leading slash
+func containerName(container docker.APIContainers) string {
+	if len(container.Names) == 0 {
+		return container.ID
+	}
+	return strings.TrimPrefix(container.Names[0], "/")
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	plugins.Add("docker", func() plugins.Plugin {
+		return &Docker{}
+	})
+}
@@ -0,0 +1,197 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statsJSON is a sample from GET /containers/(id)/stats?stream=false
+const statsJSON = `
+{
+  "read": "2015-10-28T21:12:42.305434529Z",
+  "network": {
+    "rx_bytes": 5893,
+    "rx_packets": 56,
+    "rx_errors": 0,
+    "rx_dropped": 0,
+    "tx_bytes": 648,
+    "tx_packets": 8,
+    "tx_errors": 0,
+    "tx_dropped": 0
+  },
+  "precpu_stats": {
+    "cpu_usage": {
+      "total_usage": 86358950,
+      "percpu_usage": [53124876, 33234074],
+      "usage_in_kernelmode": 20000000,
+      "usage_in_usermode": 50000000
+    },
+    "system_cpu_usage": 3259306420000000,
+    "throttling_data": {"periods": 0, "throttled_periods": 0, "throttled_time": 0}
+  },
+  "cpu_stats": {
+    "cpu_usage": {
+      "total_usage": 96358950,
+      "percpu_usage": [58124876, 38234074],
+      "usage_in_kernelmode": 20000000,
+      "usage_in_usermode": 60000000
+    },
+    "system_cpu_usage": 3259306520000000,
+    "throttling_data": {"periods": 10, "throttled_periods": 2, "throttled_time": 1500}
+  },
+  "memory_stats": {
+    "usage": 30000000,
+    "max_usage": 33190912,
+    "stats": {
+      "cache": 4395008,
+      "rss": 25604992,
+      "pgfault": 7548,
+      "total_rss": 25604992
+    },
+    "failcnt": 0,
+    "limit": 2099998720
+  },
+  "blkio_stats": {
+    "io_service_bytes_recursive": [
+      {"major": 8, "minor": 0, "op": "Read", "value": 4395008},
+      {"major": 8, "minor": 0, "op": "Write", "value": 8192},
+      {"major": 8, "minor": 0, "op": "Total", "value": 4403200}
+    ],
+    "io_serviced_recursive": [
+      {"major": 8, "minor": 0, "op": "Read", "value": 52},
+      {"major": 8, "minor": 0, "op": "Write", "value": 2}
+    ],
+    "sectors_recursive": [
+      {"major": 8, "minor": 0, "op": "", "value": 8600}
+    ]
+  }
+}
+`
+
+type fakeDockerClient struct {
+	containers []docker.APIContainers
+	stats      map[string]string
+	err        error
+}
+
+func (c *fakeDockerClient) ListContainers(opts docker.ListContainersOptions) ([]docker.APIContainers, error) {
+	return c.containers, c.err
+}
+
+func (c *fakeDockerClient) Stats(opts docker.StatsOptions) error {
+	defer close(opts.Stats)
+
+	body, ok := c.stats[opts.ID]
+	if !ok {
+		return &docker.NoSuchContainer{ID: opts.ID}
+	}
+	var stats docker.Stats
+	if err := json.Unmarshal([]byte(body), &stats); err != nil {
+		return err
+	}
+	opts.Stats <- &stats
+	return nil
+}
+
+var containers = []docker.APIContainers{
+	{ID: "b7dfbb9478a6", Names: []string{"/rethinkdb"}, Image: "rethinkdb:2.1"},
+	{ID: "e2173b9478a6", Names: []string{"/nginx"}, Image: "nginx:latest"},
+}
+
+func TestGather(t *testing.T) {
+	d := &Docker{client: &fakeDockerClient{
+		containers: containers,
+		stats:      map[string]string{"b7dfbb9478a6": statsJSON, "e2173b9478a6": statsJSON},
+	}}
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	// cpu, mem, net and one block device per container
+	assert.Len(t, acc.Points, 8)
+}
+
+func TestGatherContainerNames(t *testing.T) {
+	d := &Docker{
+		ContainerNames: []string{"rethinkdb"},
+		client: &fakeDockerClient{
+			containers: containers,
+			stats:      map[string]string{"b7dfbb9478a6": statsJSON},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	require.Len(t, acc.Points, 4)
+
+	tags := map[string]string{
+		"container_name":  "rethinkdb",
+		"container_image": "rethinkdb:2.1",
+	}
+	points := make(map[string]*testutil.Point)
+	for _, pt := range acc.Points {
+		points[pt.Measurement] = pt
+		if pt.Measurement != "blkio" {
+			assert.Equal(t, tags, pt.Tags)
+		}
+	}
+
+	cpu := points["cpu"]
+	require.NotNil(t, cpu)
+	// 10ms of the 100ms the system spent on both cpus
+	assert.InDelta(t, 20.0, cpu.Values["usage_percent"], 0.0001)
+	assert.Equal(t, uint64(96358950), cpu.Values["usage_total"])
+	assert.Equal(t, uint64(2), cpu.Values["throttling_throttled_periods"])
+	assert.Equal(t, "2015-10-28T21:12:42.305434529Z", cpu.Time.Format("2006-01-02T15:04:05.999999999Z07:00"))
+
+	mem := points["mem"]
+	require.NotNil(t, mem)
+	assert.Equal(t, uint64(30000000), mem.Values["usage"])
+	assert.Equal(t, uint64(2099998720), mem.Values["limit"])
+	assert.Equal(t, uint64(4395008), mem.Values["cache"])
+	assert.InDelta(t, 1.4285, mem.Values["usage_percent"], 0.0001)
+
+	net := points["net"]
+	require.NotNil(t, net)
+	assert.Equal(t, uint64(5893), net.Values["rx_bytes"])
+	assert.Equal(t, uint64(648), net.Values["tx_bytes"])
+
+	blkio := points["blkio"]
+	require.NotNil(t, blkio)
+	assert.Equal(t, "8:0", blkio.Tags["device"])
+	assert.Equal(t, "rethinkdb", blkio.Tags["container_name"])
+	assert.Equal(t, uint64(4395008), blkio.Values["io_service_bytes_recursive_read"])
+	assert.Equal(t, uint64(8192), blkio.Values["io_service_bytes_recursive_write"])
+	assert.Equal(t, uint64(52), blkio.Values["io_serviced_recursive_read"])
+	assert.Equal(t, uint64(8600), blkio.Values["sectors_recursive"])
+}
+
+func TestGatherErrors(t *testing.T) {
+	d := &Docker{client: &fakeDockerClient{err: errors.New("connection refused")}}
+	var acc testutil.Accumulator
+	assert.Error(t, d.Gather(&acc))
+
+	// the stats of the second container cannot be read
+	d = &Docker{client: &fakeDockerClient{
+		containers: containers,
+		stats:      map[string]string{"b7dfbb9478a6": statsJSON},
+	}}
+	err := d.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nginx")
+	assert.Len(t, acc.Points, 4)
+}
+
+func TestCPUPercentWithoutPreviousSample(t *testing.T) {
+	var stats docker.Stats
+	require.NoError(t, json.Unmarshal([]byte(statsJSON), &stats))
+	stats.PreCPUStats = docker.CPUStats{}
+	stats.CPUStats.SystemCPUUsage = 0
+
+	assert.Equal(t, 0.0, cpuPercent(&stats))
+}
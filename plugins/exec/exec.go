@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/gonuts/go-shellquote"
 	"github.com/influxdb/telegraf/plugins"
+	"github.com/influxdb/telegraf/plugins/parsers"
 	"math"
 	"os/exec"
 	"strings"
@@ -26,6 +27,17 @@ const sampleConfig = `
   # Only run this command if it has been at least this many
   # seconds since it last ran
   interval = 10
+
+  # Data format of the output. By default every number of the JSON output is
+  # its own measurement, prefixed by name. With "json" the output is a single
+  # point, or one per object of an array, named after the command. "influx"
+  # parses line protocol and "grok" matches every line with patterns, see
+  # the tail plugin.
+  # data_format = "json"
+  # Keys of JSON objects, or grok captures, gathered as tags
+  # tag_keys = ["host"]
+  # patterns = ['%{QUEUE:queue:tag} %{INT:messages:int}']
+  # custom_patterns = 'QUEUE [\w.]+'
 `
 
 type Exec struct {
@@ -35,9 +47,18 @@ type Exec struct {
 }
 
 type Command struct {
-	Command   string
-	Name      string
-	Interval  int
+	Command  string
+	Name     string
+	Interval int
+
+	// DataFormat parses the output with a parser when set, see
+	// parsers.Config
+	DataFormat string
+	TagKeys    []string
+	// grok patterns of the "grok" data format
+	Patterns       []string
+	CustomPatterns string
+
 	lastRunAt time.Time
 }
 
@@ -133,6 +154,10 @@ func (e *Exec) gatherCommand(c *Command, acc plugins.Accumulator) error {
 			return err
 		}
 
+		if c.DataFormat != "" {
+			return parseOutput(acc, c, out)
+		}
+
 		var jsonOut interface{}
 		err = json.Unmarshal(out, &jsonOut)
 		if err != nil {
@@ -144,6 +169,27 @@ func (e *Exec) gatherCommand(c *Command, acc plugins.Accumulator) error {
 	return nil
 }
 
+func parseOutput(acc plugins.Accumulator, c *Command, out []byte) error {
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat: c.DataFormat,
+		MetricName:     c.Name,
+		TagKeys:        c.TagKeys,
+		Patterns:       c.Patterns,
+		CustomPatterns: c.CustomPatterns,
+	})
+	if err != nil {
+		return err
+	}
+	points, err := parser.Parse(out)
+	if err != nil {
+		return fmt.Errorf("exec: unable to parse output of '%s', %s", c.Command, err)
+	}
+	for _, pt := range points {
+		acc.AddFields(pt.Name(), pt.Fields(), pt.Tags(), pt.Time())
+	}
+	return nil
+}
+
 func processResponse(acc plugins.Accumulator, prefix string, tags map[string]string, v interface{}) {
 	switch t := v.(type) {
 	case map[string]interface{}:
@@ -260,3 +260,77 @@ func TestExecOneNotEnoughTimeAndOneEnoughTime(t *testing.T) {
 
 	assert.Equal(t, deltaPoints, 4, "Only one command should have been run")
 }
+
+func TestExecDataFormat(t *testing.T) {
+	tests := []struct {
+		dataFormat string
+		out        string
+	}{
+		{"json", `{"host": "localhost", "cpu": {"used": 8234}, "num_processes": 82}`},
+		{"influx", "mycollector,host=localhost cpu_used=8234,num_processes=82\n"},
+	}
+
+	for _, tt := range tests {
+		e := &Exec{
+			runner: newRunnerMock([]byte(tt.out), nil),
+			clock:  newClockMock(time.Unix(baseTimeSeconds, 0)),
+			Commands: []*Command{{
+				Command:    "testcommand",
+				Name:       "mycollector",
+				DataFormat: tt.dataFormat,
+				TagKeys:    []string{"host"},
+			}},
+		}
+
+		var acc testutil.Accumulator
+		require.NoError(t, e.Gather(&acc), tt.dataFormat)
+		require.Len(t, acc.Points, 1, tt.dataFormat)
+		pt := acc.Points[0]
+		assert.Equal(t, "mycollector", pt.Measurement, tt.dataFormat)
+		assert.Equal(t, map[string]string{"host": "localhost"}, pt.Tags, tt.dataFormat)
+		assert.Equal(t, map[string]interface{}{"cpu_used": 8234.0, "num_processes": 82.0},
+			pt.Values, tt.dataFormat)
+	}
+}
+
+func TestExecGrok(t *testing.T) {
+	e := &Exec{
+		runner: newRunnerMock([]byte("ready 12\nunacked 3\n"), nil),
+		clock:  newClockMock(time.Unix(baseTimeSeconds, 0)),
+		Commands: []*Command{{
+			Command:        "testcommand",
+			Name:           "queues",
+			DataFormat:     "grok",
+			Patterns:       []string{`%{STATE:state:tag} %{INT:messages:int}`},
+			CustomPatterns: `STATE ready|unacked`,
+		}},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+	require.Len(t, acc.Points, 2)
+	for i, state := range []string{"ready", "unacked"} {
+		assert.Equal(t, "queues", acc.Points[i].Measurement)
+		assert.Equal(t, map[string]string{"state": state}, acc.Points[i].Tags)
+	}
+	assert.Equal(t, int64(12), acc.Points[0].Values["messages"])
+	assert.Equal(t, int64(3), acc.Points[1].Values["messages"])
+}
+
+func TestExecDataFormatError(t *testing.T) {
+	e := &Exec{
+		runner: newRunnerMock([]byte(malformedJson), nil),
+		clock:  newClockMock(time.Unix(baseTimeSeconds, 0)),
+		Commands: []*Command{
+			{Command: "badcommand", Name: "mycollector", DataFormat: "json"},
+			{Command: "badcommand", Name: "mycollector", DataFormat: "xml"},
+		},
+	}
+
+	var acc testutil.Accumulator
+	err := e.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to parse output of 'badcommand'")
+	assert.Contains(t, err.Error(), "Invalid data format: xml")
+	assert.Len(t, acc.Points, 0)
+}
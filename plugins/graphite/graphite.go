@@ -0,0 +1,331 @@
+package graphite
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/influxdb/influxdb/models"
+
+	"github.com/influxdb/telegraf/plugins"
+	"github.com/influxdb/telegraf/plugins/parsers"
+)
+
+const (
+	defaultAllowedPendingPoints = 10000
+	// UDP packets larger than this are truncated
+	udpBufferSize = 64 * 1024
+	// pickled messages larger than this are refused, as carbon does
+	maxPickleLength = 1024 * 1024
+)
+
+var dropwarn = "ERROR: Point buffer full. Discarding line [%s] " +
+	"You may want to increase allowed_pending_points in the config\n"
+
+// Graphite accepts the carbon plaintext protocol over TCP or UDP, and the
+// carbon pickle protocol over TCP, and adds the received metrics on each
+// Gather. Metric paths are turned into measurements, tags and fields by
+// templates, see parsers.GraphiteParser.
+type Graphite struct {
+	// ServiceAddress is the URL to listen on for plaintext lines, ie
+	// "tcp://:2003" or "udp://:2003"
+	ServiceAddress string
+	// PickleAddress is the URL to listen on for pickled metrics, ie
+	// "tcp://:2004", pickles are not accepted when it is empty
+	PickleAddress string
+
+	Templates []string
+	Separator string
+
+	// Number of points allowed to queue up in between calls to Gather. Lines
+	// received while the buffer is full are dropped.
+	AllowedPendingPoints int
+
+	sync.Mutex
+	points []models.Point
+
+	parser      *parsers.GraphiteParser
+	lineAddr    net.Addr
+	pickleAddr  net.Addr
+	listeners   []net.Listener
+	packetConns []net.PacketConn
+	conns       map[net.Conn]bool
+	done        chan struct{}
+	wg          sync.WaitGroup
+}
+
+var sampleConfig = `
+  # URL to listen on for the carbon plaintext protocol, the scheme is one of
+  # tcp, tcp4, tcp6, udp, udp4 or udp6
+  service_address = "tcp://:2003"
+  # URL to listen on for the carbon pickle protocol, over tcp only
+  # pickle_address = "tcp://:2004"
+
+  # Templates turning metric paths into measurements, tags and fields:
+  # "[filter] <template> [tag=value,...]". The dot separated parts of a
+  # template are measurement, field, a tag name or empty to skip the part,
+  # and measurement* or field* take the remaining parts. Paths matching no
+  # filter are the measurement.
+  templates = [
+    "servers.* .host.measurement*",
+    "rethinkdb.* .host.measurement.field dc=east",
+  ]
+  # Separator joining the parts of the measurement or field
+  separator = "."
+
+  # Number of points to buffer in between collection intervals
+  allowed_pending_points = 10000
+`
+
+func (g *Graphite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *Graphite) Description() string {
+	return "Accept the carbon plaintext and pickle protocols over TCP or UDP"
+}
+
+func (g *Graphite) Start() error {
+	parser, err := parsers.NewGraphiteParser(g.Templates, g.Separator)
+	if err != nil {
+		return err
+	}
+	g.parser = parser
+	g.done = make(chan struct{})
+	g.conns = make(map[net.Conn]bool)
+
+	g.lineAddr, err = g.listen(g.ServiceAddress, g.readLines, false)
+	if err != nil {
+		g.Stop()
+		return err
+	}
+	if g.PickleAddress != "" {
+		g.pickleAddr, err = g.listen(g.PickleAddress, g.readPickles, true)
+		if err != nil {
+			g.Stop()
+			return err
+		}
+	}
+	return nil
+}
+
+// listen starts reading address with read, for each TCP connection or for
+// every UDP packet, and returns the address it is bound to
+func (g *Graphite) listen(address string, read func(io.Reader), tcpOnly bool) (net.Addr, error) {
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("Invalid address '%s'", address)
+	}
+
+	var addr net.Addr
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		listener, err := net.Listen(u.Scheme, u.Host)
+		if err != nil {
+			return nil, err
+		}
+		g.listeners = append(g.listeners, listener)
+		addr = listener.Addr()
+		g.wg.Add(1)
+		go g.acceptTCP(listener, read)
+	case "udp", "udp4", "udp6":
+		if tcpOnly {
+			return nil, fmt.Errorf("Pickles are only accepted over tcp, not '%s'", address)
+		}
+		conn, err := net.ListenPacket(u.Scheme, u.Host)
+		if err != nil {
+			return nil, err
+		}
+		g.packetConns = append(g.packetConns, conn)
+		addr = conn.LocalAddr()
+		g.wg.Add(1)
+		go g.readUDP(conn, read)
+	default:
+		return nil, fmt.Errorf("Unsupported scheme '%s' in address '%s'", u.Scheme, address)
+	}
+
+	log.Printf("Graphite listening on %s\n", address)
+	return addr, nil
+}
+
+// Addr returns the address the plaintext listener is bound to
+func (g *Graphite) Addr() net.Addr {
+	return g.lineAddr
+}
+
+// PickleAddr returns the address the pickle listener is bound to
+func (g *Graphite) PickleAddr() net.Addr {
+	return g.pickleAddr
+}
+
+func (g *Graphite) acceptTCP(listener net.Listener, read func(io.Reader)) {
+	defer g.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-g.done:
+				return
+			default:
+				log.Printf("ERROR: accepting connection on %s: %s\n", listener.Addr(), err)
+				continue
+			}
+		}
+
+		g.Lock()
+		g.conns[conn] = true
+		g.Unlock()
+
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			read(conn)
+
+			g.Lock()
+			delete(g.conns, conn)
+			g.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+func (g *Graphite) readUDP(conn net.PacketConn, read func(io.Reader)) {
+	defer g.wg.Done()
+	buf := make([]byte, udpBufferSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-g.done:
+				return
+			default:
+				log.Printf("ERROR: reading packet on %s: %s\n", conn.LocalAddr(), err)
+				continue
+			}
+		}
+		read(bytes.NewReader(buf[:n]))
+	}
+}
+
+// readLines parses every plaintext line of r until it ends, skipping
+// malformed lines
+func (g *Graphite) readLines(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		g.parseLine(line)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("ERROR: reading graphite lines: %s\n", err)
+	}
+}
+
+// readPickles parses the pickled messages of r, each prefixed by its length
+// as a big endian uint32, until it ends or a message is malformed
+func (g *Graphite) readPickles(r io.Reader) {
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err != io.EOF {
+				log.Printf("ERROR: reading pickle: %s\n", err)
+			}
+			return
+		}
+		length := binary.BigEndian.Uint32(header[:])
+		if length > maxPickleLength {
+			log.Printf("ERROR: refusing pickle of %d bytes\n", length)
+			return
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			log.Printf("ERROR: reading pickle: %s\n", err)
+			return
+		}
+
+		lines, err := unpickle(data)
+		if err != nil {
+			log.Printf("ERROR: unable to unpickle metrics: %s\n", err)
+			return
+		}
+		for _, line := range lines {
+			g.parseLine(line)
+		}
+	}
+}
+
+func (g *Graphite) parseLine(line string) {
+	points, err := g.parser.Parse([]byte(line))
+	if err != nil {
+		log.Printf("ERROR: unable to parse line [%s]: %s\n", line, err)
+		return
+	}
+
+	limit := g.AllowedPendingPoints
+	if limit == 0 {
+		limit = defaultAllowedPendingPoints
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	if len(g.points)+len(points) > limit {
+		log.Printf(dropwarn, line)
+		return
+	}
+	g.points = append(g.points, points...)
+}
+
+func (g *Graphite) Gather(acc plugins.Accumulator) error {
+	g.Lock()
+	points := g.points
+	g.points = nil
+	g.Unlock()
+
+	for _, pt := range points {
+		acc.AddFields(pt.Name(), pt.Fields(), pt.Tags(), pt.Time())
+	}
+	return nil
+}
+
+func (g *Graphite) Stop() {
+	if g.done == nil {
+		return
+	}
+	close(g.done)
+	for _, l := range g.listeners {
+		l.Close()
+	}
+	for _, c := range g.packetConns {
+		c.Close()
+	}
+
+	g.Lock()
+	for conn := range g.conns {
+		conn.Close()
+	}
+	g.Unlock()
+
+	g.wg.Wait()
+	g.done = nil
+	g.listeners = nil
+	g.packetConns = nil
+}
+
+func init() {
+	plugins.Add("graphite", func() plugins.Plugin {
+		return &Graphite{
+			ServiceAddress:       "tcp://:2003",
+			Separator:            ".",
+			AllowedPendingPoints: defaultAllowedPendingPoints,
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package graphite
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTemplates = []string{
+	"servers.* .host.measurement.field",
+	"rethinkdb.* .host.measurement.field dc=east",
+}
+
+func waitForPoints(t *testing.T, g *Graphite, acc *testutil.Accumulator, n int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		require.NoError(t, g.Gather(acc))
+		if len(acc.Points) >= n {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, acc.Points, n)
+}
+
+func send(t *testing.T, network string, addr net.Addr, data []byte) {
+	conn, err := net.Dial(network, addr.String())
+	require.NoError(t, err)
+	_, err = conn.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+}
+
+func TestGraphiteLines(t *testing.T) {
+	for _, scheme := range []string{"tcp", "udp"} {
+		g := &Graphite{ServiceAddress: scheme + "://127.0.0.1:0", Templates: testTemplates}
+		require.NoError(t, g.Start())
+
+		send(t, scheme, g.Addr(), []byte("rethinkdb.db1.engine.queries_per_sec 7.5 1447196400\n"+
+			"not a carbon line\n"+
+			"servers.web1.cpu.load 0.42 1447196400\n"))
+
+		var acc testutil.Accumulator
+		waitForPoints(t, g, &acc, 2)
+		g.Stop()
+
+		assert.Equal(t, "engine", acc.Points[0].Measurement, scheme)
+		assert.Equal(t, map[string]string{"host": "db1", "dc": "east"}, acc.Points[0].Tags, scheme)
+		assert.Equal(t, map[string]interface{}{"queries_per_sec": 7.5}, acc.Points[0].Values, scheme)
+		assert.Equal(t, time.Unix(1447196400, 0), acc.Points[0].Time, scheme)
+		assert.Equal(t, "cpu", acc.Points[1].Measurement, scheme)
+		assert.Equal(t, 0.42, acc.Points[1].Values["load"], scheme)
+	}
+}
+
+func TestGraphitePickle(t *testing.T) {
+	g := &Graphite{
+		ServiceAddress: "tcp://127.0.0.1:0",
+		PickleAddress:  "tcp://127.0.0.1:0",
+		Templates:      testTemplates,
+	}
+	require.NoError(t, g.Start())
+	defer g.Stop()
+
+	pickle, err := hex.DecodeString(testPickles[2])
+	require.NoError(t, err)
+	message := make([]byte, 4)
+	binary.BigEndian.PutUint32(message, uint32(len(pickle)))
+	message = append(message, pickle...)
+	// two messages on a connection
+	send(t, "tcp", g.PickleAddr(), append(message, message...))
+
+	var acc testutil.Accumulator
+	waitForPoints(t, g, &acc, 8)
+	assert.Equal(t, "cpu", acc.Points[0].Measurement)
+	assert.Equal(t, map[string]string{"host": "web1"}, acc.Points[0].Tags)
+	assert.Equal(t, map[string]interface{}{"load": 0.42}, acc.Points[0].Values)
+	assert.Equal(t, time.Unix(1447196400, 500000000), acc.Points[1].Time)
+	assert.Equal(t, map[string]interface{}{"used": float64(1 << 40)}, acc.Points[2].Values)
+}
+
+func TestGraphiteStartErrors(t *testing.T) {
+	for _, g := range []*Graphite{
+		{ServiceAddress: "127.0.0.1:2003"},
+		{ServiceAddress: "unix:///tmp/graphite.sock"},
+		{ServiceAddress: "tcp://127.0.0.1:0", PickleAddress: "udp://127.0.0.1:0"},
+		{ServiceAddress: "tcp://127.0.0.1:0", Templates: []string{"servers.* .host.measurement* dc"}},
+	} {
+		assert.Error(t, g.Start(), "%+v", g)
+		g.Stop()
+	}
+}
@@ -0,0 +1,354 @@
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// unpickle decodes the pickled list of (path, (timestamp, value)) tuples
+// sent by carbon clients into carbon plaintext lines. It implements the
+// subset of the pickle protocols 0 to 4 needed for lists, tuples, numbers
+// and strings, any other opcode fails.
+func unpickle(data []byte) ([]string, error) {
+	v, err := (&unpickler{data: data, memo: make(map[int]interface{})}).load()
+	if err != nil {
+		return nil, err
+	}
+
+	l, ok := v.(*list)
+	if !ok {
+		return nil, errors.New("Pickled data is not a list")
+	}
+	lines := make([]string, 0, len(l.items))
+	for _, item := range l.items {
+		metric, ok := item.(tuple)
+		if !ok || len(metric) != 2 {
+			return nil, errors.New("Pickled metric is not a (path, (timestamp, value)) tuple")
+		}
+		datapoint, ok := metric[1].(tuple)
+		if !ok || len(datapoint) != 2 {
+			return nil, errors.New("Pickled metric is not a (path, (timestamp, value)) tuple")
+		}
+		path, ok := metric[0].(string)
+		if !ok {
+			return nil, errors.New("Pickled metric path is not a string")
+		}
+		timestamp, err := number(datapoint[0])
+		if err != nil {
+			return nil, fmt.Errorf("Timestamp of %s: %s", path, err)
+		}
+		value, err := number(datapoint[1])
+		if err != nil {
+			return nil, fmt.Errorf("Value of %s: %s", path, err)
+		}
+		lines = append(lines, path+" "+value+" "+timestamp)
+	}
+	return lines, nil
+}
+
+// number formats a pickled int, float or numeric string
+func number(v interface{}) (string, error) {
+	switch n := v.(type) {
+	case int64:
+		return strconv.FormatInt(n, 10), nil
+	case *big.Int:
+		return n.String(), nil
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case string:
+		if _, err := strconv.ParseFloat(n, 64); err != nil {
+			return "", fmt.Errorf("'%s' is not a number", n)
+		}
+		return n, nil
+	}
+	return "", fmt.Errorf("%v is not a number", v)
+}
+
+type tuple []interface{}
+
+// list is a pointer so that the lists in the memo are appended to as well
+type list struct {
+	items []interface{}
+}
+
+// mark is pushed on the stack by the MARK opcode
+type mark struct{}
+
+type unpickler struct {
+	data  []byte
+	pos   int
+	stack []interface{}
+	memo  map[int]interface{}
+}
+
+func (u *unpickler) load() (interface{}, error) {
+	for {
+		op, err := u.byte()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case '.': // STOP
+			return u.pop()
+		case 0x80: // PROTO
+			_, err = u.byte()
+		case 0x95: // FRAME
+			_, err = u.read(8)
+		case '(': // MARK
+			u.push(mark{})
+		case ']', ')': // EMPTY_LIST, EMPTY_TUPLE
+			if op == ']' {
+				u.push(&list{})
+			} else {
+				u.push(tuple{})
+			}
+		case 'l', 't': // LIST, TUPLE
+			var items []interface{}
+			items, err = u.popMark()
+			if op == 'l' {
+				u.push(&list{items: items})
+			} else {
+				u.push(tuple(items))
+			}
+		case 0x85, 0x86, 0x87: // TUPLE1, TUPLE2, TUPLE3
+			n := int(op-0x85) + 1
+			if len(u.stack) < n {
+				return nil, errors.New("Pickle stack underflow")
+			}
+			items := append(tuple{}, u.stack[len(u.stack)-n:]...)
+			u.stack = u.stack[:len(u.stack)-n]
+			u.push(items)
+		case 'a': // APPEND
+			var item interface{}
+			if item, err = u.pop(); err == nil {
+				err = u.appendItems([]interface{}{item})
+			}
+		case 'e': // APPENDS
+			var items []interface{}
+			if items, err = u.popMark(); err == nil {
+				err = u.appendItems(items)
+			}
+		case 'N': // NONE
+			u.push(nil)
+		case 0x88, 0x89: // NEWTRUE, NEWFALSE
+			u.push(op == 0x88)
+		case 'I', 'L': // INT, LONG
+			var line string
+			if line, err = u.line(); err == nil {
+				err = u.pushInt(strings.TrimSuffix(line, "L"))
+			}
+		case 'J': // BININT
+			var b []byte
+			if b, err = u.read(4); err == nil {
+				u.push(int64(int32(binary.LittleEndian.Uint32(b))))
+			}
+		case 'K': // BININT1
+			var b byte
+			if b, err = u.byte(); err == nil {
+				u.push(int64(b))
+			}
+		case 'M': // BININT2
+			var b []byte
+			if b, err = u.read(2); err == nil {
+				u.push(int64(binary.LittleEndian.Uint16(b)))
+			}
+		case 0x8a: // LONG1
+			var n byte
+			var b []byte
+			if n, err = u.byte(); err == nil {
+				if b, err = u.read(int(n)); err == nil {
+					u.push(decodeLong(b))
+				}
+			}
+		case 'F': // FLOAT
+			var line string
+			var f float64
+			if line, err = u.line(); err == nil {
+				if f, err = strconv.ParseFloat(line, 64); err == nil {
+					u.push(f)
+				}
+			}
+		case 'G': // BINFLOAT
+			var b []byte
+			if b, err = u.read(8); err == nil {
+				u.push(math.Float64frombits(binary.BigEndian.Uint64(b)))
+			}
+		case 'S': // STRING
+			var line string
+			var s string
+			if line, err = u.line(); err == nil {
+				// the repr of a Python 2 str, ie 'cpu.load'
+				quoted := strings.Replace(strings.Trim(line, `'"`), `\'`, `'`, -1)
+				if s, err = strconv.Unquote(`"` + quoted + `"`); err == nil {
+					u.push(s)
+				}
+			}
+		case 'V': // UNICODE
+			var line string
+			if line, err = u.line(); err == nil {
+				u.push(line)
+			}
+		case 'U', 0x8c: // SHORT_BINSTRING, SHORT_BINUNICODE
+			var n byte
+			var b []byte
+			if n, err = u.byte(); err == nil {
+				if b, err = u.read(int(n)); err == nil {
+					u.push(string(b))
+				}
+			}
+		case 'T', 'X': // BINSTRING, BINUNICODE
+			var n, b []byte
+			if n, err = u.read(4); err == nil {
+				if b, err = u.read(int(binary.LittleEndian.Uint32(n))); err == nil {
+					u.push(string(b))
+				}
+			}
+		case 'p', 'g': // PUT, GET
+			var line string
+			var i int
+			if line, err = u.line(); err == nil {
+				if i, err = strconv.Atoi(line); err == nil {
+					err = u.memoize(op == 'p', i)
+				}
+			}
+		case 'q', 'h': // BINPUT, BINGET
+			var b byte
+			if b, err = u.byte(); err == nil {
+				err = u.memoize(op == 'q', int(b))
+			}
+		case 'r', 'j': // LONG_BINPUT, LONG_BINGET
+			var b []byte
+			if b, err = u.read(4); err == nil {
+				err = u.memoize(op == 'r', int(binary.LittleEndian.Uint32(b)))
+			}
+		case 0x94: // MEMOIZE
+			err = u.memoize(true, len(u.memo))
+		default:
+			return nil, fmt.Errorf("Unsupported pickle opcode 0x%02x", op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (u *unpickler) byte() (byte, error) {
+	if u.pos >= len(u.data) {
+		return 0, errors.New("Truncated pickle")
+	}
+	u.pos++
+	return u.data[u.pos-1], nil
+}
+
+func (u *unpickler) read(n int) ([]byte, error) {
+	if n < 0 || u.pos+n > len(u.data) {
+		return nil, errors.New("Truncated pickle")
+	}
+	u.pos += n
+	return u.data[u.pos-n : u.pos], nil
+}
+
+// line reads the argument of a text opcode, up to a newline
+func (u *unpickler) line() (string, error) {
+	i := bytes.IndexByte(u.data[u.pos:], '\n')
+	if i < 0 {
+		return "", errors.New("Truncated pickle")
+	}
+	line := string(u.data[u.pos : u.pos+i])
+	u.pos += i + 1
+	return line, nil
+}
+
+func (u *unpickler) push(v interface{}) {
+	u.stack = append(u.stack, v)
+}
+
+func (u *unpickler) pop() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, errors.New("Pickle stack underflow")
+	}
+	v := u.stack[len(u.stack)-1]
+	u.stack = u.stack[:len(u.stack)-1]
+	return v, nil
+}
+
+// popMark pops the items pushed since the last mark
+func (u *unpickler) popMark() ([]interface{}, error) {
+	for i := len(u.stack) - 1; i >= 0; i-- {
+		if _, ok := u.stack[i].(mark); ok {
+			items := append([]interface{}{}, u.stack[i+1:]...)
+			u.stack = u.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, errors.New("Pickle mark not found")
+}
+
+// appendItems appends items to the list on top of the stack
+func (u *unpickler) appendItems(items []interface{}) error {
+	if len(u.stack) == 0 {
+		return errors.New("Pickle stack underflow")
+	}
+	l, ok := u.stack[len(u.stack)-1].(*list)
+	if !ok {
+		return errors.New("Pickle append to a non list")
+	}
+	l.items = append(l.items, items...)
+	return nil
+}
+
+func (u *unpickler) pushInt(s string) error {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		u.push(i)
+		return nil
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("Invalid pickled int '%s'", s)
+	}
+	u.push(n)
+	return nil
+}
+
+// memoize stores the top of the stack in the memo when put is set, or
+// pushes the memoized value otherwise
+func (u *unpickler) memoize(put bool, i int) error {
+	if put {
+		if len(u.stack) == 0 {
+			return errors.New("Pickle stack underflow")
+		}
+		u.memo[i] = u.stack[len(u.stack)-1]
+		return nil
+	}
+	v, ok := u.memo[i]
+	if !ok {
+		return fmt.Errorf("Pickle memo %d not found", i)
+	}
+	u.push(v)
+	return nil
+}
+
+// decodeLong decodes a little endian two's complement integer
+func decodeLong(b []byte) interface{} {
+	if len(b) == 0 {
+		return int64(0)
+	}
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	n := new(big.Int).SetBytes(be)
+	if b[len(b)-1]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	if n.BitLen() < 64 {
+		return n.Int64()
+	}
+	return n
+}
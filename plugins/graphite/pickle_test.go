@@ -0,0 +1,81 @@
+package graphite
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pickle.dumps of testMetrics with the protocols 0, 2 and 4 of Python 3:
+//
+//	[('servers.web1.cpu.load', (1447196400, 0.42)),
+//	 ('servers.web1.cpu.count', (1447196400.5, 4)),
+//	 ('servers.web1.disk.used', (1447196400, 2**40)),
+//	 ('servers.web1.disk.free', (1447196400, '12.5'))]
+var testPickles = map[int]string{
+	0: "286c70300a2856736572766572732e776562312e6370752e6c6f61640a70310a2849313434373139363430300a46302e34320a7470320a7470330a612856736572766572732e776562312e6370752e636f756e740a70340a2846313434373139363430302e350a49340a7470350a7470360a612856736572766572732e776562312e6469736b2e757365640a70370a2849313434373139363430300a4c313039393531313632373737364c0a7470380a7470390a612856736572766572732e776562312e6469736b2e667265650a7031300a2849313434373139363430300a5631322e350a7031310a747031320a747031330a612e",
+	2: "80025d7100285815000000736572766572732e776562312e6370752e6c6f616471014af0764256473fdae147ae147ae18671028671035816000000736572766572732e776562312e6370752e636f756e7471044741d5909dbc2000004b048671058671065816000000736572766572732e776562312e6469736b2e7573656471074af07642568a060000000000018671088671095816000000736572766572732e776562312e6469736b2e66726565710a4af0764256580400000031322e35710b86710c86710d652e",
+	4: "800495aa000000000000005d94288c15736572766572732e776562312e6370752e6c6f6164944af0764256473fdae147ae147ae1869486948c16736572766572732e776562312e6370752e636f756e74944741d5909dbc2000004b04869486948c16736572766572732e776562312e6469736b2e75736564944af07642568a06000000000001869486948c16736572766572732e776562312e6469736b2e66726565944af07642568c0431322e359486948694652e",
+}
+
+var testLines = []string{
+	"servers.web1.cpu.load 0.42 1447196400",
+	"servers.web1.cpu.count 4 1447196400.5",
+	"servers.web1.disk.used 1099511627776 1447196400",
+	"servers.web1.disk.free 12.5 1447196400",
+}
+
+func TestUnpickle(t *testing.T) {
+	for protocol, pickle := range testPickles {
+		data, err := hex.DecodeString(pickle)
+		require.NoError(t, err)
+		lines, err := unpickle(data)
+		require.NoError(t, err, "protocol %d", protocol)
+		assert.Equal(t, testLines, lines, "protocol %d", protocol)
+	}
+}
+
+func TestUnpicklePython2(t *testing.T) {
+	// pickle.dumps of Python 2, whose strings are str rather than unicode
+	pickle := "(lp0\n(S'servers.web1.cpu.load'\np1\n(I1447196400\nF0.42\ntp2\ntp3\na(S'servers.web1.disk.used'\np4\n(I1447196400\nL-1099511627776L\ntp5\ntp6\na."
+	lines, err := unpickle([]byte(pickle))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"servers.web1.cpu.load 0.42 1447196400",
+		"servers.web1.disk.used -1099511627776 1447196400",
+	}, lines)
+}
+
+func TestDecodeLong(t *testing.T) {
+	assert.Equal(t, int64(0), decodeLong(nil))
+	assert.Equal(t, int64(1<<40), decodeLong([]byte{0, 0, 0, 0, 0, 1}))
+	assert.Equal(t, int64(-1), decodeLong([]byte{0xff}))
+	assert.Equal(t, "18446744073709551616",
+		decodeLong([]byte{0, 0, 0, 0, 0, 0, 0, 0, 1}).(interface {
+			String() string
+		}).String())
+}
+
+func TestUnpickleErrors(t *testing.T) {
+	data, err := hex.DecodeString(testPickles[2])
+	require.NoError(t, err)
+
+	for _, pickle := range [][]byte{
+		data[:len(data)-1],
+		// a dict
+		[]byte("(dp0\n."),
+		// not a list
+		[]byte("I1\n."),
+		// a metric without a timestamp
+		[]byte("(lp0\n(S'cpu.load'\nF0.42\ntp1\na."),
+		// a value that is not a number
+		[]byte("(lp0\n(S'cpu.load'\n(I1\nS'high'\ntp1\ntp2\na."),
+		// an unknown memo
+		[]byte("(lp0\ng7\na."),
+	} {
+		_, err := unpickle(pickle)
+		assert.Error(t, err, "%q", pickle)
+	}
+}
@@ -0,0 +1,167 @@
+package http_response
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// HTTPResponse checks HTTP endpoints, reporting how they respond
+type HTTPResponse struct {
+	Urls            []string
+	Method          string
+	ResponseTimeout internal.Duration
+	FollowRedirects bool
+	Headers         map[string]string
+
+	// ResponseStringMatch is a regular expression the response body is
+	// matched against
+	ResponseStringMatch string
+
+	compiledStringMatch *regexp.Regexp
+	client              *http.Client
+}
+
+var sampleConfig = `
+  # URLs to check
+  urls = ["http://localhost"]
+  # HTTP method, GET or HEAD
+  method = "GET"
+  # Time to wait for the response, including its body
+  response_timeout = "5s"
+  # Whether to follow redirects from the server
+  follow_redirects = false
+
+  # Optional regular expression the body of the response must match,
+  # reported as the response_string_match field (1 or 0)
+  # response_string_match = "ok"
+
+  # HTTP request headers
+  # [http_response.headers]
+  #   Host = "github.com"
+`
+
+func (h *HTTPResponse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HTTPResponse) Description() string {
+	return "HTTP/HTTPS request given an address, a method and a timeout"
+}
+
+func (h *HTTPResponse) init() error {
+	switch h.Method {
+	case "":
+		h.Method = "GET"
+	case "GET", "HEAD":
+	default:
+		return fmt.Errorf("Invalid method %s, must be GET or HEAD", h.Method)
+	}
+
+	if h.ResponseStringMatch != "" && h.compiledStringMatch == nil {
+		re, err := regexp.Compile(h.ResponseStringMatch)
+		if err != nil {
+			return fmt.Errorf("Invalid response_string_match '%s': %s",
+				h.ResponseStringMatch, err)
+		}
+		h.compiledStringMatch = re
+	}
+
+	if h.client == nil {
+		h.client = &http.Client{Timeout: h.ResponseTimeout.Duration}
+		if !h.FollowRedirects {
+			h.client.CheckRedirect = func(*http.Request, []*http.Request) error {
+				// the redirect itself is the response
+				return http.ErrUseLastResponse
+			}
+		}
+	}
+	return nil
+}
+
+func (h *HTTPResponse) Gather(acc plugins.Accumulator) error {
+	if err := h.init(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []string
+	for _, u := range h.Urls {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			if err := h.check(u, acc); err != nil {
+				mu.Lock()
+				errs = append(errs, err.Error())
+				mu.Unlock()
+			}
+		}(u)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// check requests u, adding how it responded to acc. Only an invalid url is
+// returned as an error, a failing endpoint is reported by result_type.
+func (h *HTTPResponse) check(u string, acc plugins.Accumulator) error {
+	req, err := http.NewRequest(h.Method, u, nil)
+	if err != nil {
+		return fmt.Errorf("Invalid url %s: %s", u, err)
+	}
+	for k, v := range h.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		} else {
+			req.Header.Set(k, v)
+		}
+	}
+
+	tags := map[string]string{"server": u, "method": h.Method}
+	fields := make(map[string]interface{})
+
+	start := time.Now()
+	resp, err := h.client.Do(req)
+
+	var body []byte
+	if err == nil {
+		defer resp.Body.Close()
+		fields["http_response_code"] = resp.StatusCode
+		body, err = ioutil.ReadAll(resp.Body)
+	}
+	fields["response_time"] = time.Since(start).Seconds()
+
+	result := internal.ScrapeResult(err)
+	if h.compiledStringMatch != nil {
+		matched := 0
+		if err == nil && h.compiledStringMatch.Match(body) {
+			matched = 1
+		}
+		fields["response_string_match"] = matched
+	}
+
+	tags["result_type"] = result
+	acc.AddFields("check", fields, tags)
+	return nil
+}
+
+func init() {
+	plugins.Add("http_response", func() plugins.Plugin {
+		return &HTTPResponse{
+			Method:          "GET",
+			ResponseTimeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package http_response
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/good", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hit the good page!")
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/good", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	})
+	return httptest.NewServer(mux)
+}
+
+func gatherCheck(t *testing.T, h *HTTPResponse) *testutil.Point {
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	require.Len(t, acc.Points, 1)
+	assert.Equal(t, "check", acc.Points[0].Measurement)
+	assert.IsType(t, float64(0), acc.Points[0].Values["response_time"])
+	return acc.Points[0]
+}
+
+func TestHTTPResponseSuccess(t *testing.T) {
+	ts := testServer()
+	defer ts.Close()
+
+	h := &HTTPResponse{
+		Urls:                []string{ts.URL + "/good"},
+		ResponseTimeout:     internal.Duration{Duration: time.Second},
+		ResponseStringMatch: "good page",
+	}
+	pt := gatherCheck(t, h)
+	assert.Equal(t, map[string]string{
+		"server":      ts.URL + "/good",
+		"method":      "GET",
+		"result_type": "success",
+	}, pt.Tags)
+	assert.Equal(t, http.StatusOK, pt.Values["http_response_code"])
+	assert.Equal(t, 1, pt.Values["response_string_match"])
+}
+
+func TestHTTPResponseStringMismatch(t *testing.T) {
+	ts := testServer()
+	defer ts.Close()
+
+	h := &HTTPResponse{
+		Urls:                []string{ts.URL + "/good"},
+		ResponseStringMatch: "bad page",
+	}
+	pt := gatherCheck(t, h)
+	assert.Equal(t, "success", pt.Tags["result_type"])
+	assert.Equal(t, 0, pt.Values["response_string_match"])
+}
+
+func TestHTTPResponseErrorCode(t *testing.T) {
+	ts := testServer()
+	defer ts.Close()
+
+	h := &HTTPResponse{Urls: []string{ts.URL + "/error"}, Method: "HEAD"}
+	pt := gatherCheck(t, h)
+	assert.Equal(t, "success", pt.Tags["result_type"])
+	assert.Equal(t, "HEAD", pt.Tags["method"])
+	assert.Equal(t, http.StatusInternalServerError, pt.Values["http_response_code"])
+	_, ok := pt.Values["response_string_match"]
+	assert.False(t, ok)
+}
+
+func TestHTTPResponseRedirects(t *testing.T) {
+	ts := testServer()
+	defer ts.Close()
+
+	h := &HTTPResponse{Urls: []string{ts.URL + "/redirect"}}
+	pt := gatherCheck(t, h)
+	assert.Equal(t, http.StatusMovedPermanently, pt.Values["http_response_code"])
+
+	h = &HTTPResponse{Urls: []string{ts.URL + "/redirect"}, FollowRedirects: true}
+	pt = gatherCheck(t, h)
+	assert.Equal(t, http.StatusOK, pt.Values["http_response_code"])
+}
+
+func TestHTTPResponseTimeout(t *testing.T) {
+	ts := testServer()
+	defer ts.Close()
+
+	h := &HTTPResponse{
+		Urls:                []string{ts.URL + "/slow"},
+		ResponseTimeout:     internal.Duration{Duration: 50 * time.Millisecond},
+		ResponseStringMatch: ".*",
+	}
+	pt := gatherCheck(t, h)
+	assert.Equal(t, "timeout", pt.Tags["result_type"])
+	_, ok := pt.Values["http_response_code"]
+	assert.False(t, ok)
+	assert.Equal(t, 0, pt.Values["response_string_match"])
+}
+
+func TestHTTPResponseConnectionFailed(t *testing.T) {
+	// find a port nothing listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	h := &HTTPResponse{Urls: []string{"http://" + addr}}
+	pt := gatherCheck(t, h)
+	assert.Equal(t, "connection_failed", pt.Tags["result_type"])
+}
+
+func TestHTTPResponseDNSError(t *testing.T) {
+	h := &HTTPResponse{
+		Urls:            []string{"http://nonexistent.invalid"},
+		ResponseTimeout: internal.Duration{Duration: 5 * time.Second},
+	}
+	pt := gatherCheck(t, h)
+	assert.Equal(t, "dns_error", pt.Tags["result_type"])
+}
+
+func TestHTTPResponseBadConfig(t *testing.T) {
+	var acc testutil.Accumulator
+
+	h := &HTTPResponse{Urls: []string{"http://localhost"}, Method: "POST"}
+	assert.Error(t, h.Gather(&acc))
+
+	h = &HTTPResponse{Urls: []string{"http://localhost"}, ResponseStringMatch: "("}
+	assert.Error(t, h.Gather(&acc))
+
+	h = &HTTPResponse{Urls: []string{"http://bad url/%zz"}}
+	assert.Error(t, h.Gather(&acc))
+	assert.Len(t, acc.Points, 0)
+}
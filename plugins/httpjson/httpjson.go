@@ -4,18 +4,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/influxdb/telegraf/internal"
 	"github.com/influxdb/telegraf/plugins"
 )
 
 type HttpJson struct {
 	Services []Service
 	client   HTTPClient
+
+	internal.HTTPProxy
 }
 
 type Service struct {
@@ -24,6 +30,18 @@ type Service struct {
 	Method     string
 	TagKeys    []string
 	Parameters map[string]string
+
+	// JsonQuery selects the part of the response to gather, ie
+	// "stats.servers", see queryJSON
+	JsonQuery string
+	// Fields limits the gathered values to the given flattened keys, ie
+	// "parent_child", all values are gathered when empty
+	Fields []string
+
+	// Body is sent as the request body, Parameters then go in the URL
+	// query even for POST and PUT requests
+	Body    string
+	Headers map[string]string
 }
 
 type HTTPClient interface {
@@ -47,6 +65,10 @@ func (c RealHTTPClient) MakeRequest(req *http.Request) (*http.Response, error) {
 }
 
 var sampleConfig = `
+  # HTTP proxy to send the requests through, HTTP_PROXY and HTTPS_PROXY
+  # from the environment are used when not set
+  # http_proxy_url = "http://localhost:8888"
+
   # Specify services via an array of tables
   [[httpjson.services]]
 
@@ -59,19 +81,35 @@ var sampleConfig = `
       "http://localhost:9998/stats/",
     ]
 
-    # HTTP method to use (case-sensitive)
+    # HTTP method to use (case-sensitive). For POST and PUT the parameters
+    # are sent form encoded in the body, unless a body is given.
     method = "GET"
 
-    # List of tag names to extract from top-level of JSON server response
+    # Request body, ie a JSON document for APIs that expect one
+    # body = '{"stats": true}'
+
+    # Dot separated path of the part of the response to gather, ie
+    # "stats.servers" or "stats.servers.0". When it selects an array, each
+    # object of the array is gathered with its own tags.
+    # json_query = "stats"
+
+    # List of tag names to extract from top-level of the selected JSON
     # tag_keys = [
     # 	"my_tag_1",
     # 	"my_tag_2"
     # ]
 
+    # Only gather these values, named by their flattened keys
+    # fields = ["parent_child", "integer"]
+
     # HTTP parameters (all values must be strings)
     [httpjson.services.parameters]
       event_type = "cpu_spike"
       threshold = "0.75"
+
+    # HTTP headers (all values must be strings)
+    # [httpjson.services.headers]
+    #   Content-Type = "application/json"
 `
 
 func (h *HttpJson) SampleConfig() string {
@@ -84,6 +122,14 @@ func (h *HttpJson) Description() string {
 
 // Gathers data for all servers.
 func (h *HttpJson) Gather(acc plugins.Accumulator) error {
+	if h.client == nil {
+		client, err := internal.NewHTTPClient(0, nil, h.HTTPProxyURL)
+		if err != nil {
+			return err
+		}
+		h.client = RealHTTPClient{client: client}
+	}
+
 	var wg sync.WaitGroup
 
 	totalServers := 0
@@ -128,40 +174,102 @@ func (h *HttpJson) Gather(acc plugins.Accumulator) error {
 // Returns:
 //     error: Any error that may have occurred
 func (h *HttpJson) gatherServer(acc plugins.Accumulator, service Service, serverURL string) error {
-	resp, err := h.sendRequest(service, serverURL)
+	resp, err := h.sendRequest(acc, service, serverURL)
 	if err != nil {
 		return err
 	}
 
-	var jsonOut map[string]interface{}
+	var jsonOut interface{}
 	if err = json.Unmarshal([]byte(resp), &jsonOut); err != nil {
 		return errors.New("Error decoding JSON response")
 	}
 
-	tags := map[string]string{
-		"server": serverURL,
+	selected, err := queryJSON(jsonOut, service.JsonQuery)
+	if err != nil {
+		return err
 	}
 
-	for _, tag := range service.TagKeys {
-		switch v := jsonOut[tag].(type) {
-		case string:
-			tags[tag] = v
+	var objects []map[string]interface{}
+	switch v := selected.(type) {
+	case map[string]interface{}:
+		objects = append(objects, v)
+	case []interface{}:
+		for _, item := range v {
+			if obj, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, obj)
+			}
 		}
-		delete(jsonOut, tag)
+	default:
+		return fmt.Errorf("JSON query \"%s\" did not select an object", service.JsonQuery)
 	}
 
-	processResponse(acc, service.Name, tags, jsonOut)
+	for _, obj := range objects {
+		tags := map[string]string{
+			"server": serverURL,
+		}
+
+		for _, tag := range service.TagKeys {
+			switch v := obj[tag].(type) {
+			case string:
+				tags[tag] = v
+			case float64:
+				tags[tag] = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				tags[tag] = strconv.FormatBool(v)
+			}
+			delete(obj, tag)
+		}
+
+		processResponse(acc, service.Name, "", tags, obj, service.Fields)
+	}
 	return nil
 }
 
-// Sends an HTTP request to the server using the HttpJson object's HTTPClient
+// queryJSON returns the part of v selected by a dot separated path of
+// object keys and array indexes, ie "stats.servers.0.load". An empty query,
+// or "$", selects v itself.
+func queryJSON(v interface{}, query string) (interface{}, error) {
+	query = strings.TrimPrefix(strings.TrimPrefix(query, "$"), ".")
+	if query == "" {
+		return v, nil
+	}
+
+	for _, key := range strings.Split(query, ".") {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			child, ok := t[key]
+			if !ok {
+				return nil, fmt.Errorf("JSON query \"%s\": key \"%s\" not found", query, key)
+			}
+			v = child
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, fmt.Errorf("JSON query \"%s\": invalid index \"%s\"", query, key)
+			}
+			v = t[i]
+		default:
+			return nil, fmt.Errorf("JSON query \"%s\": \"%s\" is not an object or array", query, key)
+		}
+	}
+	return v, nil
+}
+
+// Sends an HTTP request to the server using the HttpJson object's HTTPClient,
+// adding the response time and status code of the request to acc
 // Parameters:
+//     acc      : The telegraf Accumulator to use
+//     service  : the service being queried
 //     serverURL: endpoint to send request to
 //
 // Returns:
 //     string: body of the response
 //     error : Any error that may have occurred
-func (h *HttpJson) sendRequest(service Service, serverURL string) (string, error) {
+func (h *HttpJson) sendRequest(
+	acc plugins.Accumulator,
+	service Service,
+	serverURL string,
+) (string, error) {
 	// Prepare URL
 	requestURL, err := url.Parse(serverURL)
 	if err != nil {
@@ -172,15 +280,37 @@ func (h *HttpJson) sendRequest(service Service, serverURL string) (string, error
 	for k, v := range service.Parameters {
 		params.Add(k, v)
 	}
-	requestURL.RawQuery = params.Encode()
 
 	// Create + send request
-	req, err := http.NewRequest(service.Method, requestURL.String(), nil)
+	var reqBody io.Reader
+	contentType := ""
+	switch {
+	case service.Body != "":
+		requestURL.RawQuery = params.Encode()
+		reqBody = strings.NewReader(service.Body)
+	case service.Method == "POST" || service.Method == "PUT":
+		reqBody = strings.NewReader(params.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	default:
+		requestURL.RawQuery = params.Encode()
+	}
+
+	req, err := http.NewRequest(service.Method, requestURL.String(), reqBody)
 	if err != nil {
 		return "", err
 	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range service.Headers {
+		req.Header.Set(k, v)
+	}
 
+	start := time.Now()
 	resp, err := h.client.MakeRequest(req)
+	fields, result := internal.ScrapeStats(time.Since(start), resp, err)
+	acc.AddFields(service.Name+"_scrape", fields,
+		map[string]string{"server": serverURL, "result": result})
 	if err != nil {
 		return "", err
 	}
@@ -210,20 +340,45 @@ func (h *HttpJson) sendRequest(service Service, serverURL string) (string, error
 // Parameters:
 //     acc: the Accumulator to use
 //     prefix: What the name of the measurement name should be prefixed by.
+//     key: the flattened key of v, matched against fields
 //     tags: telegraf tags to
-func processResponse(acc plugins.Accumulator, prefix string, tags map[string]string, v interface{}) {
+//     fields: flattened keys to gather, all keys when empty
+func processResponse(
+	acc plugins.Accumulator,
+	prefix string,
+	key string,
+	tags map[string]string,
+	v interface{},
+	fields []string,
+) {
 	switch t := v.(type) {
 	case map[string]interface{}:
 		for k, v := range t {
-			processResponse(acc, prefix+"_"+k, tags, v)
+			childKey := k
+			if key != "" {
+				childKey = key + "_" + k
+			}
+			processResponse(acc, prefix+"_"+k, childKey, tags, v, fields)
 		}
 	case float64:
+		if len(fields) > 0 && !contains(fields, key) {
+			return
+		}
 		acc.Add(prefix, v, tags)
 	}
 }
 
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
 func init() {
 	plugins.Add("httpjson", func() plugins.Plugin {
-		return &HttpJson{client: RealHTTPClient{client: &http.Client{}}}
+		return &HttpJson{}
 	})
 }
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/influxdb/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
@@ -120,7 +122,8 @@ func TestHttpJson200(t *testing.T) {
 	err := httpjson.Gather(&acc)
 	require.NoError(t, err)
 
-	assert.Equal(t, 8, len(acc.Points))
+	// 2 measurements and a scrape for each of the 4 servers
+	assert.Equal(t, 12, len(acc.Points))
 
 	for _, service := range httpjson.Services {
 		for _, srv := range service.Servers {
@@ -152,7 +155,8 @@ func TestHttpJson500(t *testing.T) {
 	assert.NotNil(t, err)
 	// 4 error lines for (2 urls) * (2 services)
 	assert.Equal(t, len(strings.Split(err.Error(), "\n")), 4)
-	assert.Equal(t, 0, len(acc.Points))
+	// only the scrape of each server
+	assert.Equal(t, 4, len(acc.Points))
 }
 
 // Test response to HTTP 405
@@ -167,8 +171,9 @@ func TestHttpJsonBadMethod(t *testing.T) {
 	// 2 error lines for (2 urls) * (1 falied service)
 	assert.Equal(t, len(strings.Split(err.Error(), "\n")), 2)
 
-	// (2 measurements) * (2 servers) * (1 successful service)
-	assert.Equal(t, 4, len(acc.Points))
+	// (2 measurements) * (2 servers) * (1 successful service), and a
+	// scrape for each of the 4 servers
+	assert.Equal(t, 8, len(acc.Points))
 }
 
 // Test response to malformed JSON
@@ -181,7 +186,8 @@ func TestHttpJsonBadJson(t *testing.T) {
 	assert.NotNil(t, err)
 	// 4 error lines for (2 urls) * (2 services)
 	assert.Equal(t, len(strings.Split(err.Error(), "\n")), 4)
-	assert.Equal(t, 0, len(acc.Points))
+	// only the scrape of each server
+	assert.Equal(t, 4, len(acc.Points))
 }
 
 // Test response to empty string as response objectgT
@@ -194,7 +200,8 @@ func TestHttpJsonEmptyResponse(t *testing.T) {
 	assert.NotNil(t, err)
 	// 4 error lines for (2 urls) * (2 services)
 	assert.Equal(t, len(strings.Split(err.Error(), "\n")), 4)
-	assert.Equal(t, 0, len(acc.Points))
+	// only the scrape of each server
+	assert.Equal(t, 4, len(acc.Points))
 }
 
 // Test that the proper values are ignored or collected
@@ -205,7 +212,8 @@ func TestHttpJson200Tags(t *testing.T) {
 	err := httpjson.Gather(&acc)
 	require.NoError(t, err)
 
-	assert.Equal(t, 4, len(acc.Points))
+	// a value and a scrape for each of the 4 servers
+	assert.Equal(t, 8, len(acc.Points))
 
 	for _, service := range httpjson.Services {
 		if service.Name == "other_webapp" {
@@ -221,3 +229,207 @@ func TestHttpJson200Tags(t *testing.T) {
 		}
 	}
 }
+
+const nestedJSON = `
+	{
+		"cluster": {
+			"name": "prod",
+			"servers": [
+				{"name": "db1", "id": 1, "stats": {"queries": 10, "clients": 2}},
+				{"name": "db2", "id": 2, "stats": {"queries": 20, "clients": 4}},
+				"not an object"
+			],
+			"uptime": 300
+		}
+	}`
+
+func genNestedHttpJson(query string, tagKeys []string, fields []string) *HttpJson {
+	return &HttpJson{
+		client: mockHTTPClient{responseBody: nestedJSON, statusCode: 200},
+		Services: []Service{
+			Service{
+				Servers:   []string{"http://server1.example.com/metrics/"},
+				Name:      "rethinkdb",
+				Method:    "GET",
+				JsonQuery: query,
+				TagKeys:   tagKeys,
+				Fields:    fields,
+			},
+		},
+	}
+}
+
+// Test that a JSON query selects a subtree, each element of a selected array
+// being gathered with its own tags
+func TestHttpJsonQueryArray(t *testing.T) {
+	httpjson := genNestedHttpJson("cluster.servers", []string{"name", "id"}, nil)
+
+	var acc testutil.Accumulator
+	require.NoError(t, httpjson.Gather(&acc))
+	assert.Equal(t, 5, len(acc.Points))
+
+	server := "http://server1.example.com/metrics/"
+	require.NoError(t, acc.ValidateTaggedValue("rethinkdb_stats_queries", 10.0,
+		map[string]string{"server": server, "name": "db1", "id": "1"}))
+	require.NoError(t, acc.ValidateTaggedValue("rethinkdb_stats_clients", 4.0,
+		map[string]string{"server": server, "name": "db2", "id": "2"}))
+	assert.False(t, acc.HasMeasurement("rethinkdb_uptime"))
+}
+
+// Test that only the requested fields of the selected object are gathered
+func TestHttpJsonQueryFields(t *testing.T) {
+	httpjson := genNestedHttpJson("$.cluster.servers.1", []string{"name"},
+		[]string{"stats_queries"})
+
+	var acc testutil.Accumulator
+	require.NoError(t, httpjson.Gather(&acc))
+	require.Equal(t, 2, len(acc.Points))
+	require.NoError(t, acc.ValidateTaggedValue("rethinkdb_stats_queries", 20.0,
+		map[string]string{"server": "http://server1.example.com/metrics/", "name": "db2"}))
+}
+
+// Test that queries not selecting an object fail
+func TestHttpJsonBadQuery(t *testing.T) {
+	for _, query := range []string{
+		"cluster.missing",
+		"cluster.servers.5",
+		"cluster.servers.name",
+		"cluster.uptime",
+		"cluster.uptime.value",
+	} {
+		httpjson := genNestedHttpJson(query, nil, nil)
+
+		var acc testutil.Accumulator
+		assert.Error(t, httpjson.Gather(&acc), query)
+		// only the scrape
+		assert.Equal(t, 1, len(acc.Points), query)
+	}
+}
+
+func genServerHttpJson(url string, service Service) *HttpJson {
+	service.Name = "my_webapp"
+	service.Servers = []string{url}
+	return &HttpJson{
+		client:   RealHTTPClient{client: &http.Client{}},
+		Services: []Service{service},
+	}
+}
+
+// Test that the parameters of a POST are sent form encoded in the body
+func TestHttpJsonPostParameters(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "", r.URL.RawQuery)
+		assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "12", r.PostForm.Get("httpParam1"))
+		assert.Equal(t, "the second parameter", r.PostForm.Get("httpParam2"))
+		fmt.Fprint(w, validJSON)
+	}))
+	defer ts.Close()
+
+	httpjson := genServerHttpJson(ts.URL, Service{
+		Method: "POST",
+		Parameters: map[string]string{
+			"httpParam1": "12",
+			"httpParam2": "the second parameter",
+		},
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, httpjson.Gather(&acc))
+	require.NoError(t, acc.ValidateTaggedValue("my_webapp_integer", 4.0,
+		map[string]string{"server": ts.URL}))
+}
+
+// Test that a configured body is sent as is, with the parameters in the URL
+func TestHttpJsonPostBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "12", r.URL.Query().Get("httpParam1"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"stats": true}`, string(body))
+		fmt.Fprint(w, validJSON)
+	}))
+	defer ts.Close()
+
+	httpjson := genServerHttpJson(ts.URL, Service{
+		Method:     "POST",
+		Parameters: map[string]string{"httpParam1": "12"},
+		Body:       `{"stats": true}`,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, httpjson.Gather(&acc))
+	assert.Equal(t, 3, len(acc.Points))
+}
+
+// Test that GET parameters stay in the URL query
+func TestHttpJsonGetParameters(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "12", r.URL.Query().Get("httpParam1"))
+		fmt.Fprint(w, validJSON)
+	}))
+	defer ts.Close()
+
+	httpjson := genServerHttpJson(ts.URL, Service{
+		Method:     "GET",
+		Parameters: map[string]string{"httpParam1": "12"},
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, httpjson.Gather(&acc))
+	assert.Equal(t, 3, len(acc.Points))
+}
+
+func scrapePoint(t *testing.T, acc *testutil.Accumulator) *testutil.Point {
+	pt, ok := acc.Get("my_webapp_scrape")
+	require.True(t, ok)
+	return pt
+}
+
+// Test that every scrape adds its response time and status code
+func TestHttpJsonScrapeStats(t *testing.T) {
+	for _, code := range []int{http.StatusOK, http.StatusInternalServerError} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+			fmt.Fprint(w, validJSON)
+		}))
+
+		httpjson := genServerHttpJson(ts.URL, Service{Method: "GET"})
+		var acc testutil.Accumulator
+		httpjson.Gather(&acc)
+		ts.Close()
+
+		pt := scrapePoint(t, &acc)
+		assert.Equal(t, map[string]string{"server": ts.URL, "result": "success"}, pt.Tags)
+		assert.Equal(t, code, pt.Values["http_response_code"])
+		assert.IsType(t, float64(0), pt.Values["response_time_ms"])
+	}
+}
+
+// Test that a timed out scrape is reported
+func TestHttpJsonScrapeStatsTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	httpjson := genServerHttpJson(ts.URL, Service{Method: "GET"})
+	httpjson.client = RealHTTPClient{client: &http.Client{Timeout: 50 * time.Millisecond}}
+
+	var acc testutil.Accumulator
+	assert.Error(t, httpjson.Gather(&acc))
+
+	require.Equal(t, 1, len(acc.Points))
+	pt := scrapePoint(t, &acc)
+	assert.Equal(t, map[string]string{"server": ts.URL, "result": "timeout"}, pt.Tags)
+	_, ok := pt.Values["http_response_code"]
+	assert.False(t, ok)
+}
@@ -0,0 +1,232 @@
+package jolokia
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdb/telegraf/plugins"
+)
+
+type Server struct {
+	Name     string
+	Url      string
+	Username string
+	Password string
+}
+
+// Metric is an MBean attribute read from every server. Attribute may be
+// empty to read all attributes of the MBean, and Path selects a part of a
+// composite attribute.
+type Metric struct {
+	Name      string
+	Mbean     string
+	Attribute string
+	Path      string
+}
+
+type JolokiaClient interface {
+	MakeRequest(req *http.Request) (*http.Response, error)
+}
+
+type JolokiaClientImpl struct {
+	client *http.Client
+}
+
+func (c JolokiaClientImpl) MakeRequest(req *http.Request) (*http.Response, error) {
+	return c.client.Do(req)
+}
+
+type Jolokia struct {
+	jClient JolokiaClient
+	Servers []Server
+	Metrics []Metric
+}
+
+// readRequest is a single read of a Jolokia bulk request
+type readRequest struct {
+	Type      string `json:"type"`
+	Mbean     string `json:"mbean"`
+	Attribute string `json:"attribute,omitempty"`
+	Path      string `json:"path,omitempty"`
+}
+
+type readResponse struct {
+	Status int         `json:"status"`
+	Error  string      `json:"error"`
+	Value  interface{} `json:"value"`
+}
+
+var sampleConfig = `
+  # Jolokia agents to read from, url is the base url of the agent
+  [[jolokia.servers]]
+    name = "kafka"
+    url = "http://localhost:8778/jolokia"
+    # username = "jolokia"
+    # password = "secret"
+
+  # MBean attributes to read from every server, reported as a measurement
+  # named after the metric and tagged by mbean. Numeric values of composite
+  # attributes are reported as separate fields.
+  [[jolokia.metrics]]
+    name = "heap_memory_usage"
+    mbean = "java.lang:type=Memory"
+    attribute = "HeapMemoryUsage"
+
+  [[jolokia.metrics]]
+    name = "thread_count"
+    mbean = "java.lang:type=Threading"
+    attribute = "TotalStartedThreadCount,ThreadCount,DaemonThreadCount,PeakThreadCount"
+`
+
+func (j *Jolokia) SampleConfig() string {
+	return sampleConfig
+}
+
+func (j *Jolokia) Description() string {
+	return "Read JMX metrics through Jolokia REST endpoints"
+}
+
+func (j *Jolokia) Gather(acc plugins.Accumulator) error {
+	if len(j.Metrics) == 0 {
+		return nil
+	}
+
+	var errs []string
+	for _, server := range j.Servers {
+		if err := j.gatherServer(acc, server); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// gatherServer reads all metrics from server with a single bulk request
+func (j *Jolokia) gatherServer(acc plugins.Accumulator, server Server) error {
+	reqs := make([]readRequest, len(j.Metrics))
+	for i, m := range j.Metrics {
+		reqs[i] = readRequest{
+			Type:      "read",
+			Mbean:     m.Mbean,
+			Attribute: m.Attribute,
+			Path:      m.Path,
+		}
+	}
+
+	resps, err := j.read(server, reqs)
+	if err != nil {
+		return err
+	}
+	if len(resps) != len(reqs) {
+		return fmt.Errorf("Jolokia %s returned %d responses for %d reads",
+			server.Url, len(resps), len(reqs))
+	}
+
+	var errs []string
+	for i, resp := range resps {
+		m := j.Metrics[i]
+		if resp.Status != http.StatusOK {
+			errs = append(errs, fmt.Sprintf("Reading %s from %s failed, status %d: %s",
+				m.Mbean, server.Url, resp.Status, resp.Error))
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		flatten(fields, "", resp.Value)
+		if len(fields) == 0 {
+			continue
+		}
+		tags := map[string]string{
+			"server": server.Name,
+			"mbean":  m.Mbean,
+		}
+		acc.AddFields(m.Name, fields, tags)
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func (j *Jolokia) read(server Server, reqs []readRequest) ([]readResponse, error) {
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimRight(server.Url, "/")+"/",
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if server.Username != "" || server.Password != "" {
+		req.SetBasicAuth(server.Username, server.Password)
+	}
+
+	resp, err := j.jClient.MakeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Response from url \"%s\" has status code %d (%s), expected %d (%s)",
+			server.Url,
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode),
+			http.StatusOK,
+			http.StatusText(http.StatusOK))
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var resps []readResponse
+	if err := json.Unmarshal(b, &resps); err != nil {
+		return nil, fmt.Errorf("Error decoding JSON response from %s: %s", server.Url, err)
+	}
+	return resps, nil
+}
+
+// flatten adds the numeric and boolean values found in v to fields, keyed by
+// their path joined with "_". A bare value is added as "value".
+func flatten(fields map[string]interface{}, prefix string, v interface{}) {
+	key := prefix
+	if key == "" {
+		key = "value"
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if prefix != "" {
+				k = prefix + "_" + k
+			}
+			flatten(fields, k, child)
+		}
+	case float64:
+		fields[key] = t
+	case bool:
+		fields[key] = t
+	}
+}
+
+func init() {
+	plugins.Add("jolokia", func() plugins.Plugin {
+		return &Jolokia{jClient: &JolokiaClientImpl{client: &http.Client{
+			Timeout: 5 * time.Second,
+		}}}
+	})
+}
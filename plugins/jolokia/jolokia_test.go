@@ -0,0 +1,165 @@
+package jolokia
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const readResponseJSON = `
+[
+  {
+    "request": {
+      "mbean": "java.lang:type=Memory",
+      "attribute": "HeapMemoryUsage",
+      "type": "read"
+    },
+    "value": {
+      "init": 67108864,
+      "committed": 456130560,
+      "max": 477626368,
+      "used": 203288528
+    },
+    "timestamp": 1446129191,
+    "status": 200
+  },
+  {
+    "request": {
+      "mbean": "java.lang:type=Threading",
+      "attribute": "ThreadCount",
+      "type": "read"
+    },
+    "value": 42,
+    "timestamp": 1446129191,
+    "status": 200
+  },
+  {
+    "request": {
+      "mbean": "kafka.server:type=ReplicaManager,name=UnderReplicatedPartitions",
+      "type": "read"
+    },
+    "value": {
+      "Value": 0,
+      "Name": "UnderReplicatedPartitions"
+    },
+    "timestamp": 1446129191,
+    "status": 200
+  }
+]
+`
+
+const errorResponseJSON = `
+[
+  {
+    "request": {"mbean": "java.lang:type=Missing", "type": "read"},
+    "error_type": "javax.management.InstanceNotFoundException",
+    "error": "javax.management.InstanceNotFoundException : java.lang:type=Missing",
+    "status": 404
+  }
+]
+`
+
+type jolokiaClientStub struct {
+	responseBody string
+	statusCode   int
+	requests     []*http.Request
+	bodies       []string
+}
+
+func (c *jolokiaClientStub) MakeRequest(req *http.Request) (*http.Response, error) {
+	body, _ := ioutil.ReadAll(req.Body)
+	c.requests = append(c.requests, req)
+	c.bodies = append(c.bodies, string(body))
+	return &http.Response{
+		StatusCode: c.statusCode,
+		Body:       ioutil.NopCloser(strings.NewReader(c.responseBody)),
+	}, nil
+}
+
+func genJolokiaClientStub(response string, statusCode int, servers []Server, metrics []Metric) (*Jolokia, *jolokiaClientStub) {
+	stub := &jolokiaClientStub{responseBody: response, statusCode: statusCode}
+	return &Jolokia{jClient: stub, Servers: servers, Metrics: metrics}, stub
+}
+
+var servers = []Server{{Name: "as1", Url: "http://127.0.0.1:8778/jolokia", Username: "user", Password: "secret"}}
+
+var metrics = []Metric{
+	{Name: "heap_memory_usage", Mbean: "java.lang:type=Memory", Attribute: "HeapMemoryUsage"},
+	{Name: "thread_count", Mbean: "java.lang:type=Threading", Attribute: "ThreadCount"},
+	{Name: "under_replicated", Mbean: "kafka.server:type=ReplicaManager,name=UnderReplicatedPartitions"},
+}
+
+func TestGather(t *testing.T) {
+	jolokia, stub := genJolokiaClientStub(readResponseJSON, 200, servers, metrics)
+
+	var acc testutil.Accumulator
+	require.NoError(t, jolokia.Gather(&acc))
+	require.Len(t, acc.Points, 3)
+
+	heap := acc.Points[0]
+	assert.Equal(t, "heap_memory_usage", heap.Measurement)
+	assert.Equal(t, map[string]string{
+		"server": "as1",
+		"mbean":  "java.lang:type=Memory",
+	}, heap.Tags)
+	assert.Equal(t, map[string]interface{}{
+		"init":      67108864.0,
+		"committed": 456130560.0,
+		"max":       477626368.0,
+		"used":      203288528.0,
+	}, heap.Values)
+
+	assert.NoError(t, acc.ValidateTaggedValue("thread_count", 42.0, map[string]string{
+		"server": "as1",
+		"mbean":  "java.lang:type=Threading",
+	}))
+
+	// the name of the gauge is not numeric
+	assert.Equal(t, map[string]interface{}{"Value": 0.0}, acc.Points[2].Values)
+
+	require.Len(t, stub.requests, 1)
+	req := stub.requests[0]
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "http://127.0.0.1:8778/jolokia/", req.URL.String())
+	user, pass, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "secret", pass)
+
+	var reads []map[string]string
+	require.NoError(t, json.Unmarshal([]byte(stub.bodies[0]), &reads))
+	assert.Equal(t, []map[string]string{
+		{"type": "read", "mbean": "java.lang:type=Memory", "attribute": "HeapMemoryUsage"},
+		{"type": "read", "mbean": "java.lang:type=Threading", "attribute": "ThreadCount"},
+		{"type": "read", "mbean": "kafka.server:type=ReplicaManager,name=UnderReplicatedPartitions"},
+	}, reads)
+}
+
+func TestGatherReadError(t *testing.T) {
+	jolokia, _ := genJolokiaClientStub(errorResponseJSON, 200, servers,
+		[]Metric{{Name: "missing", Mbean: "java.lang:type=Missing"}})
+
+	var acc testutil.Accumulator
+	err := jolokia.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "InstanceNotFoundException")
+	assert.Len(t, acc.Points, 0)
+}
+
+func TestGatherHTTPError(t *testing.T) {
+	jolokia, _ := genJolokiaClientStub("", 500, servers, metrics)
+
+	var acc testutil.Accumulator
+	assert.Error(t, jolokia.Gather(&acc))
+	assert.Len(t, acc.Points, 0)
+
+	jolokia, _ = genJolokiaClientStub("not json", 200, servers, metrics)
+	assert.Error(t, jolokia.Gather(&acc))
+	assert.Len(t, acc.Points, 0)
+}
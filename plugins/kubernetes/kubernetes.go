@@ -0,0 +1,181 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// Kubernetes reads node and pod stats from the summary API of a kubelet
+type Kubernetes struct {
+	Url string
+
+	// BearerToken is the path to a file holding the token sent to the
+	// kubelet, ie a service account token
+	BearerToken     string
+	ResponseTimeout internal.Duration
+
+	internal.ClientConfig
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  # URL of the kubelet
+  url = "http://127.0.0.1:10255"
+
+  # Path to a file holding the bearer token sent to the kubelet
+  # bearer_token = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+  # Time to wait for the kubelet to respond
+  # response_timeout = "5s"
+
+  # Optional SSL config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_ca_dir = "/etc/telegraf/ca.d"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # Use SSL but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+func (k *Kubernetes) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *Kubernetes) Description() string {
+	return "Read node and pod metrics from the kubelet summary API"
+}
+
+func (k *Kubernetes) Gather(acc plugins.Accumulator) error {
+	if k.client == nil {
+		tlsConfig, err := k.TLSConfig()
+		if err != nil {
+			return err
+		}
+		timeout := k.ResponseTimeout.Duration
+		if timeout == 0 {
+			timeout = 5 * time.Second
+		}
+		k.client = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+			Timeout:   timeout,
+		}
+	}
+
+	summary, err := k.summary()
+	if err != nil {
+		return err
+	}
+	addSummary(acc, summary)
+	return nil
+}
+
+func (k *Kubernetes) summary() (*summary, error) {
+	url := strings.TrimRight(k.Url, "/") + "/stats/summary"
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if k.BearerToken != "" {
+		token, err := ioutil.ReadFile(k.BearerToken)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read bearer token: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Response from url \"%s\" has status code %d (%s), expected %d (%s)",
+			url,
+			resp.StatusCode,
+			http.StatusText(resp.StatusCode),
+			http.StatusOK,
+			http.StatusText(http.StatusOK))
+	}
+
+	s := &summary{}
+	if err := json.NewDecoder(resp.Body).Decode(s); err != nil {
+		return nil, fmt.Errorf("Error decoding stats summary from %s: %s", url, err)
+	}
+	return s, nil
+}
+
+// addSummary adds the stats of the node, and of the containers, network and
+// volumes of each pod
+func addSummary(acc plugins.Accumulator, s *summary) {
+	nodeTags := map[string]string{"node_name": s.Node.NodeName}
+	fields := make(map[string]interface{})
+	s.Node.CPU.addFields(fields, "cpu_")
+	s.Node.Memory.addFields(fields, "memory_")
+	s.Node.Network.addFields(fields, "network_")
+	s.Node.Fs.addFields(fields, "fs_")
+	s.Node.Runtime.ImageFs.addFields(fields, "runtime_image_fs_")
+	addFields(acc, "node", fields, nodeTags)
+
+	for _, pod := range s.Pods {
+		podTags := map[string]string{
+			"node_name": s.Node.NodeName,
+			"namespace": pod.PodRef.Namespace,
+			"pod_name":  pod.PodRef.Name,
+		}
+
+		for _, c := range pod.Containers {
+			tags := withTag(podTags, "container_name", c.Name)
+			fields := make(map[string]interface{})
+			c.CPU.addFields(fields, "cpu_")
+			c.Memory.addFields(fields, "memory_")
+			c.Rootfs.addFields(fields, "rootfs_")
+			c.Logs.addFields(fields, "logsfs_")
+			addFields(acc, "pod_container", fields, tags)
+		}
+
+		fields := make(map[string]interface{})
+		pod.Network.addFields(fields, "")
+		addFields(acc, "pod_network", fields, podTags)
+
+		for _, v := range pod.Volumes {
+			fields := make(map[string]interface{})
+			v.fsStats.addFields(fields, "")
+			addFields(acc, "pod_volume", fields, withTag(podTags, "volume_name", v.Name))
+		}
+	}
+}
+
+// addFields adds a point unless none of its stats were reported
+func addFields(
+	acc plugins.Accumulator,
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+) {
+	if len(fields) > 0 {
+		acc.AddFields(measurement, fields, tags)
+	}
+}
+
+func withTag(tags map[string]string, key, value string) map[string]string {
+	t := map[string]string{key: value}
+	for k, v := range tags {
+		t[k] = v
+	}
+	return t
+}
+
+func init() {
+	plugins.Add("kubernetes", func() plugins.Plugin {
+		return &Kubernetes{}
+	})
+}
@@ -0,0 +1,226 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const summaryJSON = `
+{
+  "node": {
+    "nodeName": "node1",
+    "startTime": "2016-02-22T19:12:59Z",
+    "cpu": {
+      "time": "2016-02-24T13:45:16Z",
+      "usageNanoCores": 56652446,
+      "usageCoreNanoSeconds": 101437561712262
+    },
+    "memory": {
+      "time": "2016-02-24T13:45:16Z",
+      "availableBytes": 6538366976,
+      "usageBytes": 1402986496,
+      "workingSetBytes": 1113771008,
+      "rssBytes": 310599680,
+      "pageFaults": 1153192,
+      "majorPageFaults": 36
+    },
+    "network": {
+      "time": "2016-02-24T13:45:16Z",
+      "rxBytes": 404773995,
+      "rxErrors": 0,
+      "txBytes": 370866566,
+      "txErrors": 0
+    },
+    "fs": {
+      "availableBytes": 16612896768,
+      "capacityBytes": 31279849472,
+      "usedBytes": 13113217024
+    },
+    "runtime": {
+      "imageFs": {
+        "availableBytes": 16612896768,
+        "capacityBytes": 31279849472,
+        "usedBytes": 6835970048
+      }
+    }
+  },
+  "pods": [
+    {
+      "podRef": {
+        "name": "rethinkdb-0",
+        "namespace": "storage",
+        "uid": "b4ba9f2c-dafd-11e5-9def-42010af00002"
+      },
+      "startTime": "2016-02-22T19:14:38Z",
+      "containers": [
+        {
+          "name": "rethinkdb",
+          "startTime": "2016-02-22T19:14:40Z",
+          "cpu": {
+            "time": "2016-02-24T13:45:16Z",
+            "usageNanoCores": 1547547,
+            "usageCoreNanoSeconds": 3826074941
+          },
+          "memory": {
+            "time": "2016-02-24T13:45:16Z",
+            "usageBytes": 11988992,
+            "workingSetBytes": 11988992,
+            "rssBytes": 9015296,
+            "pageFaults": 5181,
+            "majorPageFaults": 0
+          },
+          "rootfs": {
+            "availableBytes": 16612896768,
+            "capacityBytes": 31279849472,
+            "usedBytes": 24576
+          },
+          "logs": {
+            "availableBytes": 16612896768,
+            "capacityBytes": 31279849472,
+            "usedBytes": 8192
+          }
+        }
+      ],
+      "network": {
+        "time": "2016-02-24T13:45:16Z",
+        "rxBytes": 1028293,
+        "rxErrors": 0,
+        "txBytes": 802101,
+        "txErrors": 0
+      },
+      "volume": [
+        {
+          "name": "data",
+          "availableBytes": 7903948800,
+          "capacityBytes": 7903961088,
+          "usedBytes": 12288
+        }
+      ]
+    },
+    {
+      "podRef": {"name": "pending", "namespace": "default"}
+    }
+  ]
+}
+`
+
+func kubeletServer(t *testing.T, token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stats/summary" {
+			http.NotFound(w, r)
+			return
+		}
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(summaryJSON))
+	}))
+}
+
+func pointsByMeasurement(acc *testutil.Accumulator) map[string][]*testutil.Point {
+	points := make(map[string][]*testutil.Point)
+	for _, pt := range acc.Points {
+		points[pt.Measurement] = append(points[pt.Measurement], pt)
+	}
+	return points
+}
+
+func TestGatherSummary(t *testing.T) {
+	ts := kubeletServer(t, "")
+	defer ts.Close()
+
+	k := &Kubernetes{Url: ts.URL + "/"}
+	var acc testutil.Accumulator
+	require.NoError(t, k.Gather(&acc))
+
+	points := pointsByMeasurement(&acc)
+	// the pending pod has no stats yet
+	require.Len(t, points["node"], 1)
+	require.Len(t, points["pod_container"], 1)
+	require.Len(t, points["pod_network"], 1)
+	require.Len(t, points["pod_volume"], 1)
+
+	node := points["node"][0]
+	assert.Equal(t, map[string]string{"node_name": "node1"}, node.Tags)
+	assert.Equal(t, uint64(56652446), node.Values["cpu_usage_nanocores"])
+	assert.Equal(t, uint64(1402986496), node.Values["memory_usage_bytes"])
+	assert.Equal(t, uint64(404773995), node.Values["network_rx_bytes"])
+	assert.Equal(t, uint64(13113217024), node.Values["fs_used_bytes"])
+	assert.Equal(t, uint64(6835970048), node.Values["runtime_image_fs_used_bytes"])
+
+	container := points["pod_container"][0]
+	assert.Equal(t, map[string]string{
+		"node_name":      "node1",
+		"namespace":      "storage",
+		"pod_name":       "rethinkdb-0",
+		"container_name": "rethinkdb",
+	}, container.Tags)
+	assert.Equal(t, uint64(1547547), container.Values["cpu_usage_nanocores"])
+	assert.Equal(t, uint64(11988992), container.Values["memory_working_set_bytes"])
+	assert.Equal(t, uint64(24576), container.Values["rootfs_used_bytes"])
+	assert.Equal(t, uint64(8192), container.Values["logsfs_used_bytes"])
+	// the container has no memory limit
+	_, ok := container.Values["memory_available_bytes"]
+	assert.False(t, ok)
+
+	network := points["pod_network"][0]
+	assert.Equal(t, "rethinkdb-0", network.Tags["pod_name"])
+	assert.Equal(t, uint64(1028293), network.Values["rx_bytes"])
+	assert.Equal(t, uint64(802101), network.Values["tx_bytes"])
+
+	volume := points["pod_volume"][0]
+	assert.Equal(t, "data", volume.Tags["volume_name"])
+	assert.Equal(t, "storage", volume.Tags["namespace"])
+	assert.Equal(t, uint64(12288), volume.Values["used_bytes"])
+}
+
+func TestGatherBearerToken(t *testing.T) {
+	ts := kubeletServer(t, "s3cr3t")
+	defer ts.Close()
+
+	f, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("s3cr3t\n")
+	f.Close()
+
+	k := &Kubernetes{Url: ts.URL}
+	var acc testutil.Accumulator
+	assert.Error(t, k.Gather(&acc))
+	assert.Len(t, acc.Points, 0)
+
+	k.BearerToken = f.Name()
+	require.NoError(t, k.Gather(&acc))
+	assert.NotEmpty(t, acc.Points)
+
+	k.BearerToken = "does/not/exist"
+	assert.Error(t, k.Gather(&acc))
+}
+
+func TestGatherInsecureSkipVerify(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(summaryJSON))
+	}))
+	defer ts.Close()
+
+	// the test server's certificate is self signed
+	k := &Kubernetes{Url: ts.URL}
+	var acc testutil.Accumulator
+	assert.Error(t, k.Gather(&acc))
+
+	k = &Kubernetes{
+		Url:          ts.URL,
+		ClientConfig: internal.ClientConfig{InsecureSkipVerify: true},
+	}
+	require.NoError(t, k.Gather(&acc))
+	assert.NotEmpty(t, acc.Points)
+}
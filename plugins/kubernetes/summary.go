@@ -0,0 +1,104 @@
+package kubernetes
+
+// summary is the response of the kubelet /stats/summary endpoint
+type summary struct {
+	Node nodeStats  `json:"node"`
+	Pods []podStats `json:"pods"`
+}
+
+type nodeStats struct {
+	NodeName string       `json:"nodeName"`
+	CPU      cpuStats     `json:"cpu"`
+	Memory   memoryStats  `json:"memory"`
+	Network  networkStats `json:"network"`
+	Fs       fsStats      `json:"fs"`
+	Runtime  struct {
+		ImageFs fsStats `json:"imageFs"`
+	} `json:"runtime"`
+}
+
+type podStats struct {
+	PodRef struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"podRef"`
+	Containers []containerStats `json:"containers"`
+	Network    networkStats     `json:"network"`
+	Volumes    []volumeStats    `json:"volume"`
+}
+
+type containerStats struct {
+	Name   string      `json:"name"`
+	CPU    cpuStats    `json:"cpu"`
+	Memory memoryStats `json:"memory"`
+	Rootfs fsStats     `json:"rootfs"`
+	Logs   fsStats     `json:"logs"`
+}
+
+type volumeStats struct {
+	Name string `json:"name"`
+	fsStats
+}
+
+// Stats missing from the summary, ie the memory of a container without
+// limits, decode to nil and are not reported
+
+type cpuStats struct {
+	UsageNanoCores       *uint64 `json:"usageNanoCores"`
+	UsageCoreNanoSeconds *uint64 `json:"usageCoreNanoSeconds"`
+}
+
+func (s cpuStats) addFields(fields map[string]interface{}, prefix string) {
+	addField(fields, prefix+"usage_nanocores", s.UsageNanoCores)
+	addField(fields, prefix+"usage_core_nanoseconds", s.UsageCoreNanoSeconds)
+}
+
+type memoryStats struct {
+	AvailableBytes  *uint64 `json:"availableBytes"`
+	UsageBytes      *uint64 `json:"usageBytes"`
+	WorkingSetBytes *uint64 `json:"workingSetBytes"`
+	RSSBytes        *uint64 `json:"rssBytes"`
+	PageFaults      *uint64 `json:"pageFaults"`
+	MajorPageFaults *uint64 `json:"majorPageFaults"`
+}
+
+func (s memoryStats) addFields(fields map[string]interface{}, prefix string) {
+	addField(fields, prefix+"available_bytes", s.AvailableBytes)
+	addField(fields, prefix+"usage_bytes", s.UsageBytes)
+	addField(fields, prefix+"working_set_bytes", s.WorkingSetBytes)
+	addField(fields, prefix+"rss_bytes", s.RSSBytes)
+	addField(fields, prefix+"page_faults", s.PageFaults)
+	addField(fields, prefix+"major_page_faults", s.MajorPageFaults)
+}
+
+type networkStats struct {
+	RxBytes  *uint64 `json:"rxBytes"`
+	RxErrors *uint64 `json:"rxErrors"`
+	TxBytes  *uint64 `json:"txBytes"`
+	TxErrors *uint64 `json:"txErrors"`
+}
+
+func (s networkStats) addFields(fields map[string]interface{}, prefix string) {
+	addField(fields, prefix+"rx_bytes", s.RxBytes)
+	addField(fields, prefix+"rx_errors", s.RxErrors)
+	addField(fields, prefix+"tx_bytes", s.TxBytes)
+	addField(fields, prefix+"tx_errors", s.TxErrors)
+}
+
+type fsStats struct {
+	AvailableBytes *uint64 `json:"availableBytes"`
+	CapacityBytes  *uint64 `json:"capacityBytes"`
+	UsedBytes      *uint64 `json:"usedBytes"`
+}
+
+func (s fsStats) addFields(fields map[string]interface{}, prefix string) {
+	addField(fields, prefix+"available_bytes", s.AvailableBytes)
+	addField(fields, prefix+"capacity_bytes", s.CapacityBytes)
+	addField(fields, prefix+"used_bytes", s.UsedBytes)
+}
+
+func addField(fields map[string]interface{}, key string, value *uint64) {
+	if value != nil {
+		fields[key] = *value
+	}
+}
@@ -11,6 +11,10 @@ import (
 
 type Mysql struct {
 	Servers []string
+
+	// GatherTableStats adds the size of every table from
+	// information_schema.tables
+	GatherTableStats bool
 }
 
 var sampleConfig = `
@@ -23,6 +27,9 @@ var sampleConfig = `
   #
   # If no servers are specified, then localhost is used as the host.
   servers = ["tcp(127.0.0.1:3306)/"]
+
+  # Gather the rows and sizes of every table
+  # gather_table_stats = false
 `
 
 func (m *Mysql) SampleConfig() string {
@@ -113,6 +120,21 @@ var mappings = []*mapping{
 	},
 }
 
+// rowScanner is the part of *sql.Rows used to read a result set
+type rowScanner interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Columns() ([]string, error)
+	Err() error
+}
+
+// serverQuery is a query run on every server and the function adding the
+// metrics found in its result
+type serverQuery struct {
+	query  string
+	gather func(rows rowScanner, servtag string, acc plugins.Accumulator) error
+}
+
 func (m *Mysql) gatherServer(serv string, acc plugins.Accumulator) error {
 	// If user forgot the '/', add it
 	if strings.HasSuffix(serv, ")") {
@@ -128,33 +150,62 @@ func (m *Mysql) gatherServer(serv string, acc plugins.Accumulator) error {
 
 	defer db.Close()
 
-	rows, err := db.Query(`SHOW /*!50002 GLOBAL */ STATUS`)
-	if err != nil {
-		return err
-	}
-
 	var servtag string
 	servtag, err = parseDSN(serv)
 	if err != nil {
 		servtag = "localhost"
 	}
+
+	queries := []serverQuery{
+		{`SHOW /*!50002 GLOBAL */ STATUS`, gatherStatus},
+		{`SHOW GLOBAL VARIABLES`, gatherVariables},
+		{`SHOW SLAVE STATUS`, gatherSlaveStatus},
+		{"SELECT user, sum(1) FROM INFORMATION_SCHEMA.PROCESSLIST GROUP BY user", gatherConnections},
+	}
+	if m.GatherTableStats {
+		queries = append(queries, serverQuery{tableStatsQuery, gatherTableStats})
+	}
+
+	for _, q := range queries {
+		rows, err := db.Query(q.query)
+		if err != nil {
+			return err
+		}
+		err = q.gather(rows, servtag, acc)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// gatherStatus adds the counters of SHOW GLOBAL STATUS
+func gatherStatus(rows rowScanner, servtag string, acc plugins.Accumulator) error {
 	for rows.Next() {
 		var name string
 		var val interface{}
 
-		err = rows.Scan(&name, &val)
+		err := rows.Scan(&name, &val)
 		if err != nil {
 			return err
 		}
 
+		// some status variables, ie Innodb_buffer_pool_dump_status, are
+		// not numeric
+		i, ok := parseValue(val)
+		if !ok {
+			continue
+		}
+
 		var found bool
 
 		tags := map[string]string{"server": servtag}
 
 		for _, mapped := range mappings {
 			if strings.HasPrefix(name, mapped.onServer) {
-				i, _ := strconv.Atoi(string(val.([]byte)))
-				acc.Add(mapped.inExport+name[len(mapped.onServer):], i, tags)
+				acc.Add(mapped.inExport+name[len(mapped.onServer):], int(i), tags)
 				found = true
 			}
 		}
@@ -165,42 +216,163 @@ func (m *Mysql) gatherServer(serv string, acc plugins.Accumulator) error {
 
 		switch name {
 		case "Queries":
-			i, err := strconv.ParseInt(string(val.([]byte)), 10, 64)
-			if err != nil {
-				return err
-			}
-
 			acc.Add("queries", i, tags)
 		case "Slow_queries":
-			i, err := strconv.ParseInt(string(val.([]byte)), 10, 64)
-			if err != nil {
-				return err
-			}
-
 			acc.Add("slow_queries", i, tags)
 		}
 	}
 
-	conn_rows, err := db.Query("SELECT user, sum(1) FROM INFORMATION_SCHEMA.PROCESSLIST GROUP BY user")
+	return rows.Err()
+}
+
+// variables are the settings of SHOW GLOBAL VARIABLES reported as
+// "variables_<name>", as they bound the status counters
+var variables = map[string]bool{
+	"innodb_buffer_pool_size": true,
+	"innodb_log_file_size":    true,
+	"key_buffer_size":         true,
+	"max_connections":         true,
+	"max_user_connections":    true,
+	"open_files_limit":        true,
+	"query_cache_size":        true,
+	"table_open_cache":        true,
+	"thread_cache_size":       true,
+}
+
+// gatherVariables adds the settings of SHOW GLOBAL VARIABLES listed in
+// variables
+func gatherVariables(rows rowScanner, servtag string, acc plugins.Accumulator) error {
+	tags := map[string]string{"server": servtag}
+	for rows.Next() {
+		var name string
+		var val interface{}
+
+		if err := rows.Scan(&name, &val); err != nil {
+			return err
+		}
+		if !variables[strings.ToLower(name)] {
+			continue
+		}
+		if i, ok := parseValue(val); ok {
+			acc.Add("variables_"+strings.ToLower(name), i, tags)
+		}
+	}
+
+	return rows.Err()
+}
+
+// gatherSlaveStatus adds the replication lag and thread states of a slave.
+// SHOW SLAVE STATUS returns no rows on servers that are not slaves.
+func gatherSlaveStatus(rows rowScanner, servtag string, acc plugins.Accumulator) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	tags := map[string]string{"server": servtag}
+	for rows.Next() {
+		vals := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+
+		for i, column := range columns {
+			switch column {
+			case "Seconds_Behind_Master":
+				// NULL while replication is stopped
+				if lag, ok := parseValue(vals[i]); ok {
+					acc.Add("slave_seconds_behind_master", lag, tags)
+				}
+			case "Slave_IO_Running", "Slave_SQL_Running":
+				running := int64(0)
+				if b, ok := vals[i].([]byte); ok && string(b) == "Yes" {
+					running = 1
+				}
+				acc.Add(strings.ToLower(column), running, tags)
+			}
+		}
+	}
+
+	return rows.Err()
+}
 
-	for conn_rows.Next() {
+// gatherConnections adds the number of connections of each user
+func gatherConnections(rows rowScanner, servtag string, acc plugins.Accumulator) error {
+	for rows.Next() {
 		var user string
 		var connections int64
 
-		err = conn_rows.Scan(&user, &connections)
+		err := rows.Scan(&user, &connections)
 		if err != nil {
 			return err
 		}
 
 		tags := map[string]string{"server": servtag, "user": user}
+		acc.Add("connections", connections, tags)
+	}
 
+	return rows.Err()
+}
+
+const tableStatsQuery = `
+SELECT table_schema, table_name, table_rows, data_length, index_length, data_free
+FROM information_schema.tables
+WHERE table_schema NOT IN ('mysql', 'information_schema', 'performance_schema')
+`
+
+// gatherTableStats adds the size of each table, tagged by schema and table
+func gatherTableStats(rows rowScanner, servtag string, acc plugins.Accumulator) error {
+	for rows.Next() {
+		var schema, table string
+		var tableRows, dataLength, indexLength, dataFree interface{}
+
+		err := rows.Scan(&schema, &table, &tableRows, &dataLength, &indexLength, &dataFree)
 		if err != nil {
 			return err
 		}
-		acc.Add("connections", connections, tags)
+
+		fields := make(map[string]interface{})
+		for key, val := range map[string]interface{}{
+			"rows":         tableRows,
+			"data_length":  dataLength,
+			"index_length": indexLength,
+			"data_free":    dataFree,
+		} {
+			// views have no sizes
+			if i, ok := parseValue(val); ok {
+				fields[key] = i
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		tags := map[string]string{
+			"server": servtag,
+			"schema": schema,
+			"table":  table,
+		}
+		acc.AddFields("schema_tables", fields, tags)
 	}
 
-	return nil
+	return rows.Err()
+}
+
+// parseValue returns the integer held by a column scanned into an
+// interface{}, which the driver returns as []byte or int64
+func parseValue(val interface{}) (int64, bool) {
+	switch v := val.(type) {
+	case int64:
+		return v, true
+	case []byte:
+		i, err := strconv.ParseInt(string(v), 10, 64)
+		return i, err == nil
+	}
+	return 0, false
 }
 
 func init() {
@@ -140,3 +140,158 @@ func TestMysqlParseDSN(t *testing.T) {
 		}
 	}
 }
+
+// fakeRows is a result set returned by the server
+type fakeRows struct {
+	columns []string
+	rows    [][]interface{}
+	i       int
+}
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	row := r.rows[r.i-1]
+	if len(dest) != len(row) {
+		return fmt.Errorf("expected %d destinations, got %d", len(row), len(dest))
+	}
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *string:
+			*d = string(row[i].([]byte))
+		case *int64:
+			*d = row[i].(int64)
+		case *interface{}:
+			*d = row[i]
+		default:
+			return fmt.Errorf("unsupported destination %T", d)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRows) Columns() ([]string, error) {
+	return r.columns, nil
+}
+
+func (r *fakeRows) Err() error {
+	return nil
+}
+
+// nameValueRows returns the result of SHOW STATUS or SHOW VARIABLES
+func nameValueRows(pairs ...string) *fakeRows {
+	rows := &fakeRows{columns: []string{"Variable_name", "Value"}}
+	for i := 0; i < len(pairs); i += 2 {
+		rows.rows = append(rows.rows, []interface{}{[]byte(pairs[i]), []byte(pairs[i+1])})
+	}
+	return rows
+}
+
+var servtags = map[string]string{"server": "127.0.0.1:3306"}
+
+func TestMysqlGatherStatus(t *testing.T) {
+	rows := nameValueRows(
+		"Aborted_clients", "3",
+		"Com_select", "1234",
+		"Innodb_buffer_pool_pages_free", "8000",
+		"Innodb_buffer_pool_dump_status", "not started",
+		"Queries", "98765",
+		"Slow_queries", "12",
+		"Threads_connected", "7",
+		"Uptime", "3600",
+	)
+
+	var acc testutil.Accumulator
+	require.NoError(t, gatherStatus(rows, "127.0.0.1:3306", &acc))
+	assert.Len(t, acc.Points, 6)
+
+	assert.NoError(t, acc.ValidateTaggedValue("aborted_clients", 3, servtags))
+	assert.NoError(t, acc.ValidateTaggedValue("commands_select", 1234, servtags))
+	assert.NoError(t, acc.ValidateTaggedValue("innodb_buffer_pool_pages_free", 8000, servtags))
+	assert.NoError(t, acc.ValidateTaggedValue("threads_connected", 7, servtags))
+	assert.NoError(t, acc.ValidateTaggedValue("queries", int64(98765), servtags))
+	assert.NoError(t, acc.ValidateTaggedValue("slow_queries", int64(12), servtags))
+	assert.False(t, acc.HasMeasurement("innodb_buffer_pool_dump_status"))
+}
+
+func TestMysqlGatherVariables(t *testing.T) {
+	rows := nameValueRows(
+		"max_connections", "151",
+		"innodb_buffer_pool_size", "134217728",
+		"version", "5.6.27",
+	)
+
+	var acc testutil.Accumulator
+	require.NoError(t, gatherVariables(rows, "127.0.0.1:3306", &acc))
+	assert.Len(t, acc.Points, 2)
+
+	assert.NoError(t, acc.ValidateTaggedValue("variables_max_connections", int64(151), servtags))
+	assert.NoError(t, acc.ValidateTaggedValue("variables_innodb_buffer_pool_size",
+		int64(134217728), servtags))
+}
+
+func TestMysqlGatherSlaveStatus(t *testing.T) {
+	rows := &fakeRows{
+		columns: []string{"Slave_IO_State", "Master_Host", "Slave_IO_Running",
+			"Slave_SQL_Running", "Seconds_Behind_Master"},
+		rows: [][]interface{}{{
+			[]byte("Waiting for master to send event"),
+			[]byte("10.0.0.1"),
+			[]byte("Yes"),
+			[]byte("No"),
+			[]byte("42"),
+		}},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, gatherSlaveStatus(rows, "127.0.0.1:3306", &acc))
+	assert.Len(t, acc.Points, 3)
+
+	assert.NoError(t, acc.ValidateTaggedValue("slave_seconds_behind_master", int64(42), servtags))
+	assert.NoError(t, acc.ValidateTaggedValue("slave_io_running", int64(1), servtags))
+	assert.NoError(t, acc.ValidateTaggedValue("slave_sql_running", int64(0), servtags))
+
+	// replication is stopped
+	rows.rows[0][4] = nil
+	rows.i = 0
+	acc = testutil.Accumulator{}
+	require.NoError(t, gatherSlaveStatus(rows, "127.0.0.1:3306", &acc))
+	assert.False(t, acc.HasMeasurement("slave_seconds_behind_master"))
+
+	// not a slave
+	acc = testutil.Accumulator{}
+	require.NoError(t, gatherSlaveStatus(&fakeRows{columns: rows.columns}, "127.0.0.1:3306", &acc))
+	assert.Len(t, acc.Points, 0)
+}
+
+func TestMysqlGatherTableStats(t *testing.T) {
+	rows := &fakeRows{
+		columns: []string{"table_schema", "table_name", "table_rows",
+			"data_length", "index_length", "data_free"},
+		rows: [][]interface{}{
+			{[]byte("app"), []byte("users"), int64(1000), int64(65536), int64(16384), int64(0)},
+			{[]byte("app"), []byte("active_users"), nil, nil, nil, nil},
+		},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, gatherTableStats(rows, "127.0.0.1:3306", &acc))
+	require.Len(t, acc.Points, 1)
+
+	pt := acc.Points[0]
+	assert.Equal(t, "schema_tables", pt.Measurement)
+	assert.Equal(t, map[string]string{
+		"server": "127.0.0.1:3306",
+		"schema": "app",
+		"table":  "users",
+	}, pt.Tags)
+	assert.Equal(t, map[string]interface{}{
+		"rows":         int64(1000),
+		"data_length":  int64(65536),
+		"index_length": int64(16384),
+		"data_free":    int64(0),
+	}, pt.Values)
+}
@@ -0,0 +1,216 @@
+package net_response
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// Results of a check besides those of internal.ScrapeResult
+const (
+	resultReadFailed     = "read_failed"
+	resultStringMismatch = "string_mismatch"
+)
+
+// NetResponse checks that TCP or UDP ports answer
+type NetResponse struct {
+	Checks []Check
+
+	// Timeout to connect and ReadTimeout to wait for the response, used by
+	// checks that do not set their own
+	Timeout     internal.Duration
+	ReadTimeout internal.Duration
+}
+
+// Check is a single port to check
+type Check struct {
+	Protocol string
+	Address  string
+
+	// Send is written once connected, Expect is a regular expression the
+	// first line of the response must match
+	Send   string
+	Expect string
+
+	Timeout     internal.Duration
+	ReadTimeout internal.Duration
+}
+
+var sampleConfig = `
+  # Default timeouts to connect and to read the response
+  timeout = "1s"
+  read_timeout = "1s"
+
+  # Specify checks via an array of tables
+  [[net_response.checks]]
+    # tcp or udp
+    protocol = "tcp"
+    # host:port to connect to
+    address = "localhost:28015"
+    # Optional string to send once connected, required for udp
+    # send = "ping"
+    # Optional regular expression the first line of the response must match
+    # expect = "pong"
+    # timeout = "1s"
+    # read_timeout = "1s"
+`
+
+func (n *NetResponse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NetResponse) Description() string {
+	return "TCP or UDP 'ping' given a host:port, optionally checking the response"
+}
+
+func (n *NetResponse) Gather(acc plugins.Accumulator) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []string
+	for _, c := range n.Checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+			if err := n.check(c, acc); err != nil {
+				mu.Lock()
+				errs = append(errs, err.Error())
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// check runs a single check, adding its result to acc. Only an invalid
+// check is returned as an error, an unreachable port is reported by
+// result_type.
+func (n *NetResponse) check(c Check, acc plugins.Accumulator) error {
+	host, port, err := net.SplitHostPort(c.Address)
+	if err != nil {
+		return fmt.Errorf("Invalid address '%s': %s", c.Address, err)
+	}
+
+	var expect *regexp.Regexp
+	if c.Expect != "" {
+		expect, err = regexp.Compile(c.Expect)
+		if err != nil {
+			return fmt.Errorf("Invalid expect '%s': %s", c.Expect, err)
+		}
+	}
+
+	switch c.Protocol {
+	case "tcp":
+	case "udp":
+		if c.Send == "" {
+			return fmt.Errorf("A string to send is required to check udp address %s",
+				c.Address)
+		}
+	default:
+		return fmt.Errorf("Invalid protocol '%s', must be tcp or udp", c.Protocol)
+	}
+
+	timeout := c.Timeout.Duration
+	if timeout == 0 {
+		timeout = n.Timeout.Duration
+	}
+	readTimeout := c.ReadTimeout.Duration
+	if readTimeout == 0 {
+		readTimeout = n.ReadTimeout.Duration
+	}
+
+	tags := map[string]string{
+		"protocol": c.Protocol,
+		"server":   host,
+		"port":     port,
+	}
+	fields := make(map[string]interface{})
+
+	start := time.Now()
+	result := dial(c, timeout, readTimeout, expect)
+	fields["response_time"] = time.Since(start).Seconds()
+	if expect != nil {
+		found := 0
+		if result == internal.ScrapeSuccess {
+			found = 1
+		}
+		fields["string_found"] = found
+	}
+
+	tags["result_type"] = result
+	acc.AddFields("check", fields, tags)
+	return nil
+}
+
+// dial connects to the address of c, sends and reads as configured, and
+// returns the result type
+func dial(c Check, timeout, readTimeout time.Duration, expect *regexp.Regexp) string {
+	conn, err := net.DialTimeout(c.Protocol, c.Address, timeout)
+	if err != nil {
+		return internal.ScrapeResult(err)
+	}
+	defer conn.Close()
+
+	if c.Send != "" {
+		if _, err := conn.Write([]byte(c.Send)); err != nil {
+			return internal.ScrapeResult(err)
+		}
+	}
+	// udp has no connection, so a reply is needed to know the port answered
+	if expect == nil && c.Protocol == "tcp" {
+		return internal.ScrapeSuccess
+	}
+
+	if readTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+	}
+	var line string
+	if c.Protocol == "udp" {
+		buf := make([]byte, 1024)
+		var n int
+		n, err = conn.Read(buf)
+		line = string(buf[:n])
+	} else {
+		line, err = bufio.NewReader(conn).ReadString('\n')
+		if err != nil && line != "" {
+			// a response without a trailing newline
+			err = nil
+		}
+	}
+	if err != nil {
+		if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+			return internal.ScrapeTimeout
+		}
+		if c.Protocol == "udp" {
+			// the port is closed when the read is refused
+			return internal.ScrapeConnectionFailed
+		}
+		return resultReadFailed
+	}
+
+	if expect != nil && !expect.MatchString(line) {
+		return resultStringMismatch
+	}
+	return internal.ScrapeSuccess
+}
+
+func init() {
+	plugins.Add("net_response", func() plugins.Plugin {
+		return &NetResponse{
+			Timeout:     internal.Duration{Duration: time.Second},
+			ReadTimeout: internal.Duration{Duration: time.Second},
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package net_response
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tcpEchoServer answers every line it reads with "pong <line>"
+func tcpEchoServer(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetReadDeadline(time.Now().Add(time.Second))
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				conn.Write([]byte("pong " + line))
+			}()
+		}
+	}()
+	return l
+}
+
+func gatherCheck(t *testing.T, c Check) *testutil.Point {
+	n := &NetResponse{
+		Checks:      []Check{c},
+		Timeout:     internal.Duration{Duration: time.Second},
+		ReadTimeout: internal.Duration{Duration: time.Second},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.Len(t, acc.Points, 1)
+	pt := acc.Points[0]
+	assert.Equal(t, "check", pt.Measurement)
+	assert.IsType(t, float64(0), pt.Values["response_time"])
+	return pt
+}
+
+func TestTCPConnect(t *testing.T) {
+	l := tcpEchoServer(t)
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	pt := gatherCheck(t, Check{Protocol: "tcp", Address: l.Addr().String()})
+	assert.Equal(t, map[string]string{
+		"protocol":    "tcp",
+		"server":      "127.0.0.1",
+		"port":        port,
+		"result_type": "success",
+	}, pt.Tags)
+	_, ok := pt.Values["string_found"]
+	assert.False(t, ok)
+}
+
+func TestTCPExpect(t *testing.T) {
+	l := tcpEchoServer(t)
+	defer l.Close()
+
+	pt := gatherCheck(t, Check{
+		Protocol: "tcp",
+		Address:  l.Addr().String(),
+		Send:     "ping\n",
+		Expect:   "^pong ping",
+	})
+	assert.Equal(t, "success", pt.Tags["result_type"])
+	assert.Equal(t, 1, pt.Values["string_found"])
+
+	pt = gatherCheck(t, Check{
+		Protocol: "tcp",
+		Address:  l.Addr().String(),
+		Send:     "ping\n",
+		Expect:   "^pang",
+	})
+	assert.Equal(t, "string_mismatch", pt.Tags["result_type"])
+	assert.Equal(t, 0, pt.Values["string_found"])
+}
+
+func TestTCPReadTimeout(t *testing.T) {
+	l := tcpEchoServer(t)
+	defer l.Close()
+
+	// the server waits for a line that never comes
+	pt := gatherCheck(t, Check{
+		Protocol:    "tcp",
+		Address:     l.Addr().String(),
+		Expect:      "pong",
+		ReadTimeout: internal.Duration{Duration: 50 * time.Millisecond},
+	})
+	assert.Equal(t, "timeout", pt.Tags["result_type"])
+}
+
+func TestTCPConnectionRefused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	pt := gatherCheck(t, Check{Protocol: "tcp", Address: addr})
+	assert.Equal(t, "connection_failed", pt.Tags["result_type"])
+}
+
+func TestUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		conn.WriteTo(append([]byte("pong "), buf[:n]...), addr)
+	}()
+
+	pt := gatherCheck(t, Check{
+		Protocol: "udp",
+		Address:  conn.LocalAddr().String(),
+		Send:     "ping",
+		Expect:   "pong ping",
+	})
+	assert.Equal(t, "success", pt.Tags["result_type"])
+	assert.Equal(t, 1, pt.Values["string_found"])
+}
+
+func TestUDPConnectionRefused(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := conn.LocalAddr().String()
+	conn.Close()
+
+	pt := gatherCheck(t, Check{Protocol: "udp", Address: addr, Send: "ping"})
+	assert.Equal(t, "connection_failed", pt.Tags["result_type"])
+}
+
+func TestBadChecks(t *testing.T) {
+	for _, c := range []Check{
+		{Protocol: "tcp", Address: "localhost"},
+		{Protocol: "icmp", Address: "localhost:80"},
+		{Protocol: "udp", Address: "localhost:53"},
+		{Protocol: "tcp", Address: "localhost:80", Expect: "("},
+	} {
+		n := &NetResponse{Checks: []Check{c}}
+		var acc testutil.Accumulator
+		assert.Error(t, n.Gather(&acc), c.Address)
+		assert.Len(t, acc.Points, 0)
+	}
+}
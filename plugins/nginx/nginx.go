@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influxdb/telegraf/internal"
 	"github.com/influxdb/telegraf/plugins"
 )
 
@@ -60,7 +61,9 @@ var tr = &http.Transport{
 var client = &http.Client{Transport: tr}
 
 func (n *Nginx) gatherUrl(addr *url.URL, acc plugins.Accumulator) error {
+	start := time.Now()
 	resp, err := client.Get(addr.String())
+	addScrapeStats(addr, time.Since(start), resp, err, acc)
 	if err != nil {
 		return fmt.Errorf("error making HTTP request to %s: %s", addr.String(), err)
 	}
@@ -139,6 +142,21 @@ func (n *Nginx) gatherUrl(addr *url.URL, acc plugins.Accumulator) error {
 	return nil
 }
 
+// addScrapeStats adds the response time and status code of a status request,
+// whether or not it succeeded
+func addScrapeStats(
+	addr *url.URL,
+	elapsed time.Duration,
+	resp *http.Response,
+	err error,
+	acc plugins.Accumulator,
+) {
+	fields, result := internal.ScrapeStats(elapsed, resp, err)
+	tags := getTags(addr)
+	tags["result"] = result
+	acc.AddFields("scrape", fields, tags)
+}
+
 // Get tag(s) for the nginx plugin
 func getTags(addr *url.URL) map[string]string {
 	h := addr.Host
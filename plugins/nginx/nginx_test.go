@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/influxdb/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
@@ -89,3 +90,69 @@ func TestNginxGeneratesMetrics(t *testing.T) {
 		assert.NoError(t, acc.ValidateTaggedValue(m.name, m.value, tags))
 	}
 }
+
+func scrapeTags(t *testing.T, ts *httptest.Server, result string) map[string]string {
+	addr, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	tags := getTags(addr)
+	tags["result"] = result
+	return tags
+}
+
+func TestNginxScrapeStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sampleResponse)
+	}))
+	defer ts.Close()
+
+	n := &Nginx{Urls: []string{ts.URL}}
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+
+	pt, ok := acc.Get("scrape")
+	require.True(t, ok)
+	assert.Equal(t, scrapeTags(t, ts, "success"), pt.Tags)
+	assert.Equal(t, http.StatusOK, pt.Values["http_response_code"])
+	assert.IsType(t, float64(0), pt.Values["response_time_ms"])
+}
+
+func TestNginxScrapeStatsError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	n := &Nginx{Urls: []string{ts.URL}}
+	var acc testutil.Accumulator
+	assert.Error(t, n.Gather(&acc))
+
+	require.Len(t, acc.Points, 1)
+	pt := acc.Points[0]
+	assert.Equal(t, "scrape", pt.Measurement)
+	assert.Equal(t, scrapeTags(t, ts, "success"), pt.Tags)
+	assert.Equal(t, http.StatusInternalServerError, pt.Values["http_response_code"])
+}
+
+func TestNginxScrapeStatsTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	defaultClient := client
+	client = &http.Client{Timeout: 50 * time.Millisecond}
+	defer func() { client = defaultClient }()
+
+	n := &Nginx{Urls: []string{ts.URL}}
+	var acc testutil.Accumulator
+	assert.Error(t, n.Gather(&acc))
+
+	require.Len(t, acc.Points, 1)
+	pt := acc.Points[0]
+	assert.Equal(t, scrapeTags(t, ts, "timeout"), pt.Tags)
+	_, ok := pt.Values["http_response_code"]
+	assert.False(t, ok)
+	assert.True(t, pt.Values["response_time_ms"].(float64) >= 50)
+}
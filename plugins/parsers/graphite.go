@@ -0,0 +1,67 @@
+package parsers
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/influxdb/influxdb/models"
+	"github.com/influxdb/influxdb/services/graphite"
+)
+
+// GraphiteParser parses the carbon plaintext protocol, one
+// "<path> <value> [timestamp]" per line, naming the points with InfluxDB's
+// graphite templates: "[filter] <template> [tag=value,...]", ie
+// "servers.* .host.measurement.field". Paths not matching any filter are the
+// measurement.
+type GraphiteParser struct {
+	parser *graphite.Parser
+}
+
+// NewGraphiteParser returns a GraphiteParser, the separator joins the parts
+// of a path making the measurement or field and defaults to "."
+func NewGraphiteParser(templates []string, separator string) (*GraphiteParser, error) {
+	if separator == "" {
+		separator = graphite.DefaultSeparator
+	}
+	config := &graphite.Config{Templates: templates, Separator: separator}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	p, err := graphite.NewParserWithOptions(graphite.Options{
+		Templates: templates,
+		Separator: separator,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &GraphiteParser{parser: p}, nil
+}
+
+// Parse parses every line of buf, failing at the first invalid one
+func (p *GraphiteParser) Parse(buf []byte) ([]models.Point, error) {
+	var points []models.Point
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		pt, err := p.parser.Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, pt)
+	}
+	return points, scanner.Err()
+}
+
+func init() {
+	Add("graphite", func(config *Config) (Parser, error) {
+		graphite, err := NewGraphiteParser(config.Templates, config.Separator)
+		if err != nil {
+			return nil, err
+		}
+		return graphite, nil
+	})
+}
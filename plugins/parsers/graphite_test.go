@@ -0,0 +1,64 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphiteParse(t *testing.T) {
+	p, err := NewParser(&Config{
+		DataFormat: "graphite",
+		Templates: []string{
+			"rethinkdb.* .host.measurement.field dc=east",
+			"servers.* .host.measurement*",
+		},
+	})
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte("rethinkdb.db1.engine.queries_per_sec 7.5 1447196400\n" +
+		"\n" +
+		"servers.web1.cpu.load.shortterm 0.42 1447196400\n" +
+		"carbon.agents.relay 3 1447196400\n"))
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+
+	assert.Equal(t, "engine", points[0].Name())
+	assert.Equal(t, map[string]string{"host": "db1", "dc": "east"},
+		map[string]string(points[0].Tags()))
+	assert.Equal(t, map[string]interface{}{"queries_per_sec": 7.5},
+		map[string]interface{}(points[0].Fields()))
+	assert.Equal(t, time.Unix(1447196400, 0), points[0].Time())
+
+	assert.Equal(t, "cpu.load.shortterm", points[1].Name())
+	assert.Equal(t, map[string]string{"host": "web1"}, map[string]string(points[1].Tags()))
+	assert.Equal(t, 0.42, points[1].Fields()["value"])
+
+	// paths matching no template are the measurement
+	assert.Equal(t, "carbon.agents.relay", points[2].Name())
+	assert.Equal(t, 3.0, points[2].Fields()["value"])
+}
+
+func TestGraphiteSeparator(t *testing.T) {
+	p, err := NewGraphiteParser([]string{"measurement.measurement.field"}, "_")
+	require.NoError(t, err)
+	points, err := p.Parse([]byte("rethinkdb.engine.clients 2"))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, "rethinkdb_engine", points[0].Name())
+	assert.Equal(t, 2.0, points[0].Fields()["clients"])
+}
+
+func TestGraphiteErrors(t *testing.T) {
+	_, err := NewGraphiteParser([]string{"servers.* .host.measurement* dc"}, "")
+	assert.Error(t, err)
+
+	p, err := NewGraphiteParser(nil, "")
+	require.NoError(t, err)
+	for _, line := range []string{"cpu.load", "cpu.load high", "cpu.load 1 yesterday"} {
+		_, err := p.Parse([]byte(line))
+		assert.Error(t, err, line)
+	}
+}
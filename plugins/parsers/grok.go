@@ -0,0 +1,271 @@
+package parsers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+)
+
+// GrokPatterns are the built-in grok patterns that may be referenced in the
+// patterns of a GrokParser
+var GrokPatterns = map[string]string{
+	"INT":          `[+-]?\d+`,
+	"NUMBER":       `[+-]?(?:\d+(?:\.\d+)?|\.\d+)`,
+	"WORD":         `\w+`,
+	"NOTSPACE":     `\S+`,
+	"SPACE":        `\s*`,
+	"DATA":         `.*?`,
+	"GREEDYDATA":   `.*`,
+	"IP":           `(?:\d{1,3}\.){3}\d{1,3}`,
+	"QUOTEDSTRING": `"(?:[^"\\]|\\.)*"`,
+	"HTTPDATE":     `\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+}
+
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::(\w+))?(?::(\w+))?\}`)
+
+// maximum depth of patterns referencing patterns
+const grokMaxDepth = 16
+
+// GrokParser parses text, one point per line, with grok patterns: regular
+// expressions that may reference named patterns as %{PATTERN},
+// %{PATTERN:name} or %{PATTERN:name:type}. A named reference is captured as
+// a field, and so is a named capture (?P<name>...) of the regular expression.
+//
+// The type of a capture is one of:
+//
+//	int     an int64
+//	float   a float64
+//	bool    a bool
+//	string  the text as is
+//	tag     a tag rather than a field
+//
+// Untyped captures are int64 or float64 when they hold a number and strings
+// otherwise, and the captures named in TagKeys are tags. Empty captures and
+// captures failing their type conversion are skipped.
+//
+// The first of Patterns matching a line is used, lines without a match or
+// without fields are skipped. Points are named MetricName, "grok" by default,
+// and timestamped with the time they are parsed at.
+type GrokParser struct {
+	Patterns []string
+	// CustomPatterns defines patterns, one "NAME regexp" per line, that may be
+	// referenced like the built-in ones and override them. Empty lines and
+	// lines starting with # are ignored.
+	CustomPatterns string
+	MetricName     string
+	TagKeys        []string
+
+	compiled []*grokPattern
+
+	// now returns the timestamp of the points, replaced in tests
+	now func() time.Time
+}
+
+type grokPattern struct {
+	re    *regexp.Regexp
+	types map[string]string
+}
+
+// NewGrokParser returns a GrokParser with its patterns compiled
+func NewGrokParser(
+	patterns []string,
+	customPatterns string,
+	metricName string,
+	tagKeys []string,
+) (*GrokParser, error) {
+	p := &GrokParser{
+		Patterns:       patterns,
+		CustomPatterns: customPatterns,
+		MetricName:     metricName,
+		TagKeys:        tagKeys,
+	}
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *GrokParser) compile() error {
+	if len(p.Patterns) == 0 {
+		return fmt.Errorf("No grok pattern")
+	}
+
+	definitions := make(map[string]string)
+	for k, v := range GrokPatterns {
+		definitions[k] = v
+	}
+	scanner := bufio.NewScanner(strings.NewReader(p.CustomPatterns))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return fmt.Errorf("Invalid grok pattern definition '%s'", line)
+		}
+		definitions[parts[0]] = strings.TrimSpace(parts[1])
+	}
+
+	p.compiled = nil
+	for _, pattern := range p.Patterns {
+		types := make(map[string]string)
+		expanded, err := expandGrok(pattern, definitions, types, 0)
+		if err != nil {
+			return err
+		}
+		re, err := regexp.Compile(expanded)
+		if err != nil {
+			return fmt.Errorf("Invalid pattern '%s': %s", pattern, err)
+		}
+		p.compiled = append(p.compiled, &grokPattern{re: re, types: types})
+	}
+	return nil
+}
+
+// expandGrok replaces the grok references of pattern with the regular
+// expressions they stand for, capturing the named ones. The types of the
+// named references are added to types. Unknown patterns are left as is.
+func expandGrok(
+	pattern string,
+	definitions map[string]string,
+	types map[string]string,
+	depth int,
+) (string, error) {
+	if depth > grokMaxDepth {
+		return "", fmt.Errorf("Grok patterns nested too deep in '%s'", pattern)
+	}
+
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		m := grokReference.FindStringSubmatch(ref)
+		definition, ok := definitions[m[1]]
+		if !ok || err != nil {
+			return ref
+		}
+		switch m[3] {
+		case "", "int", "float", "bool", "string", "tag":
+		default:
+			err = fmt.Errorf("Invalid grok type '%s' in '%s'", m[3], ref)
+			return ref
+		}
+
+		var re string
+		re, err = expandGrok(definition, definitions, types, depth+1)
+		if m[2] == "" {
+			return "(?:" + re + ")"
+		}
+		if m[3] != "" {
+			types[m[2]] = m[3]
+		}
+		return "(?P<" + m[2] + ">" + re + ")"
+	})
+	return expanded, err
+}
+
+func (p *GrokParser) Parse(buf []byte) ([]models.Point, error) {
+	if p.compiled == nil {
+		if err := p.compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	name := p.MetricName
+	if name == "" {
+		name = "grok"
+	}
+
+	var points []models.Point
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		fields, tags := p.parseLine(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		points = append(points, models.NewPoint(name, tags, fields, now()))
+	}
+	return points, scanner.Err()
+}
+
+// parseLine returns the fields and tags captured from line by the first
+// matching pattern, or no fields when none matches
+func (p *GrokParser) parseLine(line string) (map[string]interface{}, map[string]string) {
+	fields := make(map[string]interface{})
+	tags := make(map[string]string)
+	for _, pattern := range p.compiled {
+		match := pattern.re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		for i, name := range pattern.re.SubexpNames() {
+			if name == "" || match[i] == "" {
+				continue
+			}
+			typ := pattern.types[name]
+			if typ == "tag" || (typ == "" && contains(p.TagKeys, name)) {
+				tags[name] = match[i]
+				continue
+			}
+			if value, ok := convert(match[i], typ); ok {
+				fields[name] = value
+			}
+		}
+		break
+	}
+	return fields, tags
+}
+
+// convert returns s as a value of the given grok type
+func convert(s, typ string) (interface{}, bool) {
+	switch typ {
+	case "int":
+		i, err := strconv.ParseInt(s, 10, 64)
+		return i, err == nil
+	case "float":
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	case "bool":
+		b, err := strconv.ParseBool(s)
+		return b, err == nil
+	case "string":
+		return s, true
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return s, true
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	Add("grok", func(config *Config) (Parser, error) {
+		grok, err := NewGrokParser(config.Patterns, config.CustomPatterns,
+			config.MetricName, config.TagKeys)
+		if err != nil {
+			return nil, err
+		}
+		return grok, nil
+	})
+}
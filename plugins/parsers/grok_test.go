@@ -0,0 +1,117 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const accessLog = `10.0.0.1 - - [10/Nov/2015:23:00:00 +0000] "GET /dbs/test HTTP/1.1" 200 2326 0.042 true`
+
+const accessPattern = `%{IP:client} \S+ \S+ \[%{HTTPDATE}\] "%{WORD:method} %{NOTSPACE:request} \S+" %{INT:status:tag} %{INT:bytes:int} %{NUMBER:duration:float} %{WORD:cached:bool}`
+
+func TestGrokParse(t *testing.T) {
+	p, err := NewGrokParser([]string{accessPattern}, "", "apache_access", []string{"method"})
+	require.NoError(t, err)
+	p.now = func() time.Time { return testTime }
+
+	points, err := p.Parse([]byte(accessLog + "\nnot a log line\n"))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+
+	pt := points[0]
+	assert.Equal(t, "apache_access", pt.Name())
+	assert.Equal(t, map[string]string{"method": "GET", "status": "200"},
+		map[string]string(pt.Tags()))
+	assert.Equal(t, map[string]interface{}{
+		"client":   "10.0.0.1",
+		"request":  "/dbs/test",
+		"bytes":    int64(2326),
+		"duration": 0.042,
+		"cached":   true,
+	}, map[string]interface{}(pt.Fields()))
+	assert.Equal(t, testTime, pt.Time())
+}
+
+func TestGrokUntypedCaptures(t *testing.T) {
+	p, err := NewParser(&Config{
+		DataFormat: "grok",
+		Patterns:   []string{`%{WORD:name} %{NUMBER:count} %{NUMBER:ratio} (?P<unit>\w+)`},
+	})
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte("reads 12 0.5 docs"))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, "grok", points[0].Name())
+	assert.Equal(t, map[string]interface{}{
+		"name":  "reads",
+		"count": int64(12),
+		"ratio": 0.5,
+		"unit":  "docs",
+	}, map[string]interface{}(points[0].Fields()))
+}
+
+func TestGrokCustomPatterns(t *testing.T) {
+	p, err := NewGrokParser(
+		[]string{`%{RETHINKDB_LOG}`, `%{WORD:level}: %{GREEDYDATA:message}`},
+		`
+# RethinkDB log lines
+RETHINKDB_LOG %{TIMESTAMP:time} %{LEVEL:level:tag}: %{GREEDYDATA:message}
+TIMESTAMP %{NUMBER}s
+LEVEL info|notice|warn|error
+`, "rethinkdb_log", nil)
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte("2015-11-10T23:00:00 1.52s error: Connection closed\nwarn: disk full"))
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, map[string]string{"level": "error"}, map[string]string(points[0].Tags()))
+	assert.Equal(t, "1.52s", points[0].Fields()["time"])
+	assert.Equal(t, "Connection closed", points[0].Fields()["message"])
+
+	// the second pattern is used when the first does not match
+	assert.Equal(t, map[string]interface{}{"level": "warn", "message": "disk full"},
+		map[string]interface{}(points[1].Fields()))
+}
+
+func TestGrokConversionFailure(t *testing.T) {
+	p, err := NewGrokParser([]string{`%{NOTSPACE:bytes:int} %{NOTSPACE:ok:bool}`}, "", "", nil)
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte("12 maybe\nmany true"))
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, map[string]interface{}{"bytes": int64(12)},
+		map[string]interface{}(points[0].Fields()))
+	assert.Equal(t, map[string]interface{}{"ok": true},
+		map[string]interface{}(points[1].Fields()))
+}
+
+func TestExpandGrok(t *testing.T) {
+	types := make(map[string]string)
+	expanded, err := expandGrok(`%{INT:n} %{WORD} %{UNKNOWN:x} %{NUMBER:f:float}`,
+		GrokPatterns, types, 0)
+	require.NoError(t, err)
+	assert.Equal(t, `(?P<n>[+-]?\d+) (?:\w+) %{UNKNOWN:x} (?P<f>[+-]?(?:\d+(?:\.\d+)?|\.\d+))`, expanded)
+	assert.Equal(t, map[string]string{"f": "float"}, types)
+}
+
+func TestGrokErrors(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		custom   string
+	}{
+		{nil, ""},
+		{[]string{`(?P<broken`}, ""},
+		{[]string{`%{INT:n:duration}`}, ""},
+		{[]string{`%{LOOP}`}, "LOOP %{LOOP}"},
+		{[]string{`%{INT}`}, "MISSING_REGEXP"},
+	}
+	for _, tt := range tests {
+		_, err := NewGrokParser(tt.patterns, tt.custom, "", nil)
+		assert.Error(t, err, "%v %s", tt.patterns, tt.custom)
+	}
+}
@@ -0,0 +1,105 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+)
+
+// JSONParser parses a JSON object, or an array of objects, into one point
+// per object named MetricName, "json" by default. Nested objects are flattened into fields
+// named after their path joined by "_", ie {"cache":{"hits":3}} is the field
+// cache_hits. Numbers are fields, the top level keys of TagKeys are tags and
+// other values are skipped. Points are timestamped with the time they are
+// parsed at.
+type JSONParser struct {
+	MetricName string
+	TagKeys    []string
+
+	// now returns the timestamp of the points, replaced in tests
+	now func() time.Time
+}
+
+func (p *JSONParser) Parse(buf []byte) ([]models.Point, error) {
+	var v interface{}
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return nil, fmt.Errorf("Unable to parse JSON, %s", err)
+	}
+
+	var objects []map[string]interface{}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		objects = append(objects, t)
+	case []interface{}:
+		for _, item := range t {
+			if obj, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, obj)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("JSON is not an object or an array of objects")
+	}
+
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	timestamp := now()
+	name := p.MetricName
+	if name == "" {
+		name = "json"
+	}
+
+	var points []models.Point
+	for _, obj := range objects {
+		tags := make(map[string]string)
+		for _, key := range p.TagKeys {
+			switch t := obj[key].(type) {
+			case string:
+				tags[key] = t
+			case float64:
+				tags[key] = strconv.FormatFloat(t, 'f', -1, 64)
+			case bool:
+				tags[key] = strconv.FormatBool(t)
+			}
+		}
+
+		fields := make(map[string]interface{})
+		for k, v := range obj {
+			if _, ok := tags[k]; ok {
+				continue
+			}
+			flatten(fields, k, v)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		points = append(points,
+			models.NewPoint(name, tags, fields, timestamp))
+	}
+	return points, nil
+}
+
+// flatten adds the numbers of v to fields, named after their path from key
+func flatten(fields map[string]interface{}, key string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			flatten(fields, key+"_"+k, v)
+		}
+	case float64:
+		fields[key] = t
+	}
+}
+
+func init() {
+	Add("json", func(config *Config) (Parser, error) {
+		return &JSONParser{
+			MetricName: config.MetricName,
+			TagKeys:    config.TagKeys,
+		}, nil
+	})
+}
@@ -0,0 +1,74 @@
+package parsers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2015, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+const testJSON = `
+{
+    "host": "localhost",
+    "clients": 2,
+    "status": "ok",
+    "engine": {
+        "queries_per_sec": 7.5,
+        "written_docs": {"total": 100}
+    },
+    "servers": [1, 2]
+}`
+
+func TestJSONRoundTrip(t *testing.T) {
+	p := &JSONParser{MetricName: "rethinkdb", TagKeys: []string{"host"},
+		now: func() time.Time { return testTime }}
+
+	points, err := p.Parse([]byte(testJSON))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+
+	pt := points[0]
+	assert.Equal(t, "rethinkdb", pt.Name())
+	assert.Equal(t, map[string]string{"host": "localhost"}, map[string]string(pt.Tags()))
+	fields := map[string]interface{}{
+		"clients":                   2.0,
+		"engine_queries_per_sec":    7.5,
+		"engine_written_docs_total": 100.0,
+	}
+	assert.Equal(t, fields, map[string]interface{}(pt.Fields()))
+	assert.Equal(t, testTime, pt.Time())
+
+	// the parsed fields are those of the flattened object
+	b, err := json.Marshal(map[string]interface{}(pt.Fields()))
+	require.NoError(t, err)
+	again, err := p.Parse(b)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, fields, map[string]interface{}(again[0].Fields()))
+}
+
+func TestJSONArray(t *testing.T) {
+	p, err := NewParser(&Config{DataFormat: "json", TagKeys: []string{"db"}})
+	require.NoError(t, err)
+
+	points, err := p.Parse([]byte(`[{"db": "test", "docs": 3}, "skipped", {"db": 1, "docs": 4}, {"db": "empty"}]`))
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, "json", points[0].Name())
+	assert.Equal(t, "test", points[0].Tags()["db"])
+	assert.Equal(t, 3.0, points[0].Fields()["docs"])
+	assert.Equal(t, "1", points[1].Tags()["db"])
+	assert.Equal(t, 4.0, points[1].Fields()["docs"])
+}
+
+func TestJSONErrors(t *testing.T) {
+	p := &JSONParser{}
+	for _, buf := range []string{`{"clients": `, `3`, `"ok"`} {
+		_, err := p.Parse([]byte(buf))
+		assert.Error(t, err, buf)
+	}
+}
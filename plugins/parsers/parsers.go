@@ -0,0 +1,70 @@
+// Package parsers turns the bytes read by plugins that accept several data
+// formats into points, so that they share their parsing.
+package parsers
+
+import (
+	"fmt"
+
+	"github.com/influxdb/influxdb/models"
+)
+
+// Parser parses a buffer of one or more metrics into points
+type Parser interface {
+	Parse(buf []byte) ([]models.Point, error)
+}
+
+// Config selects and configures a parser
+type Config struct {
+	// DataFormat is the name of a registered parser, ie "influx" (the
+	// default), "json", "grok" or "graphite"
+	DataFormat string
+
+	// JSON and grok options, see JSONParser and GrokParser
+	MetricName string
+	TagKeys    []string
+
+	// Grok options, see GrokParser
+	Patterns       []string
+	CustomPatterns string
+
+	// Graphite options, see GraphiteParser
+	Templates []string
+	Separator string
+}
+
+// Creator builds a parser from the options of its data format
+type Creator func(config *Config) (Parser, error)
+
+// Parsers are the registered data formats
+var Parsers = map[string]Creator{}
+
+// Add registers the parser of a data format
+func Add(name string, creator Creator) {
+	Parsers[name] = creator
+}
+
+// NewParser returns the parser for the configured data format
+func NewParser(config *Config) (Parser, error) {
+	format := config.DataFormat
+	if format == "" {
+		format = "influx"
+	}
+	creator, ok := Parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("Invalid data format: %s", config.DataFormat)
+	}
+	return creator(config)
+}
+
+// InfluxParser parses the InfluxDB line protocol, one point per line
+type InfluxParser struct{}
+
+func (p *InfluxParser) Parse(buf []byte) ([]models.Point, error) {
+	return models.ParsePoints(buf)
+}
+
+func init() {
+	Add("influx", func(config *Config) (Parser, error) {
+		return &InfluxParser{}, nil
+	})
+}
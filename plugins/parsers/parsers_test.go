@@ -0,0 +1,66 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLines = "rethinkdb_engine,host=localhost,type=cluster clients=2i,queries_per_sec=7.5 1447196400000000000\n" +
+	"rethinkdb_storage,host=localhost,type=data cache_bytes_in_use=3i 1447196400000000000\n"
+
+func TestInfluxRoundTrip(t *testing.T) {
+	p, err := NewParser(&Config{})
+	require.NoError(t, err)
+	assert.IsType(t, &InfluxParser{}, p)
+
+	points, err := p.Parse([]byte(testLines))
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+
+	var lines string
+	for _, pt := range points {
+		lines += pt.String() + "\n"
+	}
+	assert.Equal(t, testLines, lines)
+}
+
+func TestInfluxParseError(t *testing.T) {
+	p, err := NewParser(&Config{DataFormat: "influx"})
+	require.NoError(t, err)
+	_, err = p.Parse([]byte("this is not line protocol"))
+	assert.Error(t, err)
+}
+
+func TestNewParser(t *testing.T) {
+	p, err := NewParser(&Config{DataFormat: "json", MetricName: "rethinkdb"})
+	require.NoError(t, err)
+	assert.Equal(t, &JSONParser{MetricName: "rethinkdb"}, p)
+
+	_, err = NewParser(&Config{DataFormat: "xml"})
+	assert.Error(t, err)
+}
+
+type lineCountParser struct{}
+
+func (p *lineCountParser) Parse(buf []byte) ([]models.Point, error) {
+	return []models.Point{models.NewPoint("lines",
+		nil, map[string]interface{}{"bytes": int64(len(buf))}, time.Unix(0, 0))}, nil
+}
+
+func TestAddParser(t *testing.T) {
+	Add("count", func(config *Config) (Parser, error) {
+		return &lineCountParser{}, nil
+	})
+	defer delete(Parsers, "count")
+
+	p, err := NewParser(&Config{DataFormat: "count"})
+	require.NoError(t, err)
+	points, err := p.Parse([]byte("abc"))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, int64(3), points[0].Fields()["bytes"])
+}
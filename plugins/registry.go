@@ -15,6 +15,13 @@ type Accumulator interface {
 		fields map[string]interface{},
 		tags map[string]string,
 		t ...time.Time)
+
+	// Create an event, ie a deploy, rather than a metric. Events are written
+	// to the events measurement with the title and text as fields.
+	AddEvent(title string,
+		text string,
+		tags map[string]string,
+		t ...time.Time)
 }
 
 type Plugin interface {
@@ -47,6 +54,14 @@ type ServicePlugin interface {
 	Stop()
 }
 
+// ReadyPlugin is implemented by plugins depending on a service that may not
+// be up yet when telegraf starts. When startup_wait is set, the agent waits
+// for Ready to return true before the first collection.
+type ReadyPlugin interface {
+	// Ready returns whether the plugin can reach the service it gathers
+	Ready() bool
+}
+
 type Creator func() Plugin
 
 var Plugins = map[string]Creator{}
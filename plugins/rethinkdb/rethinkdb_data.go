@@ -1,7 +1,9 @@
 package rethinkdb
 
 import (
+	"net"
 	"reflect"
+	"sort"
 	"time"
 
 	"github.com/influxdb/telegraf/plugins"
@@ -9,6 +11,7 @@ import (
 
 type serverStatus struct {
 	Id      string `gorethink:"id"`
+	Name    string `gorethink:"name"`
 	Network struct {
 		Addresses  []Address `gorethink:"canonical_addresses"`
 		Hostname   string    `gorethink:"hostname"`
@@ -20,13 +23,86 @@ type serverStatus struct {
 	} `gorethink:"process"`
 }
 
+type serverConfig struct {
+	Id   string `gorethink:"id"`
+	Name string `gorethink:"name"`
+}
+
+// serverRole returns "data" when the server is part of server_config, which
+// only lists servers that can host data, and "proxy" otherwise.
+func serverRole(id string, configs []serverConfig) string {
+	for _, config := range configs {
+		if config.Id == id {
+			return "data"
+		}
+	}
+	return "proxy"
+}
+
 type Address struct {
 	Host string `gorethink:"host"`
 	Port int    `gorethink:"port"`
 }
 
+// reachableHost returns the first canonical address of the server that is
+// not a loopback address, falling back to the first address listed.
+func (ss *serverStatus) reachableHost() string {
+	for _, address := range ss.Network.Addresses {
+		if ip := net.ParseIP(address.Host); ip == nil || !ip.IsLoopback() {
+			return address.Host
+		}
+	}
+	if len(ss.Network.Addresses) > 0 {
+		return ss.Network.Addresses[0].Host
+	}
+	return ""
+}
+
+// addUptime adds how long the server has been running at now, in seconds.
+// Nothing is added when the server did not report when it started.
+func (ss *serverStatus) addUptime(
+	acc plugins.Accumulator,
+	tags map[string]string,
+	now time.Time,
+) {
+	if ss.Process.RunningSince.IsZero() {
+		return
+	}
+	uptime := now.Sub(ss.Process.RunningSince)
+	if uptime < 0 {
+		uptime = 0
+	}
+	acc.Add("uptime_s", int64(uptime/time.Second), tags)
+}
+
 type stats struct {
-	Engine Engine `gorethink:"query_engine"`
+	Id     []string `gorethink:"id"`
+	Engine Engine   `gorethink:"query_engine"`
+	// Time the document was last updated, only set by servers that
+	// timestamp their stats
+	Time time.Time `gorethink:"time,omitempty"`
+}
+
+// addStatsAge adds how old the stats document updated at updated is at now,
+// in seconds, and returns whether its metrics are recent enough to be added.
+// Documents older than maxAge are dropped when maxAge is set. Nothing is
+// added for documents without a timestamp, which are always recent enough.
+func addStatsAge(
+	acc plugins.Accumulator,
+	tags map[string]string,
+	updated time.Time,
+	now time.Time,
+	maxAge time.Duration,
+) bool {
+	if updated.IsZero() {
+		return true
+	}
+	age := now.Sub(updated)
+	if age < 0 {
+		age = 0
+	}
+	acc.Add("stats_age_s", age.Seconds(), tags)
+	return maxAge <= 0 || age <= maxAge
 }
 
 type Engine struct {
@@ -46,6 +122,35 @@ type tableStatus struct {
 	Name string `gorethink:"name"`
 }
 
+type tableConfig struct {
+	Id     string        `gorethink:"id"`
+	DB     string        `gorethink:"db"`
+	Name   string        `gorethink:"name"`
+	Shards []shardConfig `gorethink:"shards"`
+}
+
+type shardConfig struct {
+	PrimaryReplica string   `gorethink:"primary_replica"`
+	Replicas       []string `gorethink:"replicas"`
+}
+
+type tableShardStatus struct {
+	Id     string        `gorethink:"id"`
+	DB     string        `gorethink:"db"`
+	Name   string        `gorethink:"name"`
+	Shards []shardStatus `gorethink:"shards"`
+}
+
+type shardStatus struct {
+	PrimaryReplicas []string        `gorethink:"primary_replicas"`
+	Replicas        []replicaStatus `gorethink:"replicas"`
+}
+
+type replicaStatus struct {
+	Server string `gorethink:"server"`
+	State  string `gorethink:"state"`
+}
+
 type tableStats struct {
 	Engine  Engine  `gorethink:"query_engine"`
 	Storage Storage `gorethink:"storage_engine"`
@@ -86,25 +191,151 @@ var engineStats = map[string]string{
 	"total_writes":         "TotalWrites",
 }
 
-func (e *Engine) AddEngineStats(keys []string, acc plugins.Accumulator, tags map[string]string) {
+// Measurements of the stats when they are added as measurement sets, see
+// RethinkDB.MeasurementSets
+const (
+	engineMeasurement  = "engine"
+	storageMeasurement = "storage"
+	tableMeasurement   = "table"
+)
+
+func (e *Engine) AddEngineStats(
+	keys []string,
+	acc plugins.Accumulator,
+	tags map[string]string,
+	t ...time.Time,
+) {
+	fields := e.fields(keys)
+	for _, key := range keys {
+		acc.Add(key, fields[key], tags, t...)
+	}
+}
+
+// fields returns the engine stats named by keys
+func (e *Engine) fields(keys []string) map[string]interface{} {
 	engine := reflect.ValueOf(e).Elem()
+	fields := make(map[string]interface{}, len(keys))
 	for _, key := range keys {
-		acc.Add(
-			key,
-			engine.FieldByName(engineStats[key]).Interface(),
-			tags,
-		)
+		fields[key] = engine.FieldByName(engineStats[key]).Interface()
+	}
+	return fields
+}
+
+// addEngineStats adds the engine stats named by keys, as the fields of the
+// engine measurement when sets is true or each as its own measurement
+func addEngineStats(
+	acc plugins.Accumulator,
+	sets bool,
+	e *Engine,
+	keys []string,
+	tags map[string]string,
+	t ...time.Time,
+) {
+	if sets {
+		acc.AddFields(engineMeasurement, e.fields(keys), tags, t...)
+		return
 	}
+	e.AddEngineStats(keys, acc, tags, t...)
 }
 
 func (s *Storage) AddStats(acc plugins.Accumulator, tags map[string]string) {
-	acc.Add("cache_bytes_in_use", s.Cache.BytesInUse, tags)
-	acc.Add("disk_read_bytes_per_sec", s.Disk.ReadBytesPerSec, tags)
-	acc.Add("disk_read_bytes_total", s.Disk.ReadBytesTotal, tags)
-	acc.Add("disk_written_bytes_per_sec", s.Disk.WriteBytesPerSec, tags)
-	acc.Add("disk_written_bytes_total", s.Disk.WriteBytesTotal, tags)
-	acc.Add("disk_usage_data_bytes", s.Disk.SpaceUsage.Data, tags)
-	acc.Add("disk_usage_garbage_bytes", s.Disk.SpaceUsage.Garbage, tags)
-	acc.Add("disk_usage_metadata_bytes", s.Disk.SpaceUsage.Metadata, tags)
-	acc.Add("disk_usage_preallocated_bytes", s.Disk.SpaceUsage.Prealloc, tags)
+	addEach(acc, s.fields(), tags)
+}
+
+func (s *Storage) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"cache_bytes_in_use":            s.Cache.BytesInUse,
+		"disk_read_bytes_per_sec":       s.Disk.ReadBytesPerSec,
+		"disk_read_bytes_total":         s.Disk.ReadBytesTotal,
+		"disk_written_bytes_per_sec":    s.Disk.WriteBytesPerSec,
+		"disk_written_bytes_total":      s.Disk.WriteBytesTotal,
+		"disk_usage_data_bytes":         s.Disk.SpaceUsage.Data,
+		"disk_usage_garbage_bytes":      s.Disk.SpaceUsage.Garbage,
+		"disk_usage_metadata_bytes":     s.Disk.SpaceUsage.Metadata,
+		"disk_usage_preallocated_bytes": s.Disk.SpaceUsage.Prealloc,
+	}
+}
+
+// addEach adds every field as its own measurement, in the order of their
+// names
+func addEach(
+	acc plugins.Accumulator,
+	fields map[string]interface{},
+	tags map[string]string,
+) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		acc.Add(name, fields[name], tags)
+	}
+}
+
+// AddReplicaStats reports shard and replica counts for a table, comparing the
+// replicas configured in table_config with the ones reported by table_status.
+func (c *tableConfig) AddReplicaStats(
+	status *tableShardStatus,
+	acc plugins.Accumulator,
+	tags map[string]string,
+) {
+	fields := make(map[string]interface{})
+	for name, value := range c.replicaFields(status) {
+		fields["table_"+name] = value
+	}
+	addEach(acc, fields, tags)
+}
+
+// replicaFields returns the shard and replica counts of the table, see
+// AddReplicaStats
+func (c *tableConfig) replicaFields(status *tableShardStatus) map[string]interface{} {
+	var configured int64
+	for _, shard := range c.Shards {
+		configured += int64(len(shard.Replicas))
+	}
+
+	var ready, notReady int64
+	for _, shard := range status.Shards {
+		for _, replica := range shard.Replicas {
+			if replica.State == "ready" {
+				ready++
+			} else {
+				notReady++
+			}
+		}
+	}
+
+	var underReplicated int64
+	if ready < configured {
+		underReplicated = 1
+	}
+
+	return map[string]interface{}{
+		"shards":             int64(len(c.Shards)),
+		"replicas":           configured,
+		"replicas_ready":     ready,
+		"replicas_not_ready": notReady,
+		"under_replicated":   underReplicated,
+	}
+}
+
+type currentIssue struct {
+	Id       string `gorethink:"id"`
+	Type     string `gorethink:"type"`
+	Critical bool   `gorethink:"critical"`
+}
+
+// AddIssueStats counts the issues reported in current_issues by type, and
+// reports the total number of issues.
+func AddIssueStats(issues []currentIssue, acc plugins.Accumulator, tags map[string]string) {
+	fields := make(map[string]interface{})
+	for _, issue := range issues {
+		count, _ := fields[issue.Type].(int64)
+		fields[issue.Type] = count + 1
+	}
+	if len(fields) > 0 {
+		acc.AddFields("current_issues", fields, tags)
+	}
+	acc.Add("total_issues", int64(len(issues)), tags)
 }
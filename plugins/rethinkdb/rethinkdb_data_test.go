@@ -2,9 +2,13 @@ package rethinkdb
 
 import (
 	"testing"
+	"time"
 
 	"github.com/influxdb/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/dancannon/gorethink.v1/encoding"
 )
 
 var tags = make(map[string]string)
@@ -110,3 +114,262 @@ func TestAddStorageStats(t *testing.T) {
 		assert.True(t, acc.HasIntValue(metric))
 	}
 }
+
+func TestAddReplicaStatsSharded(t *testing.T) {
+	config := &tableConfig{
+		DB:   "test",
+		Name: "users",
+		Shards: []shardConfig{
+			{PrimaryReplica: "a", Replicas: []string{"a", "b", "c"}},
+			{PrimaryReplica: "b", Replicas: []string{"b", "c", "a"}},
+		},
+	}
+	status := &tableShardStatus{
+		DB:   "test",
+		Name: "users",
+		Shards: []shardStatus{
+			{
+				PrimaryReplicas: []string{"a"},
+				Replicas: []replicaStatus{
+					{Server: "a", State: "ready"},
+					{Server: "b", State: "ready"},
+					{Server: "c", State: "ready"},
+				},
+			},
+			{
+				PrimaryReplicas: []string{"b"},
+				Replicas: []replicaStatus{
+					{Server: "b", State: "ready"},
+					{Server: "c", State: "backfilling"},
+					{Server: "a", State: "disconnected"},
+				},
+			},
+		},
+	}
+
+	var acc testutil.Accumulator
+	tableTags := map[string]string{"db": "test", "table": "users"}
+	config.AddReplicaStats(status, &acc, tableTags)
+
+	expected := map[string]int64{
+		"table_shards":             2,
+		"table_replicas":           6,
+		"table_replicas_ready":     4,
+		"table_replicas_not_ready": 2,
+		"table_under_replicated":   1,
+	}
+	for metric, value := range expected {
+		assert.NoError(t, acc.ValidateTaggedValue(metric, value, tableTags))
+	}
+}
+
+func TestAddReplicaStatsFullyReplicated(t *testing.T) {
+	config := &tableConfig{
+		Shards: []shardConfig{
+			{PrimaryReplica: "a", Replicas: []string{"a", "b"}},
+		},
+	}
+	status := &tableShardStatus{
+		Shards: []shardStatus{
+			{
+				Replicas: []replicaStatus{
+					{Server: "a", State: "ready"},
+					{Server: "b", State: "ready"},
+				},
+			},
+		},
+	}
+
+	var acc testutil.Accumulator
+	config.AddReplicaStats(status, &acc, tags)
+
+	assert.NoError(t, acc.ValidateValue("table_under_replicated", int64(0)))
+	assert.NoError(t, acc.ValidateValue("table_replicas_ready", int64(2)))
+}
+
+func TestServerRole(t *testing.T) {
+	statuses := []serverStatus{
+		{Id: "data-node"},
+		{Id: "proxy-node"},
+	}
+	configs := []serverConfig{
+		{Id: "data-node", Name: "db1"},
+	}
+
+	assert.Equal(t, "data", serverRole(statuses[0].Id, configs))
+	assert.Equal(t, "proxy", serverRole(statuses[1].Id, configs))
+}
+
+func TestAddIssueStatsByType(t *testing.T) {
+	issues := []currentIssue{
+		{Id: "1", Type: "outdated_index"},
+		{Id: "2", Type: "table_availability", Critical: true},
+		{Id: "3", Type: "outdated_index"},
+	}
+
+	var acc testutil.Accumulator
+	AddIssueStats(issues, &acc, tags)
+
+	assert.NoError(t, acc.ValidateValue("total_issues", int64(3)))
+
+	p, ok := acc.Get("current_issues")
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), p.Values["outdated_index"])
+	assert.Equal(t, int64(1), p.Values["table_availability"])
+}
+
+func TestAddIssueStatsNoIssues(t *testing.T) {
+	var acc testutil.Accumulator
+	AddIssueStats(nil, &acc, tags)
+
+	assert.NoError(t, acc.ValidateValue("total_issues", int64(0)))
+	assert.False(t, acc.HasMeasurement("current_issues"))
+}
+
+func TestAddUptime(t *testing.T) {
+	started := time.Date(2015, 11, 2, 10, 0, 0, 0, time.UTC)
+	var ss serverStatus
+	err := encoding.Decode(&ss, map[string]interface{}{
+		"id": "abc",
+		"process": map[string]interface{}{
+			"version":      "rethinkdb 2.1.5",
+			"time_started": started,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, started, ss.Process.RunningSince)
+
+	var acc testutil.Accumulator
+	ss.addUptime(&acc, tags, started.Add(90*time.Minute+500*time.Millisecond))
+	assert.NoError(t, acc.ValidateTaggedValue("uptime_s", int64(5400), tags))
+}
+
+func TestAddUptimeMissingStartTime(t *testing.T) {
+	var ss serverStatus
+	err := encoding.Decode(&ss, map[string]interface{}{
+		"id":      "abc",
+		"process": map[string]interface{}{"version": "rethinkdb 1.16.0"},
+	})
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	ss.addUptime(&acc, tags, time.Now())
+	assert.False(t, acc.HasMeasurement("uptime_s"))
+}
+
+func TestAddStatsAgeStale(t *testing.T) {
+	updated := time.Date(2015, 11, 2, 10, 0, 0, 0, time.UTC)
+	var doc stats
+	err := encoding.Decode(&doc, map[string]interface{}{
+		"id":           []interface{}{"server", "abc"},
+		"query_engine": map[string]interface{}{"queries_per_sec": 7},
+		"time":         updated,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, updated, doc.Time)
+
+	now := updated.Add(2 * time.Minute)
+
+	var acc testutil.Accumulator
+	assert.False(t, addStatsAge(&acc, tags, doc.Time, now, time.Minute))
+	assert.NoError(t, acc.ValidateTaggedValue("stats_age_s", 120.0, tags))
+
+	acc = testutil.Accumulator{}
+	assert.True(t, addStatsAge(&acc, tags, doc.Time, now, 5*time.Minute))
+	assert.True(t, addStatsAge(&acc, tags, doc.Time, now, 0))
+	assert.Len(t, acc.Points, 2)
+}
+
+func TestAddStatsAgeNoTimestamp(t *testing.T) {
+	var acc testutil.Accumulator
+	assert.True(t, addStatsAge(&acc, tags, time.Time{}, time.Now(), time.Second))
+	assert.Empty(t, acc.Points)
+}
+
+func TestAddEngineStatsMeasurementSet(t *testing.T) {
+	e := &Engine{ClientConns: 2, ClientActive: 1, QueriesPerSec: 7}
+	engineTags := map[string]string{"type": "table", "ns": "test.users"}
+
+	var acc testutil.Accumulator
+	addEngineStats(&acc, true, e, []string{"active_clients", "clients", "queries_per_sec"}, engineTags)
+
+	require.Len(t, acc.Points, 1)
+	p, ok := acc.Get("engine")
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"active_clients":  int64(1),
+		"clients":         int64(2),
+		"queries_per_sec": int64(7),
+	}, p.Values)
+	assert.Equal(t, engineTags, p.Tags)
+}
+
+func TestStorageMeasurementSet(t *testing.T) {
+	storage := &Storage{
+		Cache: Cache{BytesInUse: 1},
+		Disk: Disk{
+			ReadBytesPerSec:  2,
+			ReadBytesTotal:   3,
+			WriteBytesPerSec: 4,
+			WriteBytesTotal:  5,
+			SpaceUsage: SpaceUsage{
+				Data:     6,
+				Garbage:  7,
+				Metadata: 8,
+				Prealloc: 9,
+			},
+		},
+	}
+	storageTags := map[string]string{"type": "data", "ns": "test.users"}
+
+	var acc testutil.Accumulator
+	acc.AddFields(storageMeasurement, storage.fields(), storageTags)
+
+	p, ok := acc.Get("storage")
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"cache_bytes_in_use":            int64(1),
+		"disk_read_bytes_per_sec":       int64(2),
+		"disk_read_bytes_total":         int64(3),
+		"disk_written_bytes_per_sec":    int64(4),
+		"disk_written_bytes_total":      int64(5),
+		"disk_usage_data_bytes":         int64(6),
+		"disk_usage_garbage_bytes":      int64(7),
+		"disk_usage_metadata_bytes":     int64(8),
+		"disk_usage_preallocated_bytes": int64(9),
+	}, p.Values)
+	assert.Equal(t, storageTags, p.Tags)
+}
+
+func TestTableMeasurementSet(t *testing.T) {
+	config := &tableConfig{
+		Shards: []shardConfig{
+			{PrimaryReplica: "a", Replicas: []string{"a", "b"}},
+		},
+	}
+	status := &tableShardStatus{
+		Shards: []shardStatus{
+			{
+				Replicas: []replicaStatus{
+					{Server: "a", State: "ready"},
+					{Server: "b", State: "backfilling"},
+				},
+			},
+		},
+	}
+	tableTags := map[string]string{"db": "test", "table": "users"}
+
+	var acc testutil.Accumulator
+	acc.AddFields(tableMeasurement, config.replicaFields(status), tableTags)
+
+	p, ok := acc.Get("table")
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"shards":             int64(1),
+		"replicas":           int64(2),
+		"replicas_ready":     int64(1),
+		"replicas_not_ready": int64(1),
+		"under_replicated":   int64(1),
+	}, p.Values)
+	assert.Equal(t, tableTags, p.Tags)
+}
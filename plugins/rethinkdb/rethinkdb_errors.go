@@ -0,0 +1,78 @@
+package rethinkdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/influxdb/telegraf/plugins"
+
+	"gopkg.in/dancannon/gorethink.v1"
+)
+
+// Values of the type tag of the errors measurement
+const (
+	errorConnection = "connection"
+	errorAuth       = "auth"
+	errorQuery      = "query"
+	errorTimeout    = "timeout"
+)
+
+// errorType classifies an error returned by gorethink, or by runWithContext,
+// so that a server that is down is told apart from a rejected auth key or a
+// failing query. Unknown errors are query errors.
+func errorType(err error) string {
+	if err == context.DeadlineExceeded || err == context.Canceled {
+		return errorTimeout
+	}
+	if netErr, ok := err.(net.Error); ok {
+		if netErr.Timeout() {
+			return errorTimeout
+		}
+		return errorConnection
+	}
+
+	switch err.(type) {
+	case gorethink.RQLCompileError, gorethink.RQLRuntimeError, gorethink.RQLClientError:
+		return errorQuery
+	case gorethink.RQLDriverError:
+		// the server closes the handshake of a wrong auth key with a message
+		if strings.Contains(strings.ToLower(err.Error()), "authorization") {
+			return errorAuth
+		}
+		return errorQuery
+	case gorethink.RQLConnectionError:
+		if strings.Contains(err.Error(), "timeout") {
+			return errorTimeout
+		}
+		return errorConnection
+	}
+
+	switch err {
+	case io.EOF, gorethink.ErrNoHosts, gorethink.ErrNoConnectionsStarted,
+		gorethink.ErrNoConnections, gorethink.ErrConnectionClosed, gorethink.ErrBadConn:
+		return errorConnection
+	}
+	if strings.HasPrefix(err.Error(), "Unexpected EOF") {
+		// the server closed the connection during the handshake
+		return errorConnection
+	}
+	return errorQuery
+}
+
+// addError counts a failed gather of the server in the errors measurement,
+// tagged with the type of the error, and returns err prefixed by its type.
+func (s *Server) addError(acc plugins.Accumulator, errType string, err error) error {
+	tags := s.configuredTags()
+	tags["type"] = errType
+	if !s.omitHostTag {
+		tags["host"] = s.Url.Host
+	}
+	if s.measurementPerServer {
+		acc = perServer(acc, s.name())
+	}
+	acc.Add("errors", int64(1), tags)
+	return fmt.Errorf("%s error, %s", errType, err.Error())
+}
@@ -0,0 +1,197 @@
+package rethinkdb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/influxdb/telegraf/plugins"
+
+	"gopkg.in/dancannon/gorethink.v1"
+)
+
+// feedStatScopes are the stats scopes streamed by a changefeed, the other
+// scopes of a server with a feed are still polled
+var feedStatScopes = []string{"cluster", "server"}
+
+// changeCursor is the part of a *gorethink.Cursor used to read a changefeed.
+type changeCursor interface {
+	Next(dest interface{}) bool
+	Err() error
+	Close() error
+}
+
+type statsChange struct {
+	NewVal stats `gorethink:"new_val"`
+}
+
+// feedUpdate is a stats document received from a changefeed, kept until the
+// next Gather together with the time it arrived.
+type feedUpdate struct {
+	stats  stats
+	tags   map[string]string
+	time   time.Time
+	server string
+}
+
+type feed struct {
+	server *Server
+	cursor changeCursor
+}
+
+// Start subscribes to the stats changefeed of every configured server when
+// changefeed is enabled. Servers whose feed cannot be opened are polled on
+// each Gather instead.
+func (r *RethinkDB) Start() error {
+	if !r.Changefeed {
+		return nil
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.feeds = make(map[string]*feed)
+
+	urls, err := r.serverUrls()
+	if err != nil {
+		return err
+	}
+
+	for _, u := range urls {
+		server := r.newServer(u)
+		cursor, err := r.openFeed(server)
+		if err != nil {
+			log.Printf("Changefeed unavailable for RethinkDB %s, polling instead, %s\n",
+				u.Host, err)
+			continue
+		}
+		f := &feed{server: server, cursor: cursor}
+		r.feeds[u.Host] = f
+		go r.consumeFeed(f)
+	}
+	return nil
+}
+
+// Stop closes all open changefeeds and their sessions, and the sessions
+// cached for gathering.
+func (r *RethinkDB) Stop() {
+	r.Lock()
+	feeds := r.feeds
+	r.feeds = nil
+	sessions := r.sessions
+	r.sessions = nil
+	r.Unlock()
+
+	if sessions != nil {
+		sessions.Close()
+	}
+
+	for _, f := range feeds {
+		f.cursor.Close()
+		if f.server.session != nil {
+			f.server.session.Close()
+		}
+	}
+}
+
+func (r *RethinkDB) openFeed(server *Server) (changeCursor, error) {
+	var err error
+	server.session, err = r.connect(server)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to RethinkDB, %s\n", err.Error())
+	}
+
+	ctx := context.Background()
+	if err := server.getServerStatus(ctx); err != nil {
+		server.session.Close()
+		return nil, fmt.Errorf("Failed to get server_status, %s\n", err)
+	}
+	server.getServerRole(ctx)
+
+	cursor, err := gorethink.DB("rethinkdb").Table("stats").
+		GetAll([]string{"cluster"}, []string{"server", server.serverStatus.Id}).
+		Changes().
+		Run(server.session)
+	if err != nil {
+		server.session.Close()
+		return nil, err
+	}
+	return cursor, nil
+}
+
+// consumeFeed buffers every update received on the feed until the cursor is
+// closed or fails, after which the server goes back to being polled.
+func (r *RethinkDB) consumeFeed(f *feed) {
+	var change statsChange
+	for f.cursor.Next(&change) {
+		tags := f.server.getDefaultTags()
+		if len(change.NewVal.Id) > 0 && change.NewVal.Id[0] == "cluster" {
+			tags["type"] = "cluster"
+		} else {
+			tags["type"] = "member"
+		}
+
+		r.Lock()
+		r.updates = append(r.updates, feedUpdate{
+			stats:  change.NewVal,
+			tags:   tags,
+			time:   time.Now(),
+			server: f.server.name(),
+		})
+		r.Unlock()
+	}
+
+	if err := f.cursor.Err(); err != nil {
+		log.Printf("Changefeed for RethinkDB %s failed, polling instead, %s\n",
+			f.server.Url.Host, err)
+	}
+
+	r.Lock()
+	if r.feeds != nil && r.feeds[f.server.Url.Host] == f {
+		delete(r.feeds, f.server.Url.Host)
+		if f.server.session != nil {
+			f.server.session.Close()
+		}
+	}
+	r.Unlock()
+}
+
+// flushFeeds adds all buffered changefeed updates to the accumulator using
+// the time each update was received, and returns the hosts with an open
+// feed, whose feedStatScopes are not polled.
+func (r *RethinkDB) flushFeeds(acc plugins.Accumulator) map[string]bool {
+	r.Lock()
+	updates := r.updates
+	r.updates = nil
+	fed := make(map[string]bool, len(r.feeds))
+	for host := range r.feeds {
+		fed[host] = true
+	}
+	r.Unlock()
+
+	scopes := r.gatheredScopes()
+	now := time.Now()
+	for _, update := range updates {
+		keys, scope := MemberTracking, "server"
+		if update.tags["type"] == "cluster" {
+			keys, scope = ClusterTracking, "cluster"
+		}
+		if !scopeEnabled(scopes, scope) {
+			continue
+		}
+		updateAcc := acc
+		if r.MeasurementPerServer {
+			updateAcc = perServer(acc, update.server)
+		}
+		updated := update.stats.Time
+		if updated.IsZero() {
+			updated = update.time
+		}
+		if !addStatsAge(updateAcc, update.tags, updated, now, r.MaxStatsAge.Duration) {
+			continue
+		}
+		addEngineStats(updateAcc, r.MeasurementSets, &update.stats.Engine, keys,
+			update.tags, update.time)
+	}
+	return fed
+}
@@ -0,0 +1,238 @@
+package rethinkdb
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCursor struct {
+	updates []statsChange
+	err     error
+	closed  bool
+}
+
+func (c *mockCursor) Next(dest interface{}) bool {
+	if len(c.updates) == 0 {
+		return false
+	}
+	*dest.(*statsChange) = c.updates[0]
+	c.updates = c.updates[1:]
+	return true
+}
+
+func (c *mockCursor) Err() error {
+	return c.err
+}
+
+func (c *mockCursor) Close() error {
+	c.closed = true
+	return nil
+}
+
+func memberChange(queries int64) statsChange {
+	return statsChange{
+		NewVal: stats{
+			Id:     []string{"server", "abc"},
+			Engine: Engine{QueriesPerSec: queries},
+		},
+	}
+}
+
+func TestChangefeedUpdates(t *testing.T) {
+	u := &url.URL{Host: "10.0.0.1:28015"}
+	cursor := &mockCursor{
+		updates: []statsChange{
+			{NewVal: stats{Id: []string{"cluster"}, Engine: Engine{QueriesPerSec: 7}}},
+			memberChange(1),
+			memberChange(5),
+			memberChange(3),
+		},
+	}
+	r := &RethinkDB{Changefeed: true}
+	f := &feed{server: &Server{Url: u}, cursor: cursor}
+	r.feeds = map[string]*feed{u.Host: f}
+
+	r.consumeFeed(f)
+
+	var acc testutil.Accumulator
+	fed := r.flushFeeds(&acc)
+
+	// the feed ended without error, so the server is polled from now on
+	assert.Empty(t, fed)
+	assert.Empty(t, r.updates)
+
+	var qps []int64
+	for _, p := range acc.Points {
+		if p.Measurement != "queries_per_sec" {
+			continue
+		}
+		assert.False(t, p.Time.IsZero())
+		if p.Tags["type"] == "member" {
+			qps = append(qps, p.Values["value"].(int64))
+		} else {
+			assert.Equal(t, "cluster", p.Tags["type"])
+			assert.Equal(t, int64(7), p.Values["value"])
+		}
+	}
+	assert.Equal(t, []int64{1, 5, 3}, qps)
+	assert.True(t, acc.HasIntValue("total_queries"))
+}
+
+func TestChangefeedActiveFeeds(t *testing.T) {
+	streaming := &url.URL{Host: "10.0.0.1:28015"}
+	r := &RethinkDB{Changefeed: true}
+	r.feeds = map[string]*feed{
+		streaming.Host: {server: &Server{Url: streaming}, cursor: &mockCursor{}},
+	}
+
+	var acc testutil.Accumulator
+	fed := r.flushFeeds(&acc)
+
+	assert.Equal(t, map[string]bool{streaming.Host: true}, fed)
+}
+
+func TestChangefeedFailureFallsBackToPolling(t *testing.T) {
+	u := &url.URL{Host: "10.0.0.1:28015"}
+	cursor := &mockCursor{
+		updates: []statsChange{memberChange(2)},
+		err:     errors.New("connection reset"),
+	}
+	r := &RethinkDB{Changefeed: true}
+	f := &feed{server: &Server{Url: u}, cursor: cursor}
+	r.feeds = map[string]*feed{u.Host: f}
+
+	r.consumeFeed(f)
+
+	var acc testutil.Accumulator
+	fed := r.flushFeeds(&acc)
+
+	assert.Empty(t, fed)
+	assert.NoError(t, acc.ValidateTaggedValue("queries_per_sec", int64(2),
+		map[string]string{"host": u.Host, "hostname": "", "type": "member"}))
+}
+
+func TestChangefeedGatherStatsScopes(t *testing.T) {
+	u := &url.URL{Host: "10.0.0.1:28015"}
+	cursor := &mockCursor{
+		updates: []statsChange{
+			{NewVal: stats{Id: []string{"cluster"}, Engine: Engine{QueriesPerSec: 7}}},
+			memberChange(1),
+		},
+	}
+	r := &RethinkDB{Changefeed: true, GatherStats: []string{"server"}}
+	f := &feed{server: &Server{Url: u}, cursor: cursor}
+	r.feeds = map[string]*feed{u.Host: f}
+
+	r.consumeFeed(f)
+
+	var acc testutil.Accumulator
+	r.flushFeeds(&acc)
+
+	for _, p := range acc.Points {
+		assert.Equal(t, "member", p.Tags["type"])
+	}
+	assert.NoError(t, acc.ValidateTaggedValue("queries_per_sec", int64(1),
+		map[string]string{"host": u.Host, "hostname": "", "type": "member"}))
+}
+
+func TestChangefeedMeasurementPerServer(t *testing.T) {
+	u := &url.URL{Host: "10.0.0.1:28015"}
+	server := &Server{Url: u}
+	server.serverStatus.Name = "rethinkdb01"
+	cursor := &mockCursor{updates: []statsChange{memberChange(1)}}
+	r := &RethinkDB{Changefeed: true, MeasurementPerServer: true}
+	f := &feed{server: server, cursor: cursor}
+	r.feeds = map[string]*feed{u.Host: f}
+
+	r.consumeFeed(f)
+
+	var acc testutil.Accumulator
+	r.flushFeeds(&acc)
+	assert.True(t, acc.HasIntValue("rethinkdb01.queries_per_sec"))
+	assert.False(t, acc.HasMeasurement("queries_per_sec"))
+}
+
+func TestChangefeedMaxStatsAge(t *testing.T) {
+	r := &RethinkDB{Changefeed: true}
+	r.MaxStatsAge.Duration = time.Minute
+	r.feeds = map[string]*feed{}
+	r.updates = []feedUpdate{
+		{stats: memberChange(1).NewVal, tags: map[string]string{"type": "member"},
+			time: time.Now().Add(-2 * time.Minute)},
+		{stats: memberChange(5).NewVal, tags: map[string]string{"type": "member"},
+			time: time.Now()},
+	}
+
+	var acc testutil.Accumulator
+	r.flushFeeds(&acc)
+
+	var qps []int64
+	var stale int
+	for _, p := range acc.Points {
+		switch p.Measurement {
+		case "queries_per_sec":
+			qps = append(qps, p.Values["value"].(int64))
+		case "stats_age_s":
+			if p.Values["value"].(float64) > 60 {
+				stale++
+			}
+		}
+	}
+	assert.Equal(t, []int64{5}, qps)
+	assert.Equal(t, 1, stale)
+}
+
+func TestChangefeedPollsOtherStats(t *testing.T) {
+	listener := newSessionServer(t)
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.respondTo(`"server_status"`, `{"t":2,"r":[{"id":"abc","name":"rdb01",`+
+		`"network":{"hostname":"rdb01","reql_port":`+port+`,`+
+		`"canonical_addresses":[{"host":"127.0.0.1","port":29015}]},`+
+		`"process":{"version":"rethinkdb 2.3.5"}}]}`)
+	listener.respondTo(`"server_config"`, `{"t":2,"r":[{"id":"abc","name":"rdb01"}]}`)
+	listener.respondTo(`"table_server"`, `{"t":1,"r":[{"query_engine":{"read_docs_per_sec":4}}]}`)
+	listener.respondTo(`"table_status"`, `{"t":2,"r":[{"id":"t1","db":"app","name":"users"}]}`)
+	listener.respondTo(`"current_issues"`, `{"t":2,"r":[{"type":"log_write_error"}]}`)
+
+	r := &RethinkDB{
+		Servers:       []string{listener.Addr().String()},
+		Changefeed:    true,
+		CurrentIssues: true,
+	}
+	defer r.Stop()
+	urls, err := r.serverUrls()
+	require.NoError(t, err)
+	u := urls[0]
+	r.feeds = map[string]*feed{u.Host: {server: &Server{Url: u}, cursor: &mockCursor{}}}
+	r.updates = []feedUpdate{{stats: memberChange(1).NewVal,
+		tags: map[string]string{"host": u.Host, "type": "member"}, time: time.Now()}}
+
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+
+	// the server stats come from the feed, the stats of its tables and its
+	// issues are still polled
+	assert.NoError(t, acc.ValidateTaggedValue("queries_per_sec", int64(1),
+		map[string]string{"host": u.Host, "type": "member"}))
+	assert.NoError(t, acc.ValidateTaggedValue("read_docs_per_sec", int64(4),
+		map[string]string{"host": u.Host, "hostname": "rdb01", "role": "data", "type": "data",
+			"ns": "app.users"}))
+	assert.NoError(t, acc.ValidateTaggedValue("total_issues", int64(1),
+		map[string]string{"host": u.Host, "hostname": "rdb01", "role": "data"}))
+	assert.True(t, acc.HasMeasurement("connection"))
+
+	listener.mu.Lock()
+	defer listener.mu.Unlock()
+	for _, q := range listener.queries {
+		assert.NotContains(t, q, `"server","abc"`, "the streamed server stats were polled")
+		assert.NotContains(t, q, `["cluster"]`, "the streamed cluster stats were polled")
+	}
+}
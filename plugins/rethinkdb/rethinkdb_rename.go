@@ -0,0 +1,102 @@
+package rethinkdb
+
+import (
+	"time"
+
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// renamingAccumulator renames the metrics listed in renames before adding
+// them to the wrapped accumulator. Both the names passed to Add and the
+// field keys passed to AddFields are renamed.
+type renamingAccumulator struct {
+	plugins.Accumulator
+	renames map[string]string
+}
+
+func (a *renamingAccumulator) rename(name string) string {
+	if renamed, ok := a.renames[name]; ok {
+		return renamed
+	}
+	return name
+}
+
+func (a *renamingAccumulator) Add(
+	measurement string,
+	value interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.Accumulator.Add(a.rename(measurement), value, tags, t...)
+}
+
+func (a *renamingAccumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	renamed := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		renamed[a.rename(k)] = v
+	}
+	a.Accumulator.AddFields(a.rename(measurement), renamed, tags, t...)
+}
+
+// serverAccumulator names every measurement after the server it was gathered
+// from, ie "rethinkdb01.queries_per_sec", and leaves out the tags that
+// identify the server, for backends like Graphite that only have paths.
+type serverAccumulator struct {
+	plugins.Accumulator
+	server string
+}
+
+// perServer wraps acc so that measurements are named after server. When acc
+// renames metrics, the renaming is kept outermost so it still matches the
+// names the metrics are gathered as.
+func perServer(acc plugins.Accumulator, server string) plugins.Accumulator {
+	if renaming, ok := acc.(*renamingAccumulator); ok {
+		return &renamingAccumulator{
+			Accumulator: perServer(renaming.Accumulator, server),
+			renames:     renaming.renames,
+		}
+	}
+	return &serverAccumulator{Accumulator: acc, server: server}
+}
+
+func (a *serverAccumulator) measurement(name string) string {
+	if name == "" {
+		return a.server
+	}
+	return a.server + "." + name
+}
+
+// tags returns a copy of tags without the host and hostname tags, as tags
+// passed to the accumulator must not be modified
+func (a *serverAccumulator) tags(tags map[string]string) map[string]string {
+	stripped := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if k != "host" && k != "hostname" {
+			stripped[k] = v
+		}
+	}
+	return stripped
+}
+
+func (a *serverAccumulator) Add(
+	measurement string,
+	value interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.Accumulator.Add(a.measurement(measurement), value, a.tags(tags), t...)
+}
+
+func (a *serverAccumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.Accumulator.AddFields(a.measurement(measurement), fields, a.tags(tags), t...)
+}
@@ -1,6 +1,7 @@
 package rethinkdb
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -8,6 +9,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/influxdb/telegraf/plugins"
 
@@ -18,33 +20,274 @@ type Server struct {
 	Url          *url.URL
 	session      *gorethink.Session
 	serverStatus serverStatus
+	role         string
+
+	gatherIssues bool
+	// gatherStats are the stats scopes gathered, see statScopes
+	gatherStats []string
+	omitHostTag bool
+	// measurementPerServer names measurements after the server, see name
+	measurementPerServer bool
+	skipVersionCheck     bool
+	// measurementSets adds stats as the fields of a few measurements, see
+	// RethinkDB.MeasurementSets
+	measurementSets bool
+	// maxStatsAge drops stats documents updated longer ago, see addStatsAge
+	maxStatsAge time.Duration
+	// tags are configured for this server only, see RethinkDB.ServerTags
+	tags map[string]string
+	// queryErrorType is the type of the first failed query, see errorType
+	queryErrorType string
+	// skipTableStats leaves out the stats of tables on this gather, see
+	// RethinkDB.TableStatsIntervalMultiplier
+	skipTableStats bool
+	// queries are the custom count queries, see RethinkDB.Queries
+	queries []Query
+	// pooled is the cached session wrapping session, see
+	// RethinkDB.openSession
+	pooled *pooledSession
+}
+
+// statScopes are the scopes of the rethinkdb.stats table that can be
+// gathered, in the order they are gathered
+var statScopes = []string{"cluster", "server", "table", "table_server"}
+
+// tableStatScopes are the scopes of the stats of tables, see
+// RethinkDB.TableStatsIntervalMultiplier
+var tableStatScopes = []string{"table", "table_server"}
+
+// defaultStatScopes are gathered when gather_stats is not set
+var defaultStatScopes = []string{"cluster", "server", "table_server"}
+
+// validStatScopes returns an error unless every scope is one of statScopes
+func validStatScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !scopeEnabled(statScopes, scope) {
+			return fmt.Errorf("Unknown gather_stats scope '%s', expected one of %s",
+				scope, strings.Join(statScopes, ", "))
+		}
+	}
+	return nil
+}
+
+// withoutScopes returns the scopes that are not in removed
+func withoutScopes(scopes, removed []string) []string {
+	var kept []string
+	for _, scope := range scopes {
+		if !scopeEnabled(removed, scope) {
+			kept = append(kept, scope)
+		}
+	}
+	return kept
+}
+
+func scopeEnabled(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// run runs the query on the server's session. If ctx is done before the
+// query returns, the session is closed to abort it and ctx.Err() is returned.
+func (s *Server) run(ctx context.Context, term gorethink.Term) (*gorethink.Cursor, error) {
+	var cursor *gorethink.Cursor
+	err := runWithContext(ctx, func() error {
+		var err error
+		cursor, err = term.Run(s.session)
+		return err
+	}, func() {
+		s.session.Close()
+	})
+	if err != nil && s.queryErrorType == "" {
+		s.queryErrorType = errorType(err)
+	}
+	return cursor, err
+}
+
+// runWithContext calls run and waits for it to return, unless ctx is done
+// first, in which case abort is called and ctx.Err() is returned.
+func runWithContext(ctx context.Context, run func() error, abort func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- run()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		abort()
+		return ctx.Err()
+	}
 }
 
-func (s *Server) gatherData(acc plugins.Accumulator) error {
-	if err := s.getServerStatus(); err != nil {
+func (s *Server) gatherData(ctx context.Context, acc plugins.Accumulator) error {
+	if err := s.getServerStatus(ctx); err != nil {
 		return fmt.Errorf("Failed to get server_status, %s\n", err)
 	}
 
-	if err := s.validateVersion(); err != nil {
+	if err := s.checkVersion(); err != nil {
 		return fmt.Errorf("Failed version validation, %s\n", err.Error())
 	}
 
-	if err := s.addClusterStats(acc); err != nil {
-		fmt.Printf("error adding cluster stats, %s\n", err.Error())
-		return fmt.Errorf("Error adding cluster stats, %s\n", err.Error())
+	if err := s.getServerRole(ctx); err != nil {
+		return fmt.Errorf("Failed to get server_config, %s\n", err)
 	}
 
-	if err := s.addMemberStats(acc); err != nil {
-		return fmt.Errorf("Error adding member stats, %s\n", err.Error())
+	if s.measurementPerServer {
+		acc = perServer(acc, s.name())
 	}
 
-	if err := s.addTableStats(acc); err != nil {
-		return fmt.Errorf("Error adding table stats, %s\n", err.Error())
+	tags := s.getDefaultTags()
+	tags["type"] = "member"
+	s.serverStatus.addUptime(acc, tags, time.Now())
+
+	// the stats, replicas and issues are gathered independently, so that
+	// a failing query does not keep the others from being added
+	var msgs []string
+	if err := s.addStats(ctx, acc, map[string]statsGatherer{
+		"cluster":      s.addClusterStats,
+		"server":       s.addMemberStats,
+		"table":        s.addTableClusterStats,
+		"table_server": s.addTableStats,
+	}); err != nil {
+		msgs = append(msgs, strings.TrimSpace(err.Error()))
+	}
+
+	if !s.skipTableStats {
+		if err := s.addReplicaStats(ctx, acc); err != nil {
+			msgs = append(msgs, fmt.Sprintf("Error adding replica stats, %s", err.Error()))
+		}
 	}
 
+	if s.gatherIssues {
+		if err := s.addIssueStats(ctx, acc); err != nil {
+			msgs = append(msgs, fmt.Sprintf("Error adding current issues, %s", err.Error()))
+		}
+	}
+
+	for _, q := range s.queries {
+		if err := s.addQueryStats(ctx, acc, q); err != nil {
+			msgs = append(msgs, fmt.Sprintf("Error running query %s, %s", q.Name, err.Error()))
+		}
+	}
+
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
 	return nil
 }
 
+// Values of the connect_result tag of the connection measurement
+const (
+	connectSuccess = "success"
+	connectFailure = "failure"
+)
+
+// addConnectionStats adds how long connecting to the server took and
+// whether it succeeded, so that unreachable servers are still reported.
+func (s *Server) addConnectionStats(
+	acc plugins.Accumulator,
+	elapsed time.Duration,
+	err error,
+) {
+	tags := s.configuredTags()
+	tags["connect_result"] = connectSuccess
+	if err != nil {
+		tags["connect_result"] = connectFailure
+	}
+	if !s.omitHostTag {
+		tags["host"] = s.Url.Host
+	}
+	if s.measurementPerServer {
+		acc = perServer(acc, s.name())
+	}
+
+	acc.AddFields("connection", map[string]interface{}{
+		"connection_time_ms": float64(elapsed) / float64(time.Millisecond),
+	}, tags)
+}
+
+// healthcheck runs a trivial query, so that a server that accepts
+// connections but does not answer queries is reported down
+func (s *Server) healthcheck(ctx context.Context) error {
+	cursor, err := s.run(ctx, gorethink.Now())
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	var now time.Time
+	return cursor.One(&now)
+}
+
+// addHealthcheck adds whether the server is up, as up 1 or 0, and how long
+// connecting and querying took in healthcheck_ms.
+func (s *Server) addHealthcheck(
+	acc plugins.Accumulator,
+	elapsed time.Duration,
+	up bool,
+) {
+	tags := s.configuredTags()
+	if !s.omitHostTag {
+		tags["host"] = s.Url.Host
+	}
+	if s.measurementPerServer {
+		acc = perServer(acc, s.name())
+	}
+
+	upValue := int64(0)
+	if up {
+		upValue = 1
+	}
+	acc.Add("up", upValue, tags)
+	acc.Add("healthcheck_ms", float64(elapsed)/float64(time.Millisecond), tags)
+}
+
+type statsGatherer func(ctx context.Context, acc plugins.Accumulator) error
+
+// addStats calls the gatherer of every scope in s.gatherStats. A failing
+// scope does not stop the following ones, the errors of all failed scopes are
+// joined, one per line.
+func (s *Server) addStats(
+	ctx context.Context,
+	acc plugins.Accumulator,
+	gatherers map[string]statsGatherer,
+) error {
+	var msgs []string
+	for _, scope := range statScopes {
+		if !scopeEnabled(s.gatherStats, scope) {
+			continue
+		}
+		if s.skipTableStats && scopeEnabled(tableStatScopes, scope) {
+			continue
+		}
+		if err := gatherers[scope](ctx, acc); err != nil {
+			msgs = append(msgs, fmt.Sprintf("Error adding %s stats, %s", scope,
+				strings.TrimSpace(err.Error())))
+		}
+	}
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+	return nil
+}
+
+// checkVersion validates the version of the server unless the check is
+// skipped
+func (s *Server) checkVersion() error {
+	if s.skipVersionCheck {
+		return nil
+	}
+	return s.validateVersion()
+}
+
 func (s *Server) validateVersion() error {
 	if s.serverStatus.Process.Version == "" {
 		return errors.New("could not determine the RethinkDB server version: process.version key missing")
@@ -63,42 +306,135 @@ func (s *Server) validateVersion() error {
 	return nil
 }
 
-func (s *Server) getServerStatus() error {
-	cursor, err := gorethink.DB("rethinkdb").Table("server_status").Run(s.session)
+func (s *Server) getServerStatuses(ctx context.Context) ([]serverStatus, error) {
+	cursor, err := s.run(ctx, gorethink.DB("rethinkdb").Table("server_status"))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if cursor.IsNil() {
-		return errors.New("could not determine the RethinkDB server version: no rows returned from the server_status table")
+		return nil, errors.New("could not determine the RethinkDB server version: no rows returned from the server_status table")
 	}
 	defer cursor.Close()
 	var serverStatuses []serverStatus
 	err = cursor.All(&serverStatuses)
 	if err != nil {
-		return errors.New("could not parse server_status results")
+		return nil, errors.New("could not parse server_status results")
+	}
+	return serverStatuses, nil
+}
+
+func (s *Server) getServerStatus(ctx context.Context) error {
+	serverStatuses, err := s.getServerStatuses(ctx)
+	if err != nil {
+		return err
 	}
-	host, port, err := net.SplitHostPort(s.Url.Host)
+	ss, err := matchServerStatus(s.Url.Host, serverStatuses)
 	if err != nil {
-		return fmt.Errorf("unable to determine provided hostname from %s\n", s.Url.Host)
+		return err
+	}
+	s.serverStatus = ss
+	return nil
+}
+
+// matchServerStatus returns the status of the server listening on hostport,
+// which may hold a bracketed IPv6 address, ie "[::1]:28015".
+func matchServerStatus(hostport string, serverStatuses []serverStatus) (serverStatus, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return serverStatus{}, fmt.Errorf("unable to determine provided hostname from %s\n", hostport)
 	}
 	driverPort, _ := strconv.Atoi(port)
+	if ss, ok := findServerStatus([]string{host}, driverPort, serverStatuses); ok {
+		return ss, nil
+	}
+	// server_status lists the IP addresses of the servers, the configured
+	// host name, ie the default localhost, is matched by its addresses
+	if net.ParseIP(host) == nil {
+		if addrs, err := net.LookupHost(host); err == nil {
+			if ss, ok := findServerStatus(addrs, driverPort, serverStatuses); ok {
+				return ss, nil
+			}
+		}
+	}
+
+	return serverStatus{}, fmt.Errorf("unable to determine host id from server_status with %s", hostport)
+}
+
+// findServerStatus returns the status of the server listening on driverPort
+// at one of hosts
+func findServerStatus(hosts []string, driverPort int, serverStatuses []serverStatus) (serverStatus, bool) {
 	for _, ss := range serverStatuses {
+		if ss.Network.DriverPort != driverPort {
+			continue
+		}
 		for _, address := range ss.Network.Addresses {
-			if address.Host == host && ss.Network.DriverPort == driverPort {
-				s.serverStatus = ss
-				return nil
+			for _, host := range hosts {
+				if sameHost(address.Host, host) {
+					return ss, true
+				}
 			}
 		}
 	}
+	return serverStatus{}, false
+}
 
-	return fmt.Errorf("unable to determine host id from server_status with %s", s.Url.Host)
+// sameHost compares host names as they are, and IP addresses by value so
+// that different spellings of an IPv6 address match.
+func sameHost(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	return ipA != nil && ipB != nil && ipA.Equal(ipB)
+}
+
+func (s *Server) getServerRole(ctx context.Context) error {
+	cursor, err := s.run(ctx, gorethink.DB("rethinkdb").Table("server_config"))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	var serverConfigs []serverConfig
+	if err := cursor.All(&serverConfigs); err != nil {
+		return errors.New("could not parse server_config results")
+	}
+	s.role = serverRole(s.serverStatus.Id, serverConfigs)
+	return nil
+}
+
+// configuredTags returns a copy of the tags configured for the server, to
+// which the tags of each metric are added
+func (s *Server) configuredTags() map[string]string {
+	tags := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// name returns the name of the server in the cluster, or its hostname when
+// the name is unknown, with dots replaced so it is a single path element.
+func (s *Server) name() string {
+	name := s.serverStatus.Name
+	if name == "" {
+		name = s.serverStatus.Network.Hostname
+	}
+	if name == "" {
+		name = s.Url.Host
+	}
+	return strings.NewReplacer(".", "_", ":", "_", "[", "", "]", "").Replace(name)
 }
 
 func (s *Server) getDefaultTags() map[string]string {
-	tags := make(map[string]string)
-	tags["host"] = s.Url.Host
+	tags := s.configuredTags()
+	if !s.omitHostTag {
+		tags["host"] = s.Url.Host
+	}
 	tags["hostname"] = s.serverStatus.Network.Hostname
+	if s.role != "" {
+		tags["role"] = s.role
+	}
 	return tags
 }
 
@@ -110,8 +446,8 @@ var ClusterTracking = []string{
 	"written_docs_per_sec",
 }
 
-func (s *Server) addClusterStats(acc plugins.Accumulator) error {
-	cursor, err := gorethink.DB("rethinkdb").Table("stats").Get([]string{"cluster"}).Run(s.session)
+func (s *Server) addClusterStats(ctx context.Context, acc plugins.Accumulator) error {
+	cursor, err := s.run(ctx, gorethink.DB("rethinkdb").Table("stats").Get([]string{"cluster"}))
 	if err != nil {
 		return fmt.Errorf("cluster stats query error, %s\n", err.Error())
 	}
@@ -123,7 +459,9 @@ func (s *Server) addClusterStats(acc plugins.Accumulator) error {
 
 	tags := s.getDefaultTags()
 	tags["type"] = "cluster"
-	clusterStats.Engine.AddEngineStats(ClusterTracking, acc, tags)
+	if addStatsAge(acc, tags, clusterStats.Time, time.Now(), s.maxStatsAge) {
+		addEngineStats(acc, s.measurementSets, &clusterStats.Engine, ClusterTracking, tags)
+	}
 	return nil
 }
 
@@ -138,8 +476,8 @@ var MemberTracking = []string{
 	"total_writes",
 }
 
-func (s *Server) addMemberStats(acc plugins.Accumulator) error {
-	cursor, err := gorethink.DB("rethinkdb").Table("stats").Get([]string{"server", s.serverStatus.Id}).Run(s.session)
+func (s *Server) addMemberStats(ctx context.Context, acc plugins.Accumulator) error {
+	cursor, err := s.run(ctx, gorethink.DB("rethinkdb").Table("stats").Get([]string{"server", s.serverStatus.Id}))
 	if err != nil {
 		return fmt.Errorf("member stats query error, %s\n", err.Error())
 	}
@@ -151,7 +489,9 @@ func (s *Server) addMemberStats(acc plugins.Accumulator) error {
 
 	tags := s.getDefaultTags()
 	tags["type"] = "member"
-	memberStats.Engine.AddEngineStats(MemberTracking, acc, tags)
+	if addStatsAge(acc, tags, memberStats.Time, time.Now(), s.maxStatsAge) {
+		addEngineStats(acc, s.measurementSets, &memberStats.Engine, MemberTracking, tags)
+	}
 	return nil
 }
 
@@ -162,18 +502,54 @@ var TableTracking = []string{
 	"total_writes",
 }
 
-func (s *Server) addTableStats(acc plugins.Accumulator) error {
-	tablesCursor, err := gorethink.DB("rethinkdb").Table("table_status").Run(s.session)
+func (s *Server) getTables(ctx context.Context) ([]tableStatus, error) {
+	tablesCursor, err := s.run(ctx, gorethink.DB("rethinkdb").Table("table_status"))
+	if err != nil {
+		return nil, fmt.Errorf("table status query error, %s\n", err.Error())
+	}
 	defer tablesCursor.Close()
 	var tables []tableStatus
-	err = tablesCursor.All(&tables)
+	if err := tablesCursor.All(&tables); err != nil {
+		return nil, errors.New("could not parse table_status results")
+	}
+	return tables, nil
+}
+
+// addTableClusterStats adds the stats of every table across the cluster
+func (s *Server) addTableClusterStats(ctx context.Context, acc plugins.Accumulator) error {
+	tables, err := s.getTables(ctx)
 	if err != nil {
-		return errors.New("could not parse table_status results")
+		return err
+	}
+	for _, table := range tables {
+		cursor, err := s.run(ctx, gorethink.DB("rethinkdb").Table("stats").
+			Get([]string{"table", table.Id}))
+		if err != nil {
+			return fmt.Errorf("table stats query error, %s\n", err.Error())
+		}
+		defer cursor.Close()
+		var ts tableStats
+		if err := cursor.One(&ts); err != nil {
+			return fmt.Errorf("failure to parse table stats, %s\n", err.Error())
+		}
+
+		tags := s.getDefaultTags()
+		tags["type"] = "table"
+		tags["ns"] = fmt.Sprintf("%s.%s", table.DB, table.Name)
+		addEngineStats(acc, s.measurementSets, &ts.Engine, TableTracking, tags)
+	}
+	return nil
+}
+
+// addTableStats adds the stats of every table on this server
+func (s *Server) addTableStats(ctx context.Context, acc plugins.Accumulator) error {
+	tables, err := s.getTables(ctx)
+	if err != nil {
+		return err
 	}
 	for _, table := range tables {
-		cursor, err := gorethink.DB("rethinkdb").Table("stats").
-			Get([]string{"table_server", table.Id, s.serverStatus.Id}).
-			Run(s.session)
+		cursor, err := s.run(ctx, gorethink.DB("rethinkdb").Table("stats").
+			Get([]string{"table_server", table.Id, s.serverStatus.Id}))
 		if err != nil {
 			return fmt.Errorf("table stats query error, %s\n", err.Error())
 		}
@@ -186,8 +562,97 @@ func (s *Server) addTableStats(acc plugins.Accumulator) error {
 		tags := s.getDefaultTags()
 		tags["type"] = "data"
 		tags["ns"] = fmt.Sprintf("%s.%s", table.DB, table.Name)
-		ts.Engine.AddEngineStats(TableTracking, acc, tags)
-		ts.Storage.AddStats(acc, tags)
+		addEngineStats(acc, s.measurementSets, &ts.Engine, TableTracking, tags)
+		if s.measurementSets {
+			acc.AddFields(storageMeasurement, ts.Storage.fields(), tags)
+		} else {
+			ts.Storage.AddStats(acc, tags)
+		}
+	}
+	return nil
+}
+
+func (s *Server) addReplicaStats(ctx context.Context, acc plugins.Accumulator) error {
+	configCursor, err := s.run(ctx, gorethink.DB("rethinkdb").Table("table_config"))
+	if err != nil {
+		return fmt.Errorf("table config query error, %s\n", err.Error())
+	}
+	defer configCursor.Close()
+	var configs []tableConfig
+	if err := configCursor.All(&configs); err != nil {
+		return errors.New("could not parse table_config results")
+	}
+
+	statusCursor, err := s.run(ctx, gorethink.DB("rethinkdb").Table("table_status"))
+	if err != nil {
+		return fmt.Errorf("table status query error, %s\n", err.Error())
+	}
+	defer statusCursor.Close()
+	var statuses []tableShardStatus
+	if err := statusCursor.All(&statuses); err != nil {
+		return errors.New("could not parse table_status results")
+	}
+
+	statusById := make(map[string]tableShardStatus)
+	for _, status := range statuses {
+		statusById[status.Id] = status
+	}
+
+	for _, config := range configs {
+		status := statusById[config.Id]
+		tags := s.getDefaultTags()
+		tags["db"] = config.DB
+		tags["table"] = config.Name
+		if s.measurementSets {
+			acc.AddFields(tableMeasurement, config.replicaFields(&status), tags)
+		} else {
+			config.AddReplicaStats(&status, acc, tags)
+		}
+	}
+	return nil
+}
+
+func (s *Server) addIssueStats(ctx context.Context, acc plugins.Accumulator) error {
+	cursor, err := s.run(ctx, gorethink.DB("rethinkdb").Table("current_issues"))
+	if err != nil {
+		return fmt.Errorf("current issues query error, %s\n", err.Error())
+	}
+	defer cursor.Close()
+	var issues []currentIssue
+	if err := cursor.All(&issues); err != nil {
+		return errors.New("could not parse current_issues results")
+	}
+
+	AddIssueStats(issues, acc, s.getDefaultTags())
+	return nil
+}
+
+// addQueryStats counts the documents of the table of a custom query, read
+// from the database of the query or else the default database of the session
+func (s *Server) addQueryStats(ctx context.Context, acc plugins.Accumulator, q Query) error {
+	db := q.Database
+	term := gorethink.Table(q.Table)
+	if db != "" {
+		term = gorethink.DB(db).Table(q.Table)
+	} else {
+		db = urlDatabase(s.Url)
+	}
+
+	cursor, err := s.run(ctx, term.Count())
+	if err != nil {
+		return fmt.Errorf("count query error, %s\n", err.Error())
+	}
+	defer cursor.Close()
+	var count int64
+	if err := cursor.One(&count); err != nil {
+		return errors.New("could not parse count results")
+	}
+
+	tags := s.getDefaultTags()
+	tags["query"] = q.Name
+	if db != "" {
+		tags["db"] = db
 	}
+	acc.AddFields("query", map[string]interface{}{"count": count}, tags)
 	return nil
 }
@@ -3,6 +3,7 @@
 package rethinkdb
 
 import (
+	"context"
 	"testing"
 
 	"github.com/influxdb/telegraf/testutil"
@@ -15,6 +16,12 @@ func TestValidateVersion(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestGetServerRole(t *testing.T) {
+	err := server.getServerRole(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "data", server.role)
+}
+
 func TestGetDefaultTags(t *testing.T) {
 	var tagTests = []struct {
 		in  string
@@ -22,6 +29,7 @@ func TestGetDefaultTags(t *testing.T) {
 	}{
 		{"host", server.Url.Host},
 		{"hostname", server.serverStatus.Network.Hostname},
+		{"role", server.role},
 	}
 	defaultTags := server.getDefaultTags()
 	for _, tt := range tagTests {
@@ -34,7 +42,7 @@ func TestGetDefaultTags(t *testing.T) {
 func TestAddClusterStats(t *testing.T) {
 	var acc testutil.Accumulator
 
-	err := server.addClusterStats(&acc)
+	err := server.addClusterStats(context.Background(), &acc)
 	require.NoError(t, err)
 
 	for _, metric := range ClusterTracking {
@@ -45,7 +53,7 @@ func TestAddClusterStats(t *testing.T) {
 func TestAddMemberStats(t *testing.T) {
 	var acc testutil.Accumulator
 
-	err := server.addMemberStats(&acc)
+	err := server.addMemberStats(context.Background(), &acc)
 	require.NoError(t, err)
 
 	for _, metric := range MemberTracking {
@@ -56,7 +64,7 @@ func TestAddMemberStats(t *testing.T) {
 func TestAddTableStats(t *testing.T) {
 	var acc testutil.Accumulator
 
-	err := server.addTableStats(&acc)
+	err := server.addTableStats(context.Background(), &acc)
 	require.NoError(t, err)
 
 	for _, metric := range TableTracking {
@@ -79,3 +87,31 @@ func TestAddTableStats(t *testing.T) {
 		assert.True(t, acc.HasIntValue(metric))
 	}
 }
+
+func TestAddReplicaStats(t *testing.T) {
+	var acc testutil.Accumulator
+
+	err := server.addReplicaStats(context.Background(), &acc)
+	require.NoError(t, err)
+
+	keys := []string{
+		"table_shards",
+		"table_replicas",
+		"table_replicas_ready",
+		"table_replicas_not_ready",
+		"table_under_replicated",
+	}
+
+	for _, metric := range keys {
+		assert.True(t, acc.HasIntValue(metric))
+	}
+}
+
+func TestAddIssueStats(t *testing.T) {
+	var acc testutil.Accumulator
+
+	err := server.addIssueStats(context.Background(), &acc)
+	require.NoError(t, err)
+
+	assert.True(t, acc.HasIntValue("total_issues"))
+}
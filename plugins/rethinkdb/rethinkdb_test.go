@@ -3,6 +3,7 @@
 package rethinkdb
 
 import (
+	"context"
 	"log"
 	"math/rand"
 	"net/url"
@@ -37,7 +38,7 @@ func testSetup(m *testing.M) {
 		log.Fatalln(err.Error())
 	}
 
-	err = server.getServerStatus()
+	err = server.getServerStatus(context.Background())
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
@@ -0,0 +1,40 @@
+package plugins
+
+import (
+	"fmt"
+
+	"github.com/naoina/toml"
+)
+
+// Run creates the plugin registered under name, applies the given TOML
+// configuration to it and runs a single Gather into acc. It allows plugins
+// to be used when telegraf is embedded as a library.
+//
+// configTOML holds the plugin's own settings, without the [name] header,
+// ie `servers = ["127.0.0.1:28015"]`.
+//
+// Service plugins are started before the Gather and stopped after it, as the
+// agent does, so that what they hold, ie the cached sessions of RethinkDB, is
+// released.
+func Run(name string, configTOML []byte, acc Accumulator) error {
+	creator, ok := Plugins[name]
+	if !ok {
+		return fmt.Errorf("Undefined plugin: %s", name)
+	}
+
+	plugin := creator()
+	if len(configTOML) > 0 {
+		if err := toml.Unmarshal(configTOML, plugin); err != nil {
+			return fmt.Errorf("Unable to parse config for plugin %s: %s", name, err)
+		}
+	}
+
+	if service, ok := plugin.(ServicePlugin); ok {
+		if err := service.Start(); err != nil {
+			return fmt.Errorf("Service for plugin %s failed to start: %s", name, err)
+		}
+		defer service.Stop()
+	}
+
+	return plugin.Gather(acc)
+}
@@ -0,0 +1,117 @@
+package plugins_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/influxdb/telegraf/plugins"
+	_ "github.com/influxdb/telegraf/plugins/rethinkdb"
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type configurable struct {
+	Name  string
+	Value int64
+}
+
+func (c *configurable) SampleConfig() string { return "" }
+func (c *configurable) Description() string  { return "" }
+func (c *configurable) Gather(acc plugins.Accumulator) error {
+	acc.Add(c.Name, c.Value, nil)
+	return nil
+}
+
+// service records the calls of its service methods
+type service struct {
+	configurable
+	StartErr bool
+	calls    []string
+}
+
+func (s *service) Start() error {
+	s.calls = append(s.calls, "start")
+	if s.StartErr {
+		return errors.New("address in use")
+	}
+	return nil
+}
+
+func (s *service) Stop() { s.calls = append(s.calls, "stop") }
+
+func (s *service) Gather(acc plugins.Accumulator) error {
+	s.calls = append(s.calls, "gather")
+	return s.configurable.Gather(acc)
+}
+
+// lastService is the last service created by the run_test_service plugin
+var lastService *service
+
+func init() {
+	plugins.Add("run_test", func() plugins.Plugin {
+		return &configurable{Name: "default"}
+	})
+	plugins.Add("run_test_service", func() plugins.Plugin {
+		lastService = &service{configurable: configurable{Name: "service"}}
+		return lastService
+	})
+}
+
+func TestRunAppliesConfig(t *testing.T) {
+	var acc testutil.Accumulator
+
+	err := plugins.Run("run_test", []byte("name = \"answer\"\nvalue = 42\n"), &acc)
+	require.NoError(t, err)
+
+	assert.NoError(t, acc.ValidateValue("answer", int64(42)))
+}
+
+func TestRunWithoutConfig(t *testing.T) {
+	var acc testutil.Accumulator
+
+	err := plugins.Run("run_test", nil, &acc)
+	require.NoError(t, err)
+
+	assert.NoError(t, acc.ValidateValue("default", int64(0)))
+}
+
+func TestRunUnknownPlugin(t *testing.T) {
+	var acc testutil.Accumulator
+
+	err := plugins.Run("does_not_exist", nil, &acc)
+	assert.Error(t, err)
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	var acc testutil.Accumulator
+
+	err := plugins.Run("run_test", []byte("value = \"not a number\""), &acc)
+	assert.Error(t, err)
+	assert.Empty(t, acc.Points)
+}
+
+func TestRunRethinkDB(t *testing.T) {
+	var acc testutil.Accumulator
+
+	// nothing listens on port 1, so the gather fails to connect
+	err := plugins.Run("rethinkdb", []byte(`servers = ["127.0.0.1:1"]`), &acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to connect to RethinkDB")
+	// only the failed connection is reported
+	for _, p := range acc.Points {
+		assert.Contains(t, []string{"connection", "errors"}, p.Measurement)
+	}
+}
+
+func TestRunStopsServices(t *testing.T) {
+	var acc testutil.Accumulator
+
+	require.NoError(t, plugins.Run("run_test_service", nil, &acc))
+	assert.Equal(t, []string{"start", "gather", "stop"}, lastService.calls)
+	assert.NoError(t, acc.ValidateValue("service", int64(0)))
+
+	err := plugins.Run("run_test_service", []byte("start_err = true"), &acc)
+	assert.EqualError(t, err, "Service for plugin run_test_service failed to start: address in use")
+	assert.Equal(t, []string{"start"}, lastService.calls)
+}
@@ -0,0 +1,363 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER types used by SNMP
+const (
+	typeInteger        byte = 0x02
+	typeOctetString    byte = 0x04
+	typeNull           byte = 0x05
+	typeObjectID       byte = 0x06
+	typeSequence       byte = 0x30
+	typeIPAddress      byte = 0x40
+	typeCounter32      byte = 0x41
+	typeGauge32        byte = 0x42
+	typeTimeTicks      byte = 0x43
+	typeOpaque         byte = 0x44
+	typeCounter64      byte = 0x46
+	typeNoSuchObject   byte = 0x80
+	typeNoSuchInstance byte = 0x81
+	typeEndOfMibView   byte = 0x82
+
+	pduGetRequest     byte = 0xa0
+	pduGetNextRequest byte = 0xa1
+	pduGetResponse    byte = 0xa2
+	pduReport         byte = 0xa8
+)
+
+// errNoSuchName is the v1 error status returned past the end of a walk
+const errNoSuchName = 2
+
+var errTruncated = errors.New("truncated packet")
+
+// variable is a variable binding, Value is an int64 for the numeric types,
+// a string for octet strings, IP addresses and OIDs, and nil otherwise
+type variable struct {
+	OID   string
+	Type  byte
+	Value interface{}
+}
+
+type packet struct {
+	Version   int
+	Community string
+
+	// MsgID and the parameters of the authoritative engine are only set in
+	// SNMPv3 messages, see usm
+	MsgID       int32
+	EngineID    string
+	EngineBoots int32
+	EngineTime  int32
+
+	PDUType     byte
+	RequestID   int32
+	ErrorStatus int
+	ErrorIndex  int
+	Variables   []variable
+}
+
+func (p *packet) marshal() ([]byte, error) {
+	pdu, err := p.marshalPDU()
+	if err != nil {
+		return nil, err
+	}
+
+	var msg []byte
+	msg = append(msg, tlv(typeInteger, marshalInt(int64(p.Version)))...)
+	msg = append(msg, tlv(typeOctetString, []byte(p.Community))...)
+	msg = append(msg, pdu...)
+	return tlv(typeSequence, msg), nil
+}
+
+// marshalPDU encodes the PDU of the packet, without the message around it
+func (p *packet) marshalPDU() ([]byte, error) {
+	var vbs []byte
+	for _, v := range p.Variables {
+		oid, err := marshalOID(v.OID)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalValue(v)
+		if err != nil {
+			return nil, err
+		}
+		vbs = append(vbs, tlv(typeSequence, append(oid, value...))...)
+	}
+
+	var pdu []byte
+	pdu = append(pdu, tlv(typeInteger, marshalInt(int64(p.RequestID)))...)
+	pdu = append(pdu, tlv(typeInteger, marshalInt(int64(p.ErrorStatus)))...)
+	pdu = append(pdu, tlv(typeInteger, marshalInt(int64(p.ErrorIndex)))...)
+	pdu = append(pdu, tlv(typeSequence, vbs)...)
+	return tlv(p.PDUType, pdu), nil
+}
+
+func unmarshalPacket(b []byte) (*packet, error) {
+	t, msg, _, err := readTLV(b)
+	if err != nil {
+		return nil, err
+	}
+	if t != typeSequence {
+		return nil, fmt.Errorf("unexpected type 0x%x for message", t)
+	}
+
+	p := &packet{}
+	var field []byte
+	if field, msg, err = expect(msg, typeInteger); err != nil {
+		return nil, err
+	}
+	p.Version = int(unmarshalInt(field))
+	if field, msg, err = expect(msg, typeOctetString); err != nil {
+		return nil, err
+	}
+	p.Community = string(field)
+
+	if err := p.unmarshalPDU(msg); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// unmarshalPDU decodes the PDU at the start of b into p
+func (p *packet) unmarshalPDU(b []byte) error {
+	var pdu, field []byte
+	var err error
+	if p.PDUType, pdu, _, err = readTLV(b); err != nil {
+		return err
+	}
+	if field, pdu, err = expect(pdu, typeInteger); err != nil {
+		return err
+	}
+	p.RequestID = int32(unmarshalInt(field))
+	if field, pdu, err = expect(pdu, typeInteger); err != nil {
+		return err
+	}
+	p.ErrorStatus = int(unmarshalInt(field))
+	if field, pdu, err = expect(pdu, typeInteger); err != nil {
+		return err
+	}
+	p.ErrorIndex = int(unmarshalInt(field))
+
+	var vbs []byte
+	if vbs, _, err = expect(pdu, typeSequence); err != nil {
+		return err
+	}
+	for len(vbs) > 0 {
+		var vb []byte
+		if vb, vbs, err = expect(vbs, typeSequence); err != nil {
+			return err
+		}
+		if field, vb, err = expect(vb, typeObjectID); err != nil {
+			return err
+		}
+		v := variable{OID: unmarshalOID(field)}
+		if v.Type, field, _, err = readTLV(vb); err != nil {
+			return err
+		}
+		if v.Value, err = unmarshalValue(v.Type, field); err != nil {
+			return err
+		}
+		p.Variables = append(p.Variables, v)
+	}
+	return nil
+}
+
+// tlv encodes a type, the length of value and value
+func tlv(t byte, value []byte) []byte {
+	b := []byte{t}
+	n := len(value)
+	if n < 0x80 {
+		b = append(b, byte(n))
+	} else {
+		var l []byte
+		for ; n > 0; n >>= 8 {
+			l = append([]byte{byte(n)}, l...)
+		}
+		b = append(b, 0x80|byte(len(l)))
+		b = append(b, l...)
+	}
+	return append(b, value...)
+}
+
+// readTLV decodes the type and value at the start of b, returning the bytes
+// following them as rest
+func readTLV(b []byte) (t byte, value, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errTruncated
+	}
+	t = b[0]
+	n := int(b[1])
+	b = b[2:]
+	if n&0x80 != 0 {
+		size := n & 0x7f
+		if size == 0 || size > 4 || len(b) < size {
+			return 0, nil, nil, errTruncated
+		}
+		n = 0
+		for _, c := range b[:size] {
+			n = n<<8 | int(c)
+		}
+		b = b[size:]
+	}
+	if n < 0 || len(b) < n {
+		return 0, nil, nil, errTruncated
+	}
+	return t, b[:n], b[n:], nil
+}
+
+func expect(b []byte, t byte) (value, rest []byte, err error) {
+	got, value, rest, err := readTLV(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if got != t {
+		return nil, nil, fmt.Errorf("unexpected type 0x%x, expected 0x%x", got, t)
+	}
+	return value, rest, nil
+}
+
+func marshalInt(i int64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(i)}, b...)
+		i >>= 8
+		// stop once the remaining bits only repeat the sign bit
+		if (i == 0 && b[0]&0x80 == 0) || (i == -1 && b[0]&0x80 != 0) {
+			return b
+		}
+	}
+}
+
+func unmarshalInt(b []byte) int64 {
+	var i int64
+	for n, c := range b {
+		if n == 0 && c&0x80 != 0 {
+			i = -1
+		}
+		i = i<<8 | int64(c)
+	}
+	return i
+}
+
+func unmarshalUint(b []byte) uint64 {
+	var i uint64
+	for _, c := range b {
+		i = i<<8 | uint64(c)
+	}
+	return i
+}
+
+func marshalOID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %s", oid)
+	}
+	ids := make([]uint64, len(parts))
+	for i, part := range parts {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %s", oid)
+		}
+		ids[i] = id
+	}
+
+	b := base128(ids[0]*40 + ids[1])
+	for _, id := range ids[2:] {
+		b = append(b, base128(id)...)
+	}
+	return tlv(typeObjectID, b), nil
+}
+
+func base128(id uint64) []byte {
+	b := []byte{byte(id & 0x7f)}
+	for id >>= 7; id > 0; id >>= 7 {
+		b = append([]byte{byte(id&0x7f) | 0x80}, b...)
+	}
+	return b
+}
+
+func unmarshalOID(b []byte) string {
+	var ids []string
+	var id uint64
+	for _, c := range b {
+		id = id<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			continue
+		}
+		if len(ids) == 0 {
+			first := id / 40
+			if first > 2 {
+				first = 2
+			}
+			ids = append(ids, strconv.FormatUint(first, 10),
+				strconv.FormatUint(id-first*40, 10))
+		} else {
+			ids = append(ids, strconv.FormatUint(id, 10))
+		}
+		id = 0
+	}
+	return strings.Join(ids, ".")
+}
+
+func marshalValue(v variable) ([]byte, error) {
+	switch v.Type {
+	case typeInteger, typeCounter32, typeGauge32, typeTimeTicks, typeCounter64:
+		i, ok := v.Value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("invalid value %v for %s", v.Value, v.OID)
+		}
+		b := marshalInt(i)
+		if v.Type != typeInteger && len(b) > 1 && b[0] == 0 {
+			// unsigned types need no sign byte
+			b = b[1:]
+		}
+		return tlv(v.Type, b), nil
+	case typeOctetString, typeOpaque:
+		s, _ := v.Value.(string)
+		return tlv(v.Type, []byte(s)), nil
+	case typeIPAddress:
+		s, _ := v.Value.(string)
+		var ip []byte
+		for _, part := range strings.Split(s, ".") {
+			n, err := strconv.ParseUint(part, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP address %s for %s", s, v.OID)
+			}
+			ip = append(ip, byte(n))
+		}
+		return tlv(v.Type, ip), nil
+	case typeObjectID:
+		s, _ := v.Value.(string)
+		return marshalOID(s)
+	case typeNull, typeNoSuchObject, typeNoSuchInstance, typeEndOfMibView:
+		return tlv(v.Type, nil), nil
+	}
+	return nil, fmt.Errorf("unsupported type 0x%x for %s", v.Type, v.OID)
+}
+
+func unmarshalValue(t byte, b []byte) (interface{}, error) {
+	switch t {
+	case typeInteger:
+		return unmarshalInt(b), nil
+	case typeCounter32, typeGauge32, typeTimeTicks, typeCounter64:
+		return int64(unmarshalUint(b)), nil
+	case typeOctetString, typeOpaque:
+		return string(b), nil
+	case typeIPAddress:
+		parts := make([]string, len(b))
+		for i, c := range b {
+			parts[i] = strconv.Itoa(int(c))
+		}
+		return strings.Join(parts, "."), nil
+	case typeObjectID:
+		return unmarshalOID(b), nil
+	case typeNull, typeNoSuchObject, typeNoSuchInstance, typeEndOfMibView:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported type 0x%x", t)
+}
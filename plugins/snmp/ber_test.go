@@ -0,0 +1,83 @@
+package snmp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalInt(t *testing.T) {
+	for i, b := range map[int64][]byte{
+		0:      {0x00},
+		127:    {0x7f},
+		128:    {0x00, 0x80},
+		256:    {0x01, 0x00},
+		-1:     {0xff},
+		-128:   {0x80},
+		-129:   {0xff, 0x7f},
+		100000: {0x01, 0x86, 0xa0},
+	} {
+		assert.Equal(t, b, marshalInt(i), "%d", i)
+		assert.Equal(t, i, unmarshalInt(b), "%d", i)
+	}
+}
+
+func TestMarshalOID(t *testing.T) {
+	b, err := marshalOID("1.3.6.1.2.1.1.3.0")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x03, 0x00}, b)
+
+	b, err = marshalOID(".1.3.6.1.4.1.2636")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x06, 0x07, 0x2b, 0x06, 0x01, 0x04, 0x01, 0x94, 0x4c}, b)
+	assert.Equal(t, "1.3.6.1.4.1.2636", unmarshalOID(b[2:]))
+
+	for _, oid := range []string{"", "1", "1.3.x", "1.3.-6"} {
+		_, err := marshalOID(oid)
+		assert.Error(t, err, oid)
+	}
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	long := make([]byte, 300)
+	for i := range long {
+		long[i] = 'a'
+	}
+	p := &packet{
+		Version:   1,
+		Community: "public",
+		PDUType:   pduGetResponse,
+		RequestID: 1234567,
+		Variables: []variable{
+			{OID: "1.3.6.1.2.1.1.1.0", Type: typeOctetString, Value: string(long)},
+			{OID: "1.3.6.1.2.1.1.3.0", Type: typeTimeTicks, Value: int64(4294967295)},
+			{OID: "1.3.6.1.2.1.1.2.0", Type: typeObjectID, Value: "1.3.6.1.4.1.8072"},
+			{OID: "1.3.6.1.2.1.4.20.1.1", Type: typeIPAddress, Value: "10.0.0.1"},
+			{OID: "1.3.6.1.2.1.31.1.1.1.6.1", Type: typeCounter64, Value: int64(1) << 40},
+			{OID: "1.3.6.1.2.1.2.2.1.8.1", Type: typeInteger, Value: int64(-2)},
+			{OID: "1.3.6.1.2.1.1.9.0", Type: typeNoSuchObject},
+		},
+	}
+	b, err := p.marshal()
+	require.NoError(t, err)
+
+	got, err := unmarshalPacket(b)
+	require.NoError(t, err)
+	assert.Equal(t, p, got)
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	p := &packet{
+		Community: "public",
+		PDUType:   pduGetRequest,
+		Variables: []variable{{OID: "1.3.6.1.2.1.1.3.0", Type: typeNull}},
+	}
+	b, err := p.marshal()
+	require.NoError(t, err)
+
+	for i := 0; i < len(b)-1; i++ {
+		_, err := unmarshalPacket(b[:i])
+		assert.Error(t, err, "%d bytes", i)
+	}
+}
@@ -0,0 +1,496 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// Snmp polls SNMP agents
+type Snmp struct {
+	Hosts []Host
+}
+
+// Host is an SNMP agent and the objects polled from it
+type Host struct {
+	// Address of the agent as host or host:port, port 161 by default
+	Address   string
+	Community string
+	// Version is 1, 2 (for v2c) or 3
+	Version int
+	Timeout internal.Duration
+	Retries int
+
+	// SNMPv3 credentials of the user-based security model. SecLevel is
+	// noAuthNoPriv, authNoPriv or authPriv, AuthProtocol MD5 or SHA and
+	// PrivProtocol DES or AES (AES-128).
+	SecName      string
+	SecLevel     string
+	AuthProtocol string
+	AuthPassword string
+	PrivProtocol string
+	PrivPassword string
+	ContextName  string
+
+	// Get lists the objects read with a GET
+	Get []Object
+	// Walk lists the subtrees every object of which is read
+	Walk []Object
+}
+
+// Object names an OID or a subtree. Oid is a numeric OID or a name from the
+// SNMPv2-MIB system group or the IF-MIB interfaces table, optionally
+// followed by an index such as "sysUpTime.0".
+type Object struct {
+	Name string
+	Oid  string
+}
+
+var sampleConfig = `
+  # Specify agents via an array of tables
+  [[snmp.hosts]]
+    # host or host:port of the agent, port 161 by default
+    address = "127.0.0.1:161"
+    community = "public"
+    # SNMP version, 1, 2 (for v2c) or 3
+    version = 2
+    timeout = "5s"
+    retries = 3
+
+    # SNMPv3 user, the community is not used with version 3
+    # sec_name = "telegraf"
+    # noAuthNoPriv, authNoPriv or authPriv
+    # sec_level = "authPriv"
+    # MD5 or SHA
+    # auth_protocol = "SHA"
+    # auth_password = "secret-auth"
+    # DES or AES (AES-128)
+    # priv_protocol = "AES"
+    # priv_password = "secret-priv"
+    # context_name = ""
+
+    # Objects to GET, by numeric OID or by name from SNMPv2-MIB::system
+    # and IF-MIB::ifTable. name defaults to the name of the OID, or to the
+    # OID itself when given by number.
+    [[snmp.hosts.get]]
+      name = "uptime"
+      oid = "sysUpTime.0"
+
+    # Subtrees to WALK, each object tagged by its instance index
+    [[snmp.hosts.walk]]
+      oid = "ifInOctets"
+    [[snmp.hosts.walk]]
+      name = "if_out_octets"
+      oid = "1.3.6.1.2.1.2.2.1.16"
+`
+
+func (s *Snmp) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Snmp) Description() string {
+	return "Read values from SNMP agents using GET and WALK"
+}
+
+func (s *Snmp) Gather(acc plugins.Accumulator) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []string
+	for _, h := range s.Hosts {
+		wg.Add(1)
+		go func(h Host) {
+			defer wg.Done()
+			if err := h.gather(acc); err != nil {
+				mu.Lock()
+				errs = append(errs, err.Error())
+				mu.Unlock()
+			}
+		}(h)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func (h *Host) gather(acc plugins.Accumulator) error {
+	c, err := h.dial()
+	if err != nil {
+		return err
+	}
+	defer c.conn.Close()
+	host := c.host
+
+	if len(h.Get) > 0 {
+		oids := make([]string, len(h.Get))
+		names := make(map[string]string)
+		for i, o := range h.Get {
+			oid, name, err := resolve(o)
+			if err != nil {
+				return err
+			}
+			oids[i] = oid
+			names[oid] = name
+		}
+
+		vars, err := c.get(oids)
+		if err != nil {
+			return fmt.Errorf("SNMP get from %s failed: %s", h.Address, err)
+		}
+		for _, v := range vars {
+			if v.Value == nil {
+				continue
+			}
+			acc.Add(names[v.OID], v.Value, map[string]string{
+				"host": host,
+				"oid":  v.OID,
+			})
+		}
+	}
+
+	for _, o := range h.Walk {
+		root, name, err := resolve(o)
+		if err != nil {
+			return err
+		}
+		vars, err := c.walk(root)
+		if err != nil {
+			return fmt.Errorf("SNMP walk of %s from %s failed: %s", root, h.Address, err)
+		}
+		for _, v := range vars {
+			if v.Value == nil {
+				continue
+			}
+			acc.Add(name, v.Value, map[string]string{
+				"host":     host,
+				"oid":      v.OID,
+				"instance": strings.TrimPrefix(v.OID, root+"."),
+			})
+		}
+	}
+	return nil
+}
+
+func (h *Host) dial() (*client, error) {
+	var version int
+	switch h.Version {
+	case 1:
+		version = 0
+	case 0, 2:
+		version = 1
+	case 3:
+		version = 3
+	default:
+		return nil, fmt.Errorf("Unsupported SNMP version %d for %s, must be 1, 2 or 3",
+			h.Version, h.Address)
+	}
+	var sec *usm
+	if version == 3 {
+		var err error
+		if sec, err = newUSM(h); err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := internal.ParseServer(h.Address, "161")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial("udp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	community := h.Community
+	if community == "" {
+		community = "public"
+	}
+	timeout := h.Timeout.Duration
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	return &client{
+		host:      u.Hostname(),
+		conn:      conn,
+		version:   version,
+		community: community,
+		timeout:   timeout,
+		retries:   h.Retries,
+		requestID: rand.Int31(),
+		usm:       sec,
+	}, nil
+}
+
+// resolve returns the numeric OID of o and the name its values are reported
+// under
+func resolve(o Object) (oid, name string, err error) {
+	oid = strings.TrimPrefix(o.Oid, ".")
+	symbol := oid
+	if i := strings.Index(oid, "."); i >= 0 {
+		symbol = oid[:i]
+	}
+	name = o.Name
+	if base, ok := mibNames[symbol]; ok {
+		oid = base + strings.TrimPrefix(oid, symbol)
+		if name == "" {
+			name = symbol
+		}
+	} else if _, err := marshalOID(oid); err != nil {
+		return "", "", fmt.Errorf("Invalid OID '%s'", o.Oid)
+	}
+
+	if name == "" {
+		name = oid
+	}
+	return oid, name, nil
+}
+
+// mibNames maps the objects of the SNMPv2-MIB system group and the IF-MIB
+// interfaces table to their OIDs
+var mibNames = map[string]string{
+	"sysDescr":    "1.3.6.1.2.1.1.1",
+	"sysObjectID": "1.3.6.1.2.1.1.2",
+	"sysUpTime":   "1.3.6.1.2.1.1.3",
+	"sysContact":  "1.3.6.1.2.1.1.4",
+	"sysName":     "1.3.6.1.2.1.1.5",
+	"sysLocation": "1.3.6.1.2.1.1.6",
+	"sysServices": "1.3.6.1.2.1.1.7",
+
+	"ifNumber":          "1.3.6.1.2.1.2.1",
+	"ifTable":           "1.3.6.1.2.1.2.2",
+	"ifIndex":           "1.3.6.1.2.1.2.2.1.1",
+	"ifDescr":           "1.3.6.1.2.1.2.2.1.2",
+	"ifType":            "1.3.6.1.2.1.2.2.1.3",
+	"ifMtu":             "1.3.6.1.2.1.2.2.1.4",
+	"ifSpeed":           "1.3.6.1.2.1.2.2.1.5",
+	"ifPhysAddress":     "1.3.6.1.2.1.2.2.1.6",
+	"ifAdminStatus":     "1.3.6.1.2.1.2.2.1.7",
+	"ifOperStatus":      "1.3.6.1.2.1.2.2.1.8",
+	"ifLastChange":      "1.3.6.1.2.1.2.2.1.9",
+	"ifInOctets":        "1.3.6.1.2.1.2.2.1.10",
+	"ifInUcastPkts":     "1.3.6.1.2.1.2.2.1.11",
+	"ifInNUcastPkts":    "1.3.6.1.2.1.2.2.1.12",
+	"ifInDiscards":      "1.3.6.1.2.1.2.2.1.13",
+	"ifInErrors":        "1.3.6.1.2.1.2.2.1.14",
+	"ifInUnknownProtos": "1.3.6.1.2.1.2.2.1.15",
+	"ifOutOctets":       "1.3.6.1.2.1.2.2.1.16",
+	"ifOutUcastPkts":    "1.3.6.1.2.1.2.2.1.17",
+	"ifOutNUcastPkts":   "1.3.6.1.2.1.2.2.1.18",
+	"ifOutDiscards":     "1.3.6.1.2.1.2.2.1.19",
+	"ifOutErrors":       "1.3.6.1.2.1.2.2.1.20",
+	"ifOutQLen":         "1.3.6.1.2.1.2.2.1.21",
+}
+
+// client sends requests to a single agent
+type client struct {
+	host      string
+	conn      net.Conn
+	version   int
+	community string
+	timeout   time.Duration
+	retries   int
+	requestID int32
+	// usm secures the SNMPv3 messages, nil for v1 and v2c
+	usm *usm
+}
+
+// maxWalk bounds the number of objects read by a single walk
+const maxWalk = 10000
+
+func (c *client) get(oids []string) ([]variable, error) {
+	resp, err := c.request(pduGetRequest, oids)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ErrorStatus != 0 {
+		oid := ""
+		if resp.ErrorIndex > 0 && resp.ErrorIndex <= len(oids) {
+			oid = oids[resp.ErrorIndex-1]
+		}
+		return nil, fmt.Errorf("agent returned error status %d for %s",
+			resp.ErrorStatus, oid)
+	}
+	return resp.Variables, nil
+}
+
+// walk reads every object in the subtree under root with GETNEXT requests
+func (c *client) walk(root string) ([]variable, error) {
+	var vars []variable
+	oid := root
+	for len(vars) < maxWalk {
+		resp, err := c.request(pduGetNextRequest, []string{oid})
+		if err != nil {
+			return nil, err
+		}
+		if resp.ErrorStatus == errNoSuchName {
+			// v1 agents signal the end of the MIB with noSuchName
+			return vars, nil
+		}
+		if resp.ErrorStatus != 0 {
+			return nil, fmt.Errorf("agent returned error status %d for %s",
+				resp.ErrorStatus, oid)
+		}
+		if len(resp.Variables) != 1 {
+			return nil, fmt.Errorf("expected 1 variable, got %d", len(resp.Variables))
+		}
+
+		v := resp.Variables[0]
+		if v.Type == typeEndOfMibView || !strings.HasPrefix(v.OID, root+".") {
+			return vars, nil
+		}
+		if v.OID == oid {
+			return nil, fmt.Errorf("agent did not advance past %s", oid)
+		}
+		vars = append(vars, v)
+		oid = v.OID
+	}
+	return vars, nil
+}
+
+// request sends a request for oids, retrying when no response arrives in
+// time
+func (c *client) request(pduType byte, oids []string) (*packet, error) {
+	c.requestID++
+	req := &packet{
+		Version:   c.version,
+		Community: c.community,
+		PDUType:   pduType,
+		RequestID: c.requestID,
+	}
+	for _, oid := range oids {
+		req.Variables = append(req.Variables, variable{OID: oid, Type: typeNull})
+	}
+	if c.usm != nil {
+		return c.requestV3(req)
+	}
+	b, err := req.marshal()
+	if err != nil {
+		return nil, err
+	}
+	return c.exchange(b, req.RequestID)
+}
+
+// requestV3 sends req in an SNMPv3 message, discovering the engine of the
+// agent first. The request is sent again once when the agent reports that
+// its engine changed or that its time moved, ie after a reboot.
+func (c *client) requestV3(req *packet) (*packet, error) {
+	if c.usm.engineID == "" {
+		if err := c.discover(); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req.MsgID = req.RequestID
+		b, err := c.usm.marshal(req, c.usm.level|flagReportable)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.exchange(b, req.MsgID)
+		if err != nil {
+			return nil, err
+		}
+		if resp.PDUType != pduReport {
+			return resp, nil
+		}
+
+		report := reportName(resp)
+		if attempt > 0 || (report != "usmStatsNotInTimeWindows" &&
+			report != "usmStatsUnknownEngineIDs") {
+			return nil, fmt.Errorf("agent reported %s", report)
+		}
+		c.usm.setEngine(resp.EngineID, resp.EngineBoots, resp.EngineTime)
+		c.requestID++
+		req.RequestID = c.requestID
+	}
+}
+
+// discover asks the agent for the ID, boots and time of its engine, which
+// it reports in answer to an empty unauthenticated request
+func (c *client) discover() error {
+	c.requestID++
+	probe := &packet{
+		Version:   3,
+		MsgID:     c.requestID,
+		PDUType:   pduGetRequest,
+		RequestID: c.requestID,
+	}
+	b, err := c.usm.marshal(probe, flagReportable)
+	if err != nil {
+		return err
+	}
+	resp, err := c.exchange(b, probe.MsgID)
+	if err != nil {
+		return fmt.Errorf("engine discovery failed: %s", err)
+	}
+	if resp.EngineID == "" {
+		return errors.New("engine discovery failed: no engine ID reported")
+	}
+	c.usm.setEngine(resp.EngineID, resp.EngineBoots, resp.EngineTime)
+	return nil
+}
+
+// exchange sends the message b, retrying when no response to the request
+// with the given id arrives in time
+func (c *client) exchange(b []byte, id int32) (*packet, error) {
+	buf := make([]byte, 65536)
+	for attempt := 0; ; attempt++ {
+		if _, err := c.conn.Write(b); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.read(buf, id)
+		if err == nil {
+			return resp, nil
+		}
+		if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() || attempt >= c.retries {
+			return nil, err
+		}
+	}
+}
+
+// read waits for the response to the request with the given id, discarding
+// responses to earlier requests. SNMPv3 responses are matched by the id of
+// their message, as reports may not carry the id of the request.
+func (c *client) read(buf []byte, id int32) (*packet, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if c.usm != nil {
+			resp, err := c.usm.unmarshal(buf[:n])
+			if err != nil {
+				return nil, err
+			}
+			if (resp.PDUType == pduGetResponse || resp.PDUType == pduReport) &&
+				resp.MsgID == id {
+				return resp, nil
+			}
+			continue
+		}
+		resp, err := unmarshalPacket(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		if resp.PDUType == pduGetResponse && resp.RequestID == id {
+			return resp, nil
+		}
+	}
+}
+
+func init() {
+	plugins.Add("snmp", func() plugins.Plugin {
+		return &Snmp{}
+	})
+}
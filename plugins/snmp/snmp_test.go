@@ -0,0 +1,334 @@
+package snmp
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockAgent answers GET and GETNEXT requests for a fixed set of objects
+type mockAgent struct {
+	conn      net.PacketConn
+	community string
+	objects   []variable
+	// usm is the user of an SNMPv3 agent, nil for v1 and v2c
+	usm *usm
+}
+
+// newMockV3Agent is a mock SNMPv3 agent with the user of h. It reports the
+// boots and time of its engine only once a request is authenticated, as
+// RFC 3414 4 discovery allows.
+func newMockV3Agent(t *testing.T, h Host, objects ...variable) *mockAgent {
+	sec, err := newUSM(&h)
+	require.NoError(t, err)
+	sec.setEngine("\x80\x00\x1f\x88mock", 3, 1000)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	sort.Sort(byOID(objects))
+	a := &mockAgent{conn: conn, objects: objects, usm: sec}
+	go a.serve()
+	return a
+}
+
+func newMockAgent(t *testing.T, objects ...variable) *mockAgent {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	sort.Sort(byOID(objects))
+	a := &mockAgent{conn: conn, community: "public", objects: objects}
+	go a.serve()
+	return a
+}
+
+func (a *mockAgent) Addr() string {
+	return a.conn.LocalAddr().String()
+}
+
+func (a *mockAgent) serve() {
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if a.usm != nil {
+			a.serveV3(buf[:n], addr)
+			continue
+		}
+		req, err := unmarshalPacket(buf[:n])
+		if err != nil || req.Community != a.community {
+			continue
+		}
+
+		resp := a.respond(req)
+		resp.Community = req.Community
+		b, _ := resp.marshal()
+		a.conn.WriteTo(b, addr)
+	}
+}
+
+func (a *mockAgent) respond(req *packet) *packet {
+	resp := &packet{
+		Version:   req.Version,
+		PDUType:   pduGetResponse,
+		RequestID: req.RequestID,
+	}
+	for i, v := range req.Variables {
+		var found variable
+		if req.PDUType == pduGetNextRequest {
+			found = a.next(v.OID)
+		} else {
+			found = a.get(v.OID)
+		}
+		if req.Version == 0 && found.Value == nil {
+			resp.ErrorStatus = errNoSuchName
+			resp.ErrorIndex = i + 1
+			found = v
+		}
+		resp.Variables = append(resp.Variables, found)
+	}
+	return resp
+}
+
+// serveV3 answers an SNMPv3 request, or reports why it was not processed
+func (a *mockAgent) serveV3(b []byte, addr net.Addr) {
+	report := func(msgID int32, oid string, from *usm, flags byte) {
+		resp := &packet{MsgID: msgID, PDUType: pduReport, Variables: []variable{
+			{OID: oid, Type: typeCounter32, Value: int64(1)},
+		}}
+		b, _ := from.marshal(resp, flags)
+		a.conn.WriteTo(b, addr)
+	}
+
+	req, err := a.usm.unmarshal(b)
+	if err != nil {
+		if m, err := parseV3Message(b); err == nil {
+			report(m.msgID, "1.3.6.1.6.3.15.1.1.5.0", &usm{}, 0)
+		}
+		return
+	}
+
+	switch {
+	case req.EngineID == "":
+		// the engine ID is discovered with boots and time left unknown
+		discovery := &usm{}
+		discovery.setEngine(a.usm.engineID, 0, 0)
+		report(req.MsgID, "1.3.6.1.6.3.15.1.1.4.0", discovery, 0)
+	case a.usm.level&flagAuth != 0 && (req.EngineBoots != a.usm.boots ||
+		req.EngineTime < a.usm.engineTime()-150 || req.EngineTime > a.usm.engineTime()+150):
+		report(req.MsgID, "1.3.6.1.6.3.15.1.1.2.0", a.usm, flagAuth)
+	default:
+		resp := a.respond(req)
+		resp.MsgID = req.MsgID
+		b, _ := a.usm.marshal(resp, a.usm.level)
+		a.conn.WriteTo(b, addr)
+	}
+}
+
+func (a *mockAgent) get(oid string) variable {
+	for _, v := range a.objects {
+		if v.OID == oid {
+			return v
+		}
+	}
+	return variable{OID: oid, Type: typeNoSuchObject}
+}
+
+func (a *mockAgent) next(oid string) variable {
+	for _, v := range a.objects {
+		if compareOIDs(v.OID, oid) > 0 {
+			return v
+		}
+	}
+	return variable{OID: oid, Type: typeEndOfMibView}
+}
+
+func compareOIDs(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, _ := strconv.Atoi(as[i])
+		y, _ := strconv.Atoi(bs[i])
+		if x != y {
+			return x - y
+		}
+	}
+	return len(as) - len(bs)
+}
+
+type byOID []variable
+
+func (s byOID) Len() int           { return len(s) }
+func (s byOID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byOID) Less(i, j int) bool { return compareOIDs(s[i].OID, s[j].OID) < 0 }
+
+var agentObjects = []variable{
+	{OID: "1.3.6.1.2.1.1.1.0", Type: typeOctetString, Value: "mock agent"},
+	{OID: "1.3.6.1.2.1.1.3.0", Type: typeTimeTicks, Value: int64(123456)},
+	{OID: "1.3.6.1.2.1.2.2.1.2.1", Type: typeOctetString, Value: "lo"},
+	{OID: "1.3.6.1.2.1.2.2.1.2.2", Type: typeOctetString, Value: "eth0"},
+	{OID: "1.3.6.1.2.1.2.2.1.10.1", Type: typeCounter32, Value: int64(1000)},
+	{OID: "1.3.6.1.2.1.2.2.1.10.2", Type: typeCounter32, Value: int64(2000)},
+	{OID: "1.3.6.1.2.1.2.2.1.10.10", Type: typeCounter32, Value: int64(3000)},
+	{OID: "1.3.6.1.2.1.2.2.1.16.1", Type: typeCounter32, Value: int64(4000)},
+}
+
+func TestGatherGet(t *testing.T) {
+	agent := newMockAgent(t, agentObjects...)
+	defer agent.conn.Close()
+
+	s := &Snmp{Hosts: []Host{{
+		Address: agent.Addr(),
+		Get: []Object{
+			{Name: "uptime", Oid: "sysUpTime.0"},
+			{Oid: "1.3.6.1.2.1.1.1.0", Name: "description"},
+			{Oid: "sysName.0"},
+		},
+	}}}
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	// sysName.0 does not exist
+	require.Len(t, acc.Points, 2)
+
+	assert.NoError(t, acc.ValidateTaggedValue("uptime", int64(123456), map[string]string{
+		"host": "127.0.0.1",
+		"oid":  "1.3.6.1.2.1.1.3.0",
+	}))
+	assert.NoError(t, acc.ValidateTaggedValue("description", "mock agent", map[string]string{
+		"host": "127.0.0.1",
+		"oid":  "1.3.6.1.2.1.1.1.0",
+	}))
+}
+
+func TestGatherWalk(t *testing.T) {
+	for _, version := range []int{1, 2} {
+		agent := newMockAgent(t, agentObjects...)
+
+		s := &Snmp{Hosts: []Host{{
+			Address: agent.Addr(),
+			Version: version,
+			Walk: []Object{
+				{Oid: "ifInOctets"},
+				{Oid: "1.3.6.1.2.1.2.2.1.16", Name: "out_octets"},
+			},
+		}}}
+
+		var acc testutil.Accumulator
+		require.NoError(t, s.Gather(&acc), "version %d", version)
+		require.Len(t, acc.Points, 4, "version %d", version)
+
+		for instance, octets := range map[string]int64{"1": 1000, "2": 2000, "10": 3000} {
+			assert.NoError(t, acc.ValidateTaggedValue("ifInOctets", octets, map[string]string{
+				"host":     "127.0.0.1",
+				"oid":      "1.3.6.1.2.1.2.2.1.10." + instance,
+				"instance": instance,
+			}))
+		}
+		// the walk ends with the last object of the agent
+		assert.NoError(t, acc.ValidateTaggedValue("out_octets", int64(4000), map[string]string{
+			"host":     "127.0.0.1",
+			"oid":      "1.3.6.1.2.1.2.2.1.16.1",
+			"instance": "1",
+		}))
+		agent.conn.Close()
+	}
+}
+
+func TestGatherV3(t *testing.T) {
+	for _, h := range v3Hosts {
+		agent := newMockV3Agent(t, h, agentObjects...)
+
+		h.Address = agent.Addr()
+		h.Get = []Object{{Name: "uptime", Oid: "sysUpTime.0"}}
+		h.Walk = []Object{{Oid: "ifInOctets"}}
+		s := &Snmp{Hosts: []Host{h}}
+
+		var acc testutil.Accumulator
+		require.NoError(t, s.Gather(&acc), "%+v", h)
+		require.Len(t, acc.Points, 4, "%+v", h)
+		assert.NoError(t, acc.ValidateTaggedValue("uptime", int64(123456), map[string]string{
+			"host": "127.0.0.1",
+			"oid":  "1.3.6.1.2.1.1.3.0",
+		}), "%+v", h)
+		assert.NoError(t, acc.ValidateTaggedValue("ifInOctets", int64(3000), map[string]string{
+			"host":     "127.0.0.1",
+			"oid":      "1.3.6.1.2.1.2.2.1.10.10",
+			"instance": "10",
+		}), "%+v", h)
+		agent.conn.Close()
+	}
+}
+
+func TestGatherV3WrongCredentials(t *testing.T) {
+	h := v3Host("authPriv", "SHA", "AES")
+	agent := newMockV3Agent(t, h, agentObjects...)
+	defer agent.conn.Close()
+
+	h.Address = agent.Addr()
+	h.AuthPassword = "other-password"
+	h.Get = []Object{{Oid: "sysUpTime.0"}}
+	s := &Snmp{Hosts: []Host{h}}
+
+	var acc testutil.Accumulator
+	assert.EqualError(t, s.Gather(&acc), "SNMP get from "+agent.Addr()+
+		" failed: agent reported usmStatsWrongDigests")
+	assert.Len(t, acc.Points, 0)
+}
+
+func TestGatherTimeout(t *testing.T) {
+	agent := newMockAgent(t, agentObjects...)
+	defer agent.conn.Close()
+
+	s := &Snmp{Hosts: []Host{{
+		Address:   agent.Addr(),
+		Community: "private",
+		Timeout:   internal.Duration{Duration: 20 * time.Millisecond},
+		Retries:   1,
+		Get:       []Object{{Oid: "sysUpTime.0"}},
+	}}}
+
+	var acc testutil.Accumulator
+	assert.Error(t, s.Gather(&acc))
+	assert.Len(t, acc.Points, 0)
+}
+
+func TestGatherBadConfig(t *testing.T) {
+	for _, h := range []Host{
+		{Address: "127.0.0.1", Version: 4},
+		{Address: "127.0.0.1", Version: 3},
+		{Address: "", Version: 2},
+		{Address: "127.0.0.1", Get: []Object{{Oid: "notAnObject"}}},
+		{Address: "127.0.0.1", Walk: []Object{{Oid: "1.3.x"}}},
+	} {
+		s := &Snmp{Hosts: []Host{h}}
+		var acc testutil.Accumulator
+		assert.Error(t, s.Gather(&acc), "%+v", h)
+		assert.Len(t, acc.Points, 0)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	oid, name, err := resolve(Object{Oid: "ifDescr"})
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.6.1.2.1.2.2.1.2", oid)
+	assert.Equal(t, "ifDescr", name)
+
+	oid, name, err = resolve(Object{Oid: ".1.3.6.1.2.1.1.5.0", Name: "name"})
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.6.1.2.1.1.5.0", oid)
+	assert.Equal(t, "name", name)
+
+	oid, name, err = resolve(Object{Oid: "1.3.6.1.4.1.2021.10.1.3.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.6.1.4.1.2021.10.1.3.1", oid)
+	assert.Equal(t, "1.3.6.1.4.1.2021.10.1.3.1", name)
+}
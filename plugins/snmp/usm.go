@@ -0,0 +1,470 @@
+package snmp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// msgFlags of SNMPv3 messages
+const (
+	flagAuth       byte = 0x01
+	flagPriv       byte = 0x02
+	flagReportable byte = 0x04
+)
+
+const (
+	// securityModelUSM is the user-based security model of RFC 3414
+	securityModelUSM = 3
+	// maxMessageSize is the largest response accepted, a UDP datagram
+	maxMessageSize = 65507
+	// authParamsSize is the length of the truncated HMAC of a message
+	authParamsSize = 12
+)
+
+// reportNames are the usmStats counters agents report failed requests with
+var reportNames = map[string]string{
+	"1.3.6.1.6.3.15.1.1.1.0": "usmStatsUnsupportedSecLevels",
+	"1.3.6.1.6.3.15.1.1.2.0": "usmStatsNotInTimeWindows",
+	"1.3.6.1.6.3.15.1.1.3.0": "usmStatsUnknownUserNames",
+	"1.3.6.1.6.3.15.1.1.4.0": "usmStatsUnknownEngineIDs",
+	"1.3.6.1.6.3.15.1.1.5.0": "usmStatsWrongDigests",
+	"1.3.6.1.6.3.15.1.1.6.0": "usmStatsDecryptionErrors",
+}
+
+// usm holds the credentials of an SNMPv3 user and the parameters of the
+// authoritative engine of the agent, which its keys are localized to.
+type usm struct {
+	user        string
+	contextName string
+	// level are the msgFlags of the security level of the user
+	level byte
+	hash  func() hash.Hash
+	priv  string
+
+	// authKu and privKu are the keys derived from the passwords, authKey
+	// and privKey the same keys localized to engineID
+	authKu, privKu   []byte
+	authKey, privKey []byte
+
+	engineID string
+	boots    int32
+	time     int32
+	// synced is when time was received, see engineTime
+	synced time.Time
+	salt   uint64
+}
+
+// newUSM validates the SNMPv3 credentials of the host
+func newUSM(h *Host) (*usm, error) {
+	if h.SecName == "" {
+		return nil, fmt.Errorf("sec_name is required for SNMPv3 agent %s", h.Address)
+	}
+	u := &usm{user: h.SecName, contextName: h.ContextName, salt: uint64(rand.Int63())}
+
+	switch strings.ToLower(h.SecLevel) {
+	case "", "noauthnopriv":
+	case "authnopriv":
+		u.level = flagAuth
+	case "authpriv":
+		u.level = flagAuth | flagPriv
+	default:
+		return nil, fmt.Errorf("Invalid sec_level '%s' for %s, must be noAuthNoPriv, authNoPriv or authPriv",
+			h.SecLevel, h.Address)
+	}
+	if u.level&flagAuth == 0 {
+		return u, nil
+	}
+
+	switch strings.ToUpper(h.AuthProtocol) {
+	case "", "MD5":
+		u.hash = md5.New
+	case "SHA":
+		u.hash = sha1.New
+	default:
+		return nil, fmt.Errorf("Invalid auth_protocol '%s' for %s, must be MD5 or SHA",
+			h.AuthProtocol, h.Address)
+	}
+	if len(h.AuthPassword) < 8 {
+		return nil, fmt.Errorf("auth_password of %s must be at least 8 characters", h.Address)
+	}
+	u.authKu = passwordToKey(u.hash, h.AuthPassword)
+	if u.level&flagPriv == 0 {
+		return u, nil
+	}
+
+	u.priv = strings.ToUpper(h.PrivProtocol)
+	switch u.priv {
+	case "":
+		u.priv = "DES"
+	case "DES", "AES":
+	default:
+		return nil, fmt.Errorf("Invalid priv_protocol '%s' for %s, must be DES or AES",
+			h.PrivProtocol, h.Address)
+	}
+	if len(h.PrivPassword) < 8 {
+		return nil, fmt.Errorf("priv_password of %s must be at least 8 characters", h.Address)
+	}
+	u.privKu = passwordToKey(u.hash, h.PrivPassword)
+	return u, nil
+}
+
+// passwordToKey derives a key from password by hashing a megabyte of its
+// repetitions, see RFC 3414 A.2
+func passwordToKey(h func() hash.Hash, password string) []byte {
+	d := h()
+	block := make([]byte, 64)
+	for n := 0; n < 1048576; n += len(block) {
+		for i := range block {
+			block[i] = password[(n+i)%len(password)]
+		}
+		d.Write(block)
+	}
+	return d.Sum(nil)
+}
+
+// localizeKey returns the key ku localized to the engine engineID
+func localizeKey(h func() hash.Hash, ku []byte, engineID string) []byte {
+	d := h()
+	d.Write(ku)
+	d.Write([]byte(engineID))
+	d.Write(ku)
+	return d.Sum(nil)
+}
+
+// setEngine records the parameters of the authoritative engine, localizing
+// the keys when the engine changed
+func (u *usm) setEngine(engineID string, boots, engineTime int32) {
+	if engineID != u.engineID && u.authKu != nil {
+		u.authKey = localizeKey(u.hash, u.authKu, engineID)
+		if u.privKu != nil {
+			u.privKey = localizeKey(u.hash, u.privKu, engineID)
+		}
+	}
+	u.engineID = engineID
+	u.boots = boots
+	u.time = engineTime
+	u.synced = time.Now()
+}
+
+// engineTime estimates the current time of the authoritative engine, 0
+// until it is discovered
+func (u *usm) engineTime() int32 {
+	if u.synced.IsZero() {
+		return 0
+	}
+	return u.time + int32(time.Since(u.synced)/time.Second)
+}
+
+// marshal encodes p in an SNMPv3 message from the user to the engine,
+// authenticated and encrypted as flags ask
+func (u *usm) marshal(p *packet, flags byte) ([]byte, error) {
+	pdu, err := p.marshalPDU()
+	if err != nil {
+		return nil, err
+	}
+	var scoped []byte
+	scoped = append(scoped, tlv(typeOctetString, []byte(u.engineID))...)
+	scoped = append(scoped, tlv(typeOctetString, []byte(u.contextName))...)
+	scoped = append(scoped, pdu...)
+	data := tlv(typeSequence, scoped)
+
+	engineTime := u.engineTime()
+	var privParams []byte
+	if flags&flagPriv != 0 {
+		var encrypted []byte
+		if encrypted, privParams, err = u.encrypt(data, engineTime); err != nil {
+			return nil, err
+		}
+		data = tlv(typeOctetString, encrypted)
+	}
+	var authParams []byte
+	if flags&flagAuth != 0 {
+		authParams = make([]byte, authParamsSize)
+	}
+
+	var sec []byte
+	sec = append(sec, tlv(typeOctetString, []byte(u.engineID))...)
+	sec = append(sec, tlv(typeInteger, marshalInt(int64(u.boots)))...)
+	sec = append(sec, tlv(typeInteger, marshalInt(int64(engineTime)))...)
+	sec = append(sec, tlv(typeOctetString, []byte(u.user))...)
+	sec = append(sec, tlv(typeOctetString, authParams)...)
+	sec = append(sec, tlv(typeOctetString, privParams)...)
+
+	var header []byte
+	header = append(header, tlv(typeInteger, marshalInt(int64(p.MsgID)))...)
+	header = append(header, tlv(typeInteger, marshalInt(maxMessageSize))...)
+	header = append(header, tlv(typeOctetString, []byte{flags})...)
+	header = append(header, tlv(typeInteger, marshalInt(securityModelUSM))...)
+
+	var msg []byte
+	msg = append(msg, tlv(typeInteger, marshalInt(3))...)
+	msg = append(msg, tlv(typeSequence, header)...)
+	msg = append(msg, tlv(typeOctetString, tlv(typeSequence, sec))...)
+	msg = append(msg, data...)
+	b := tlv(typeSequence, msg)
+
+	if flags&flagAuth != 0 {
+		m, err := parseV3Message(b)
+		if err != nil {
+			return nil, err
+		}
+		// authParams of m is a slice of b, the digest is written in place
+		copy(m.authParams, u.digest(b))
+	}
+	return b, nil
+}
+
+// unmarshal decodes an SNMPv3 message, checking its digest and decrypting
+// it when it is authenticated or encrypted
+func (u *usm) unmarshal(b []byte) (*packet, error) {
+	m, err := parseV3Message(b)
+	if err != nil {
+		return nil, err
+	}
+	p := &packet{
+		Version:     3,
+		MsgID:       m.msgID,
+		EngineID:    m.engineID,
+		EngineBoots: m.boots,
+		EngineTime:  m.time,
+	}
+
+	data := m.data
+	if m.flags&flagAuth != 0 {
+		if u.authKey == nil || m.user != u.user || m.engineID != u.engineID {
+			return nil, fmt.Errorf("unexpected authenticated message from user %s", m.user)
+		}
+		if len(m.authParams) != authParamsSize {
+			return nil, errors.New("invalid message digest")
+		}
+		digest := append([]byte(nil), m.authParams...)
+		unsigned := append([]byte(nil), b...)
+		unsignedMsg, err := parseV3Message(unsigned)
+		if err != nil {
+			return nil, err
+		}
+		copy(unsignedMsg.authParams, make([]byte, authParamsSize))
+		if !hmac.Equal(digest, u.digest(unsigned)) {
+			return nil, errors.New("wrong message digest")
+		}
+	}
+	if m.flags&flagPriv != 0 {
+		if m.flags&flagAuth == 0 || u.privKey == nil {
+			return nil, errors.New("unexpected encrypted message")
+		}
+		var encrypted []byte
+		if encrypted, _, err = expect(data, typeOctetString); err != nil {
+			return nil, err
+		}
+		if data, err = u.decrypt(encrypted, m.privParams, m.boots, m.time); err != nil {
+			return nil, err
+		}
+	}
+
+	scoped, _, err := expect(data, typeSequence)
+	if err != nil {
+		return nil, err
+	}
+	// the context engine ID and context name
+	for i := 0; i < 2; i++ {
+		if _, scoped, err = expect(scoped, typeOctetString); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.unmarshalPDU(scoped); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// digest returns the truncated HMAC of msg, see RFC 3414 6.3 and 7.3
+func (u *usm) digest(msg []byte) []byte {
+	mac := hmac.New(u.hash, u.authKey)
+	mac.Write(msg)
+	return mac.Sum(nil)[:authParamsSize]
+}
+
+// encrypt encrypts the scoped PDU, returning the salt to send as the
+// privacy parameters, see RFC 3414 8.1.1 for DES and RFC 3826 3.1 for AES
+func (u *usm) encrypt(scoped []byte, engineTime int32) (encrypted, salt []byte, err error) {
+	u.salt++
+	salt = make([]byte, 8)
+	if u.priv == "AES" {
+		binary.BigEndian.PutUint64(salt, u.salt)
+		block, err := aes.NewCipher(u.privKey[:16])
+		if err != nil {
+			return nil, nil, err
+		}
+		encrypted = make([]byte, len(scoped))
+		cipher.NewCFBEncrypter(block, u.aesIV(salt, u.boots, engineTime)).
+			XORKeyStream(encrypted, scoped)
+		return encrypted, salt, nil
+	}
+
+	binary.BigEndian.PutUint32(salt, uint32(u.boots))
+	binary.BigEndian.PutUint32(salt[4:], uint32(u.salt))
+	block, err := des.NewCipher(u.privKey[:8])
+	if err != nil {
+		return nil, nil, err
+	}
+	// the padding follows the scoped PDU, which is decoded by its length
+	if n := len(scoped) % des.BlockSize; n != 0 {
+		scoped = append(scoped, make([]byte, des.BlockSize-n)...)
+	}
+	encrypted = make([]byte, len(scoped))
+	cipher.NewCBCEncrypter(block, u.desIV(salt)).CryptBlocks(encrypted, scoped)
+	return encrypted, salt, nil
+}
+
+// decrypt decrypts a scoped PDU encrypted with salt by the engine
+func (u *usm) decrypt(encrypted, salt []byte, boots, engineTime int32) ([]byte, error) {
+	if len(salt) != 8 {
+		return nil, errors.New("invalid privacy parameters")
+	}
+	scoped := make([]byte, len(encrypted))
+	if u.priv == "AES" {
+		block, err := aes.NewCipher(u.privKey[:16])
+		if err != nil {
+			return nil, err
+		}
+		cipher.NewCFBDecrypter(block, u.aesIV(salt, boots, engineTime)).
+			XORKeyStream(scoped, encrypted)
+		return scoped, nil
+	}
+
+	if len(encrypted)%des.BlockSize != 0 {
+		return nil, errors.New("invalid length of encrypted PDU")
+	}
+	block, err := des.NewCipher(u.privKey[:8])
+	if err != nil {
+		return nil, err
+	}
+	cipher.NewCBCDecrypter(block, u.desIV(salt)).CryptBlocks(scoped, encrypted)
+	return scoped, nil
+}
+
+// desIV is the pre-IV of the privacy key xored with the salt
+func (u *usm) desIV(salt []byte) []byte {
+	iv := make([]byte, des.BlockSize)
+	for i := range iv {
+		iv[i] = u.privKey[8+i] ^ salt[i]
+	}
+	return iv
+}
+
+// aesIV is the boots and time of the engine followed by the salt
+func (u *usm) aesIV(salt []byte, boots, engineTime int32) []byte {
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint32(iv, uint32(boots))
+	binary.BigEndian.PutUint32(iv[4:], uint32(engineTime))
+	copy(iv[8:], salt)
+	return iv
+}
+
+// v3Message holds the fields of an encoded SNMPv3 message, the byte slices
+// pointing into it
+type v3Message struct {
+	msgID      int32
+	flags      byte
+	engineID   string
+	boots      int32
+	time       int32
+	user       string
+	authParams []byte
+	privParams []byte
+	// data is the scoped PDU, or the octet string holding it encrypted
+	data []byte
+}
+
+func parseV3Message(b []byte) (*v3Message, error) {
+	msg, _, err := expect(b, typeSequence)
+	if err != nil {
+		return nil, err
+	}
+	var field []byte
+	if field, msg, err = expect(msg, typeInteger); err != nil {
+		return nil, err
+	}
+	if v := unmarshalInt(field); v != 3 {
+		return nil, fmt.Errorf("unexpected message version %d", v)
+	}
+
+	m := &v3Message{}
+	var header []byte
+	if header, msg, err = expect(msg, typeSequence); err != nil {
+		return nil, err
+	}
+	if field, header, err = expect(header, typeInteger); err != nil {
+		return nil, err
+	}
+	m.msgID = int32(unmarshalInt(field))
+	if _, header, err = expect(header, typeInteger); err != nil {
+		return nil, err
+	}
+	if field, header, err = expect(header, typeOctetString); err != nil {
+		return nil, err
+	}
+	if len(field) != 1 {
+		return nil, errors.New("invalid msgFlags")
+	}
+	m.flags = field[0]
+	if field, _, err = expect(header, typeInteger); err != nil {
+		return nil, err
+	}
+	if unmarshalInt(field) != securityModelUSM {
+		return nil, fmt.Errorf("unsupported security model %d", unmarshalInt(field))
+	}
+
+	var sec []byte
+	if sec, m.data, err = expect(msg, typeOctetString); err != nil {
+		return nil, err
+	}
+	if sec, _, err = expect(sec, typeSequence); err != nil {
+		return nil, err
+	}
+	if field, sec, err = expect(sec, typeOctetString); err != nil {
+		return nil, err
+	}
+	m.engineID = string(field)
+	if field, sec, err = expect(sec, typeInteger); err != nil {
+		return nil, err
+	}
+	m.boots = int32(unmarshalInt(field))
+	if field, sec, err = expect(sec, typeInteger); err != nil {
+		return nil, err
+	}
+	m.time = int32(unmarshalInt(field))
+	if field, sec, err = expect(sec, typeOctetString); err != nil {
+		return nil, err
+	}
+	m.user = string(field)
+	if m.authParams, sec, err = expect(sec, typeOctetString); err != nil {
+		return nil, err
+	}
+	if m.privParams, _, err = expect(sec, typeOctetString); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// reportName names the counter of a report PDU
+func reportName(p *packet) string {
+	if len(p.Variables) == 0 {
+		return "an unknown error"
+	}
+	if name, ok := reportNames[p.Variables[0].OID]; ok {
+		return name
+	}
+	return p.Variables[0].OID
+}
@@ -0,0 +1,122 @@
+package snmp
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the key of RFC 3414 A.3, the password maplesyrup localized to the engine
+// 000000000000000000000002
+func TestPasswordToKey(t *testing.T) {
+	engineID := string([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2})
+
+	ku := passwordToKey(md5.New, "maplesyrup")
+	assert.Equal(t, "9faf3283884e92834ebc9847d8edd963", hex.EncodeToString(ku))
+	assert.Equal(t, "526f5eed9fcce26f8964c2930787d82b",
+		hex.EncodeToString(localizeKey(md5.New, ku, engineID)))
+
+	ku = passwordToKey(sha1.New, "maplesyrup")
+	assert.Equal(t, "9fb5cc0381497b3793528939ff788d5d79145211", hex.EncodeToString(ku))
+	assert.Equal(t, "6695febc9288e36282235fc7151f128497b38f3f",
+		hex.EncodeToString(localizeKey(sha1.New, ku, engineID)))
+}
+
+// v3Host is a user of every security level and protocol
+func v3Host(level, auth, priv string) Host {
+	return Host{
+		Address:      "127.0.0.1",
+		Version:      3,
+		SecName:      "telegraf",
+		SecLevel:     level,
+		AuthProtocol: auth,
+		AuthPassword: "auth-password",
+		PrivProtocol: priv,
+		PrivPassword: "priv-password",
+	}
+}
+
+var v3Hosts = []Host{
+	v3Host("noAuthNoPriv", "", ""),
+	v3Host("authNoPriv", "MD5", ""),
+	v3Host("authNoPriv", "SHA", ""),
+	v3Host("authPriv", "MD5", "DES"),
+	v3Host("authPriv", "SHA", "DES"),
+	v3Host("authPriv", "MD5", "AES"),
+	v3Host("authPriv", "SHA", "AES"),
+}
+
+func TestUSMMessages(t *testing.T) {
+	for _, h := range v3Hosts {
+		sender, err := newUSM(&h)
+		require.NoError(t, err)
+		receiver, err := newUSM(&h)
+		require.NoError(t, err)
+		sender.setEngine("engine", 3, 1000)
+		receiver.setEngine("engine", 3, 1000)
+
+		sent := &packet{
+			MsgID:     7,
+			PDUType:   pduGetResponse,
+			RequestID: 7,
+			Variables: []variable{
+				{OID: "1.3.6.1.2.1.1.1.0", Type: typeOctetString, Value: "mock agent"},
+			},
+		}
+		b, err := sender.marshal(sent, sender.level)
+		require.NoError(t, err, "%+v", h)
+		if sender.level&flagPriv != 0 {
+			assert.NotContains(t, string(b), "mock agent", "%+v", h)
+		}
+
+		received, err := receiver.unmarshal(b)
+		require.NoError(t, err, "%+v", h)
+		assert.Equal(t, int32(7), received.MsgID)
+		assert.Equal(t, "engine", received.EngineID)
+		assert.Equal(t, int32(3), received.EngineBoots)
+		assert.Equal(t, sent.Variables, received.Variables, "%+v", h)
+
+		if sender.level&flagAuth != 0 {
+			// a message that was changed fails its digest
+			b[len(b)-1]++
+			_, err = receiver.unmarshal(b)
+			assert.Error(t, err, "%+v", h)
+		}
+	}
+}
+
+func TestUSMWrongPassword(t *testing.T) {
+	h := v3Host("authPriv", "SHA", "AES")
+	sender, err := newUSM(&h)
+	require.NoError(t, err)
+	h.AuthPassword = "other-password"
+	receiver, err := newUSM(&h)
+	require.NoError(t, err)
+	sender.setEngine("engine", 1, 10)
+	receiver.setEngine("engine", 1, 10)
+
+	b, err := sender.marshal(&packet{PDUType: pduGetRequest}, sender.level)
+	require.NoError(t, err)
+	_, err = receiver.unmarshal(b)
+	assert.EqualError(t, err, "wrong message digest")
+}
+
+func TestNewUSMInvalid(t *testing.T) {
+	for _, h := range []Host{
+		{Address: "127.0.0.1", Version: 3},
+		v3Host("authOnly", "MD5", ""),
+		v3Host("authNoPriv", "SHA256", ""),
+		v3Host("authPriv", "SHA", "3DES"),
+		{Address: "127.0.0.1", Version: 3, SecName: "telegraf", SecLevel: "authNoPriv",
+			AuthPassword: "short"},
+		{Address: "127.0.0.1", Version: 3, SecName: "telegraf", SecLevel: "authPriv",
+			AuthPassword: "auth-password", PrivPassword: "short"},
+	} {
+		_, err := newUSM(&h)
+		assert.Error(t, err, "%+v", h)
+	}
+}
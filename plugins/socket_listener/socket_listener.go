@@ -0,0 +1,277 @@
+package socket_listener
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/influxdb/influxdb/models"
+
+	"github.com/influxdb/telegraf/plugins"
+	"github.com/influxdb/telegraf/plugins/parsers"
+)
+
+const (
+	defaultAllowedPendingPoints = 10000
+	// UDP packets larger than this are truncated
+	udpBufferSize = 64 * 1024
+)
+
+var dropwarn = "ERROR: Point buffer full. Discarding line [%s] " +
+	"You may want to increase allowed_pending_points in the config\n"
+
+// SocketListener accepts InfluxDB line protocol over TCP or UDP and adds
+// the parsed points on each Gather.
+type SocketListener struct {
+	// ServiceAddress is the URL to listen on, ie "tcp://:8094" or
+	// "udp://:8094"
+	ServiceAddress string
+
+	// MaxConnections limits the number of open TCP connections, zero means
+	// no limit
+	MaxConnections int
+
+	// Number of points allowed to queue up in between calls to Gather. Lines
+	// received while the buffer is full are dropped.
+	AllowedPendingPoints int
+
+	// DataFormat of the lines, "influx" (the default) or "json", see
+	// parsers.Config
+	DataFormat string
+	// TagKeys are the keys of JSON objects gathered as tags
+	TagKeys []string
+
+	sync.Mutex
+	points []models.Point
+	parser parsers.Parser
+
+	listener   net.Listener
+	packetConn net.PacketConn
+	conns      map[net.Conn]bool
+	done       chan struct{}
+	wg         sync.WaitGroup
+}
+
+var sampleConfig = `
+  # URL to listen on, the scheme is one of tcp, tcp4, tcp6, udp, udp4 or udp6
+  service_address = "tcp://:8094"
+
+  # Maximum number of concurrent TCP connections, 0 means unlimited
+  max_connections = 0
+
+  # Number of points to buffer in between collection intervals
+  allowed_pending_points = 10000
+
+  # Data format of each line, "influx" (line protocol) or "json" (an object
+  # per line, stored in the socket_listener_json measurement)
+  data_format = "influx"
+  # Keys of JSON objects gathered as tags rather than fields
+  # tag_keys = ["host"]
+`
+
+func (s *SocketListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SocketListener) Description() string {
+	return "Accept InfluxDB line protocol over TCP or UDP"
+}
+
+func (s *SocketListener) Start() error {
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat: s.DataFormat,
+		TagKeys:    s.TagKeys,
+	})
+	if err != nil {
+		return err
+	}
+	s.parser = parser
+
+	s.done = make(chan struct{})
+	s.conns = make(map[net.Conn]bool)
+
+	u, err := url.Parse(s.ServiceAddress)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("Invalid service_address '%s'", s.ServiceAddress)
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		s.listener, err = net.Listen(u.Scheme, u.Host)
+		if err != nil {
+			return err
+		}
+		s.wg.Add(1)
+		go s.acceptTCP()
+	case "udp", "udp4", "udp6":
+		s.packetConn, err = net.ListenPacket(u.Scheme, u.Host)
+		if err != nil {
+			return err
+		}
+		s.wg.Add(1)
+		go s.readUDP()
+	default:
+		return fmt.Errorf("Unsupported scheme '%s' in service_address '%s'",
+			u.Scheme, s.ServiceAddress)
+	}
+
+	log.Printf("Socket listener listening on %s\n", s.ServiceAddress)
+	return nil
+}
+
+// Addr returns the address the listener is bound to
+func (s *SocketListener) Addr() net.Addr {
+	if s.listener != nil {
+		return s.listener.Addr()
+	}
+	if s.packetConn != nil {
+		return s.packetConn.LocalAddr()
+	}
+	return nil
+}
+
+func (s *SocketListener) acceptTCP() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				log.Printf("ERROR: accepting connection on %s: %s\n",
+					s.ServiceAddress, err)
+				continue
+			}
+		}
+
+		s.Lock()
+		if s.MaxConnections > 0 && len(s.conns) >= s.MaxConnections {
+			s.Unlock()
+			log.Printf("Socket listener reached max_connections (%d), "+
+				"refusing connection from %s\n", s.MaxConnections, conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = true
+		s.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.read(conn)
+
+			s.Lock()
+			delete(s.conns, conn)
+			s.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+func (s *SocketListener) readUDP() {
+	defer s.wg.Done()
+	buf := make([]byte, udpBufferSize)
+	for {
+		n, _, err := s.packetConn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				log.Printf("ERROR: reading packet on %s: %s\n", s.ServiceAddress, err)
+				continue
+			}
+		}
+		s.read(bytes.NewReader(buf[:n]))
+	}
+}
+
+// read parses every line of r until it ends, skipping malformed lines
+func (s *SocketListener) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		s.parseLine(line)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("ERROR: reading line protocol: %s\n", err)
+	}
+}
+
+func (s *SocketListener) parseLine(line string) {
+	// lines parsed before Start, ie in tests, are line protocol
+	parser := s.parser
+	if parser == nil {
+		parser = &parsers.InfluxParser{}
+	}
+	points, err := parser.Parse([]byte(line))
+	if err != nil {
+		log.Printf("ERROR: unable to parse line [%s]: %s\n", line, err)
+		return
+	}
+
+	limit := s.AllowedPendingPoints
+	if limit == 0 {
+		limit = defaultAllowedPendingPoints
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if len(s.points)+len(points) > limit {
+		log.Printf(dropwarn, line)
+		return
+	}
+	s.points = append(s.points, points...)
+}
+
+func (s *SocketListener) Gather(acc plugins.Accumulator) error {
+	s.Lock()
+	points := s.points
+	s.points = nil
+	s.Unlock()
+
+	for _, pt := range points {
+		acc.AddFields(pt.Name(), pt.Fields(), pt.Tags(), pt.Time())
+	}
+	return nil
+}
+
+func (s *SocketListener) Stop() {
+	if s.done == nil {
+		return
+	}
+	close(s.done)
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	if s.packetConn != nil {
+		s.packetConn.Close()
+	}
+
+	s.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.Unlock()
+
+	s.wg.Wait()
+}
+
+func init() {
+	plugins.Add("socket_listener", func() plugins.Plugin {
+		return &SocketListener{
+			ServiceAddress:       "tcp://:8094",
+			AllowedPendingPoints: defaultAllowedPendingPoints,
+		}
+	})
+}
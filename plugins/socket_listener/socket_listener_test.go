@@ -0,0 +1,101 @@
+package socket_listener
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLines = "cpu,host=server01 usage_idle=95.5 1136214245000000000\n" +
+	"not line protocol\n" +
+	"mem,host=server01 free=1024i 1136214245000000000\n"
+
+func waitForPoints(t *testing.T, s *SocketListener, acc *testutil.Accumulator, n int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		require.NoError(t, s.Gather(acc))
+		if len(acc.Points) >= n {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, acc.Points, n)
+}
+
+func assertTestPoints(t *testing.T, acc *testutil.Accumulator) {
+	assert.Equal(t, "cpu", acc.Points[0].Measurement)
+	assert.Equal(t, map[string]string{"host": "server01"}, acc.Points[0].Tags)
+	assert.Equal(t, 95.5, acc.Points[0].Values["usage_idle"])
+	assert.Equal(t, time.Unix(1136214245, 0).UTC(), acc.Points[0].Time.UTC())
+	assert.Equal(t, "mem", acc.Points[1].Measurement)
+	assert.Equal(t, int64(1024), acc.Points[1].Values["free"])
+}
+
+func TestSocketListenerTCP(t *testing.T) {
+	s := &SocketListener{ServiceAddress: "tcp://127.0.0.1:0"}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(testLines))
+	require.NoError(t, err)
+	conn.Close()
+
+	var acc testutil.Accumulator
+	waitForPoints(t, s, &acc, 2)
+	assertTestPoints(t, &acc)
+}
+
+func TestSocketListenerUDP(t *testing.T) {
+	s := &SocketListener{ServiceAddress: "udp://127.0.0.1:0"}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(testLines))
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	waitForPoints(t, s, &acc, 2)
+	assertTestPoints(t, &acc)
+}
+
+func TestSocketListenerMaxConnections(t *testing.T) {
+	s := &SocketListener{ServiceAddress: "tcp://127.0.0.1:0", MaxConnections: 1}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	first, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer first.Close()
+	_, err = first.Write([]byte("cpu value=1\n"))
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	waitForPoints(t, s, &acc, 1)
+
+	// the second connection is closed by the listener
+	second, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = second.Read(make([]byte, 1))
+	assert.Error(t, err)
+	if nerr, ok := err.(net.Error); ok {
+		assert.False(t, nerr.Timeout(), "connection was not closed")
+	}
+}
+
+func TestSocketListenerBadAddress(t *testing.T) {
+	for _, addr := range []string{"", ":8094", "unix:///tmp/sock", "http://:8094"} {
+		s := &SocketListener{ServiceAddress: addr}
+		assert.Error(t, s.Start(), addr)
+	}
+}
@@ -0,0 +1,209 @@
+package stdin
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/influxdb/influxdb/models"
+
+	"github.com/influxdb/telegraf/plugins"
+	"github.com/influxdb/telegraf/plugins/parsers"
+)
+
+const defaultAllowedPendingPoints = 10000
+
+var dropwarn = "ERROR: Point buffer full. Discarding line [%s] " +
+	"You may want to increase allowed_pending_points in the config\n"
+
+// Stdin reads InfluxDB line protocol from stdin, or from the connections to
+// a Unix socket, and adds the parsed points on each Gather.
+type Stdin struct {
+	// UnixSocket is the path of a Unix socket to listen on instead of stdin
+	UnixSocket string
+
+	// Number of points allowed to queue up in between calls to Gather. Lines
+	// received while the buffer is full are dropped.
+	AllowedPendingPoints int
+
+	// DataFormat of the lines, "influx" (the default) or "json", see
+	// parsers.Config
+	DataFormat string
+	// TagKeys are the keys of JSON objects gathered as tags
+	TagKeys []string
+
+	sync.Mutex
+	points []models.Point
+	parser parsers.Parser
+
+	input    io.Reader
+	listener net.Listener
+	conns    map[net.Conn]bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+var sampleConfig = `
+  # Line protocol is read from stdin unless a Unix socket is given, in which
+  # case every connection to the socket is read.
+  # unix_socket = "/tmp/telegraf.sock"
+
+  # Number of points to buffer in between collection intervals
+  allowed_pending_points = 10000
+
+  # Data format of each line, "influx" (line protocol) or "json" (an object
+  # per line, stored in the stdin_json measurement)
+  data_format = "influx"
+  # Keys of JSON objects gathered as tags rather than fields
+  # tag_keys = ["host"]
+`
+
+func (s *Stdin) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Stdin) Description() string {
+	return "Read InfluxDB line protocol from stdin or a Unix socket"
+}
+
+func (s *Stdin) Start() error {
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat: s.DataFormat,
+		TagKeys:    s.TagKeys,
+	})
+	if err != nil {
+		return err
+	}
+	s.parser = parser
+
+	s.done = make(chan struct{})
+	s.conns = make(map[net.Conn]bool)
+
+	if s.UnixSocket == "" {
+		input := s.input
+		if input == nil {
+			input = os.Stdin
+		}
+		// reading stdin can not be interrupted, so it is not waited on in Stop
+		go s.read(input)
+		return nil
+	}
+
+	listener, err := net.Listen("unix", s.UnixSocket)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.accept()
+	log.Printf("Stdin plugin listening on %s\n", s.UnixSocket)
+	return nil
+}
+
+func (s *Stdin) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				log.Printf("ERROR: accepting connection on %s: %s\n", s.UnixSocket, err)
+				continue
+			}
+		}
+
+		s.Lock()
+		s.conns[conn] = true
+		s.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.read(conn)
+
+			s.Lock()
+			delete(s.conns, conn)
+			s.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// read parses every line of r until it ends, skipping malformed lines
+func (s *Stdin) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		s.parseLine(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("ERROR: reading line protocol: %s\n", err)
+	}
+}
+
+func (s *Stdin) parseLine(line string) {
+	// lines parsed before Start, ie in tests, are line protocol
+	parser := s.parser
+	if parser == nil {
+		parser = &parsers.InfluxParser{}
+	}
+	points, err := parser.Parse([]byte(line))
+	if err != nil {
+		log.Printf("ERROR: unable to parse line [%s]: %s\n", line, err)
+		return
+	}
+
+	limit := s.AllowedPendingPoints
+	if limit == 0 {
+		limit = defaultAllowedPendingPoints
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if len(s.points)+len(points) > limit {
+		log.Printf(dropwarn, line)
+		return
+	}
+	s.points = append(s.points, points...)
+}
+
+func (s *Stdin) Gather(acc plugins.Accumulator) error {
+	s.Lock()
+	points := s.points
+	s.points = nil
+	s.Unlock()
+
+	for _, pt := range points {
+		acc.AddFields(pt.Name(), pt.Fields(), pt.Tags(), pt.Time())
+	}
+	return nil
+}
+
+func (s *Stdin) Stop() {
+	if s.done == nil {
+		return
+	}
+	close(s.done)
+	if s.listener != nil {
+		s.listener.Close()
+	}
+
+	s.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.Unlock()
+
+	s.wg.Wait()
+}
+
+func init() {
+	plugins.Add("stdin", func() plugins.Plugin {
+		return &Stdin{AllowedPendingPoints: defaultAllowedPendingPoints}
+	})
+}
@@ -0,0 +1,117 @@
+package stdin
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLines = `cpu,host=server01 usage_idle=95.5,usage_user=2i 1136214245000000000
+this is not line protocol
+mem,host=server01 free=1024i
+disk,host=server01,path=/ used=0.5 1136214245000000000
+`
+
+func TestReadLines(t *testing.T) {
+	s := &Stdin{}
+	s.read(strings.NewReader(testLines))
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Points, 3)
+
+	cpu := acc.Points[0]
+	assert.Equal(t, "cpu", cpu.Measurement)
+	assert.Equal(t, map[string]string{"host": "server01"}, cpu.Tags)
+	assert.Equal(t, map[string]interface{}{"usage_idle": 95.5, "usage_user": int64(2)}, cpu.Values)
+	assert.Equal(t, time.Unix(1136214245, 0).UTC(), cpu.Time.UTC())
+
+	assert.Equal(t, "mem", acc.Points[1].Measurement)
+	assert.Equal(t, int64(1024), acc.Points[1].Values["free"])
+	assert.Equal(t, "disk", acc.Points[2].Measurement)
+	assert.Equal(t, "/", acc.Points[2].Tags["path"])
+
+	// points are only added once
+	acc = testutil.Accumulator{}
+	require.NoError(t, s.Gather(&acc))
+	assert.Len(t, acc.Points, 0)
+}
+
+func TestAllowedPendingPoints(t *testing.T) {
+	s := &Stdin{AllowedPendingPoints: 1}
+	s.read(strings.NewReader(testLines))
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Points, 1)
+	assert.Equal(t, "cpu", acc.Points[0].Measurement)
+}
+
+func TestStartStdin(t *testing.T) {
+	s := &Stdin{input: strings.NewReader(testLines)}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	var acc testutil.Accumulator
+	require.True(t, waitForPoints(t, s, &acc, 3))
+}
+
+func TestStartStdinJSON(t *testing.T) {
+	s := &Stdin{
+		input:      strings.NewReader("{\"host\": \"server01\", \"free\": 1024}\nnot json\n"),
+		DataFormat: "json",
+		TagKeys:    []string{"host"},
+	}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	var acc testutil.Accumulator
+	require.True(t, waitForPoints(t, s, &acc, 1))
+	assert.Equal(t, "json", acc.Points[0].Measurement)
+	assert.Equal(t, map[string]string{"host": "server01"}, acc.Points[0].Tags)
+	assert.Equal(t, map[string]interface{}{"free": 1024.0}, acc.Points[0].Values)
+}
+
+func TestStartBadDataFormat(t *testing.T) {
+	s := &Stdin{input: strings.NewReader(testLines), DataFormat: "xml"}
+	assert.Error(t, s.Start())
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := &Stdin{UnixSocket: filepath.Join(dir, "telegraf.sock")}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	conn, err := net.Dial("unix", s.UnixSocket)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(testLines))
+	require.NoError(t, err)
+	conn.Close()
+
+	var acc testutil.Accumulator
+	require.True(t, waitForPoints(t, s, &acc, 3))
+}
+
+func waitForPoints(t *testing.T, s *Stdin, acc *testutil.Accumulator, n int) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		require.NoError(t, s.Gather(acc))
+		if len(acc.Points) >= n {
+			return len(acc.Points) == n
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
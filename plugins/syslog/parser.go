@@ -0,0 +1,213 @@
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var severities = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console",
+	"solaris-cron", "local0", "local1", "local2", "local3", "local4",
+	"local5", "local6", "local7",
+}
+
+// message is a syslog message in either format, fields missing from the
+// message are left empty
+type message struct {
+	facility  int
+	severity  int
+	version   int
+	timestamp time.Time
+	hostname  string
+	appname   string
+	procid    string
+	msgid     string
+	// structured maps "<sd-id>_<param-name>" to the param value
+	structured map[string]string
+	msg        string
+}
+
+// parseMessage parses an RFC 5424 message, or an RFC 3164 one when there is
+// no version after the priority. now is used to complete the year of RFC
+// 3164 timestamps.
+func parseMessage(data string, now time.Time) (*message, error) {
+	if !strings.HasPrefix(data, "<") {
+		return nil, errors.New("Missing priority")
+	}
+	end := strings.IndexByte(data, '>')
+	if end < 2 || end > 4 {
+		return nil, errors.New("Invalid priority")
+	}
+	pri, err := strconv.Atoi(data[1:end])
+	if err != nil || pri > 191 {
+		return nil, fmt.Errorf("Invalid priority '%s'", data[1:end])
+	}
+	m := &message{facility: pri / 8, severity: pri % 8}
+
+	rest := data[end+1:]
+	if i := strings.IndexByte(rest, ' '); i > 0 && i <= 3 {
+		if version, err := strconv.Atoi(rest[:i]); err == nil {
+			m.version = version
+			return m, m.parse5424(rest[i+1:])
+		}
+	}
+	m.parse3164(rest, now)
+	return m, nil
+}
+
+// parse5424 parses what follows the version of an RFC 5424 message
+func (m *message) parse5424(rest string) error {
+	var header [5]string
+	for i := range header {
+		sp := strings.IndexByte(rest, ' ')
+		if sp < 0 {
+			return errors.New("Truncated header")
+		}
+		header[i], rest = rest[:sp], rest[sp+1:]
+		if header[i] == "-" {
+			header[i] = ""
+		}
+	}
+
+	if header[0] != "" {
+		ts, err := time.Parse(time.RFC3339Nano, header[0])
+		if err != nil {
+			return fmt.Errorf("Invalid timestamp '%s'", header[0])
+		}
+		m.timestamp = ts
+	}
+	m.hostname, m.appname, m.procid, m.msgid = header[1], header[2], header[3], header[4]
+
+	rest, err := m.parseStructuredData(rest)
+	if err != nil {
+		return err
+	}
+	m.msg = strings.TrimPrefix(strings.TrimPrefix(rest, " "), "\xef\xbb\xbf")
+	return nil
+}
+
+// parseStructuredData parses the structured data at the start of rest and
+// returns what follows it
+func (m *message) parseStructuredData(rest string) (string, error) {
+	if strings.HasPrefix(rest, "-") {
+		return rest[1:], nil
+	}
+
+	m.structured = make(map[string]string)
+	for strings.HasPrefix(rest, "[") {
+		end := strings.IndexAny(rest, " ]")
+		if end < 0 {
+			return "", errors.New("Unterminated structured data")
+		}
+		id := rest[1:end]
+		rest = rest[end:]
+
+		for strings.HasPrefix(rest, " ") {
+			eq := strings.Index(rest, "=\"")
+			if eq < 0 {
+				return "", fmt.Errorf("Invalid param in structured data '%s'", id)
+			}
+			name := rest[1:eq]
+			rest = rest[eq+2:]
+
+			var value []byte
+			i := 0
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) &&
+					strings.IndexByte(`"\]`, rest[i+1]) >= 0 {
+					i++
+				}
+				value = append(value, rest[i])
+			}
+			if i == len(rest) {
+				return "", fmt.Errorf("Unterminated param in structured data '%s'", id)
+			}
+			m.structured[id+"_"+name] = string(value)
+			rest = rest[i+1:]
+		}
+
+		if !strings.HasPrefix(rest, "]") {
+			return "", fmt.Errorf("Unterminated structured data '%s'", id)
+		}
+		rest = rest[1:]
+	}
+	return rest, nil
+}
+
+// parse3164 parses what follows the priority of an RFC 3164 message. As
+// the format is loosely followed, whatever cannot be parsed is kept in msg.
+func (m *message) parse3164(rest string, now time.Time) {
+	if len(rest) >= len(time.Stamp)+1 && rest[len(time.Stamp)] == ' ' {
+		if ts, err := time.ParseInLocation(time.Stamp, rest[:len(time.Stamp)], now.Location()); err == nil {
+			ts = ts.AddDate(now.Year(), 0, 0)
+			// messages sent just before new year are from the previous year
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			m.timestamp = ts
+			rest = rest[len(time.Stamp)+1:]
+
+			if sp := strings.IndexByte(rest, ' '); sp > 0 {
+				m.hostname, rest = rest[:sp], rest[sp+1:]
+			}
+		}
+	}
+
+	// the tag, ie "sshd[1234]:", is made of at most 32 alphanumerics
+	if i := strings.IndexAny(rest, "[: "); i > 0 && i <= 32 {
+		tag, after := rest[:i], rest[i:]
+		if strings.HasPrefix(after, "[") {
+			if end := strings.Index(after, "]"); end > 0 {
+				m.procid, after = after[1:end], after[end+1:]
+			}
+		}
+		if strings.HasPrefix(after, ":") {
+			m.appname = tag
+			rest = strings.TrimPrefix(after[1:], " ")
+		} else {
+			m.procid = ""
+		}
+	}
+	m.msg = rest
+}
+
+// fields returns the fields and tags of the syslog measurement for m
+func (m *message) fields() (map[string]interface{}, map[string]string) {
+	tags := map[string]string{
+		"severity": severities[m.severity],
+		"facility": facilities[m.facility],
+	}
+	if m.hostname != "" {
+		tags["hostname"] = m.hostname
+	}
+	if m.appname != "" {
+		tags["appname"] = m.appname
+	}
+
+	fields := map[string]interface{}{
+		"message":       m.msg,
+		"severity_code": m.severity,
+		"facility_code": m.facility,
+	}
+	if m.version > 0 {
+		fields["version"] = m.version
+	}
+	if m.procid != "" {
+		fields["procid"] = m.procid
+	}
+	if m.msgid != "" {
+		fields["msgid"] = m.msgid
+	}
+	for k, v := range m.structured {
+		fields[k] = v
+	}
+	return fields, tags
+}
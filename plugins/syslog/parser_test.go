@@ -0,0 +1,130 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2015, time.October, 12, 10, 0, 0, 0, time.UTC)
+
+func TestParseRFC5424(t *testing.T) {
+	m, err := parseMessage(`<165>1 2015-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 `+
+		`[exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"] `+
+		"\xef\xbb\xbfAn application event log entry", now)
+	require.NoError(t, err)
+
+	assert.Equal(t, 20, m.facility)
+	assert.Equal(t, 5, m.severity)
+	assert.Equal(t, 1, m.version)
+	assert.Equal(t, time.Date(2015, time.October, 11, 22, 14, 15, 3000000, time.UTC), m.timestamp)
+	assert.Equal(t, "mymachine.example.com", m.hostname)
+	assert.Equal(t, "evntslog", m.appname)
+	assert.Equal(t, "", m.procid)
+	assert.Equal(t, "ID47", m.msgid)
+	assert.Equal(t, map[string]string{
+		"exampleSDID@32473_iut":         "3",
+		"exampleSDID@32473_eventSource": "Application",
+		"exampleSDID@32473_eventID":     "1011",
+	}, m.structured)
+	assert.Equal(t, "An application event log entry", m.msg)
+
+	fields, tags := m.fields()
+	assert.Equal(t, map[string]string{
+		"severity": "notice",
+		"facility": "local4",
+		"hostname": "mymachine.example.com",
+		"appname":  "evntslog",
+	}, tags)
+	assert.Equal(t, "An application event log entry", fields["message"])
+	assert.Equal(t, 5, fields["severity_code"])
+	assert.Equal(t, 20, fields["facility_code"])
+	assert.Equal(t, "ID47", fields["msgid"])
+	assert.Equal(t, "3", fields["exampleSDID@32473_iut"])
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	m, err := parseMessage(`<34>1 - - - - - -`, now)
+	require.NoError(t, err)
+
+	assert.True(t, m.timestamp.IsZero())
+	assert.Equal(t, "", m.hostname)
+	assert.Nil(t, m.structured)
+	assert.Equal(t, "", m.msg)
+}
+
+func TestParseRFC5424EscapedStructuredData(t *testing.T) {
+	m, err := parseMessage(`<34>1 2003-10-11T22:14:15.003Z host app 42 - `+
+		`[a x="q\"uo\]te" y="\\"][b z="1"] hello`, now)
+	require.NoError(t, err)
+
+	assert.Equal(t, "42", m.procid)
+	assert.Equal(t, map[string]string{
+		"a_x": `q"uo]te`,
+		"a_y": `\`,
+		"b_z": "1",
+	}, m.structured)
+	assert.Equal(t, "hello", m.msg)
+}
+
+func TestParseRFC5424Invalid(t *testing.T) {
+	for _, data := range []string{
+		`<34>1 2003-10-11T22:14:15.003Z host`,
+		`<34>1 yesterday host app - - - msg`,
+		`<34>1 - host app - - [a x="1" msg`,
+		`<34>1 - host app - - [a x=1] msg`,
+	} {
+		_, err := parseMessage(data, now)
+		assert.Error(t, err, data)
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	m, err := parseMessage(`<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8`, now)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, m.facility)
+	assert.Equal(t, 2, m.severity)
+	assert.Equal(t, 0, m.version)
+	assert.Equal(t, time.Date(2015, time.October, 11, 22, 14, 15, 0, time.UTC), m.timestamp)
+	assert.Equal(t, "mymachine", m.hostname)
+	assert.Equal(t, "su", m.appname)
+	assert.Equal(t, "123", m.procid)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", m.msg)
+
+	fields, tags := m.fields()
+	assert.Equal(t, map[string]string{
+		"severity": "crit",
+		"facility": "auth",
+		"hostname": "mymachine",
+		"appname":  "su",
+	}, tags)
+	_, ok := fields["version"]
+	assert.False(t, ok)
+	assert.Equal(t, "123", fields["procid"])
+}
+
+func TestParseRFC3164PreviousYear(t *testing.T) {
+	m, err := parseMessage(`<13>Dec 31 23:59:59 host app: bye`, time.Date(2016, time.January, 1, 0, 0, 1, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 2015, m.timestamp.Year())
+}
+
+func TestParseRFC3164NoHeader(t *testing.T) {
+	m, err := parseMessage(`<13>Use the BFG!`, now)
+	require.NoError(t, err)
+
+	assert.True(t, m.timestamp.IsZero())
+	assert.Equal(t, "", m.hostname)
+	assert.Equal(t, "", m.appname)
+	assert.Equal(t, "Use the BFG!", m.msg)
+}
+
+func TestParseInvalidPriority(t *testing.T) {
+	for _, data := range []string{"no priority", "<>1 - - - - - -", "<192>msg", "<abc>msg"} {
+		_, err := parseMessage(data, now)
+		assert.Error(t, err, data)
+	}
+}
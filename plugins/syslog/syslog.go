@@ -0,0 +1,306 @@
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const (
+	defaultAllowedPendingPoints = 10000
+	// UDP packets larger than this are truncated
+	udpBufferSize = 64 * 1024
+	// octet counted messages larger than this are refused
+	maxMessageLength = 64 * 1024
+)
+
+var dropwarn = "ERROR: Point buffer full. Discarding message [%s] " +
+	"You may want to increase allowed_pending_points in the config\n"
+
+// Syslog accepts RFC 5424 and RFC 3164 syslog messages over TCP or UDP and
+// adds them as syslog points on each Gather. Over TCP each message is
+// either prefixed with its length (octet counting) or terminated by a
+// newline (non-transparent framing), as described in RFC 6587.
+type Syslog struct {
+	// ServiceAddress is the URL to listen on, ie "tcp://:6514" or
+	// "udp://:514"
+	ServiceAddress string
+
+	// MaxConnections limits the number of open TCP connections, zero means
+	// no limit
+	MaxConnections int
+
+	// Number of points allowed to queue up in between calls to Gather.
+	// Messages received while the buffer is full are dropped.
+	AllowedPendingPoints int
+
+	sync.Mutex
+	points []point
+
+	listener   net.Listener
+	packetConn net.PacketConn
+	conns      map[net.Conn]bool
+	done       chan struct{}
+	wg         sync.WaitGroup
+}
+
+type point struct {
+	fields map[string]interface{}
+	tags   map[string]string
+	time   time.Time
+}
+
+var sampleConfig = `
+  # URL to listen on, the scheme is one of tcp, tcp4, tcp6, udp, udp4 or udp6
+  service_address = "udp://:514"
+
+  # Maximum number of concurrent TCP connections, 0 means unlimited
+  max_connections = 0
+
+  # Number of points to buffer in between collection intervals
+  allowed_pending_points = 10000
+`
+
+func (s *Syslog) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Syslog) Description() string {
+	return "Accept RFC 5424 and RFC 3164 syslog messages over TCP or UDP"
+}
+
+func (s *Syslog) Start() error {
+	s.done = make(chan struct{})
+	s.conns = make(map[net.Conn]bool)
+
+	u, err := url.Parse(s.ServiceAddress)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("Invalid service_address '%s'", s.ServiceAddress)
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		s.listener, err = net.Listen(u.Scheme, u.Host)
+		if err != nil {
+			return err
+		}
+		s.wg.Add(1)
+		go s.acceptTCP()
+	case "udp", "udp4", "udp6":
+		s.packetConn, err = net.ListenPacket(u.Scheme, u.Host)
+		if err != nil {
+			return err
+		}
+		s.wg.Add(1)
+		go s.readUDP()
+	default:
+		return fmt.Errorf("Unsupported scheme '%s' in service_address '%s'",
+			u.Scheme, s.ServiceAddress)
+	}
+
+	log.Printf("Syslog listening on %s\n", s.ServiceAddress)
+	return nil
+}
+
+// Addr returns the address the listener is bound to
+func (s *Syslog) Addr() net.Addr {
+	if s.listener != nil {
+		return s.listener.Addr()
+	}
+	if s.packetConn != nil {
+		return s.packetConn.LocalAddr()
+	}
+	return nil
+}
+
+func (s *Syslog) acceptTCP() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				log.Printf("ERROR: accepting connection on %s: %s\n",
+					s.ServiceAddress, err)
+				continue
+			}
+		}
+
+		s.Lock()
+		if s.MaxConnections > 0 && len(s.conns) >= s.MaxConnections {
+			s.Unlock()
+			log.Printf("Syslog reached max_connections (%d), "+
+				"refusing connection from %s\n", s.MaxConnections, conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = true
+		s.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := s.readStream(conn); err != nil {
+				log.Printf("ERROR: reading syslog from %s: %s\n", conn.RemoteAddr(), err)
+			}
+
+			s.Lock()
+			delete(s.conns, conn)
+			s.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+func (s *Syslog) readUDP() {
+	defer s.wg.Done()
+	buf := make([]byte, udpBufferSize)
+	for {
+		n, _, err := s.packetConn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				log.Printf("ERROR: reading packet on %s: %s\n", s.ServiceAddress, err)
+				continue
+			}
+		}
+		// each datagram holds a single message
+		s.parseMessage(strings.TrimRight(string(buf[:n]), "\r\n\x00"))
+	}
+}
+
+// readStream parses the messages of r until it ends. The framing is
+// detected for each message: octet counted messages start with their
+// length while other messages start with their priority.
+func (s *Syslog) readStream(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	for {
+		first, err := reader.Peek(1)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var msg string
+		if first[0] >= '0' && first[0] <= '9' {
+			msg, err = readOctetCounted(reader)
+		} else {
+			msg, err = reader.ReadString('\n')
+			if err == io.EOF && msg != "" {
+				err = nil
+			}
+			msg = strings.TrimRight(msg, "\r\n")
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(msg) != "" {
+			s.parseMessage(msg)
+		}
+	}
+}
+
+// readOctetCounted reads a "MSG-LEN SP SYSLOG-MSG" frame
+func readOctetCounted(reader *bufio.Reader) (string, error) {
+	prefix, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	length, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+	if err != nil || length > maxMessageLength {
+		return "", fmt.Errorf("Invalid message length '%s'", strings.TrimSpace(prefix))
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(buf), "\r\n"), nil
+}
+
+func (s *Syslog) parseMessage(data string) {
+	now := time.Now()
+	m, err := parseMessage(data, now)
+	if err != nil {
+		log.Printf("ERROR: unable to parse syslog message [%s]: %s\n", data, err)
+		return
+	}
+
+	fields, tags := m.fields()
+	t := m.timestamp
+	if t.IsZero() {
+		t = now
+	}
+
+	limit := s.AllowedPendingPoints
+	if limit == 0 {
+		limit = defaultAllowedPendingPoints
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if len(s.points) >= limit {
+		log.Printf(dropwarn, data)
+		return
+	}
+	s.points = append(s.points, point{fields: fields, tags: tags, time: t})
+}
+
+func (s *Syslog) Gather(acc plugins.Accumulator) error {
+	s.Lock()
+	points := s.points
+	s.points = nil
+	s.Unlock()
+
+	for _, pt := range points {
+		acc.AddFields("", pt.fields, pt.tags, pt.time)
+	}
+	return nil
+}
+
+func (s *Syslog) Stop() {
+	if s.done == nil {
+		return
+	}
+	close(s.done)
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	if s.packetConn != nil {
+		s.packetConn.Close()
+	}
+
+	s.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.Unlock()
+
+	s.wg.Wait()
+}
+
+func init() {
+	plugins.Add("syslog", func() plugins.Plugin {
+		return &Syslog{
+			ServiceAddress:       "udp://:514",
+			AllowedPendingPoints: defaultAllowedPendingPoints,
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package syslog
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rfc5424Message = `<165>1 2015-10-11T22:14:15.003Z mymachine evntslog - ID47 - An application event`
+	rfc3164Message = `<34>Oct 11 22:14:15 mymachine su: 'su root' failed`
+)
+
+func waitForPoints(t *testing.T, s *Syslog, acc *testutil.Accumulator, n int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		require.NoError(t, s.Gather(acc))
+		if len(acc.Points) >= n {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, acc.Points, n)
+}
+
+func assertTestPoints(t *testing.T, acc *testutil.Accumulator) {
+	assert.Equal(t, "", acc.Points[0].Measurement)
+	assert.Equal(t, "notice", acc.Points[0].Tags["severity"])
+	assert.Equal(t, "local4", acc.Points[0].Tags["facility"])
+	assert.Equal(t, "An application event", acc.Points[0].Values["message"])
+	assert.Equal(t, time.Date(2015, time.October, 11, 22, 14, 15, 3000000, time.UTC),
+		acc.Points[0].Time.UTC())
+
+	assert.Equal(t, "crit", acc.Points[1].Tags["severity"])
+	assert.Equal(t, "auth", acc.Points[1].Tags["facility"])
+	assert.Equal(t, "su", acc.Points[1].Tags["appname"])
+	assert.Equal(t, "'su root' failed", acc.Points[1].Values["message"])
+}
+
+func sendTCP(t *testing.T, s *Syslog, data string) {
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(data))
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestSyslogUDP(t *testing.T) {
+	s := &Syslog{ServiceAddress: "udp://127.0.0.1:0"}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var acc testutil.Accumulator
+	_, err = conn.Write([]byte(rfc5424Message + "\n"))
+	require.NoError(t, err)
+	waitForPoints(t, s, &acc, 1)
+	_, err = conn.Write([]byte(rfc3164Message))
+	require.NoError(t, err)
+	waitForPoints(t, s, &acc, 2)
+	assertTestPoints(t, &acc)
+}
+
+func TestSyslogTCPNonTransparent(t *testing.T) {
+	s := &Syslog{ServiceAddress: "tcp://127.0.0.1:0"}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	sendTCP(t, s, rfc5424Message+"\n"+"not syslog\n"+rfc3164Message+"\r\n")
+
+	var acc testutil.Accumulator
+	waitForPoints(t, s, &acc, 2)
+	assertTestPoints(t, &acc)
+}
+
+func TestSyslogTCPOctetCounting(t *testing.T) {
+	s := &Syslog{ServiceAddress: "tcp://127.0.0.1:0"}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	// octet counted messages may contain newlines
+	multiline := rfc3164Message + "\nsecond line"
+	sendTCP(t, s, fmt.Sprintf("%d %s%d %s", len(rfc5424Message), rfc5424Message,
+		len(multiline), multiline))
+
+	var acc testutil.Accumulator
+	waitForPoints(t, s, &acc, 2)
+	assert.Equal(t, "An application event", acc.Points[0].Values["message"])
+	assert.Equal(t, "'su root' failed\nsecond line", acc.Points[1].Values["message"])
+}
+
+func TestSyslogTCPMixedFraming(t *testing.T) {
+	s := &Syslog{ServiceAddress: "tcp://127.0.0.1:0"}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	sendTCP(t, s, fmt.Sprintf("%d %s%s\n", len(rfc5424Message), rfc5424Message,
+		rfc3164Message))
+
+	var acc testutil.Accumulator
+	waitForPoints(t, s, &acc, 2)
+	assertTestPoints(t, &acc)
+}
+
+func TestSyslogPendingPointsLimit(t *testing.T) {
+	s := &Syslog{ServiceAddress: "tcp://127.0.0.1:0", AllowedPendingPoints: 1}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	sendTCP(t, s, rfc5424Message+"\n"+rfc3164Message+"\n")
+	time.Sleep(50 * time.Millisecond)
+
+	var acc testutil.Accumulator
+	waitForPoints(t, s, &acc, 1)
+	assert.Equal(t, "An application event", acc.Points[0].Values["message"])
+}
+
+func TestSyslogInvalidAddress(t *testing.T) {
+	s := &Syslog{ServiceAddress: "unix:///tmp/syslog.sock"}
+	assert.Error(t, s.Start())
+}
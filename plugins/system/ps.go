@@ -1,29 +1,16 @@
 package system
 
 import (
-	gonet "net"
 	"os"
-	"strings"
 
-	dc "github.com/fsouza/go-dockerclient"
 	"github.com/influxdb/telegraf/plugins"
 	"github.com/shirou/gopsutil/common"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/disk"
-	"github.com/shirou/gopsutil/docker"
 	"github.com/shirou/gopsutil/mem"
 	"github.com/shirou/gopsutil/net"
 )
 
-type DockerContainerStat struct {
-	Id      string
-	Name    string
-	Command string
-	Labels  map[string]string
-	CPU     *cpu.CPUTimesStat
-	Mem     *docker.CgroupMemStat
-}
-
 type PS interface {
 	CPUTimes(perCPU, totalCPU bool) ([]cpu.CPUTimesStat, error)
 	DiskUsage() ([]*disk.DiskUsageStat, error)
@@ -31,7 +18,6 @@ type PS interface {
 	DiskIO() (map[string]disk.DiskIOCountersStat, error)
 	VMStat() (*mem.VirtualMemoryStat, error)
 	SwapStat() (*mem.SwapMemoryStat, error)
-	DockerStat() ([]*DockerContainerStat, error)
 	NetConnections() ([]net.NetConnectionStat, error)
 }
 
@@ -42,9 +28,7 @@ func add(acc plugins.Accumulator,
 	}
 }
 
-type systemPS struct {
-	dockerClient *dc.Client
-}
+type systemPS struct{}
 
 func (s *systemPS) CPUTimes(perCPU, totalCPU bool) ([]cpu.CPUTimesStat, error) {
 	var cpuTimes []cpu.CPUTimesStat
@@ -111,52 +95,3 @@ func (s *systemPS) VMStat() (*mem.VirtualMemoryStat, error) {
 func (s *systemPS) SwapStat() (*mem.SwapMemoryStat, error) {
 	return mem.SwapMemory()
 }
-
-func (s *systemPS) DockerStat() ([]*DockerContainerStat, error) {
-	if s.dockerClient == nil {
-		c, err := dc.NewClient("unix:///var/run/docker.sock")
-		if err != nil {
-			return nil, err
-		}
-
-		s.dockerClient = c
-	}
-
-	opts := dc.ListContainersOptions{}
-
-	containers, err := s.dockerClient.ListContainers(opts)
-	if err != nil {
-		if _, ok := err.(*gonet.OpError); ok {
-			return nil, nil
-		}
-
-		return nil, err
-	}
-
-	var stats []*DockerContainerStat
-
-	for _, container := range containers {
-		ctu, err := docker.CgroupCPUDocker(container.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		mem, err := docker.CgroupMemDocker(container.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		name := strings.Join(container.Names, " ")
-
-		stats = append(stats, &DockerContainerStat{
-			Id:      container.ID,
-			Name:    name,
-			Command: container.Command,
-			Labels:  container.Labels,
-			CPU:     ctu,
-			Mem:     mem,
-		})
-	}
-
-	return stats, nil
-}
@@ -0,0 +1,269 @@
+package tail
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/plugins"
+	"github.com/influxdb/telegraf/plugins/parsers"
+)
+
+const defaultAllowedPendingPoints = 10000
+
+var dropwarn = "ERROR: Point buffer full. Discarding line [%s] " +
+	"You may want to increase allowed_pending_points in the config\n"
+
+// Tail follows log files, adding the named captures of a pattern matched
+// against each new line as fields and tags on each Gather. Points are tagged
+// with the path of the file the line was read from.
+type Tail struct {
+	Files []string
+	// FromBeginning reads the files from their start rather than only the
+	// lines appended after Start
+	FromBeginning bool
+
+	// Pattern is a regular expression whose named captures are gathered. It
+	// may use grok references, see parsers.GrokParser.
+	Pattern string
+	// CustomPatterns are grok patterns, one "NAME regexp" per line, that may
+	// be referenced in Pattern
+	CustomPatterns string
+	// TagKeys are the captures gathered as tags rather than fields
+	TagKeys []string
+	// Measurement defaults to "tail"
+	Measurement string
+
+	PollInterval internal.Duration
+
+	// Number of points allowed to queue up in between calls to Gather. Lines
+	// read while the buffer is full are dropped.
+	AllowedPendingPoints int
+
+	sync.Mutex
+	points []point
+
+	parser *parsers.GrokParser
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+type point struct {
+	fields map[string]interface{}
+	tags   map[string]string
+	time   time.Time
+}
+
+var sampleConfig = `
+  # Files to follow, rotated files are reopened
+  files = ["/var/log/apache/access.log"]
+  # Read the files from their start rather than only the new lines
+  from_beginning = false
+
+  # Regular expression matched against each line, its named captures are
+  # gathered. Grok style references are expanded: %{NUMBER:bytes} is
+  # (?P<bytes>[+-]?(?:\d+(?:\.\d+)?|\.\d+)), supported patterns are INT,
+  # NUMBER, WORD, NOTSPACE, SPACE, DATA, GREEDYDATA, IP, QUOTEDSTRING and
+  # HTTPDATE. %{NUMBER:bytes:int} converts the capture to a type: int, float,
+  # bool, string or tag. Untyped captures holding a number are numbers.
+  pattern = '%{IP:client} \S+ \S+ \[%{HTTPDATE}\] "%{WORD:method} %{NOTSPACE:request} \S+" %{INT:status} %{INT:bytes:int}'
+  # Patterns that may be referenced in pattern, one "NAME regexp" per line
+  # custom_patterns = '''
+  #   DURATION %{NUMBER}(?:ms|s)
+  # '''
+  # Captures gathered as tags, the others are fields
+  tag_keys = ["method", "status"]
+  # Name of the measurement, tail by default
+  # measurement = "apache_access"
+
+  # How often the files are checked for new lines
+  poll_interval = "250ms"
+
+  # Number of points to buffer in between collection intervals
+  allowed_pending_points = 10000
+`
+
+func (t *Tail) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Tail) Description() string {
+	return "Parse metrics from the new lines of log files"
+}
+
+func (t *Tail) Start() error {
+	parser, err := parsers.NewGrokParser(
+		[]string{t.Pattern}, t.CustomPatterns, "", t.TagKeys)
+	if err != nil {
+		return err
+	}
+	t.parser = parser
+
+	interval := t.PollInterval.Duration
+	if interval == 0 {
+		interval = 250 * time.Millisecond
+	}
+
+	t.done = make(chan struct{})
+	for _, path := range t.Files {
+		f := &tailedFile{path: path, seekEnd: !t.FromBeginning}
+		// lines already in the file are skipped before Start returns, so
+		// that every line appended afterwards is read
+		lineFn := func(line string) { t.parseLine(f.path, line) }
+		f.poll(lineFn)
+
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			defer f.close()
+			for {
+				select {
+				case <-t.done:
+					return
+				case <-ticker.C:
+					f.poll(lineFn)
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+func (t *Tail) parseLine(path, line string) {
+	parsed, err := t.parser.Parse([]byte(line))
+	if err != nil || len(parsed) == 0 {
+		return
+	}
+	fields := parsed[0].Fields()
+	tags := parsed[0].Tags()
+	tags["path"] = path
+
+	limit := t.AllowedPendingPoints
+	if limit == 0 {
+		limit = defaultAllowedPendingPoints
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if len(t.points) >= limit {
+		log.Printf(dropwarn, line)
+		return
+	}
+	t.points = append(t.points, point{fields: fields, tags: tags, time: time.Now()})
+}
+
+func (t *Tail) Gather(acc plugins.Accumulator) error {
+	t.Lock()
+	points := t.points
+	t.points = nil
+	t.Unlock()
+
+	for _, pt := range points {
+		acc.AddFields(t.Measurement, pt.fields, pt.tags, pt.time)
+	}
+	return nil
+}
+
+func (t *Tail) Stop() {
+	if t.done == nil {
+		return
+	}
+	close(t.done)
+	t.wg.Wait()
+}
+
+// tailedFile reads the lines appended to a file, following it across
+// rotations and truncations
+type tailedFile struct {
+	path string
+	// seekEnd skips the content of the file when it is first opened
+	seekEnd bool
+
+	file    *os.File
+	offset  int64
+	partial []byte
+}
+
+// poll calls lineFn with every complete line appended since the last poll
+func (f *tailedFile) poll(lineFn func(string)) {
+	if f.file == nil {
+		file, err := os.Open(f.path)
+		if err != nil {
+			// the file may not have been created or rotated in yet, and is
+			// then read from its start
+			f.seekEnd = false
+			return
+		}
+		f.file = file
+		f.offset = 0
+		if f.seekEnd {
+			f.offset, _ = file.Seek(0, io.SeekEnd)
+		}
+		f.seekEnd = false
+	}
+
+	f.read(lineFn)
+
+	current, err := f.file.Stat()
+	if err != nil {
+		f.close()
+		return
+	}
+	if info, err := os.Stat(f.path); err != nil || !os.SameFile(info, current) {
+		// the file was renamed or removed, once it is read to the end the
+		// new file at path is read from its start
+		f.close()
+		return
+	}
+	if current.Size() < f.offset {
+		// the file was truncated
+		f.file.Seek(0, io.SeekStart)
+		f.offset = 0
+		f.partial = nil
+		f.read(lineFn)
+	}
+}
+
+func (f *tailedFile) read(lineFn func(string)) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.file.Read(buf)
+		f.offset += int64(n)
+		data := append(f.partial, buf[:n]...)
+		for {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				break
+			}
+			lineFn(string(bytes.TrimSuffix(data[:i], []byte("\r"))))
+			data = data[i+1:]
+		}
+		f.partial = append([]byte(nil), data...)
+		if err != nil || n == 0 {
+			return
+		}
+	}
+}
+
+func (f *tailedFile) close() {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	f.partial = nil
+}
+
+func init() {
+	plugins.Add("tail", func() plugins.Plugin {
+		return &Tail{
+			PollInterval:         internal.Duration{Duration: 250 * time.Millisecond},
+			AllowedPendingPoints: defaultAllowedPendingPoints,
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package tail
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdb/telegraf/internal"
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTail(path string) *Tail {
+	return &Tail{
+		Files:        []string{path},
+		Pattern:      `%{WORD:method} %{NOTSPACE:request} %{INT:status} %{NUMBER:duration}`,
+		TagKeys:      []string{"method", "status"},
+		PollInterval: internal.Duration{Duration: 5 * time.Millisecond},
+	}
+}
+
+func appendLines(t *testing.T, path string, lines string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(lines)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+// gatherPoints gathers until n points were added or a second passes
+func gatherPoints(t *testing.T, tail *Tail, n int) *testutil.Accumulator {
+	acc := &testutil.Accumulator{}
+	deadline := time.Now().Add(time.Second)
+	for len(acc.Points) < n && time.Now().Before(deadline) {
+		require.NoError(t, tail.Gather(acc))
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.Len(t, acc.Points, n)
+	return acc
+}
+
+func tempLog(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "access.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestTailNewLines(t *testing.T) {
+	path, cleanup := tempLog(t, "GET /old 200 0.5\n")
+	defer cleanup()
+
+	tail := newTail(path)
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+
+	appendLines(t, path, "GET /index.html 200 0.25\nnot a request\nPOST /form 500 1\n")
+	acc := gatherPoints(t, tail, 2)
+
+	p := acc.Points[0]
+	assert.Equal(t, map[string]string{"path": path, "method": "GET", "status": "200"}, p.Tags)
+	assert.Equal(t, map[string]interface{}{
+		"request":  "/index.html",
+		"duration": 0.25,
+	}, p.Values)
+
+	p = acc.Points[1]
+	assert.Equal(t, "POST", p.Tags["method"])
+	assert.Equal(t, "500", p.Tags["status"])
+	assert.Equal(t, int64(1), p.Values["duration"])
+}
+
+func TestTailFromBeginning(t *testing.T) {
+	path, cleanup := tempLog(t, "GET /old 200 0.5\n")
+	defer cleanup()
+
+	tail := newTail(path)
+	tail.FromBeginning = true
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+
+	acc := gatherPoints(t, tail, 1)
+	assert.Equal(t, "/old", acc.Points[0].Values["request"])
+}
+
+func TestTailPartialLine(t *testing.T) {
+	path, cleanup := tempLog(t, "")
+	defer cleanup()
+
+	tail := newTail(path)
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+
+	appendLines(t, path, "GET /split")
+	time.Sleep(20 * time.Millisecond)
+	appendLines(t, path, " 200 2\n")
+
+	acc := gatherPoints(t, tail, 1)
+	assert.Equal(t, "/split", acc.Points[0].Values["request"])
+	assert.Equal(t, int64(2), acc.Points[0].Values["duration"])
+}
+
+func TestTailTruncated(t *testing.T) {
+	path, cleanup := tempLog(t, "GET /old 200 0.5\nGET /old 200 0.5\n")
+	defer cleanup()
+
+	tail := newTail(path)
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("GET /new 200 1\n"), 0644))
+
+	acc := gatherPoints(t, tail, 1)
+	assert.Equal(t, "/new", acc.Points[0].Values["request"])
+}
+
+func TestTailRotated(t *testing.T) {
+	path, cleanup := tempLog(t, "")
+	defer cleanup()
+
+	tail := newTail(path)
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+
+	appendLines(t, path, "GET /before 200 1\n")
+	gatherPoints(t, tail, 1)
+
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendLines(t, path, "GET /after 200 1\n")
+
+	acc := gatherPoints(t, tail, 1)
+	assert.Equal(t, "/after", acc.Points[0].Values["request"])
+}
+
+func TestTailMissingFile(t *testing.T) {
+	path, cleanup := tempLog(t, "")
+	defer cleanup()
+	missing := path + ".missing"
+
+	tail := newTail(missing)
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+
+	appendLines(t, missing, "GET /created 200 1\n")
+
+	acc := gatherPoints(t, tail, 1)
+	assert.Equal(t, "/created", acc.Points[0].Values["request"])
+}
+
+func TestTailPendingPointsLimit(t *testing.T) {
+	path, cleanup := tempLog(t, "")
+	defer cleanup()
+
+	tail := newTail(path)
+	tail.AllowedPendingPoints = 1
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+
+	appendLines(t, path, "GET /a 200 1\nGET /b 200 1\n")
+	time.Sleep(50 * time.Millisecond)
+
+	acc := gatherPoints(t, tail, 1)
+	assert.Equal(t, "/a", acc.Points[0].Values["request"])
+}
+
+func TestTailInvalidPattern(t *testing.T) {
+	tail := &Tail{Pattern: `(?P<broken`}
+	assert.Error(t, tail.Start())
+}
+
+func TestTailCustomPatterns(t *testing.T) {
+	path, cleanup := tempLog(t, "")
+	defer cleanup()
+
+	tail := &Tail{
+		Files:          []string{path},
+		Pattern:        `%{WORD:method} took %{DURATION:duration:float}ms`,
+		CustomPatterns: "DURATION %{NUMBER}",
+		TagKeys:        []string{"method"},
+		PollInterval:   internal.Duration{Duration: 5 * time.Millisecond},
+	}
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+	appendLines(t, path, "GET took 12ms\n")
+
+	acc := gatherPoints(t, tail, 1)
+	assert.Equal(t, map[string]string{"method": "GET", "path": path}, acc.Points[0].Tags)
+	assert.Equal(t, map[string]interface{}{"duration": 12.0}, acc.Points[0].Values)
+}
@@ -0,0 +1,55 @@
+package trig
+
+import (
+	"math"
+
+	"github.com/influxdb/telegraf/plugins"
+)
+
+// Trig generates synthetic metrics, to test outputs and filters without a
+// real source of metrics. The values only depend on the number of gathers,
+// so that every run of telegraf writes the same series.
+type Trig struct {
+	// Amplitude of the sine and cosine waves
+	Amplitude float64
+	// Frequency of the waves in cycles per gather, ie 0.1 is a full wave
+	// every 10 gathers
+	Frequency float64
+
+	// gathers done so far
+	n int64
+}
+
+var sampleConfig = `
+  # Amplitude of the sine and cosine waves
+  amplitude = 10.0
+  # Cycles per gather, 0.1 is a full wave every 10 intervals
+  frequency = 0.1
+`
+
+func (t *Trig) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Trig) Description() string {
+	return "Generate sine and cosine waves and a counter, to test pipelines"
+}
+
+// Gather adds the values of the n-th gather:
+// sine = amplitude * sin(2π * frequency * n), cosine likewise and counter = n
+func (t *Trig) Gather(acc plugins.Accumulator) error {
+	x := 2 * math.Pi * t.Frequency * float64(t.n)
+	acc.AddFields("", map[string]interface{}{
+		"sine":    t.Amplitude * math.Sin(x),
+		"cosine":  t.Amplitude * math.Cos(x),
+		"counter": t.n,
+	}, nil)
+	t.n++
+	return nil
+}
+
+func init() {
+	plugins.Add("trig", func() plugins.Plugin {
+		return &Trig{Amplitude: 10, Frequency: 0.1}
+	})
+}
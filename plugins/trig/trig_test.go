@@ -0,0 +1,47 @@
+package trig
+
+import (
+	"math"
+	"testing"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrigWaves(t *testing.T) {
+	trig := &Trig{Amplitude: 5, Frequency: 0.25}
+
+	var acc testutil.Accumulator
+	for i := 0; i < 8; i++ {
+		require.NoError(t, trig.Gather(&acc))
+	}
+	require.Len(t, acc.Points, 8)
+
+	// a full wave every 4 gathers
+	sines := []float64{0, 5, 0, -5, 0, 5, 0, -5}
+	cosines := []float64{5, 0, -5, 0, 5, 0, -5, 0}
+	for i, p := range acc.Points {
+		assert.Equal(t, int64(i), p.Values["counter"])
+		assert.InDelta(t, sines[i], p.Values["sine"], 1e-9, "gather %d", i)
+		assert.InDelta(t, cosines[i], p.Values["cosine"], 1e-9, "gather %d", i)
+		assert.Empty(t, p.Tags)
+	}
+}
+
+func TestTrigIsDeterministic(t *testing.T) {
+	a, b := &Trig{Amplitude: 10, Frequency: 0.1}, &Trig{Amplitude: 10, Frequency: 0.1}
+
+	var accA, accB testutil.Accumulator
+	for i := 0; i < 20; i++ {
+		require.NoError(t, a.Gather(&accA))
+		require.NoError(t, b.Gather(&accB))
+	}
+	for i := range accA.Points {
+		assert.Equal(t, accA.Points[i].Values, accB.Points[i].Values)
+		sine := accA.Points[i].Values["sine"].(float64)
+		assert.True(t, math.Abs(sine) <= 10, "sine %v", sine)
+	}
+	// back to the start of the wave after 10 gathers
+	assert.InDelta(t, accA.Points[0].Values["sine"], accA.Points[10].Values["sine"], 1e-9)
+}
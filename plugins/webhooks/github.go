@@ -0,0 +1,146 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GithubWebhook adds the GitHub events relevant to deploys and CI to the
+// github measurement, tagged by event and repository.
+type GithubWebhook struct {
+	Path string
+	// Secret verifies the X-Hub-Signature of the payloads when set
+	Secret string
+}
+
+type githubPayload struct {
+	Action string `json:"action"`
+
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+
+	// push
+	Ref     string            `json:"ref"`
+	After   string            `json:"after"`
+	Commits []json.RawMessage `json:"commits"`
+
+	// deployment and deployment_status
+	Deployment struct {
+		Sha         string `json:"sha"`
+		Ref         string `json:"ref"`
+		Environment string `json:"environment"`
+		Description string `json:"description"`
+	} `json:"deployment"`
+	DeploymentStatus struct {
+		State       string `json:"state"`
+		Description string `json:"description"`
+	} `json:"deployment_status"`
+
+	// status
+	Sha         string `json:"sha"`
+	State       string `json:"state"`
+	Context     string `json:"context"`
+	Description string `json:"description"`
+
+	// pull_request
+	PullRequest struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Merged bool   `json:"merged"`
+	} `json:"pull_request"`
+}
+
+func (g *GithubWebhook) path() string {
+	return g.Path
+}
+
+func (g *GithubWebhook) parse(r *http.Request, body []byte) ([]point, error) {
+	if g.Secret != "" && !g.validSignature(r.Header.Get("X-Hub-Signature"), body) {
+		return nil, errors.New("Invalid X-Hub-Signature")
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if event == "" {
+		return nil, errors.New("Missing X-GitHub-Event header")
+	}
+	if event == "ping" {
+		return nil, nil
+	}
+
+	var p githubPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("Error decoding %s event", event)
+	}
+
+	tags := map[string]string{
+		"event":      event,
+		"repository": p.Repository.FullName,
+	}
+	fields := map[string]interface{}{
+		"sender": p.Sender.Login,
+	}
+	if p.Action != "" {
+		tags["action"] = p.Action
+	}
+
+	switch event {
+	case "push":
+		tags["ref"] = p.Ref
+		fields["commits"] = len(p.Commits)
+		fields["sha"] = p.After
+	case "deployment":
+		tags["environment"] = p.Deployment.Environment
+		fields["ref"] = p.Deployment.Ref
+		fields["sha"] = p.Deployment.Sha
+		fields["description"] = p.Deployment.Description
+	case "deployment_status":
+		tags["environment"] = p.Deployment.Environment
+		tags["state"] = p.DeploymentStatus.State
+		fields["ref"] = p.Deployment.Ref
+		fields["sha"] = p.Deployment.Sha
+		fields["description"] = p.DeploymentStatus.Description
+	case "status":
+		tags["state"] = p.State
+		tags["context"] = p.Context
+		fields["sha"] = p.Sha
+		fields["description"] = p.Description
+	case "pull_request":
+		fields["number"] = p.PullRequest.Number
+		fields["title"] = p.PullRequest.Title
+		fields["merged"] = p.PullRequest.Merged
+	}
+
+	return []point{{
+		measurement: "github",
+		fields:      fields,
+		tags:        tags,
+		time:        time.Now(),
+	}}, nil
+}
+
+// validSignature checks signature, ie "sha1=<hex>", is the HMAC of body
+// keyed with the secret
+func (g *GithubWebhook) validSignature(signature string, body []byte) bool {
+	if !strings.HasPrefix(signature, "sha1=") {
+		return false
+	}
+	actual, err := hex.DecodeString(strings.TrimPrefix(signature, "sha1="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha1.New, []byte(g.Secret))
+	mac.Write(body)
+	return hmac.Equal(actual, mac.Sum(nil))
+}
@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// JSONWebhook adds every value of the posted JSON object, or of each object
+// of a posted array, as a field. Nested keys are joined with "_".
+type JSONWebhook struct {
+	Path string
+	// Name of the measurement
+	Name string
+	// TagKeys are the top level keys gathered as tags
+	TagKeys []string
+}
+
+func (j *JSONWebhook) path() string {
+	return j.Path
+}
+
+func (j *JSONWebhook) parse(r *http.Request, body []byte) ([]point, error) {
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.New("Error decoding JSON payload")
+	}
+
+	var objects []map[string]interface{}
+	switch v := payload.(type) {
+	case map[string]interface{}:
+		objects = append(objects, v)
+	case []interface{}:
+		for _, item := range v {
+			if obj, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, obj)
+			}
+		}
+	default:
+		return nil, errors.New("JSON payload is not an object or array")
+	}
+
+	now := time.Now()
+	var points []point
+	for _, obj := range objects {
+		tags := make(map[string]string)
+		for _, tag := range j.TagKeys {
+			switch v := obj[tag].(type) {
+			case string:
+				tags[tag] = v
+			case float64:
+				tags[tag] = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				tags[tag] = strconv.FormatBool(v)
+			}
+			delete(obj, tag)
+		}
+
+		fields := make(map[string]interface{})
+		flatten(fields, "", obj)
+		if len(fields) == 0 {
+			continue
+		}
+		points = append(points, point{
+			measurement: j.Name,
+			fields:      fields,
+			tags:        tags,
+			time:        now,
+		})
+	}
+	return points, nil
+}
+
+// flatten adds the numbers, strings and booleans of v to fields, the keys
+// of nested objects and the indexes of arrays being joined with "_"
+func flatten(fields map[string]interface{}, key string, v interface{}) {
+	join := func(child string) string {
+		if key == "" {
+			return child
+		}
+		return key + "_" + child
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			flatten(fields, join(k), v)
+		}
+	case []interface{}:
+		for i, v := range t {
+			flatten(fields, join(strconv.Itoa(i)), v)
+		}
+	case float64, string, bool:
+		fields[key] = t
+	}
+}
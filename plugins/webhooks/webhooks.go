@@ -0,0 +1,198 @@
+package webhooks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/plugins"
+)
+
+const (
+	defaultAllowedPendingPoints = 10000
+	// request bodies larger than this are refused
+	maxBodySize = 1024 * 1024
+)
+
+var dropwarn = "ERROR: Point buffer full. Discarding %s webhook " +
+	"You may want to increase allowed_pending_points in the config\n"
+
+// webhook parses the requests received on its path into points
+type webhook interface {
+	path() string
+	parse(r *http.Request, body []byte) ([]point, error)
+}
+
+type point struct {
+	measurement string
+	fields      map[string]interface{}
+	tags        map[string]string
+	time        time.Time
+}
+
+// Webhooks listens for HTTP POSTs from the configured webhook providers,
+// each on its own path, and adds the points parsed from their payloads on
+// each Gather.
+type Webhooks struct {
+	// ServiceAddress is the address to listen on, ie ":1619"
+	ServiceAddress string
+
+	Github *GithubWebhook
+	Json   []*JSONWebhook
+
+	// Number of points allowed to queue up in between calls to Gather.
+	// Webhooks received while the buffer is full are refused.
+	AllowedPendingPoints int
+
+	sync.Mutex
+	points []point
+
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+var sampleConfig = `
+  # Address to listen on
+  service_address = ":1619"
+
+  # Number of points to buffer in between collection intervals
+  allowed_pending_points = 10000
+
+  # GitHub webhook, push, deployment, deployment_status, status and
+  # pull_request events are added to the webhooks_github measurement
+  [webhooks.github]
+    path = "/github"
+    # Secret the payloads are signed with, they are not verified when empty
+    secret = ""
+
+  # JSON webhooks, each adds the values of the posted object, or objects
+  # when an array is posted, to the webhooks_<name> measurement
+  [[webhooks.json]]
+    path = "/deploy"
+    name = "deploy"
+    # Top level keys gathered as tags rather than fields
+    tag_keys = ["service", "environment"]
+`
+
+func (w *Webhooks) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *Webhooks) Description() string {
+	return "Add the payloads of GitHub and JSON webhooks"
+}
+
+func (w *Webhooks) webhooks() []webhook {
+	var hooks []webhook
+	if w.Github != nil {
+		hooks = append(hooks, w.Github)
+	}
+	for _, j := range w.Json {
+		hooks = append(hooks, j)
+	}
+	return hooks
+}
+
+func (w *Webhooks) Start() error {
+	mux := http.NewServeMux()
+	for _, hook := range w.webhooks() {
+		if hook.path() == "" {
+			return fmt.Errorf("Missing path for %T webhook", hook)
+		}
+		mux.Handle(hook.path(), w.handler(hook))
+	}
+
+	var err error
+	w.listener, err = net.Listen("tcp", w.ServiceAddress)
+	if err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		// Serve returns once the listener is closed by Stop
+		http.Serve(w.listener, mux)
+	}()
+
+	log.Printf("Webhooks listening on %s\n", w.ServiceAddress)
+	return nil
+}
+
+// Addr returns the address the listener is bound to
+func (w *Webhooks) Addr() net.Addr {
+	if w.listener == nil {
+		return nil
+	}
+	return w.listener.Addr()
+}
+
+func (w *Webhooks) handler(hook webhook) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(rw, "Only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, maxBodySize))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		points, err := hook.parse(r, body)
+		if err != nil {
+			log.Printf("ERROR: unable to parse webhook on %s: %s\n", hook.path(), err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		limit := w.AllowedPendingPoints
+		if limit == 0 {
+			limit = defaultAllowedPendingPoints
+		}
+
+		w.Lock()
+		defer w.Unlock()
+		if len(w.points)+len(points) > limit {
+			log.Printf(dropwarn, hook.path())
+			http.Error(rw, "Point buffer full", http.StatusServiceUnavailable)
+			return
+		}
+		w.points = append(w.points, points...)
+		rw.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (w *Webhooks) Gather(acc plugins.Accumulator) error {
+	w.Lock()
+	points := w.points
+	w.points = nil
+	w.Unlock()
+
+	for _, pt := range points {
+		acc.AddFields(pt.measurement, pt.fields, pt.tags, pt.time)
+	}
+	return nil
+}
+
+func (w *Webhooks) Stop() {
+	if w.listener == nil {
+		return
+	}
+	w.listener.Close()
+	w.wg.Wait()
+}
+
+func init() {
+	plugins.Add("webhooks", func() plugins.Plugin {
+		return &Webhooks{
+			ServiceAddress:       ":1619",
+			AllowedPendingPoints: defaultAllowedPendingPoints,
+		}
+	})
+}
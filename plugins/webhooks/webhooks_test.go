@@ -0,0 +1,222 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/naoina/toml"
+
+	"github.com/influxdb/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startWebhooks(t *testing.T, w *Webhooks) string {
+	w.ServiceAddress = "127.0.0.1:0"
+	require.NoError(t, w.Start())
+	return "http://" + w.Addr().String()
+}
+
+func post(t *testing.T, url string, body string, headers map[string]string) int {
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestJSONWebhook(t *testing.T) {
+	w := &Webhooks{Json: []*JSONWebhook{{
+		Path:    "/deploy",
+		Name:    "deploy",
+		TagKeys: []string{"service"},
+	}}}
+	url := startWebhooks(t, w)
+	defer w.Stop()
+
+	status := post(t, url+"/deploy", `{
+		"service": "api",
+		"version": "1.2.3",
+		"duration": 42.5,
+		"success": true,
+		"hosts": ["web01", "web02"],
+		"stats": {"restarted": 2}
+	}`, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	var acc testutil.Accumulator
+	require.NoError(t, w.Gather(&acc))
+	require.Len(t, acc.Points, 1)
+
+	p := acc.Points[0]
+	assert.Equal(t, "deploy", p.Measurement)
+	assert.Equal(t, map[string]string{"service": "api"}, p.Tags)
+	assert.Equal(t, map[string]interface{}{
+		"version":         "1.2.3",
+		"duration":        42.5,
+		"success":         true,
+		"hosts_0":         "web01",
+		"hosts_1":         "web02",
+		"stats_restarted": 2.0,
+	}, p.Values)
+
+	// the buffer is emptied by Gather
+	acc = testutil.Accumulator{}
+	require.NoError(t, w.Gather(&acc))
+	assert.Len(t, acc.Points, 0)
+}
+
+func TestJSONWebhookArray(t *testing.T) {
+	w := &Webhooks{Json: []*JSONWebhook{{Path: "/builds", Name: "build"}}}
+	url := startWebhooks(t, w)
+	defer w.Stop()
+
+	assert.Equal(t, http.StatusNoContent, post(t, url+"/builds", `[{"n": 1}, "skipped", {"n": 2}]`, nil))
+
+	var acc testutil.Accumulator
+	require.NoError(t, w.Gather(&acc))
+	require.Len(t, acc.Points, 2)
+	assert.Equal(t, 1.0, acc.Points[0].Values["n"])
+	assert.Equal(t, 2.0, acc.Points[1].Values["n"])
+}
+
+func TestJSONWebhookInvalid(t *testing.T) {
+	w := &Webhooks{Json: []*JSONWebhook{{Path: "/deploy"}}}
+	url := startWebhooks(t, w)
+	defer w.Stop()
+
+	assert.Equal(t, http.StatusBadRequest, post(t, url+"/deploy", `not json`, nil))
+	assert.Equal(t, http.StatusBadRequest, post(t, url+"/deploy", `"a string"`, nil))
+	assert.Equal(t, http.StatusNotFound, post(t, url+"/unknown", `{"n": 1}`, nil))
+
+	resp, err := http.Get(url + "/deploy")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestWebhooksPendingPointsLimit(t *testing.T) {
+	w := &Webhooks{
+		Json:                 []*JSONWebhook{{Path: "/deploy"}},
+		AllowedPendingPoints: 1,
+	}
+	url := startWebhooks(t, w)
+	defer w.Stop()
+
+	assert.Equal(t, http.StatusNoContent, post(t, url+"/deploy", `{"n": 1}`, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, post(t, url+"/deploy", `{"n": 2}`, nil))
+}
+
+const githubDeploymentStatus = `{
+  "deployment_status": {"state": "success", "description": "Deployed"},
+  "deployment": {"sha": "9049f1265b7d", "ref": "master", "environment": "production"},
+  "repository": {"full_name": "influxdb/telegraf"},
+  "sender": {"login": "octocat"}
+}`
+
+func TestGithubWebhook(t *testing.T) {
+	w := &Webhooks{Github: &GithubWebhook{Path: "/github"}}
+	url := startWebhooks(t, w)
+	defer w.Stop()
+
+	status := post(t, url+"/github", githubDeploymentStatus,
+		map[string]string{"X-GitHub-Event": "deployment_status"})
+	assert.Equal(t, http.StatusNoContent, status)
+
+	var acc testutil.Accumulator
+	require.NoError(t, w.Gather(&acc))
+	require.Len(t, acc.Points, 1)
+
+	p := acc.Points[0]
+	assert.Equal(t, "github", p.Measurement)
+	assert.Equal(t, map[string]string{
+		"event":       "deployment_status",
+		"repository":  "influxdb/telegraf",
+		"environment": "production",
+		"state":       "success",
+	}, p.Tags)
+	assert.Equal(t, map[string]interface{}{
+		"sender":      "octocat",
+		"ref":         "master",
+		"sha":         "9049f1265b7d",
+		"description": "Deployed",
+	}, p.Values)
+}
+
+func TestGithubWebhookPush(t *testing.T) {
+	g := &GithubWebhook{}
+	req, _ := http.NewRequest("POST", "/github", nil)
+	req.Header.Set("X-GitHub-Event", "push")
+
+	points, err := g.parse(req, []byte(`{"ref": "refs/heads/master", "after": "abc",
+		"commits": [{}, {}], "repository": {"full_name": "influxdb/telegraf"}}`))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, "refs/heads/master", points[0].tags["ref"])
+	assert.Equal(t, 2, points[0].fields["commits"])
+	assert.Equal(t, "abc", points[0].fields["sha"])
+
+	req.Header.Set("X-GitHub-Event", "ping")
+	points, err = g.parse(req, []byte(`{}`))
+	require.NoError(t, err)
+	assert.Len(t, points, 0)
+
+	req.Header.Del("X-GitHub-Event")
+	_, err = g.parse(req, []byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestGithubWebhookSignature(t *testing.T) {
+	w := &Webhooks{Github: &GithubWebhook{Path: "/github", Secret: "s3cr3t"}}
+	url := startWebhooks(t, w)
+	defer w.Stop()
+
+	mac := hmac.New(sha1.New, []byte("s3cr3t"))
+	mac.Write([]byte(githubDeploymentStatus))
+	signature := "sha1=" + hex.EncodeToString(mac.Sum(nil))
+
+	headers := map[string]string{
+		"X-GitHub-Event":  "deployment_status",
+		"X-Hub-Signature": signature,
+	}
+	assert.Equal(t, http.StatusNoContent, post(t, url+"/github", githubDeploymentStatus, headers))
+
+	headers["X-Hub-Signature"] = "sha1=0000"
+	assert.Equal(t, http.StatusBadRequest, post(t, url+"/github", githubDeploymentStatus, headers))
+
+	delete(headers, "X-Hub-Signature")
+	assert.Equal(t, http.StatusBadRequest, post(t, url+"/github", githubDeploymentStatus, headers))
+}
+
+func TestWebhooksConfig(t *testing.T) {
+	var w Webhooks
+	require.NoError(t, toml.Unmarshal([]byte(`
+service_address = ":1619"
+[github]
+  path = "/github"
+  secret = "s3cr3t"
+[[json]]
+  path = "/deploy"
+  name = "deploy"
+  tag_keys = ["service"]
+`), &w))
+
+	require.NotNil(t, w.Github)
+	assert.Equal(t, "s3cr3t", w.Github.Secret)
+	require.Len(t, w.Json, 1)
+	assert.Equal(t, "/deploy", w.Json[0].Path)
+	assert.Equal(t, []string{"service"}, w.Json[0].TagKeys)
+}
+
+func TestWebhooksMissingPath(t *testing.T) {
+	w := &Webhooks{ServiceAddress: "127.0.0.1:0", Json: []*JSONWebhook{{Name: "deploy"}}}
+	assert.Error(t, w.Start())
+}
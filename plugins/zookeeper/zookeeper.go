@@ -3,11 +3,11 @@ package zookeeper
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/influxdb/telegraf/plugins"
@@ -66,37 +66,51 @@ func (z *Zookeeper) gatherServer(address string, acc plugins.Accumulator) error
 	}
 	defer c.Close()
 
+	c.SetDeadline(time.Now().Add(defaultTimeout))
 	fmt.Fprintf(c, "%s\n", "mntr")
 
-	rdr := bufio.NewReader(c)
+	host, port, _ := net.SplitHostPort(address)
+	return parseMntr(c, map[string]string{"server": host, "port": port}, acc)
+}
+
+var mntrLine = regexp.MustCompile(`^zk_(\w+)\s+([\w\.\-]+)`)
 
-	scanner := bufio.NewScanner(rdr)
+// parseMntr adds the response to the mntr command as a single zookeeper
+// measurement, tagged by the state of the server
+func parseMntr(r io.Reader, tags map[string]string, acc plugins.Accumulator) error {
+	fields := make(map[string]interface{})
 
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		re := regexp.MustCompile(`^zk_(\w+)\s+([\w\.\-]+)`)
-		parts := re.FindStringSubmatch(string(line))
-
-		service := strings.Split(address, ":")
-
-		if len(parts) != 3 || len(service) != 2 {
+		parts := mntrLine.FindStringSubmatch(line)
+		if len(parts) != 3 {
 			return fmt.Errorf("unexpected line in mntr response: %q", line)
 		}
 
-		tags := map[string]string{"server": service[0], "port": service[1]}
+		key := parts[1]
+		sValue := parts[2]
 
-		measurement := strings.TrimPrefix(parts[1], "zk_")
-		sValue := string(parts[2])
+		if key == "server_state" {
+			tags["state"] = sValue
+			continue
+		}
 
 		iVal, err := strconv.ParseInt(sValue, 10, 64)
 		if err == nil {
-			acc.Add(measurement, iVal, tags)
+			fields[key] = iVal
 		} else {
-			acc.Add(measurement, sValue, tags)
+			fields[key] = sValue
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
 
+	if len(fields) > 0 {
+		acc.AddFields("", fields, tags)
+	}
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package zookeeper
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/influxdb/telegraf/testutil"
@@ -8,6 +9,68 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+const mntrResponse = `zk_version	3.4.6-1569965, built on 02/20/2014 09:09 GMT
+zk_avg_latency	1
+zk_max_latency	15
+zk_min_latency	0
+zk_packets_received	1023
+zk_packets_sent	1022
+zk_num_alive_connections	3
+zk_outstanding_requests	0
+zk_server_state	leader
+zk_znode_count	137
+zk_watch_count	12
+zk_ephemerals_count	4
+zk_approximate_data_size	10043
+zk_open_file_descriptor_count	33
+zk_max_file_descriptor_count	1048576
+zk_followers	2
+zk_synced_followers	2
+zk_pending_syncs	0
+`
+
+func TestZookeeperParseMntr(t *testing.T) {
+	var acc testutil.Accumulator
+	tags := map[string]string{"server": "10.0.0.1", "port": "2181"}
+	require.NoError(t, parseMntr(strings.NewReader(mntrResponse), tags, &acc))
+	require.Len(t, acc.Points, 1)
+
+	pt := acc.Points[0]
+	assert.Equal(t, "", pt.Measurement)
+	assert.Equal(t, map[string]string{
+		"server": "10.0.0.1",
+		"port":   "2181",
+		"state":  "leader",
+	}, pt.Tags)
+	assert.Equal(t, map[string]interface{}{
+		"version":                    "3.4.6-1569965",
+		"avg_latency":                int64(1),
+		"max_latency":                int64(15),
+		"min_latency":                int64(0),
+		"packets_received":           int64(1023),
+		"packets_sent":               int64(1022),
+		"num_alive_connections":      int64(3),
+		"outstanding_requests":       int64(0),
+		"znode_count":                int64(137),
+		"watch_count":                int64(12),
+		"ephemerals_count":           int64(4),
+		"approximate_data_size":      int64(10043),
+		"open_file_descriptor_count": int64(33),
+		"max_file_descriptor_count":  int64(1048576),
+		"followers":                  int64(2),
+		"synced_followers":           int64(2),
+		"pending_syncs":              int64(0),
+	}, pt.Values)
+}
+
+func TestZookeeperParseMntrUnexpectedLine(t *testing.T) {
+	var acc testutil.Accumulator
+	err := parseMntr(strings.NewReader("This ZooKeeper instance is not currently serving requests\n"),
+		map[string]string{}, &acc)
+	assert.Error(t, err)
+	assert.Len(t, acc.Points, 0)
+}
+
 func TestZookeeperGeneratesMetrics(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -21,6 +84,7 @@ func TestZookeeperGeneratesMetrics(t *testing.T) {
 
 	err := z.Gather(&acc)
 	require.NoError(t, err)
+	require.Len(t, acc.Points, 1)
 
 	intMetrics := []string{
 		"avg_latency",
@@ -38,6 +102,7 @@ func TestZookeeperGeneratesMetrics(t *testing.T) {
 	}
 
 	for _, metric := range intMetrics {
-		assert.True(t, acc.HasIntValue(metric), metric)
+		assert.IsType(t, int64(0), acc.Points[0].Values[metric], metric)
 	}
+	assert.NotEmpty(t, acc.Points[0].Tags["state"])
 }
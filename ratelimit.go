@@ -0,0 +1,50 @@
+package telegraf
+
+import (
+	"time"
+)
+
+// rateLimiter is a token bucket of points, refilled at rate points per second
+// up to a burst of one second of points. It starts full.
+type rateLimiter struct {
+	rate   int
+	tokens float64
+	last   time.Time
+
+	// clock of the bucket, replaced in tests
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	return &rateLimiter{
+		rate:   rate,
+		tokens: float64(rate),
+		last:   time.Now(),
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// burst is the largest number of points a single wait can take
+func (l *rateLimiter) burst() int {
+	return l.rate
+}
+
+// wait blocks until n points, at most burst, can be written and takes them
+// from the bucket
+func (l *rateLimiter) wait(n int) {
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+	if l.tokens > float64(l.rate) {
+		l.tokens = float64(l.rate)
+	}
+	l.last = now
+
+	if missing := float64(n) - l.tokens; missing > 0 {
+		l.sleep(time.Duration(missing / float64(l.rate) * float64(time.Second)))
+		l.tokens = float64(n)
+		l.last = l.now()
+	}
+	l.tokens -= float64(n)
+}
@@ -0,0 +1,111 @@
+package telegraf
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/plugins"
+
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// RunningInput wraps a configured plugin. Every Gather of the plugin goes
+// through it, so that the plugin's filters, measurement prefix and interval
+// are applied the same way wherever it is gathered from.
+type RunningInput struct {
+	Name   string
+	Plugin plugins.Plugin
+	Config *ConfiguredPlugin
+
+	sync.Mutex
+	gatherTime time.Duration
+	gathered   bool
+}
+
+// NewRunningInput returns a RunningInput for the given plugin, config may be
+// nil.
+func NewRunningInput(
+	name string,
+	plugin plugins.Plugin,
+	config *ConfiguredPlugin,
+) *RunningInput {
+	if config == nil {
+		config = &ConfiguredPlugin{Name: name}
+	}
+	return &RunningInput{
+		Name:   name,
+		Plugin: plugin,
+		Config: config,
+	}
+}
+
+// Interval returns the plugin's own collection interval, 0 when it is
+// gathered on the agent's interval.
+func (ri *RunningInput) Interval() time.Duration {
+	return ri.Config.Interval
+}
+
+// Accumulator returns an accumulator that applies the plugin's filters,
+// prefix, tags and the given default tags before sending points to
+// pointChan.
+func (ri *RunningInput) Accumulator(
+	pointChan chan *client.Point,
+	defaultTags map[string]string,
+	debug bool,
+) Accumulator {
+	acc := NewAccumulator(ri.Config, pointChan)
+	acc.SetDebug(debug)
+	acc.SetPrefix(ri.Name + "_")
+	acc.SetDefaultTags(ri.defaultTags(defaultTags))
+	return acc
+}
+
+// defaultTags returns the plugin's tags merged over the given default tags,
+// which are left unchanged
+func (ri *RunningInput) defaultTags(tags map[string]string) map[string]string {
+	if ri.Config == nil || len(ri.Config.Tags) == 0 {
+		return tags
+	}
+
+	merged := make(map[string]string, len(tags)+len(ri.Config.Tags))
+	for k, v := range tags {
+		merged[k] = v
+	}
+	for k, v := range ri.Config.Tags {
+		merged[k] = v
+	}
+	return merged
+}
+
+// Gather runs a single Gather of the plugin into acc, recording how long it
+// took.
+func (ri *RunningInput) Gather(acc Accumulator) error {
+	start := time.Now()
+	err := ri.Plugin.Gather(acc)
+	elapsed := time.Since(start)
+
+	gathers.Add(1)
+	if err != nil {
+		gatherErrors.Add(1)
+	}
+
+	ri.Lock()
+	ri.gatherTime = elapsed
+	ri.gathered = true
+	ri.Unlock()
+	return err
+}
+
+// Gathered returns whether the plugin was gathered before
+func (ri *RunningInput) Gathered() bool {
+	ri.Lock()
+	defer ri.Unlock()
+	return ri.gathered
+}
+
+// GatherTime returns the duration of the last Gather
+func (ri *RunningInput) GatherTime() time.Duration {
+	ri.Lock()
+	defer ri.Unlock()
+	return ri.gatherTime
+}
@@ -0,0 +1,80 @@
+package telegraf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/influxdb/telegraf/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type twoMeasurementsPlugin struct{}
+
+func (p *twoMeasurementsPlugin) Description() string  { return "" }
+func (p *twoMeasurementsPlugin) SampleConfig() string { return "" }
+func (p *twoMeasurementsPlugin) Gather(acc plugins.Accumulator) error {
+	acc.Add("kept", 1, nil)
+	acc.Add("dropped", 2, nil)
+	return nil
+}
+
+func gatherPoints(t *testing.T, ri *RunningInput) []*client.Point {
+	pointChan := make(chan *client.Point, 10)
+	acc := ri.Accumulator(pointChan, map[string]string{"host": "localhost"}, false)
+	require.NoError(t, ri.Gather(acc))
+	close(pointChan)
+
+	var points []*client.Point
+	for pt := range pointChan {
+		points = append(points, pt)
+	}
+	return points
+}
+
+func TestRunningInput_Drop(t *testing.T) {
+	ri := NewRunningInput("test", &twoMeasurementsPlugin{},
+		&ConfiguredPlugin{Name: "test", Drop: []string{"dropped"}})
+
+	points := gatherPoints(t, ri)
+	require.Len(t, points, 1)
+	assert.Equal(t, "test_kept", points[0].Name())
+	assert.Equal(t, "localhost", points[0].Tags()["host"])
+}
+
+func TestRunningInput_NoConfig(t *testing.T) {
+	ri := NewRunningInput("test", &twoMeasurementsPlugin{}, nil)
+	assert.Equal(t, time.Duration(0), ri.Interval())
+
+	points := gatherPoints(t, ri)
+	require.Len(t, points, 2)
+	assert.Equal(t, "test_kept", points[0].Name())
+	assert.Equal(t, "test_dropped", points[1].Name())
+}
+
+func TestRunningInput_Tags(t *testing.T) {
+	defaultTags := map[string]string{"host": "localhost", "dc": "denver-1"}
+	tagged := NewRunningInput("tagged", &twoMeasurementsPlugin{},
+		&ConfiguredPlugin{Name: "tagged", Tags: map[string]string{"dc": "east", "cluster": "a"}})
+	other := NewRunningInput("other", &twoMeasurementsPlugin{}, nil)
+
+	pointChan := make(chan *client.Point, 10)
+	require.NoError(t, tagged.Gather(tagged.Accumulator(pointChan, defaultTags, false)))
+	require.NoError(t, other.Gather(other.Accumulator(pointChan, defaultTags, false)))
+	close(pointChan)
+
+	var points []*client.Point
+	for pt := range pointChan {
+		points = append(points, pt)
+	}
+	require.Len(t, points, 4)
+	for _, pt := range points[:2] {
+		assert.Equal(t, map[string]string{"host": "localhost", "dc": "east", "cluster": "a"},
+			pt.Tags())
+	}
+	for _, pt := range points[2:] {
+		assert.Equal(t, map[string]string{"host": "localhost", "dc": "denver-1"}, pt.Tags())
+	}
+	assert.Equal(t, map[string]string{"host": "localhost", "dc": "denver-1"}, defaultTags)
+}
@@ -0,0 +1,168 @@
+package telegraf
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/influxdb/telegraf/outputs"
+
+	"github.com/influxdb/influxdb/client/v2"
+)
+
+// RunningOutput wraps a configured output with the buffer of points waiting
+// to be written to it and statistics about its writes.
+type RunningOutput struct {
+	Name   string
+	Output outputs.Output
+	Config *ConfiguredOutput
+
+	// MetricBufferLimit is the maximum number of buffered points, the oldest
+	// points are dropped once it is reached. 0 means no limit.
+	MetricBufferLimit int
+
+	// WriteRateLimit is the maximum number of points written per second,
+	// bursts are written in batches of a second of points. 0 means no limit.
+	WriteRateLimit int
+	// limiter of the writes, only used by Write
+	limiter *rateLimiter
+
+	sync.Mutex
+	points    []*client.Point
+	dropped   int
+	failures  int
+	written   int
+	lastFlush time.Time
+}
+
+// NewRunningOutput returns a RunningOutput for the given output, config may
+// be nil.
+func NewRunningOutput(
+	name string,
+	output outputs.Output,
+	config *ConfiguredOutput,
+) *RunningOutput {
+	if config == nil {
+		config = &ConfiguredOutput{Name: name}
+	}
+	return &RunningOutput{
+		Name:              name,
+		Output:            output,
+		Config:            config,
+		MetricBufferLimit: config.MetricBufferLimit,
+	}
+}
+
+// AddPoint buffers a point for the next write
+func (ro *RunningOutput) AddPoint(pt *client.Point) {
+	ro.Lock()
+	defer ro.Unlock()
+	ro.points = append(ro.points, pt)
+	ro.trim()
+}
+
+// trim drops the oldest points above the buffer limit, ro must be locked
+func (ro *RunningOutput) trim() {
+	if ro.MetricBufferLimit > 0 && len(ro.points) > ro.MetricBufferLimit {
+		n := len(ro.points) - ro.MetricBufferLimit
+		ro.points = ro.points[n:]
+		ro.dropped += n
+	}
+}
+
+// Write writes every buffered point to the output. When the write fails the
+// points are kept, ahead of any point added in the meantime, to be written
+// by the next call.
+func (ro *RunningOutput) Write() error {
+	ro.Lock()
+	points, dropped := ro.points, ro.dropped
+	ro.points = nil
+	ro.dropped = 0
+	ro.Unlock()
+
+	if dropped > 0 {
+		log.Printf("Metric buffer of output [%s] is full, dropped %d metrics\n",
+			ro.Name, dropped)
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	n, err := ro.write(points)
+	elapsed := time.Since(start)
+
+	writes.Add(1)
+	ro.Lock()
+	defer ro.Unlock()
+	ro.written += n
+	pointsWritten.Add(int64(n))
+	if err != nil {
+		writeErrors.Add(1)
+		ro.failures++
+		ro.points = append(points[n:], ro.points...)
+		ro.trim()
+		return err
+	}
+	ro.lastFlush = time.Now()
+	log.Printf("Flushed %d metrics to output %s in %s\n", len(points), ro.Name, elapsed)
+	return nil
+}
+
+// write writes points to the output, in batches waiting for the rate limit
+// when WriteRateLimit is set. It returns the number of points written before
+// a batch failed.
+func (ro *RunningOutput) write(points []*client.Point) (int, error) {
+	if ro.WriteRateLimit <= 0 {
+		if err := ro.Output.Write(points); err != nil {
+			return 0, err
+		}
+		return len(points), nil
+	}
+
+	if ro.limiter == nil || ro.limiter.rate != ro.WriteRateLimit {
+		ro.limiter = newRateLimiter(ro.WriteRateLimit)
+	}
+	written := 0
+	for written < len(points) {
+		batch := points[written:]
+		if len(batch) > ro.limiter.burst() {
+			batch = batch[:ro.limiter.burst()]
+		}
+		ro.limiter.wait(len(batch))
+		if err := ro.Output.Write(batch); err != nil {
+			return written, err
+		}
+		written += len(batch)
+	}
+	return written, nil
+}
+
+// Buffered returns the number of points waiting to be written
+func (ro *RunningOutput) Buffered() int {
+	ro.Lock()
+	defer ro.Unlock()
+	return len(ro.points)
+}
+
+// Failures returns the number of failed writes
+func (ro *RunningOutput) Failures() int {
+	ro.Lock()
+	defer ro.Unlock()
+	return ro.failures
+}
+
+// Written returns the number of points successfully written
+func (ro *RunningOutput) Written() int {
+	ro.Lock()
+	defer ro.Unlock()
+	return ro.written
+}
+
+// LastFlush returns the time of the last successful write, the zero time if
+// there was none.
+func (ro *RunningOutput) LastFlush() time.Time {
+	ro.Lock()
+	defer ro.Unlock()
+	return ro.lastFlush
+}
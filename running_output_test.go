@@ -0,0 +1,184 @@
+package telegraf
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/client/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingOutput struct {
+	sync.Mutex
+	fail    bool
+	written []*client.Point
+}
+
+func (o *failingOutput) Connect() error       { return nil }
+func (o *failingOutput) Close() error         { return nil }
+func (o *failingOutput) Description() string  { return "" }
+func (o *failingOutput) SampleConfig() string { return "" }
+func (o *failingOutput) Write(points []*client.Point) error {
+	o.Lock()
+	defer o.Unlock()
+	if o.fail {
+		return errors.New("write failed")
+	}
+	o.written = append(o.written, points...)
+	return nil
+}
+
+func testPoint(value int) *client.Point {
+	return client.NewPoint("test", nil,
+		map[string]interface{}{"value": value}, time.Now())
+}
+
+func TestRunningOutput_MetricBufferLimit(t *testing.T) {
+	out := &failingOutput{}
+	ro := NewRunningOutput("limited", out, &ConfiguredOutput{MetricBufferLimit: 3})
+	for i := 0; i < 5; i++ {
+		ro.AddPoint(testPoint(i))
+	}
+	assert.Equal(t, 3, ro.Buffered())
+
+	require.NoError(t, ro.Write())
+	require.Len(t, out.written, 3)
+	assert.Equal(t, int64(2), out.written[0].Fields()["value"])
+	assert.Equal(t, 0, ro.Buffered())
+	assert.Equal(t, 3, ro.Written())
+}
+
+func TestRunningOutput_FailedWrite(t *testing.T) {
+	out := &failingOutput{fail: true}
+	ro := NewRunningOutput("failing", out, nil)
+	ro.AddPoint(testPoint(0))
+	ro.AddPoint(testPoint(1))
+
+	assert.Error(t, ro.Write())
+	assert.Equal(t, 1, ro.Failures())
+	assert.Equal(t, 2, ro.Buffered())
+	assert.True(t, ro.LastFlush().IsZero())
+
+	ro.AddPoint(testPoint(2))
+	assert.Error(t, ro.Write())
+	assert.Equal(t, 2, ro.Failures())
+	assert.Equal(t, 3, ro.Buffered())
+
+	out.fail = false
+	require.NoError(t, ro.Write())
+	assert.Equal(t, 2, ro.Failures())
+	assert.Equal(t, 0, ro.Buffered())
+	assert.Equal(t, 3, ro.Written())
+	assert.False(t, ro.LastFlush().IsZero())
+
+	// retained points are written in the order they were added
+	require.Len(t, out.written, 3)
+	for i, pt := range out.written {
+		assert.Equal(t, int64(i), pt.Fields()["value"])
+	}
+}
+
+func TestRunningOutput_FailedWriteBufferLimit(t *testing.T) {
+	out := &failingOutput{fail: true}
+	ro := NewRunningOutput("failing", out, &ConfiguredOutput{MetricBufferLimit: 2})
+	ro.AddPoint(testPoint(0))
+	ro.AddPoint(testPoint(1))
+	assert.Error(t, ro.Write())
+
+	// the oldest retained point makes room for the new one
+	ro.AddPoint(testPoint(2))
+	assert.Equal(t, 2, ro.Buffered())
+
+	out.fail = false
+	require.NoError(t, ro.Write())
+	require.Len(t, out.written, 2)
+	assert.Equal(t, int64(1), out.written[0].Fields()["value"])
+	assert.Equal(t, int64(2), out.written[1].Fields()["value"])
+}
+
+func TestRunningOutput_EmptyWrite(t *testing.T) {
+	out := &failingOutput{fail: true}
+	ro := NewRunningOutput("empty", out, nil)
+
+	assert.NoError(t, ro.Write())
+	assert.Equal(t, 0, ro.Failures())
+}
+
+func TestAgent_FlushKeepsFailedPoints(t *testing.T) {
+	out := &failingOutput{fail: true}
+	a := &Agent{FlushRetries: 0}
+	ro := NewRunningOutput("failing", out, nil)
+	ro.AddPoint(testPoint(0))
+
+	a.flush(ro, make(chan struct{}))
+	assert.Equal(t, 1, ro.Failures())
+	assert.Equal(t, 1, ro.Buffered())
+}
+
+// fakeClock is the clock of a rateLimiter, advanced by the limiter's sleeps
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+	c.slept += d
+}
+
+func TestRunningOutput_WriteRateLimit(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	out := &failingOutput{}
+	ro := NewRunningOutput("limited", out, nil)
+	ro.WriteRateLimit = 100
+	ro.limiter = newRateLimiter(100)
+	ro.limiter.last, ro.limiter.now, ro.limiter.sleep = clock.now, clock.Now, clock.Sleep
+
+	for i := 0; i < 250; i++ {
+		ro.AddPoint(testPoint(i))
+	}
+	require.NoError(t, ro.Write())
+
+	// a burst of a second of points, then 150 points at 100 points per second
+	assert.Equal(t, 1500*time.Millisecond, clock.slept)
+	require.Len(t, out.written, 250)
+	for i, pt := range out.written {
+		assert.Equal(t, int64(i), pt.Fields()["value"])
+	}
+
+	// the bucket refills while the output is idle
+	clock.now = clock.now.Add(time.Second)
+	clock.slept = 0
+	for i := 0; i < 100; i++ {
+		ro.AddPoint(testPoint(i))
+	}
+	require.NoError(t, ro.Write())
+	assert.Equal(t, time.Duration(0), clock.slept)
+
+	for i := 0; i < 50; i++ {
+		ro.AddPoint(testPoint(i))
+	}
+	require.NoError(t, ro.Write())
+	assert.Equal(t, 500*time.Millisecond, clock.slept)
+	assert.Equal(t, 400, ro.Written())
+}
+
+func TestRunningOutput_WriteRateLimitFailedBatch(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	out := &failingOutput{fail: true}
+	ro := NewRunningOutput("limited", out, nil)
+	ro.WriteRateLimit = 10
+	ro.limiter = newRateLimiter(10)
+	ro.limiter.last, ro.limiter.now, ro.limiter.sleep = clock.now, clock.Now, clock.Sleep
+
+	for i := 0; i < 25; i++ {
+		ro.AddPoint(testPoint(i))
+	}
+	assert.Error(t, ro.Write())
+	assert.Equal(t, 25, ro.Buffered())
+	assert.Equal(t, 0, ro.Written())
+}
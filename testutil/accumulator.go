@@ -15,10 +15,19 @@ type Point struct {
 	Time        time.Time
 }
 
+// Event defines a single event
+type Event struct {
+	Title string
+	Text  string
+	Tags  map[string]string
+	Time  time.Time
+}
+
 // Accumulator defines a mocked out accumulator
 type Accumulator struct {
 	sync.Mutex
 	Points []*Point
+	Events []*Event
 }
 
 // Add adds a measurement point to the accumulator
@@ -33,12 +42,17 @@ func (a *Accumulator) Add(
 	if tags == nil {
 		tags = map[string]string{}
 	}
+	var timestamp time.Time
+	if len(t) > 0 {
+		timestamp = t[0]
+	}
 	a.Points = append(
 		a.Points,
 		&Point{
 			Measurement: measurement,
 			Values:      map[string]interface{}{"value": value},
 			Tags:        tags,
+			Time:        timestamp,
 		},
 	)
 }
@@ -69,6 +83,25 @@ func (a *Accumulator) AddFields(
 	)
 }
 
+// AddEvent adds an event to the accumulator
+func (a *Accumulator) AddEvent(
+	title string,
+	text string,
+	tags map[string]string,
+	timestamp ...time.Time,
+) {
+	a.Lock()
+	defer a.Unlock()
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	t := time.Now()
+	if len(timestamp) > 0 {
+		t = timestamp[0]
+	}
+	a.Events = append(a.Events, &Event{Title: title, Text: text, Tags: tags, Time: t})
+}
+
 func (a *Accumulator) SetDefaultTags(tags map[string]string) {
 	// stub for implementing Accumulator interface.
 }